@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/csvstore"
+	"fund-manager/internal/dividends"
+	"fund-manager/internal/fno"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/services"
+	"fund-manager/internal/sqlitestore"
+	"os"
+)
+
+const (
+	backendPostgres = "postgres"
+	backendCSV      = "csv"
+	backendSQLite   = "sqlite"
+)
+
+// openQueries opens the read side of the configured backend. window limits
+// what the csv backend reads into memory; the others ignore it.
+func openQueries(ctx context.Context, cfg config.File, window pricestore.Options) (services.QueryInterface, func(), error) {
+	switch cfg.Database.Backend {
+	case backendPostgres, "":
+		pool, queries, err := connect(ctx, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return queries, pool.Close, nil
+	case backendCSV:
+		store, err := csvstore.Open(cfg.Data.StocksDir, cfg.Data.DailyDir, cfg.Data.IndexDir, cfg.Data.FnoList, window)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, func() {}, nil
+	case backendSQLite:
+		db, err := sqlitestore.Open(cfg.Database.SQLite)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, func() { db.Close() }, nil
+	}
+	return nil, nil, fmt.Errorf("unknown database.backend %q (want %s, %s or %s)", cfg.Database.Backend, backendPostgres, backendCSV, backendSQLite)
+}
+
+// loadLots reads the F&O lot sizes from Postgres, where every imported list
+// adds to the history, or else from the data.fno_list file. Without either
+// it returns nil and F&O membership falls back to the stocks' flag.
+func loadLots(ctx context.Context, cfg config.File, queries services.QueryInterface) (*fno.Table, error) {
+	if lq, ok := queries.(fno.LotQueries); ok {
+		lots, err := fno.LoadTable(ctx, lq)
+		if err != nil || lots.Len() > 0 {
+			return lots, err
+		}
+	}
+	if _, err := os.Stat(cfg.Data.FnoList); err != nil {
+		return nil, nil
+	}
+	underlyings, err := fno.ReadMarketLots(cfg.Data.FnoList)
+	if err != nil {
+		return nil, err
+	}
+	return fno.NewTable(underlyings), nil
+}
+
+// loadDividends reads the dividends from Postgres, or else from the
+// data.dividends file. Without either it returns nil.
+func loadDividends(ctx context.Context, cfg config.File, queries services.QueryInterface) (*dividends.Table, error) {
+	if dq, ok := queries.(dividends.Queries); ok {
+		divs, err := dividends.LoadTable(ctx, dq)
+		if err != nil || divs.Len() > 0 {
+			return divs, err
+		}
+	}
+	if _, err := os.Stat(cfg.Data.Dividends); err != nil {
+		return nil, nil
+	}
+	divs, err := dividends.ReadFile(cfg.Data.Dividends)
+	if err != nil {
+		return nil, err
+	}
+	return dividends.NewTable(divs), nil
+}
+
+// requirePostgres rejects commands that write tables only Postgres has.
+func requirePostgres(cfg config.File, what string) error {
+	if b := cfg.Database.Backend; b != backendPostgres && b != "" {
+		return fmt.Errorf("%s needs database.backend %s, not %s", what, backendPostgres, b)
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/backtest"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/runstore"
+	"fund-manager/internal/services"
+	"os"
+	"time"
+)
+
+func runBacktest(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("backtest", "Rebalance periodically into the top-N stocks by trailing return and\nreport CAGR, drawdown and trade statistics.\n\nWith a spec name or path (fundmgr backtest [flags] <spec>) the run is\ndescribed by <specs-dir>/<spec>.yaml; flags given explicitly override it.\nEvery run is archived under <runs-dir>/<name>/<run id>/ and stored in the\ndatabase; see 'fundmgr runs'.")
+	bc := &cfg.Backtest
+	specName := fs.String("spec", "", "backtest spec name (in -specs-dir) or path")
+	fs.StringVar(&bc.Start, "start", bc.Start, opt("backtest", "start", "first rebalance date YYYY-MM-DD (default one year before -end)"))
+	fs.StringVar(&bc.End, "end", bc.End, opt("backtest", "end", "last date YYYY-MM-DD (default today)"))
+	fs.IntVar(&bc.TopN, "top-n", bc.TopN, opt("backtest", "top_n", "number of stocks to hold"))
+	fs.IntVar(&bc.LookbackMonths, "lookback", bc.LookbackMonths, opt("backtest", "lookback_months", "momentum lookback in months"))
+	fs.IntVar(&bc.RebalanceMonths, "every", bc.RebalanceMonths, opt("backtest", "rebalance_months", "months between rebalances"))
+	fs.StringVar(&bc.Weighting, "weighting", bc.Weighting, opt("backtest", "weighting", "weighting of new entries: equal, rank or momentum"))
+	fs.Float64Var(&bc.CommissionBps, "commission-bps", bc.CommissionBps, opt("backtest", "commission_bps", "brokerage per side, basis points of traded value"))
+	fs.Float64Var(&bc.SlippageBps, "slippage-bps", bc.SlippageBps, opt("backtest", "slippage_bps", "slippage per side, basis points of traded value"))
+	fs.Float64Var(&bc.TaxBps, "tax-bps", bc.TaxBps, opt("backtest", "tax_bps", "transaction taxes per side, basis points of traded value"))
+	fs.Float64Var(&bc.InitialCapital, "capital", bc.InitialCapital, opt("backtest", "initial_capital", "initial capital"))
+	fs.StringVar(&bc.Dividends, "dividends", bc.Dividends, opt("backtest", "dividends", "dividends of held stocks: cash, reinvest, or empty to ignore them"))
+	fs.Var(listFlag{&cfg.Universe.ScriptTypes}, "universe", opt("universe", "script_types", "comma separated scriptTypes (large, mid, small, micro, mf)"))
+	fs.Var(listFlag{&cfg.Universe.Industries}, "industry", "comma separated industries or fund categories to rank within")
+	fs.StringVar(&bc.TradesOut, "trades-out", bc.TradesOut, opt("backtest", "trades_out", "trade log CSV to write, empty to skip"))
+	fs.StringVar(&bc.SpecsDir, "specs-dir", bc.SpecsDir, opt("backtest", "specs_dir", "directory of backtest spec files"))
+	fs.StringVar(&bc.RunsDir, "runs-dir", bc.RunsDir, opt("backtest", "runs_dir", "directory to archive runs in, empty to skip"))
+	fs.BoolVar(&bc.Preload, "preload", bc.Preload, opt("backtest", "preload", "load the universe's prices into memory once instead of querying per rebalance"))
+	fs.BoolVar(&bc.StoreDB, "store-db", bc.StoreDB, opt("backtest", "store_db", "store the run in the database for 'fundmgr runs'"))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *specName == "" && fs.NArg() > 0 {
+		*specName = fs.Arg(0)
+	}
+
+	spec, err := resolveSpec(cfg, fs, *specName)
+	if err != nil {
+		return err
+	}
+
+	queries, closeQueries, err := openQueries(ctx, cfg, spec.PriceWindow())
+	if err != nil {
+		return err
+	}
+	defer closeQueries()
+
+	svc := services.NewService(queries)
+	meta := backtest.RunMeta{StartedAt: time.Now()}
+	meta.GitCommit, meta.GitDirty = backtest.GitCommit()
+	// the csv backend holds only the spec's window, so its fingerprint
+	// counts that
+	backend, window := cfg.Database.Backend, ""
+	if backend == "" {
+		backend = backendPostgres
+	}
+	if backend == backendCSV {
+		window = spec.PriceWindow().String()
+	}
+	if meta.Data, err = backtest.Fingerprint(ctx, svc, backend, window); err != nil {
+		return err
+	}
+
+	// the csv backend is an in-memory store already
+	if bc.Preload && cfg.Database.Backend != backendCSV {
+		store, err := pricestore.Load(ctx, queries, spec.PriceWindow())
+		if err != nil {
+			return err
+		}
+		svc = services.NewService(store)
+	}
+
+	// a futures overlay runs next to the same basket held in cash
+	variants := []backtest.Spec{spec}
+	if spec.Overlay() {
+		if svc.Lots, err = loadLots(ctx, cfg, queries); err != nil {
+			return err
+		}
+		if svc.Lots.Len() == 0 {
+			return fmt.Errorf("futures need F&O lot sizes: run 'fundmgr universe' or set data.fno_list")
+		}
+		variants = []backtest.Spec{spec.CashVariant(), spec}
+	}
+	if spec.Dividends != "" {
+		if svc.Dividends, err = loadDividends(ctx, cfg, queries); err != nil {
+			return err
+		}
+		if svc.Dividends.Len() == 0 {
+			return fmt.Errorf("dividends need a dividends table: run 'fundmgr ingest' or set data.dividends")
+		}
+	}
+
+	var runs []backtest.Run
+	for _, variant := range variants {
+		meta := meta
+		btCfg, err := variant.Config(svc)
+		if err != nil {
+			return err
+		}
+
+		result := backtest.RunBacktest(ctx, btCfg)
+		meta.FinishedAt = time.Now()
+		metrics := backtest.MetricsOf(result, btCfg.InitialCapital)
+
+		fmt.Printf("Backtest %s completed.\n", variant.Name)
+		if len(variants) == 1 {
+			printMetrics(metrics)
+		}
+		if result.TaxYears != nil {
+			fmt.Println()
+			if err := printTaxYears(result.TaxYears); err != nil {
+				return err
+			}
+		}
+		meta.Name = variant.Name
+		runs = append(runs, backtest.Run{Meta: backtest.RunMeta{Name: variant.Name, RunID: variantLabel(variant)}, Metrics: metrics})
+
+		if err := saveBacktest(ctx, cfg, variant, meta, result); err != nil {
+			return err
+		}
+	}
+	if len(runs) > 1 {
+		fmt.Println()
+		return runstore.WriteComparison(os.Stdout, runs)
+	}
+	return nil
+}
+
+func variantLabel(spec backtest.Spec) string {
+	if spec.Futures == nil {
+		return backtest.FuturesCash
+	}
+	return spec.Futures.Mode
+}
+
+// saveBacktest writes the trade log, archive and database copy of a run as
+// configured. Only the last variant's trades end up in -trades-out.
+func saveBacktest(ctx context.Context, cfg config.File, spec backtest.Spec, meta backtest.RunMeta, result backtest.BacktestResult) error {
+	bc := cfg.Backtest
+	if bc.TradesOut != "" {
+		if err := backtest.ExportTradeLogsToCSV(bc.TradesOut, result.TradeLogs); err != nil {
+			return fmt.Errorf("failed to export trade logs: %w", err)
+		}
+		fmt.Printf("Trade logs exported to %s\n", bc.TradesOut)
+	}
+
+	if bc.RunsDir != "" {
+		dir, err := backtest.SaveRun(bc.RunsDir, spec, meta, result)
+		if err != nil {
+			return fmt.Errorf("failed to archive run: %w", err)
+		}
+		fmt.Printf("Run archived to %s\n", dir)
+	}
+
+	if bc.StoreDB {
+		if err := requirePostgres(cfg, "storing runs"); err != nil {
+			fmt.Printf("Run not stored: %v\n", err)
+			return nil
+		}
+		pool, _, err := connect(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+
+		id, err := runstore.Save(ctx, pool, spec, meta, result)
+		if err != nil {
+			return fmt.Errorf("failed to store run: %w", err)
+		}
+		fmt.Printf("Run stored as %s\n", runstore.FormatID(id))
+	}
+	return nil
+}
+
+// resolveSpec loads the named spec, or builds one from the config when no
+// name is given, then applies the flags that were set on the command line.
+func resolveSpec(cfg config.File, fs *flag.FlagSet, name string) (backtest.Spec, error) {
+	bc := cfg.Backtest
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var spec backtest.Spec
+	if name != "" {
+		path, err := backtest.FindSpec(bc.SpecsDir, name)
+		if err != nil {
+			return spec, err
+		}
+		if spec, err = backtest.LoadSpec(path); err != nil {
+			return spec, err
+		}
+	} else {
+		end, err := parseDate("end", bc.End, today())
+		if err != nil {
+			return spec, err
+		}
+		start, err := parseDate("start", bc.Start, end.AddDate(-1, 0, 0))
+		if err != nil {
+			return spec, err
+		}
+		bc.Start, bc.End = start.Format("2006-01-02"), end.Format("2006-01-02")
+		// everything comes from config and flags already
+		for _, f := range []string{"start", "end", "top-n", "lookback", "every", "weighting", "commission-bps", "slippage-bps", "tax-bps", "capital", "dividends", "universe"} {
+			set[f] = true
+		}
+	}
+
+	if set["start"] {
+		spec.Dates.Start = bc.Start
+	}
+	if set["end"] {
+		spec.Dates.End = bc.End
+	}
+	if set["top-n"] {
+		spec.Strategy.TopN = bc.TopN
+	}
+	if set["lookback"] {
+		spec.Strategy.LookbackMonths = bc.LookbackMonths
+	}
+	if set["every"] {
+		spec.Schedule.EveryMonths = bc.RebalanceMonths
+	}
+	if set["weighting"] {
+		spec.Weighting = bc.Weighting
+	}
+	if set["commission-bps"] {
+		spec.Costs.CommissionBps = bc.CommissionBps
+	}
+	if set["slippage-bps"] {
+		spec.Costs.SlippageBps = bc.SlippageBps
+	}
+	if set["tax-bps"] {
+		spec.Costs.TaxBps = bc.TaxBps
+	}
+	if set["capital"] {
+		spec.InitialCapital = bc.InitialCapital
+	}
+	if set["dividends"] {
+		spec.Dividends = bc.Dividends
+	}
+	if set["universe"] {
+		spec.Universe.ScriptTypes = cfg.Universe.ScriptTypes
+	}
+	if set["industry"] {
+		spec.Universe.Industries = cfg.Universe.Industries
+	}
+	return spec.Resolve()
+}
+
+func printMetrics(m backtest.Metrics) {
+	fmt.Printf("CAGR: %.2f%%\n", m.CAGR*100)
+	fmt.Printf("Max Drawdown: %.2f%%\n", m.MaxDrawdown*100)
+	fmt.Printf("Total Trades: %d\n", m.TotalTrades)
+	fmt.Printf("Winning Trades: %d\n", m.WinningTrades)
+	fmt.Printf("Win Rate: %.2f%%\n", m.WinRate*100)
+	fmt.Printf("Average Profit: %.2f\n", m.AverageProfit)
+	fmt.Printf("Total Costs: %.2f\n", m.TotalCosts)
+	if m.Dividends != 0 {
+		fmt.Printf("Dividends: %.2f\n", m.Dividends)
+		fmt.Printf("Price CAGR: %.2f%%\n", m.PriceCAGR*100)
+	}
+	if m.TaxPaid != 0 {
+		fmt.Printf("Tax Paid: %.2f\n", m.TaxPaid)
+	}
+	if m.XIRR != 0 || m.TWR != 0 {
+		fmt.Printf("Net Invested: %.2f\n", m.NetInvested)
+		fmt.Printf("XIRR: %.2f%%\n", m.XIRR*100)
+		fmt.Printf("Time-Weighted Return: %.2f%%\n", m.TWR*100)
+		fmt.Printf("Money-Weighted Return: %.2f%%\n", m.MWR*100)
+	}
+	fmt.Printf("Net Profit: %.2f\n", m.NetProfit)
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/amfi"
+	"fund-manager/internal/ingest"
+	"fund-manager/internal/repository"
+	"fund-manager/internal/services"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func runFunds(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("funds", "Import AMFI mutual fund files and compare schemes within a category.\n\n  fundmgr funds import [file|dir ...]\n  fundmgr funds list [-category c] [-amc a] [-search text]\n  fundmgr funds categories\n  fundmgr funds compare -category c [-benchmark symbol] [-date d]\n\nimport reads scheme masters (SchemeData CSV) and NAV reports (NAVAll.txt\nor the NAV history report), by default every file in -amfi-dir. Schemes\nbecome instruments MF<code> of script type mf with their category as the\nindustry: screen, backtest and portfolio take them like stocks, e.g.\n-universe mf -industry \"Other Scheme - Index Funds\".")
+	fs.StringVar(&cfg.Data.AMFIDir, "amfi-dir", cfg.Data.AMFIDir, opt("data", "amfi_dir", "directory of AMFI scheme masters and NAV reports"))
+	category := fs.String("category", "", "scheme category, e.g. \"Equity Scheme - Large Cap Fund\"")
+	amc := fs.String("amc", "", "fund house, matched as a substring")
+	search := fs.String("search", "", "text the scheme name contains")
+	plan := fs.String("plan", "all", "plans to list or compare: direct, regular or all")
+	idcw := fs.Bool("idcw", false, "include IDCW (dividend) options")
+	benchmark := fs.String("benchmark", "", "symbol to compare the category against, e.g. an index fund MF<code>")
+	dateFlag := fs.String("date", "", "date to compare as of, YYYY-MM-DD (default today)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing subcommand")
+	}
+	sub, rest := fs.Arg(0), fs.Args()[1:]
+	// allow flags after the subcommand as well
+	if err := fs.Parse(rest); err != nil {
+		return err
+	}
+	rest = fs.Args()
+
+	date, err := parseDate("date", *dateFlag, today())
+	if err != nil {
+		return err
+	}
+	switch *plan {
+	case "direct", "regular", "all":
+	default:
+		return fmt.Errorf("unknown -plan %q (want direct, regular or all)", *plan)
+	}
+	if err := requirePostgres(cfg, "funds"); err != nil {
+		return err
+	}
+	pool, queries, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	if sub == "import" {
+		if len(rest) == 0 {
+			rest = []string{cfg.Data.AMFIDir}
+		}
+		paths, err := amfiFiles(rest)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no AMFI files in %s", strings.Join(rest, ", "))
+		}
+		return ingest.ImportAMFI(ctx, queries, paths)
+	}
+
+	all, err := queries.GetMfSchemes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get schemes: %w", err)
+	}
+	var schemes []repository.MfScheme
+	for _, s := range all {
+		name := strings.ToLower(s.Name)
+		switch {
+		case *category != "" && !strings.EqualFold(s.Category, *category):
+		case *amc != "" && !strings.Contains(strings.ToLower(s.Amc), strings.ToLower(*amc)):
+		case *search != "" && !strings.Contains(name, strings.ToLower(*search)):
+		case *plan != "all" && strings.Contains(name, "direct") != (*plan == "direct"):
+		case !*idcw && (strings.Contains(name, "idcw") || strings.Contains(name, "dividend")):
+		default:
+			schemes = append(schemes, s)
+		}
+	}
+
+	switch sub {
+	case "list":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Symbol\tCategory\tAMC\tName")
+		for _, s := range schemes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", amfi.Symbol(int(s.Code)), s.Category, s.Amc, s.Name)
+		}
+		return tw.Flush()
+
+	case "categories":
+		counts := make(map[string]int)
+		for _, s := range schemes {
+			counts[s.Category]++
+		}
+		names := make([]string, 0, len(counts))
+		for c := range counts {
+			names = append(names, c)
+		}
+		sort.Strings(names)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Category\tSchemes")
+		for _, c := range names {
+			fmt.Fprintf(tw, "%s\t%d\n", c, counts[c])
+		}
+		return tw.Flush()
+
+	case "compare":
+		if *category == "" {
+			return fmt.Errorf("compare needs -category; see 'fundmgr funds categories'")
+		}
+		return compareFunds(ctx, services.NewService(queries), schemes, *benchmark, date)
+	}
+	return fmt.Errorf("unknown subcommand %q", sub)
+}
+
+// amfiFiles lists the files given, and the .txt and .csv files of the
+// directories given, in name order.
+func amfiFiles(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			ext := strings.ToLower(filepath.Ext(e.Name()))
+			if e.Type().IsRegular() && (ext == ".txt" || ext == ".csv") {
+				paths = append(paths, filepath.Join(arg, e.Name()))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// trailingYears are the periods funds are compared over
+var trailingYears = []int{1, 3, 5}
+
+type fundReturns struct {
+	symbol  string
+	name    string
+	navDate time.Time
+	returns []float64 // per trailingYears, annualised; NaN without history
+}
+
+// trailing is symbol's returns up to its last price on or before date. ok is
+// false when it has no price in the month before date, as for a scheme that
+// has closed.
+func trailing(ctx context.Context, svc *services.Service, symbol string, date time.Time) (fundReturns, bool, error) {
+	r := fundReturns{symbol: symbol}
+	prices, err := svc.GetStockPrices(ctx, repository.GetHistoricalStockPricesParams{
+		Symbol:      symbol,
+		Timestamp:   pgtype.Date{Time: date.AddDate(0, -1, 0), Valid: true},
+		Timestamp_2: pgtype.Date{Time: date, Valid: true},
+	})
+	if err != nil || len(prices) == 0 {
+		return r, false, err
+	}
+	last := prices[len(prices)-1]
+	r.navDate = last.Timestamp.Time
+	latest, err := last.Close.Float64Value()
+	if err != nil || !latest.Valid {
+		return r, false, err
+	}
+	for _, years := range trailingYears {
+		then, ok, err := svc.Close(ctx, symbol, r.navDate.AddDate(-years, 0, 0))
+		if err != nil {
+			return r, false, err
+		}
+		ret := math.NaN()
+		if ok {
+			ret = math.Pow(latest.Float64/then, 1/float64(years)) - 1
+		}
+		r.returns = append(r.returns, ret)
+	}
+	return r, true, nil
+}
+
+func median(values []float64) float64 {
+	var v []float64
+	for _, x := range values {
+		if !math.IsNaN(x) {
+			v = append(v, x)
+		}
+	}
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	sort.Float64s(v)
+	if len(v)%2 == 1 {
+		return v[len(v)/2]
+	}
+	return (v[len(v)/2-1] + v[len(v)/2]) / 2
+}
+
+func pct(r float64) string {
+	if math.IsNaN(r) {
+		return "-"
+	}
+	return fmt.Sprintf("%+.2f%%", r*100)
+}
+
+// compareFunds ranks the schemes by their longest trailing return, with the
+// category median and, given a benchmark, how many schemes beat it.
+func compareFunds(ctx context.Context, svc *services.Service, schemes []repository.MfScheme, benchmark string, date time.Time) error {
+	var funds []fundReturns
+	for _, s := range schemes {
+		r, ok, err := trailing(ctx, svc, amfi.Symbol(int(s.Code)), date)
+		if err != nil {
+			return err
+		}
+		if ok {
+			r.name = s.Name
+			funds = append(funds, r)
+		}
+	}
+	if len(funds) == 0 {
+		return fmt.Errorf("no scheme of the category has a NAV in the month to %s", date.Format("2006-01-02"))
+	}
+	// schemes with the longest history first, then by that return
+	longest := func(r fundReturns) int {
+		k := len(r.returns) - 1
+		for k >= 0 && math.IsNaN(r.returns[k]) {
+			k--
+		}
+		return k
+	}
+	sort.SliceStable(funds, func(i, j int) bool {
+		ki, kj := longest(funds[i]), longest(funds[j])
+		if ki != kj || ki < 0 {
+			return ki > kj
+		}
+		return funds[i].returns[ki] > funds[j].returns[kj]
+	})
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := "#\tSymbol\tNAV Date\t"
+	for _, y := range trailingYears {
+		header += fmt.Sprintf("%dY\t", y)
+	}
+	fmt.Fprintln(tw, header+"Name\t")
+	row := func(rank, symbol, navDate string, returns []float64, name string) {
+		line := rank + "\t" + symbol + "\t" + navDate + "\t"
+		for _, r := range returns {
+			line += pct(r) + "\t"
+		}
+		fmt.Fprintln(tw, line+name+"\t")
+	}
+	for i, f := range funds {
+		row(fmt.Sprint(i+1), f.symbol, f.navDate.Format("2006-01-02"), f.returns, f.name)
+	}
+	medians := make([]float64, len(trailingYears))
+	for k := range trailingYears {
+		var column []float64
+		for _, f := range funds {
+			column = append(column, f.returns[k])
+		}
+		medians[k] = median(column)
+	}
+	row("", "Median", "", medians, fmt.Sprintf("%d schemes", len(funds)))
+
+	if benchmark != "" {
+		b, ok, err := trailing(ctx, svc, benchmark, date)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("benchmark %s has no price in the month to %s", benchmark, date.Format("2006-01-02"))
+		}
+		row("", b.symbol, b.navDate.Format("2006-01-02"), b.returns, "benchmark")
+		beat := "Beat the benchmark"
+		for k, y := range trailingYears {
+			if math.IsNaN(b.returns[k]) {
+				continue
+			}
+			wins, of := 0, 0
+			for _, f := range funds {
+				if !math.IsNaN(f.returns[k]) {
+					of++
+					if f.returns[k] > b.returns[k] {
+						wins++
+					}
+				}
+			}
+			beat += fmt.Sprintf(" %dY: %d of %d", y, wins, of)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Println(beat)
+		return nil
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/csvstore"
+	"fund-manager/internal/download"
+	"fund-manager/internal/ingest"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/services"
+	"fund-manager/internal/sqlitestore"
+	"os"
+	"time"
+)
+
+func runIngest(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("ingest", "Download the NSE index constituent and F&O lists, then import\nthe daily OHLC CSVs of every stock in the database and the index price\nand TRI CSVs of -index-dir, e.g. NIFTY 50 as NIFTY and its TRI as\nNIFTYTRI, and the cash dividends of the -dividends corporate actions\nCSV. With the sqlite backend the stock lists, daily CSVs and index\nseries replace the SQLite file's contents.")
+	download := fs.Bool("download", true, "download the stock and F&O lists first")
+	daily := fs.Bool("daily", true, "import daily OHLC CSVs")
+	indexSeries := fs.Bool("indices", true, "import the index series of -index-dir, when it exists")
+	paperRun := fs.Bool("paper", true, "run the paper accounts through the imported days")
+	fs.StringVar(&cfg.Data.StocksDir, "stocks-dir", cfg.Data.StocksDir, opt("data", "stocks_dir", "directory of <scriptType>.csv stock lists"))
+	fs.StringVar(&cfg.Data.FnoList, "fno-list", cfg.Data.FnoList, opt("data", "fno_list", "F&O market lots CSV"))
+	fs.StringVar(&cfg.Data.DailyDir, "daily-dir", cfg.Data.DailyDir, opt("data", "daily_dir", "directory of <symbol>.csv daily OHLC files"))
+	fs.StringVar(&cfg.Data.IndexDir, "index-dir", cfg.Data.IndexDir, opt("data", "index_dir", "directory of index price and TRI CSVs"))
+	fs.StringVar(&cfg.Data.Dividends, "dividends", cfg.Data.Dividends, opt("data", "dividends", "corporate actions CSV to import dividends from, when it exists"))
+	fs.IntVar(&cfg.Download.Retries, "retries", cfg.Download.Retries, opt("download", "retries", "download retries after the first attempt"))
+	fs.StringVar(&cfg.Download.Interval, "interval", cfg.Download.Interval, opt("download", "interval", "minimum time between download requests"))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *download {
+		client, err := downloadClient(cfg.Download)
+		if err != nil {
+			return err
+		}
+		if err := ingest.DownloadStockLists(ctx, client, cfg.Data.StocksDir, cfg.Data.FnoList); err != nil {
+			return err
+		}
+	}
+	if !*daily {
+		return nil
+	}
+	if !*indexSeries {
+		cfg.Data.IndexDir = ""
+	}
+
+	switch cfg.Database.Backend {
+	case backendCSV:
+		fmt.Println("The csv backend reads the CSVs directly, nothing to import.")
+		return nil
+	case backendSQLite:
+		return importSQLite(ctx, cfg)
+	}
+
+	pool, queries, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	if err := ingest.ImportDaily(ctx, queries, cfg.Data.DailyDir); err != nil {
+		return err
+	}
+	if _, err := os.Stat(cfg.Data.IndexDir); cfg.Data.IndexDir != "" && err == nil {
+		if err := ingest.ImportIndices(ctx, queries, cfg.Data.IndexDir); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(cfg.Data.Dividends); cfg.Data.Dividends != "" && err == nil {
+		if err := ingest.ImportDividends(ctx, queries, cfg.Data.Dividends); err != nil {
+			return err
+		}
+	}
+	if !*paperRun {
+		return nil
+	}
+	// the new trading days move the paper accounts forward
+	return runPaperAccounts(ctx, queries, services.NewService(queries), nil, today())
+}
+
+func downloadClient(dc config.DownloadConfig) (*download.Client, error) {
+	opts := download.DefaultOptions()
+	opts.Retries = dc.Retries
+	var err error
+	if opts.Interval, err = time.ParseDuration(dc.Interval); err != nil {
+		return nil, fmt.Errorf("invalid download.interval %q: %w", dc.Interval, err)
+	}
+	if opts.Timeout, err = time.ParseDuration(dc.Timeout); err != nil {
+		return nil, fmt.Errorf("invalid download.timeout %q: %w", dc.Timeout, err)
+	}
+	return download.New(opts)
+}
+
+func importSQLite(ctx context.Context, cfg config.File) error {
+	store, err := csvstore.Open(cfg.Data.StocksDir, cfg.Data.DailyDir, cfg.Data.IndexDir, cfg.Data.FnoList, pricestore.Options{})
+	if err != nil {
+		return err
+	}
+	db, err := sqlitestore.Open(cfg.Database.SQLite)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Import(ctx, store); err != nil {
+		return fmt.Errorf("failed to import into %s: %w", cfg.Database.SQLite, err)
+	}
+	fmt.Printf("✅ Imported %d stocks and %d bars into %s\n", len(store.Symbols()), store.Rows(), cfg.Database.SQLite)
+	return nil
+}
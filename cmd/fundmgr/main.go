@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/repository"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type command struct {
+	name  string
+	short string
+	run   func(ctx context.Context, cfg config.File, args []string) error
+}
+
+var commands = []command{
+	{"ingest", "download NSE stock lists and import daily OHLC CSVs", runIngest},
+	{"universe", "load the stock universe CSVs into the database", runUniverse},
+	{"screen", "screen stocks with filter and rank expressions", runScreen},
+	{"backtest", "run the momentum backtest", runBacktest},
+	{"report", "summarise a backtest run or render it as HTML", runReport},
+	{"runs", "list, show and compare stored backtest runs", runRuns},
+	{"portfolio", "record transactions of live accounts and show positions and P&L", runPortfolio},
+	{"orders", "work out the rebalance orders from holdings to the target portfolio", runOrders},
+	{"funds", "import AMFI mutual fund NAVs and compare schemes within a category", runFunds},
+	{"paper", "run the strategy forward on simulated accounts and compare with the backtest", runPaper},
+	{"migrate", "apply, roll back and check schema migrations", runMigrate},
+}
+
+func main() {
+	log.SetFlags(0)
+
+	global := flag.NewFlagSet("fundmgr", flag.ExitOnError)
+	configPath := global.String("config", os.Getenv(config.EnvPrefix+"_CONFIG"), "config file (.yaml, .yml or .toml); defaults to the first of fundmgr.yaml, fundmgr.yml, fundmgr.toml")
+	global.Usage = func() {
+		out := global.Output()
+		fmt.Fprintf(out, "Usage: fundmgr [-config file] <command> [flags]\n\nCommands:\n")
+		for _, c := range commands {
+			fmt.Fprintf(out, "  %-10s %s\n", c.name, c.short)
+		}
+		fmt.Fprintf(out, "\nGlobal flags:\n")
+		global.PrintDefaults()
+		fmt.Fprintf(out, "\nRun 'fundmgr <command> -help' for the flags of a command.\n")
+		fmt.Fprintf(out, "Options are read from the config file, then %s_<SECTION>_<KEY> environment variables, then flags.\n", config.EnvPrefix)
+	}
+	global.Parse(os.Args[1:])
+
+	if global.NArg() == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+
+	name, args := global.Arg(0), global.Args()[1:]
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+
+		if err := config.LoadEnv(); err != nil {
+			log.Fatal("Failed to load env:", err)
+		}
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := c.run(context.Background(), cfg, args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			log.Fatalf("%s: %v", c.name, err)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	global.Usage()
+	os.Exit(2)
+}
+
+// newFlagSet returns a flag set whose usage lists the command's flags along
+// with the config keys and environment variables that back them.
+func newFlagSet(name, short string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: fundmgr %s [flags]\n\n%s\n\nFlags:\n", name, short)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// opt annotates a flag's usage with the config key and env variable behind it.
+func opt(section, key, usage string) string {
+	return fmt.Sprintf("%s\n(config %s.%s, env %s)", usage, section, key, config.EnvName(section, key))
+}
+
+type listFlag struct{ values *[]string }
+
+func (l listFlag) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+func (l listFlag) Set(v string) error {
+	*l.values = config.SplitList(v)
+	return nil
+}
+
+// multiFlag collects a repeatable flag. The first use on the command line
+// replaces whatever the config file supplied.
+type multiFlag struct {
+	values *[]string
+	set    bool
+}
+
+func (m *multiFlag) String() string {
+	if m.values == nil {
+		return ""
+	}
+	return strings.Join(*m.values, "; ")
+}
+
+func (m *multiFlag) Set(v string) error {
+	if !m.set {
+		*m.values = nil
+		m.set = true
+	}
+	*m.values = append(*m.values, v)
+	return nil
+}
+
+func connect(ctx context.Context, cfg config.File) (*pgxpool.Pool, *repository.Queries, error) {
+	return config.OpenDatabase(ctx, cfg.Database.URL)
+}
+
+func parseDate(name, value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: want YYYY-MM-DD", name, value)
+	}
+	return d, nil
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
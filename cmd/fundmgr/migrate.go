@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/migrate"
+	"fund-manager/sql/migrations"
+	"os"
+	"text/tabwriter"
+)
+
+func runMigrate(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("migrate", "Apply the migrations embedded from sql/migrations.\n\n  fundmgr migrate [up] [-to version]    apply pending migrations\n  fundmgr migrate down [-steps n]       roll back the latest migrations\n  fundmgr migrate redo                  roll back and reapply the latest migration\n  fundmgr migrate status                list migrations and when they were applied\n  fundmgr migrate check                 compare the migrations with -schema\n  fundmgr migrate baseline -to version  mark migrations up to version as applied\n                                        to a database created from schema.sql")
+	to := fs.Int64("to", 0, "last version to apply or baseline (default all)")
+	steps := fs.Int("steps", 1, "number of migrations to roll back")
+	fs.StringVar(&cfg.Migrate.Schema, "schema", cfg.Migrate.Schema, opt("migrate", "schema", "schema SQL file the migrations must match"))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sub := "up"
+	if fs.NArg() > 0 {
+		sub = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+	}
+
+	if err := requirePostgres(cfg, "migrate"); err != nil {
+		return err
+	}
+	ms, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	pool, _, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	switch sub {
+	case "up":
+		done, err := migrate.Up(ctx, pool, ms, *to)
+		printMigrations("Applied", done)
+		return err
+
+	case "down":
+		done, err := migrate.Down(ctx, pool, ms, *steps)
+		printMigrations("Rolled back", done)
+		return err
+
+	case "redo":
+		m, err := migrate.Redo(ctx, pool, ms)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Redid %s\n", m)
+		return nil
+
+	case "status":
+		states, err := migrate.Status(ctx, pool, ms)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Version\tName\tApplied")
+		for _, s := range states {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+
+	case "check":
+		schema, err := os.ReadFile(cfg.Migrate.Schema)
+		if err != nil {
+			return fmt.Errorf("failed to read schema: %w", err)
+		}
+		diffs, err := migrate.Check(ctx, pool, ms, string(schema))
+		if err != nil {
+			return err
+		}
+		for _, d := range diffs {
+			fmt.Println(d)
+		}
+		if len(diffs) > 0 {
+			return fmt.Errorf("migrations and %s differ in %d places", cfg.Migrate.Schema, len(diffs))
+		}
+		fmt.Printf("Migrations match %s\n", cfg.Migrate.Schema)
+		return nil
+
+	case "baseline":
+		if *to == 0 {
+			return fmt.Errorf("baseline needs -to")
+		}
+		done, err := migrate.Baseline(ctx, pool, ms, *to)
+		printMigrations("Marked as applied", done)
+		return err
+	}
+	return fmt.Errorf("unknown subcommand %q (want up, down, redo, status, check or baseline)", sub)
+}
+
+func printMigrations(verb string, ms []migrate.Migration) {
+	if len(ms) == 0 {
+		fmt.Println("Nothing to do")
+		return
+	}
+	for _, m := range ms {
+		fmt.Printf("%s %s\n", verb, m)
+	}
+}
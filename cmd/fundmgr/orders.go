@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/backtest"
+	"fund-manager/internal/orders"
+	"fund-manager/internal/portfolio"
+	"fund-manager/internal/services"
+	"os"
+	"strings"
+	"time"
+)
+
+func runOrders(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("orders", "Work out the orders that take the current holdings to the strategy's\ntarget portfolio on a date, in whole shares at the last close.\n\nThe targets are the spec's top N and weights (default: the backtest\nsettings of the config), or a CSV of symbol[,weight] rows such as\nstockList.csv. The holdings are a portfolio account's, or a CSV of\nsymbol,quantity rows.")
+	oc := &cfg.Orders
+	dateFlag := fs.String("date", "", "rebalance date YYYY-MM-DD (default today)")
+	specName := fs.String("spec", "", "backtest spec name (in backtest.specs_dir) or path for the targets and costs")
+	targetsPath := fs.String("targets", "", "CSV of target symbols with optional weights instead of the spec's ranking")
+	holdingsPath := fs.String("holdings", "", "CSV of symbol,quantity holdings instead of a portfolio account")
+	fs.StringVar(&cfg.Portfolio.Account, "account", cfg.Portfolio.Account, opt("portfolio", "account", "portfolio account holding the current positions"))
+	cash := fs.Float64("cash", 0, "cash available (default the account's cash, 0 with -holdings)")
+	fs.Float64Var(&oc.MinTrade, "min-trade", oc.MinTrade, opt("orders", "min_trade", "smallest order value; smaller adjustments are skipped"))
+	fs.StringVar(&oc.Format, "format", oc.Format, opt("orders", "format", "output format: "+strings.Join(orders.Formats(), ", ")))
+	out := fs.String("out", "", "output file (default stdout)")
+	fs.StringVar(&oc.Exchange, "exchange", oc.Exchange, opt("orders", "exchange", "exchange for the broker formats"))
+	fs.StringVar(&oc.Product, "product", oc.Product, opt("orders", "product", "product for the broker formats"))
+	fs.StringVar(&oc.OrderType, "order-type", oc.OrderType, opt("orders", "order_type", "MARKET, or LIMIT at the last close"))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	writer, err := orders.NewWriter(oc.Format, orders.Basket{Exchange: oc.Exchange, Product: oc.Product, OrderType: oc.OrderType})
+	if err != nil {
+		return err
+	}
+	date, err := parseDate("date", *dateFlag, today())
+	if err != nil {
+		return err
+	}
+	cashSet := false
+	fs.Visit(func(f *flag.Flag) { cashSet = cashSet || f.Name == "cash" })
+
+	spec, err := resolveSpec(cfg, fs, *specName)
+	if err != nil {
+		return err
+	}
+
+	var holdings []orders.Holding
+	available := *cash
+	if *holdingsPath != "" {
+		if holdings, err = orders.ReadHoldings(*holdingsPath); err != nil {
+			return err
+		}
+	} else {
+		book, err := accountBook(ctx, cfg, date)
+		if err != nil {
+			return err
+		}
+		for _, h := range book.Open() {
+			holdings = append(holdings, orders.Holding{Symbol: h.Symbol, Quantity: h.Quantity})
+		}
+		if !cashSet {
+			available = book.Cash
+		}
+	}
+
+	window := spec.PriceWindow()
+	window.From, window.To = date.AddDate(0, -spec.Strategy.LookbackMonths-1, 0), date
+	for _, h := range holdings {
+		window.Symbols = append(window.Symbols, h.Symbol)
+	}
+	var targets []backtest.Target
+	if *targetsPath != "" {
+		if targets, err = orders.ReadTargets(*targetsPath); err != nil {
+			return err
+		}
+		for _, t := range targets {
+			window.Symbols = append(window.Symbols, t.Symbol)
+		}
+	}
+
+	queries, closeQueries, err := openQueries(ctx, cfg, window)
+	if err != nil {
+		return err
+	}
+	defer closeQueries()
+	svc := services.NewService(queries)
+
+	if *targetsPath == "" {
+		btCfg, err := spec.Config(svc)
+		if err != nil {
+			return err
+		}
+		if targets, err = backtest.Targets(ctx, btCfg, date); err != nil {
+			return err
+		}
+	}
+
+	prices := make(map[string]float64)
+	for _, symbol := range append(holdingSymbols(holdings), targetSymbols(targets)...) {
+		price, ok, err := svc.Close(ctx, symbol, date)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no close for %s on or before %s", symbol, date.Format("2006-01-02"))
+		}
+		prices[symbol] = price
+	}
+
+	plan, err := orders.Generate(holdings, targets, prices, available, orders.Options{Costs: spec.Costs, MinTrade: oc.MinTrade})
+	if err != nil {
+		return err
+	}
+	plan.Date = date
+
+	w := os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := writer.WriteOrders(w, plan); err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Printf("%d orders for %s (%d targets, %d holdings) exported to %s\n", len(plan.Orders), date.Format("2006-01-02"), len(targets), len(holdings), *out)
+	}
+	return nil
+}
+
+// accountBook is the configured account as of date, or an empty book when
+// the backend has no portfolios.
+func accountBook(ctx context.Context, cfg config.File, date time.Time) (*portfolio.Book, error) {
+	if b := cfg.Database.Backend; b != backendPostgres && b != "" {
+		return portfolio.Build(nil, date)
+	}
+	pool, queries, err := connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.Close()
+	account, err := portfolio.FindAccount(ctx, queries, cfg.Portfolio.Account)
+	if err != nil {
+		return nil, err
+	}
+	txns, err := portfolio.Transactions(ctx, queries, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return portfolio.Build(txns, date)
+}
+
+func holdingSymbols(holdings []orders.Holding) []string {
+	symbols := make([]string, len(holdings))
+	for i, h := range holdings {
+		symbols[i] = h.Symbol
+	}
+	return symbols
+}
+
+func targetSymbols(targets []backtest.Target) []string {
+	symbols := make([]string, len(targets))
+	for i, t := range targets {
+		symbols[i] = t.Symbol
+	}
+	return symbols
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/paper"
+	"fund-manager/internal/repository"
+	"fund-manager/internal/services"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+func runPaper(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("paper", "Run the strategy forward on simulated accounts kept in the database.\n\n  fundmgr paper list\n  fundmgr paper open [-spec name] [-date start] [-capital c] <account>\n  fundmgr paper run [-date until]\n  fundmgr paper show\n  fundmgr paper compare\n  fundmgr paper delete <account>\n\nrun steps every account (or just -account when given) through the trading\ndays in daily since it last ran; ingest does the same after importing.\nRebalances follow the spec's schedule from the start date and trade at\nthe close with the spec's costs. compare replays the backtest over the\nsame days.")
+	fs.StringVar(&cfg.Paper.Account, "account", cfg.Paper.Account, opt("paper", "account", "paper account to show or compare"))
+	dateFlag := fs.String("date", "", "start date of a new account, or the last day to run through, YYYY-MM-DD (default today)")
+	specName := fs.String("spec", "", "backtest spec name (in backtest.specs_dir) or path for a new account")
+	capital := fs.Float64("capital", 0, "starting capital of a new account (default the spec's initial capital)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing subcommand")
+	}
+	sub, rest := fs.Arg(0), fs.Args()[1:]
+	// allow flags after the subcommand as well
+	if err := fs.Parse(rest); err != nil {
+		return err
+	}
+	rest = fs.Args()
+
+	date, err := parseDate("date", *dateFlag, today())
+	if err != nil {
+		return err
+	}
+	if err := requirePostgres(cfg, "paper"); err != nil {
+		return err
+	}
+	pool, queries, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	svc := services.NewService(queries)
+
+	switch sub {
+	case "list":
+		accounts, err := paper.LoadAll(ctx, queries)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Account\tSpec\tStart\tCapital\tLast Day\tEquity\tReturn")
+		for _, a := range accounts {
+			lastDay, equity := "-", a.Capital
+			if last, ok := a.LastDay(); ok {
+				lastDay, equity = last.Format("2006-01-02"), a.Days[len(a.Days)-1].Equity
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%.2f\t%s\t%.2f\t%+.2f%%\n", a.Name, a.Spec.Name, a.Start.Format("2006-01-02"), a.Capital, lastDay, equity, (equity/a.Capital-1)*100)
+		}
+		return tw.Flush()
+
+	case "open":
+		if len(rest) != 1 {
+			return fmt.Errorf("open takes the account name")
+		}
+		spec, err := resolveSpec(cfg, fs, *specName)
+		if err != nil {
+			return err
+		}
+		if *capital == 0 {
+			*capital = spec.InitialCapital
+		}
+		a, err := paper.Open(ctx, queries, rest[0], spec, date, *capital)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Opened paper account %s trading %s from %s with %.2f\n", a.Name, a.Spec.Name, a.Start.Format("2006-01-02"), a.Capital)
+		return nil
+
+	case "run":
+		var names []string
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "account" {
+				names = append(names, cfg.Paper.Account)
+			}
+		})
+		return runPaperAccounts(ctx, queries, svc, names, date)
+
+	case "delete":
+		if len(rest) != 1 {
+			return fmt.Errorf("delete takes the account name")
+		}
+		if err := paper.Delete(ctx, queries, rest[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted paper account %s\n", rest[0])
+		return nil
+	}
+
+	a, err := paper.Load(ctx, queries, cfg.Paper.Account)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "show":
+		return showPaper(ctx, svc, a)
+
+	case "compare":
+		div, err := a.Compare(ctx, svc)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Paper account %s against the %s backtest over the same days\n\n", a.Name, a.Spec.Name)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Date\tPaper\tBacktest\tGap\t")
+		for _, p := range div.Points {
+			fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%+.2f%%\t\n", p.Date.Format("2006-01-02"), p.Paper, p.Backtest, p.Gap())
+		}
+		tw.Flush()
+		fmt.Println()
+		tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "Paper Return\t%+.2f%%\t\n", div.PaperReturn)
+		fmt.Fprintf(tw, "Backtest Return\t%+.2f%%\t\n", div.BacktestReturn)
+		fmt.Fprintf(tw, "Max Gap\t%.2f%%\t\n", div.MaxGap)
+		fmt.Fprintf(tw, "Tracking Error\t%.2f%%\t\n", div.TrackingError)
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown subcommand %q", sub)
+}
+
+// runPaperAccounts steps the named paper accounts, or all of them, through
+// the trading days in daily up to until.
+func runPaperAccounts(ctx context.Context, queries *repository.Queries, svc *services.Service, names []string, until time.Time) error {
+	var accounts []*paper.Account
+	if len(names) == 0 {
+		var err error
+		if accounts, err = paper.LoadAll(ctx, queries); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		a, err := paper.Load(ctx, queries, name)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, a)
+	}
+
+	for _, a := range accounts {
+		days, err := paper.Run(ctx, queries, svc, a, until)
+		if err != nil {
+			return err
+		}
+		if len(days) == 0 {
+			fmt.Printf("📄 Paper account %s is up to date\n", a.Name)
+			continue
+		}
+		fills := 0
+		for _, f := range a.Fills {
+			if !f.Date.Before(days[0].Date) {
+				fills++
+			}
+		}
+		last := days[len(days)-1]
+		fmt.Printf("📄 Paper account %s: %d days through %s, %d fills, equity %.2f (%+.2f%%)\n", a.Name, len(days), last.Date.Format("2006-01-02"), fills, last.Equity, (last.Equity/a.Capital-1)*100)
+	}
+	return nil
+}
+
+func showPaper(ctx context.Context, svc *services.Service, a *paper.Account) error {
+	last, ok := a.LastDay()
+	if !ok {
+		fmt.Printf("Paper account %s (%s) starts %s and has not run yet\n", a.Name, a.Spec.Name, a.Start.Format("2006-01-02"))
+		return nil
+	}
+	day := a.Days[len(a.Days)-1]
+	fmt.Printf("Paper account %s (%s) from %s through %s\n\n", a.Name, a.Spec.Name, a.Start.Format("2006-01-02"), last.Format("2006-01-02"))
+
+	_, held := a.Positions()
+	symbols := make([]string, 0, len(held))
+	for symbol := range held {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Symbol\tQuantity\tPrice\tValue\tWeight\t")
+	for _, symbol := range symbols {
+		price, _, err := svc.Close(ctx, symbol, last)
+		if err != nil {
+			return err
+		}
+		value := held[symbol] * price
+		fmt.Fprintf(tw, "%s\t%g\t%.2f\t%.2f\t%.1f%%\t\n", symbol, held[symbol], price, value, value/day.Equity*100)
+	}
+	tw.Flush()
+
+	var rebalanced time.Time
+	for _, d := range a.Days {
+		if d.Rebalanced {
+			rebalanced = d.Date
+		}
+	}
+	fmt.Printf("\nFills of the last rebalance on %s:\n", rebalanced.Format("2006-01-02"))
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	costs := 0.0
+	for _, f := range a.Fills {
+		costs += f.Cost
+		if f.Date.Equal(rebalanced) {
+			fmt.Fprintf(tw, "%s\t%s\t%g\t%.2f\t%.2f\t\n", f.Side, f.Symbol, f.Quantity, f.Price, f.Cost)
+		}
+	}
+	tw.Flush()
+
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Capital\t%.2f\t\n", a.Capital)
+	fmt.Fprintf(tw, "Cash\t%.2f\t\n", day.Cash)
+	fmt.Fprintf(tw, "Equity\t%.2f\t\n", day.Equity)
+	fmt.Fprintf(tw, "Return\t%+.2f%%\t\n", (day.Equity/a.Capital-1)*100)
+	fmt.Fprintf(tw, "Costs\t%.2f\t\n", costs)
+	return tw.Flush()
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/portfolio"
+	"fund-manager/internal/returns"
+	"fund-manager/internal/services"
+	"fund-manager/internal/tax"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func runPortfolio(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("portfolio", "Record the transactions of real accounts and show their positions.\n\n  fundmgr portfolio accounts\n  fundmgr portfolio open [-broker name] <account>\n  fundmgr portfolio buy|sell [-fees f] [-lots ids] <symbol> <quantity> <price>\n  fundmgr portfolio dividend <symbol> <amount>\n  fundmgr portfolio fee|deposit|withdraw <amount>\n  fundmgr portfolio show [-all]\n  fundmgr portfolio returns\n  fundmgr portfolio lots\n  fundmgr portfolio gains [-fy year]\n  fundmgr portfolio transactions\n  fundmgr portfolio delete <transaction id prefix>\n\nHoldings carry the average cost of their shares; positions are valued at\nthe last close in daily on or before -date. Returns (XIRR, time- and\nmoney-weighted) run from the first deposit, with deposits and withdrawals\nas the cash flows.\n\nEvery buy is a tax lot. A sale takes its shares first in, first out, or\nfrom the buys named with -lots (transaction id prefixes, in order). gains\nprints the capital gains of every sale and the statement of each\nfinancial year under the tax section of the config.")
+	fs.StringVar(&cfg.Portfolio.Account, "account", cfg.Portfolio.Account, opt("portfolio", "account", "account to record in or show"))
+	dateFlag := fs.String("date", "", "transaction date, or the date to value the holdings at, YYYY-MM-DD (default today)")
+	fees := fs.Float64("fees", 0, "brokerage and taxes paid on a buy or sell")
+	note := fs.String("note", "", "free text stored with the transaction")
+	broker := fs.String("broker", "", "broker of a new account")
+	all := fs.Bool("all", false, "also show closed positions")
+	lots := fs.String("lots", "", "comma separated buy transaction ids a sale takes its shares from")
+	fy := fs.Int("fy", 0, "financial year of gains, by the year it starts in (default all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing subcommand")
+	}
+	sub, rest := fs.Arg(0), fs.Args()[1:]
+	// allow flags after the subcommand as well
+	if err := fs.Parse(rest); err != nil {
+		return err
+	}
+	rest = fs.Args()
+
+	date, err := parseDate("date", *dateFlag, today())
+	if err != nil {
+		return err
+	}
+	if err := requirePostgres(cfg, "portfolio"); err != nil {
+		return err
+	}
+	pool, queries, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	svc := services.NewService(queries)
+
+	switch sub {
+	case "accounts":
+		accounts, err := queries.ListPortfolioAccounts(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Account\tBroker\tOpened")
+		for _, a := range accounts {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Name, a.Broker, a.CreatedAt.Time.Format("2006-01-02"))
+		}
+		return tw.Flush()
+
+	case "open":
+		if len(rest) != 1 {
+			return fmt.Errorf("open takes the account name")
+		}
+		account, err := portfolio.OpenAccount(ctx, queries, rest[0], *broker)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Opened account %s\n", account.Name)
+		return nil
+	}
+
+	account, err := portfolio.FindAccount(ctx, queries, cfg.Portfolio.Account)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "buy", "sell", "dividend", "fee", "deposit", "withdraw":
+		t, err := parseTransaction(sub, rest)
+		if err != nil {
+			return err
+		}
+		t.Date, t.Fees, t.Note = date, *fees, *note
+		t.Lots = config.SplitList(*lots)
+		if t, err = portfolio.Record(ctx, queries, account.ID, t); err != nil {
+			return err
+		}
+		fmt.Printf("Recorded %s %s in %s\n", t.Kind, t.ID[:8], account.Name)
+		if t.Symbol != "" {
+			if _, ok, err := svc.Close(ctx, t.Symbol, t.Date); err == nil && !ok {
+				fmt.Printf("Warning: no close for %s in daily, it will not be valued\n", t.Symbol)
+			}
+		}
+		return nil
+
+	case "transactions":
+		txns, err := portfolio.Transactions(ctx, queries, account.ID)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "ID\tDate\tKind\tSymbol\tQuantity\tPrice\tFees\tCash\tNote\t")
+		for _, t := range txns {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%g\t%.2f\t%.2f\t%+.2f\t%s\t\n", t.ID[:8], t.Date.Format("2006-01-02"), t.Kind, t.Symbol, t.Quantity, t.Price, t.Fees, t.CashFlow(), t.Note)
+		}
+		return tw.Flush()
+
+	case "delete":
+		if len(rest) != 1 {
+			return fmt.Errorf("delete takes a transaction id or id prefix")
+		}
+		t, err := portfolio.Delete(ctx, queries, account.ID, rest[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %s %s of %s\n", t.Kind, t.ID[:8], t.Date.Format("2006-01-02"))
+		return nil
+
+	case "show":
+		txns, err := portfolio.Transactions(ctx, queries, account.ID)
+		if err != nil {
+			return err
+		}
+		book, err := portfolio.Build(txns, date)
+		if err != nil {
+			return err
+		}
+		missing, err := book.Value(ctx, svc)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Account %s on %s\n\n", account.Name, date.Format("2006-01-02"))
+		printBook(book, *all)
+		if len(missing) > 0 {
+			fmt.Printf("\nNo close for %s, valued at 0\n", strings.Join(missing, ", "))
+		}
+		if book.Deposits > 0 {
+			perf, err := portfolio.Performance(ctx, svc, txns, date)
+			if err != nil {
+				return err
+			}
+			fmt.Println()
+			printReturns(perf)
+		}
+		return nil
+
+	case "returns":
+		txns, err := portfolio.Transactions(ctx, queries, account.ID)
+		if err != nil {
+			return err
+		}
+		perf, err := portfolio.Performance(ctx, svc, txns, date)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Account %s from %s to %s\n\n", account.Name, perf.Start.Format("2006-01-02"), perf.End.Format("2006-01-02"))
+		printReturns(perf)
+		return nil
+
+	case "lots", "gains":
+		rules, err := taxRules(cfg)
+		if err != nil {
+			return err
+		}
+		txns, err := portfolio.Transactions(ctx, queries, account.ID)
+		if err != nil {
+			return err
+		}
+		ledger, err := portfolio.TaxLots(txns, date, rules, portfolio.FairValue(ctx, svc))
+		if err != nil {
+			return err
+		}
+		if sub == "lots" {
+			return printLots(ctx, svc, rules, ledger.Open(), date)
+		}
+		// earlier years' losses count, so the statement covers them all
+		gains, years := ledger.Gains(), rules.Statement(ledger.Gains())
+		if *fy != 0 {
+			var inYear []tax.Gain
+			for _, g := range gains {
+				if tax.FinancialYear(g.Sold) == *fy {
+					inYear = append(inYear, g)
+				}
+			}
+			gains = inYear
+			for _, y := range years {
+				if y.FY == *fy {
+					years = []tax.Year{y}
+				}
+			}
+			if len(years) != 1 || years[0].FY != *fy {
+				years = nil
+			}
+		}
+		fmt.Printf("Capital gains of %s through %s (* grandfathered)\n\n", account.Name, date.Format("2006-01-02"))
+		if err := printGains(gains); err != nil {
+			return err
+		}
+		fmt.Println()
+		return printTaxYears(years)
+	}
+	return fmt.Errorf("unknown subcommand %q", sub)
+}
+
+// parseTransaction reads the positional arguments of a recording
+// subcommand.
+func parseTransaction(sub string, args []string) (portfolio.Transaction, error) {
+	kind := sub
+	if sub == "withdraw" {
+		kind = portfolio.Withdrawal
+	}
+	t := portfolio.Transaction{Kind: kind}
+	want := map[string]string{
+		portfolio.Buy:        "<symbol> <quantity> <price>",
+		portfolio.Sell:       "<symbol> <quantity> <price>",
+		portfolio.Dividend:   "<symbol> <amount>",
+		portfolio.Fee:        "<amount>",
+		portfolio.Deposit:    "<amount>",
+		portfolio.Withdrawal: "<amount>",
+	}[kind]
+	if len(args) != len(strings.Fields(want)) {
+		return t, fmt.Errorf("%s takes %s", sub, want)
+	}
+
+	numbers := args
+	if kind == portfolio.Buy || kind == portfolio.Sell || kind == portfolio.Dividend {
+		t.Symbol, numbers = args[0], args[1:]
+	}
+	values := make([]float64, len(numbers))
+	for i, a := range numbers {
+		v, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return t, fmt.Errorf("invalid number %q", a)
+		}
+		values[i] = v
+	}
+	switch kind {
+	case portfolio.Buy, portfolio.Sell:
+		t.Quantity, t.Price = values[0], values[1]
+	default:
+		t.Amount = values[0]
+	}
+	return t, nil
+}
+
+func printBook(book *portfolio.Book, all bool) {
+	holdings := book.Open()
+	if all {
+		holdings = book.All()
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Symbol\tQuantity\tAvg Cost\tClose\tValue\tUnrealised\t%\tRealised\tDividends\t")
+	for _, h := range holdings {
+		pct := 0.0
+		if h.CostBasis > 0 && h.Price > 0 {
+			pct = h.Unrealised() / h.CostBasis * 100
+		}
+		fmt.Fprintf(tw, "%s\t%g\t%.2f\t%.2f\t%.2f\t%+.2f\t%+.2f\t%+.2f\t%.2f\t\n", h.Symbol, h.Quantity, h.AvgCost(), h.Price, h.Value(), h.Unrealised(), pct, h.Realised, h.Dividends)
+	}
+	tw.Flush()
+
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, row := range []struct {
+		label string
+		value float64
+	}{
+		{"Market Value", book.MarketValue()},
+		{"Cash", book.Cash},
+		{"Equity", book.Equity()},
+		{"Net Deposits", book.Deposits - book.Withdrawals},
+		{"Realised P&L", book.Realised()},
+		{"Unrealised P&L", book.Unrealised()},
+		{"Dividends", book.Dividends()},
+		{"Account Fees", book.Fees},
+	} {
+		fmt.Fprintf(tw, "%s\t%.2f\t\n", row.label, row.value)
+	}
+	tw.Flush()
+}
+
+// printLots lists the open lots with what selling them on date would be.
+func printLots(ctx context.Context, svc *services.Service, rules tax.Rules, lots []tax.Lot, date time.Time) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Symbol\tLot\tBought\tQuantity\tCost\tClose\tValue\tGain\tTerm\tLong Term From\t")
+	for _, lot := range lots {
+		price, _, err := svc.Close(ctx, lot.Symbol, date)
+		if err != nil {
+			return err
+		}
+		value := lot.Quantity * price
+		longFrom := lot.Date.AddDate(0, rules.LongTermMonths, 1)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%g\t%.2f\t%.2f\t%.2f\t%+.2f\t%s\t%s\t\n", lot.Symbol, lot.ID[:8], lot.Date.Format("2006-01-02"), lot.Quantity, lot.Cost, price, value, value-lot.Cost, rules.Term(lot.Date, date), longFrom.Format("2006-01-02"))
+	}
+	return tw.Flush()
+}
+
+func printReturns(s returns.Summary) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Net Invested\t%.2f\t\n", s.Invested)
+	fmt.Fprintf(tw, "Equity\t%.2f\t\n", s.Final)
+	fmt.Fprintf(tw, "XIRR\t%+.2f%%\t\n", s.XIRR*100)
+	fmt.Fprintf(tw, "Time-Weighted\t%+.2f%% (%+.2f%% a year)\t\n", s.TWR*100, s.TWRAnnual*100)
+	fmt.Fprintf(tw, "Money-Weighted\t%+.2f%%\t\n", s.MWR*100)
+	tw.Flush()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/backtest"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/report"
+	"fund-manager/internal/runstore"
+	"fund-manager/internal/services"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+func runReport(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("report", "Summarise a backtest run or a trade log written by 'fundmgr backtest'.\nWith -html the run is rendered as one self-contained HTML page.")
+	fs.StringVar(&cfg.Report.Run, "run", cfg.Report.Run, opt("report", "run", "archived run directory or stored run (see 'fundmgr runs'); takes precedence over -trades"))
+	fs.StringVar(&cfg.Report.Trades, "trades", cfg.Report.Trades, opt("report", "trades", "trade log CSV to read"))
+	fs.StringVar(&cfg.Report.HTML, "html", cfg.Report.HTML, opt("report", "html", "HTML report to write for -run"))
+	fs.StringVar(&cfg.Report.Benchmark, "benchmark", cfg.Report.Benchmark, opt("report", "benchmark", "symbol plotted against the equity curve in the HTML report, empty for none"))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.Report.HTML != "" && cfg.Report.Run == "" {
+		return fmt.Errorf("-html needs a run, see -run")
+	}
+
+	var trades []backtest.TradeLog
+	if cfg.Report.Run != "" {
+		run, err := loadRun(ctx, cfg, cfg.Report.Run)
+		if err != nil {
+			return err
+		}
+		if cfg.Report.HTML != "" {
+			return writeHTMLReport(ctx, cfg, run)
+		}
+		fmt.Printf("Run %s/%s (commit %s, data %s)\n", run.Meta.Name, run.Meta.RunID, shortCommit(run.Meta), run.Meta.Data)
+		printMetrics(run.Metrics)
+		fmt.Println()
+		trades = run.TradeLogs
+	} else {
+		var err error
+		if trades, err = backtest.ReadTradeLogsCSV(cfg.Report.Trades); err != nil {
+			return err
+		}
+	}
+
+	stats := backtest.SummarizeTrades(trades)
+	fmt.Printf("Trades: %d\n", stats.TotalTrades)
+	fmt.Printf("Winning Trades: %d\n", stats.WinningTrades)
+	fmt.Printf("Win Rate: %.2f%%\n", stats.WinRate*100)
+	fmt.Printf("Net Profit: %.2f\n", stats.Profit)
+	fmt.Printf("Average Profit: %.2f\n", stats.AverageProfit)
+	fmt.Printf("Average Days Held: %.1f\n", stats.AvgDaysHeld)
+	if stats.BestTrade != nil {
+		fmt.Printf("Best Trade: %s %+.2f%%\n", stats.BestTrade.Symbol, stats.BestTrade.ProfitPct)
+		fmt.Printf("Worst Trade: %s %+.2f%%\n", stats.WorstTrade.Symbol, stats.WorstTrade.ProfitPct)
+	}
+
+	type symbolStats struct {
+		symbol string
+		trades int
+		profit float64
+	}
+	bySymbol := make(map[string]*symbolStats)
+	for _, t := range trades {
+		s := bySymbol[t.Symbol]
+		if s == nil {
+			s = &symbolStats{symbol: t.Symbol}
+			bySymbol[t.Symbol] = s
+		}
+		s.trades++
+		s.profit += t.Profit
+	}
+	rows := make([]*symbolStats, 0, len(bySymbol))
+	for _, s := range bySymbol {
+		rows = append(rows, s)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].profit > rows[j].profit })
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Symbol\tTrades\tProfit\t")
+	for _, s := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t\n", s.symbol, s.trades, s.profit)
+	}
+	return tw.Flush()
+}
+
+// loadRun reads an archive directory without touching the database and
+// otherwise looks the run up in the database.
+func loadRun(ctx context.Context, cfg config.File, ref string) (backtest.Run, error) {
+	if info, err := os.Stat(ref); err == nil && info.IsDir() {
+		return backtest.LoadRun(ref)
+	}
+	pool, queries, err := connect(ctx, cfg)
+	if err != nil {
+		return backtest.Run{}, err
+	}
+	defer pool.Close()
+	return runstore.Resolve(ctx, queries, ref)
+}
+
+func writeHTMLReport(ctx context.Context, cfg config.File, run backtest.Run) error {
+	in := report.Input{
+		Title:    fmt.Sprintf("Backtest %s", run.Meta.Name),
+		Subtitle: fmt.Sprintf("run %s · %s to %s · commit %s", run.Meta.RunID, run.Spec.Dates.Start, run.Spec.Dates.End, shortCommit(run.Meta)),
+		Result:   run.Result(),
+		Metrics:  run.Metrics,
+	}
+	if run.Spec.Description != "" {
+		in.Subtitle = run.Spec.Description + " · " + in.Subtitle
+	}
+
+	if symbol := cfg.Report.Benchmark; symbol != "" {
+		queries, closeQueries, err := openQueries(ctx, cfg, pricestore.Options{})
+		if err != nil {
+			return err
+		}
+		defer closeQueries()
+		in.Benchmark = backtest.Closes(ctx, services.NewService(queries), symbol, in.Result.EquityDates)
+		in.BenchmarkName = symbol
+	}
+
+	file, err := os.Create(cfg.Report.HTML)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := report.WriteHTML(file, in); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	fmt.Printf("Report written to %s\n", cfg.Report.HTML)
+	return file.Close()
+}
+
+func shortCommit(meta backtest.RunMeta) string {
+	commit := meta.GitCommit
+	if len(commit) > 10 {
+		commit = commit[:10]
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if meta.GitDirty {
+		commit += "+dirty"
+	}
+	return commit
+}
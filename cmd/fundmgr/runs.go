@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/backtest"
+	"fund-manager/internal/runstore"
+	"os"
+	"text/tabwriter"
+)
+
+func runRuns(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("runs", "Inspect backtest runs stored in the database.\n\n  fundmgr runs list [-limit n]\n  fundmgr runs show <run>\n  fundmgr runs compare [-width n] [-height n] <run> <run>...\n\nA run is a run id or id prefix, <name>/<run key>, a spec name (its latest\nrun) or an archived run directory.")
+	limit := fs.Int("limit", 20, "number of runs to list")
+	width := fs.Int("width", 72, "chart width in characters")
+	height := fs.Int("height", 16, "chart height in lines")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing subcommand")
+	}
+	sub, refs := fs.Arg(0), fs.Args()[1:]
+	// allow flags after the subcommand as well
+	if err := fs.Parse(refs); err != nil {
+		return err
+	}
+	refs = fs.Args()
+
+	if err := requirePostgres(cfg, "runs"); err != nil {
+		return err
+	}
+
+	pool, queries, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	switch sub {
+	case "list":
+		runs, err := runstore.List(ctx, queries, int32(*limit))
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tName\tRun\tCommit\tData To")
+		for _, r := range runs {
+			meta := backtest.RunMeta{GitCommit: r.GitCommit, GitDirty: r.GitDirty}
+			dataTo := ""
+			if r.DataMaxDate.Valid {
+				dataTo = r.DataMaxDate.Time.Format("2006-01-02")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", runstore.FormatID(r.ID)[:8], r.Name, r.RunKey, shortCommit(meta), dataTo)
+		}
+		return tw.Flush()
+
+	case "show":
+		if len(refs) != 1 {
+			return fmt.Errorf("show takes exactly one run")
+		}
+		run, err := runstore.Resolve(ctx, queries, refs[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Run %s (commit %s, data %s)\n", runstore.Label(run), shortCommit(run.Meta), run.Meta.Data)
+		printMetrics(run.Metrics)
+		return nil
+
+	case "compare":
+		if len(refs) < 2 {
+			return fmt.Errorf("compare needs at least two runs")
+		}
+		runs := make([]backtest.Run, 0, len(refs))
+		for _, ref := range refs {
+			run, err := runstore.Resolve(ctx, queries, ref)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+		}
+		if err := runstore.WriteComparison(os.Stdout, runs); err != nil {
+			return err
+		}
+		fmt.Println()
+		return runstore.PlotEquity(os.Stdout, runs, *width, *height)
+	}
+	return fmt.Errorf("unknown subcommand %q (want list, show or compare)", sub)
+}
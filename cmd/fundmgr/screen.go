@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/screener"
+	"fund-manager/internal/services"
+	"os"
+)
+
+func runScreen(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("screen", "Compute columns for a stock universe on a date, keep the stocks\nmatching every filter and order them by the rank expressions.\n\nColumns:\n"+screener.ColumnHelp)
+	sc := &cfg.Screen
+	fs.StringVar(&sc.Date, "date", sc.Date, opt("screen", "date", "screening date YYYY-MM-DD (default today)"))
+	fs.Var(listFlag{&cfg.Universe.ScriptTypes}, "universe", opt("universe", "script_types", "comma separated scriptTypes (large, mid, small, micro, mf)"))
+	fs.StringVar(&cfg.Universe.Fno, "fno", cfg.Universe.Fno, opt("universe", "fno", `restrict to F&O ("yes") or non-F&O ("no") stocks`))
+	fs.Var(listFlag{&cfg.Universe.Industries}, "industry", opt("universe", "industries", "comma separated industries or fund categories"))
+	fs.Var(&multiFlag{values: &sc.Filters}, "filter", opt("screen", "filters", "filter expression, e.g. 'ret_12m > 30 && close > sma_200' (repeatable)"))
+	fs.Var(&multiFlag{values: &sc.Ranks}, "rank", opt("screen", "ranks", "rank expression, optionally suffixed with asc or desc (repeatable)"))
+	fs.Var(listFlag{&sc.Columns}, "columns", opt("screen", "columns", "comma separated extra columns to output"))
+	fs.IntVar(&sc.Limit, "limit", sc.Limit, opt("screen", "limit", "maximum number of rows, 0 for all"))
+	fs.StringVar(&sc.Format, "format", sc.Format, opt("screen", "format", "output format: table, csv, json or symbols"))
+	fs.StringVar(&sc.Out, "out", sc.Out, opt("screen", "out", "output file (default stdout)"))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	date, err := parseDate("date", sc.Date, today())
+	if err != nil {
+		return err
+	}
+
+	queries, closeQueries, err := openQueries(ctx, cfg, pricestore.Options{ScriptTypes: cfg.Universe.ScriptTypes})
+	if err != nil {
+		return err
+	}
+	defer closeQueries()
+
+	svc := services.NewService(queries)
+	if cfg.Universe.Fno != "" {
+		if svc.Lots, err = loadLots(ctx, cfg, queries); err != nil {
+			return err
+		}
+	}
+	result, err := screener.Run(ctx, svc, screener.Config{
+		Date:        date,
+		ScriptTypes: cfg.Universe.ScriptTypes,
+		Fno:         cfg.Universe.Fno,
+		Industries:  cfg.Universe.Industries,
+		Filters:     sc.Filters,
+		Ranks:       sc.Ranks,
+		Columns:     sc.Columns,
+		Limit:       sc.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if sc.Out != "" {
+		file, err := os.Create(sc.Out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := screener.Write(w, sc.Format, result); err != nil {
+		return err
+	}
+	if sc.Out != "" {
+		fmt.Printf("Screen of %d stocks exported to %s\n", len(result.Rows), sc.Out)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/tax"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// taxRules turns the tax section of the config into rules, the Indian
+// equity rates when it lists no regimes.
+func taxRules(cfg config.File) (tax.Rules, error) {
+	rules := tax.IndianEquity()
+	if len(cfg.Tax.Regimes) > 0 {
+		rules.Regimes = nil
+		for _, g := range cfg.Tax.Regimes {
+			from, err := time.Parse("2006-01-02", g.From)
+			if err != nil {
+				return rules, fmt.Errorf("invalid tax.regimes from %q: want YYYY-MM-DD", g.From)
+			}
+			rules.Regimes = append(rules.Regimes, tax.Regime{From: from, STCGPct: g.STCGPct, LTCGPct: g.LTCGPct, LTCGExemption: g.LTCGExemption})
+		}
+	}
+	rules.CessPct, rules.LongTermMonths = cfg.Tax.CessPct, cfg.Tax.LongTermMonths
+	return rules, rules.Validate()
+}
+
+func printGains(gains []tax.Gain) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Symbol\tBought\tSold\tQuantity\tCost\tProceeds\tGain\tTerm\t")
+	for _, g := range gains {
+		term := g.Term
+		if g.Grandfathered {
+			term += "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%g\t%.2f\t%.2f\t%+.2f\t%s\t\n", g.Symbol, g.Bought.Format("2006-01-02"), g.Sold.Format("2006-01-02"), g.Quantity, g.Cost, g.Proceeds, g.Amount(), term)
+	}
+	return tw.Flush()
+}
+
+func printTaxYears(years []tax.Year) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Year\tSTCG\tST Losses\tLTCG\tLT Losses\tBrought Fwd\tExempt\tTaxable ST\tTaxable LT\tTax\tCarried Fwd\t")
+	total := 0.0
+	for _, y := range years {
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t\n", tax.FYLabel(y.FY), y.ShortTermGains, y.ShortTermLosses, y.LongTermGains, y.LongTermLosses, y.BroughtForward, y.Exempt, y.TaxableShort, y.TaxableLong, y.Tax, y.CarriedForward)
+		total += y.Tax
+	}
+	fmt.Fprintf(tw, "Total\t\t\t\t\t\t\t\t\t%.2f\t\t\n", total)
+	return tw.Flush()
+}
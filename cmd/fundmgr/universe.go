@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/config"
+	"fund-manager/internal/fno"
+	"fund-manager/internal/universe"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func runUniverse(ctx context.Context, cfg config.File, args []string) error {
+	fs := newFlagSet("universe", "Sync the stocks in the database with the universe CSVs, matching on ISIN.\nNew stocks are added, known ones updated and unlisted ones marked removed;\nevery change is recorded with its date and printed as a report. The lot\nsizes per expiry month of the F&O list are recorded as well.")
+	fs.StringVar(&cfg.Data.StocksDir, "stocks-dir", cfg.Data.StocksDir, opt("data", "stocks_dir", "directory of <scriptType>.csv stock lists"))
+	fs.StringVar(&cfg.Data.FnoList, "fno-list", cfg.Data.FnoList, opt("data", "fno_list", "F&O market lots CSV"))
+	dateFlag := fs.String("date", "", "date the changes take effect YYYY-MM-DD (default today)")
+	dryRun := fs.Bool("dry-run", false, "print the report without changing the database")
+	history := fs.String("history", "", "print the changes recorded since YYYY-MM-DD instead of syncing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := requirePostgres(cfg, "universe (the csv and sqlite backends read the lists during ingest)"); err != nil {
+		return err
+	}
+
+	pool, queries, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	if *history != "" {
+		since, err := parseDate("history", *history, today())
+		if err != nil {
+			return err
+		}
+		changes, err := queries.GetStockChanges(ctx, pgtype.Date{Time: since, Valid: true})
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Date\tSymbol\tField\tOld\tNew")
+		for _, c := range changes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.ChangedOn.Time.Format("2006-01-02"), c.Symbol, c.Field, c.OldValue.String, c.NewValue.String)
+		}
+		return tw.Flush()
+	}
+
+	on, err := parseDate("date", *dateFlag, today())
+	if err != nil {
+		return err
+	}
+	diff, err := universe.Sync(ctx, pool, cfg.Data.StocksDir, cfg.Data.FnoList, on, *dryRun)
+	if err != nil {
+		return err
+	}
+	if err := diff.Write(os.Stdout); err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Println("Dry run, nothing written.")
+		return nil
+	}
+	return importLots(ctx, pool, cfg.Data.FnoList, on)
+}
+
+// importLots records the lot sizes of the F&O list and prints the changes
+// between expiry months and the revisions of months already stored.
+func importLots(ctx context.Context, pool *pgxpool.Pool, path string, on time.Time) error {
+	underlyings, err := fno.ReadMarketLots(path)
+	if err != nil {
+		return err
+	}
+	revisions, err := fno.Import(ctx, pool, underlyings, on)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Lot sizes of %d F&O underlyings recorded\n", len(underlyings))
+	for _, c := range fno.NewTable(underlyings).Changes() {
+		fmt.Printf("  %s lot size %d → %d from %s\n", c.Symbol, c.From, c.To, c.Month.Format("Jan-06"))
+	}
+	for _, r := range revisions {
+		fmt.Printf("  %s lot size for %s revised %d → %d\n", r.Symbol, r.Month.Format("Jan-06"), r.From, r.To)
+	}
+	return nil
+}
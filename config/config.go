@@ -26,6 +26,13 @@ func InitDatabase(ctx context.Context) (*pgxpool.Pool, *repository.Queries, erro
 	if connStr == "" {
 		return nil, nil, fmt.Errorf("POSTGRES env var not set")
 	}
+	return OpenDatabase(ctx, connStr)
+}
+
+func OpenDatabase(ctx context.Context, connStr string) (*pgxpool.Pool, *repository.Queries, error) {
+	if connStr == "" {
+		return nil, nil, fmt.Errorf("database url not set (use POSTGRES, %s or database.url)", EnvName("database", "url"))
+	}
 
 	pool, err := pgxpool.New(ctx, connStr)
 	if err != nil {
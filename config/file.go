@@ -0,0 +1,333 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFiles are tried in order when no config file is given explicitly.
+var DefaultFiles = []string{"fundmgr.yaml", "fundmgr.yml", "fundmgr.toml"}
+
+// EnvPrefix is prepended to the upper-cased section and key of every option,
+// e.g. FUNDMGR_BACKTEST_TOP_N overrides backtest.top_n.
+const EnvPrefix = "FUNDMGR"
+
+type File struct {
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Data      DataConfig      `yaml:"data" toml:"data"`
+	Download  DownloadConfig  `yaml:"download" toml:"download"`
+	Universe  UniverseConfig  `yaml:"universe" toml:"universe"`
+	Screen    ScreenConfig    `yaml:"screen" toml:"screen"`
+	Backtest  BacktestConfig  `yaml:"backtest" toml:"backtest"`
+	Report    ReportConfig    `yaml:"report" toml:"report"`
+	Portfolio PortfolioConfig `yaml:"portfolio" toml:"portfolio"`
+	Orders    OrdersConfig    `yaml:"orders" toml:"orders"`
+	Paper     PaperConfig     `yaml:"paper" toml:"paper"`
+	Tax       TaxConfig       `yaml:"tax" toml:"tax"`
+	Migrate   MigrateConfig   `yaml:"migrate" toml:"migrate"`
+}
+
+type DatabaseConfig struct {
+	URL     string `yaml:"url" toml:"url"`
+	Backend string `yaml:"backend" toml:"backend"` // postgres, csv or sqlite
+	SQLite  string `yaml:"sqlite" toml:"sqlite"`
+}
+
+type DataConfig struct {
+	StocksDir string `yaml:"stocks_dir" toml:"stocks_dir"`
+	DailyDir  string `yaml:"daily_dir" toml:"daily_dir"`
+	FnoList   string `yaml:"fno_list" toml:"fno_list"`
+	AMFIDir   string `yaml:"amfi_dir" toml:"amfi_dir"`   // scheme masters and NAV reports
+	IndexDir  string `yaml:"index_dir" toml:"index_dir"` // index price and TRI CSVs
+	Dividends string `yaml:"dividends" toml:"dividends"` // corporate actions CSV
+}
+
+type DownloadConfig struct {
+	Retries  int    `yaml:"retries" toml:"retries"`
+	Interval string `yaml:"interval" toml:"interval"` // between requests, e.g. 1s
+	Timeout  string `yaml:"timeout" toml:"timeout"`   // per request
+}
+
+type UniverseConfig struct {
+	ScriptTypes []string `yaml:"script_types" toml:"script_types"`
+	Fno         string   `yaml:"fno" toml:"fno"`
+	Industries  []string `yaml:"industries" toml:"industries"`
+}
+
+type ScreenConfig struct {
+	Date    string   `yaml:"date" toml:"date"`
+	Filters []string `yaml:"filters" toml:"filters"`
+	Ranks   []string `yaml:"ranks" toml:"ranks"`
+	Columns []string `yaml:"columns" toml:"columns"`
+	Limit   int      `yaml:"limit" toml:"limit"`
+	Format  string   `yaml:"format" toml:"format"`
+	Out     string   `yaml:"out" toml:"out"`
+}
+
+type BacktestConfig struct {
+	Start           string  `yaml:"start" toml:"start"`
+	End             string  `yaml:"end" toml:"end"`
+	TopN            int     `yaml:"top_n" toml:"top_n"`
+	LookbackMonths  int     `yaml:"lookback_months" toml:"lookback_months"`
+	RebalanceMonths int     `yaml:"rebalance_months" toml:"rebalance_months"`
+	Weighting       string  `yaml:"weighting" toml:"weighting"`
+	CommissionBps   float64 `yaml:"commission_bps" toml:"commission_bps"`
+	SlippageBps     float64 `yaml:"slippage_bps" toml:"slippage_bps"`
+	TaxBps          float64 `yaml:"tax_bps" toml:"tax_bps"`
+	InitialCapital  float64 `yaml:"initial_capital" toml:"initial_capital"`
+	Dividends       string  `yaml:"dividends" toml:"dividends"`
+	TradesOut       string  `yaml:"trades_out" toml:"trades_out"`
+	SpecsDir        string  `yaml:"specs_dir" toml:"specs_dir"`
+	RunsDir         string  `yaml:"runs_dir" toml:"runs_dir"`
+	StoreDB         bool    `yaml:"store_db" toml:"store_db"`
+	Preload         bool    `yaml:"preload" toml:"preload"`
+}
+
+type ReportConfig struct {
+	Trades    string `yaml:"trades" toml:"trades"`
+	Run       string `yaml:"run" toml:"run"`
+	HTML      string `yaml:"html" toml:"html"`
+	Benchmark string `yaml:"benchmark" toml:"benchmark"`
+}
+
+type PortfolioConfig struct {
+	Account string `yaml:"account" toml:"account"`
+}
+
+type OrdersConfig struct {
+	MinTrade  float64 `yaml:"min_trade" toml:"min_trade"`   // smallest order value
+	Format    string  `yaml:"format" toml:"format"`         // sheet, kite, basket or fix
+	Exchange  string  `yaml:"exchange" toml:"exchange"`     // exchange the broker formats name
+	Product   string  `yaml:"product" toml:"product"`       // CNC for delivery
+	OrderType string  `yaml:"order_type" toml:"order_type"` // MARKET, or LIMIT at the last close
+}
+
+type PaperConfig struct {
+	Account string `yaml:"account" toml:"account"`
+}
+
+// TaxConfig is the capital gains tax of portfolios. Without regimes the
+// Indian equity rates apply.
+type TaxConfig struct {
+	Regimes        []TaxRegime `yaml:"regimes" toml:"regimes"`
+	CessPct        float64     `yaml:"cess_pct" toml:"cess_pct"`
+	LongTermMonths int         `yaml:"long_term_months" toml:"long_term_months"`
+}
+
+type TaxRegime struct {
+	From          string  `yaml:"from" toml:"from"` // YYYY-MM-DD
+	STCGPct       float64 `yaml:"stcg_pct" toml:"stcg_pct"`
+	LTCGPct       float64 `yaml:"ltcg_pct" toml:"ltcg_pct"`
+	LTCGExemption float64 `yaml:"ltcg_exemption" toml:"ltcg_exemption"` // per financial year
+}
+
+type MigrateConfig struct {
+	Schema string `yaml:"schema" toml:"schema"`
+}
+
+func Defaults() File {
+	return File{
+		Database: DatabaseConfig{
+			Backend: "postgres",
+			SQLite:  "data/fundmgr.sqlite",
+		},
+		Data: DataConfig{
+			StocksDir: "data/stocks",
+			DailyDir:  "data/nseDaily/daily",
+			FnoList:   "data/fnoList.csv",
+			AMFIDir:   "data/amfi",
+			IndexDir:  "data/indices",
+			Dividends: "data/dividends.csv",
+		},
+		Download: DownloadConfig{
+			Retries:  4,
+			Interval: "1s",
+			Timeout:  "30s",
+		},
+		Universe: UniverseConfig{
+			ScriptTypes: []string{"mid", "small", "micro"},
+		},
+		Screen: ScreenConfig{
+			Ranks:   []string{"ret_12m"},
+			Columns: []string{"close"},
+			Limit:   10,
+			Format:  "table",
+		},
+		Backtest: BacktestConfig{
+			TopN:            10,
+			LookbackMonths:  12,
+			RebalanceMonths: 1,
+			Weighting:       "equal",
+			InitialCapital:  1000000,
+			TradesOut:       "trade_logs.csv",
+			SpecsDir:        "backtests",
+			RunsDir:         "runs",
+			StoreDB:         true,
+			Preload:         true,
+		},
+		Report: ReportConfig{
+			Trades: "trade_logs.csv",
+		},
+		Portfolio: PortfolioConfig{
+			Account: "main",
+		},
+		Orders: OrdersConfig{
+			MinTrade:  5000,
+			Format:    "sheet",
+			Exchange:  "NSE",
+			Product:   "CNC",
+			OrderType: "MARKET",
+		},
+		Paper: PaperConfig{
+			Account: "paper",
+		},
+		Tax: TaxConfig{
+			CessPct:        4,
+			LongTermMonths: 12,
+		},
+		Migrate: MigrateConfig{
+			Schema: "sql/schema.sql",
+		},
+	}
+}
+
+// Load returns the defaults overlaid with the config file at path (or the
+// first of DefaultFiles that exists when path is empty) and then with
+// environment variables. POSTGRES is honoured for the database URL.
+func Load(path string) (File, error) {
+	cfg := Defaults()
+
+	if path == "" {
+		for _, candidate := range DefaultFiles {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+	}
+	if path != "" {
+		if err := decodeFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	if url := os.Getenv("POSTGRES"); url != "" {
+		cfg.Database.URL = url
+	}
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func decodeFile(path string, cfg *File) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("unsupported config format %q (want .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// EnvName returns the environment variable that overrides section.key.
+func EnvName(section, key string) string {
+	return strings.ToUpper(EnvPrefix + "_" + section + "_" + key)
+}
+
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		key := strings.ToUpper(prefix + "_" + name)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnv(fv, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setValue(fv, raw); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func setValue(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", fv.Type())
+		}
+		// expressions may contain commas, so allow ";" as the separator
+		if strings.Contains(raw, ";") {
+			parts := make([]string, 0)
+			for _, part := range strings.Split(raw, ";") {
+				if part = strings.TrimSpace(part); part != "" {
+					parts = append(parts, part)
+				}
+			}
+			fv.Set(reflect.ValueOf(parts))
+			return nil
+		}
+		fv.Set(reflect.ValueOf(SplitList(raw)))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// SplitList splits a comma separated option, dropping empty entries.
+func SplitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,309 @@
+// Package amfi reads the mutual fund files AMFI publishes: the NAV
+// reports, of one day or a date range, and the scheme master. A scheme is
+// traded as an instrument of its own, the symbol MF<code> with script type
+// mf and its category as the industry, so that its NAVs screen, rank and
+// value like a stock's closes.
+package amfi
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"fund-manager/internal/instrument"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScriptType is the script type of schemes in stocks.
+const ScriptType = instrument.ScriptMF
+
+// Source marks daily rows copied from NAV reports.
+const Source = "amfi"
+
+const dateLayout = "02-Jan-2006"
+
+// Symbol is the symbol a scheme trades under.
+func Symbol(code int) string {
+	return "MF" + strconv.Itoa(code)
+}
+
+// ParseSymbol is the scheme code of a symbol made by Symbol.
+func ParseSymbol(symbol string) (int, bool) {
+	if !strings.HasPrefix(symbol, "MF") {
+		return 0, false
+	}
+	code, err := strconv.Atoi(symbol[2:])
+	return code, err == nil && code > 0
+}
+
+// Scheme is one plan and option of a fund; each has its own code and NAV.
+type Scheme struct {
+	Code         int
+	AMC          string
+	Name         string
+	Type         string // Open Ended, Close Ended or Interval Fund
+	Category     string // e.g. Equity Scheme - Large Cap Fund
+	ISINGrowth   string // or dividend payout
+	ISINReinvest string
+	Launch       time.Time // zero when unknown
+}
+
+// Merge fills the fields of s that other knows and s does not.
+func (s Scheme) Merge(other Scheme) Scheme {
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&s.AMC, other.AMC)
+	fill(&s.Name, other.Name)
+	fill(&s.Type, other.Type)
+	fill(&s.Category, other.Category)
+	fill(&s.ISINGrowth, other.ISINGrowth)
+	fill(&s.ISINReinvest, other.ISINReinvest)
+	if s.Launch.IsZero() {
+		s.Launch = other.Launch
+	}
+	return s
+}
+
+// NAV is a scheme's net asset value per unit on a date.
+type NAV struct {
+	Code       int
+	Date       time.Time
+	NAV        float64
+	Repurchase float64 // zero when not given
+	Sale       float64
+}
+
+// File is what one file holds. A scheme master has no NAVs; the schemes of
+// a NAV report lack launch dates.
+type File struct {
+	Schemes []Scheme
+	NAVs    []NAV
+}
+
+// Read reads a NAV report or a scheme master, telling them apart by their
+// header.
+func Read(r io.Reader) (File, error) {
+	br := bufio.NewReaderSize(r, 1<<16)
+	var header string
+	for header == "" {
+		line, err := br.ReadString('\n')
+		header = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+		if err == io.EOF && header == "" {
+			return File{}, fmt.Errorf("empty file")
+		}
+		if err != nil && err != io.EOF {
+			return File{}, err
+		}
+	}
+	if strings.Contains(header, ";") {
+		return readNAVs(header, br)
+	}
+	return readMaster(header, br)
+}
+
+func key(name string) string {
+	return strings.NewReplacer(" ", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// section headers of NAV reports, e.g. "Open Ended Schemes(Equity Scheme -
+// Large Cap Fund)" or "Open Ended Schemes ( Growth )"
+var sectionRe = regexp.MustCompile(`^(.*Schemes?)\s*\(\s*(.*?)\s*\)$`)
+
+func schemeType(s string) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(s), "Schemes"), "Scheme"))
+}
+
+// readNAVs reads the semicolon separated NAV report. Scheme type and
+// category come from the section lines and the AMC from the line naming it
+// before its schemes. Rows without a NAV, N.A. in the report, only add the
+// scheme.
+func readNAVs(header string, r *bufio.Reader) (File, error) {
+	cols := map[string]int{}
+	for i, name := range strings.Split(header, ";") {
+		switch k := key(name); {
+		case k == "schemecode":
+			cols["code"] = i
+		case k == "schemename":
+			cols["name"] = i
+		case strings.Contains(k, "growth"):
+			cols["growth"] = i
+		case strings.Contains(k, "reinvest"):
+			cols["reinvest"] = i
+		case k == "netassetvalue":
+			cols["nav"] = i
+		case k == "repurchaseprice":
+			cols["repurchase"] = i
+		case k == "saleprice":
+			cols["sale"] = i
+		case k == "date":
+			cols["date"] = i
+		}
+	}
+	for _, c := range []string{"code", "name", "nav", "date"} {
+		if _, ok := cols[c]; !ok {
+			return File{}, fmt.Errorf("NAV report header %q has no %s column", header, c)
+		}
+	}
+
+	var f File
+	index := make(map[int]int)
+	var typ, category, amc string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 2; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.Contains(line, ";") {
+			if m := sectionRe.FindStringSubmatch(line); m != nil {
+				typ, category, amc = schemeType(m[1]), m[2], ""
+			} else {
+				amc = line
+			}
+			continue
+		}
+		row := strings.Split(line, ";")
+		cell := func(c string) string {
+			i, ok := cols[c]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		code, err := strconv.Atoi(cell("code"))
+		if err != nil {
+			return f, fmt.Errorf("line %d: invalid scheme code %q", n, cell("code"))
+		}
+		s := Scheme{
+			Code:         code,
+			AMC:          amc,
+			Name:         strings.Join(strings.Fields(cell("name")), " "),
+			Type:         typ,
+			Category:     category,
+			ISINGrowth:   isin(cell("growth")),
+			ISINReinvest: isin(cell("reinvest")),
+		}
+		if i, ok := index[code]; ok {
+			f.Schemes[i] = f.Schemes[i].Merge(s)
+		} else {
+			index[code] = len(f.Schemes)
+			f.Schemes = append(f.Schemes, s)
+		}
+
+		nav, err := strconv.ParseFloat(cell("nav"), 64)
+		if err != nil || nav <= 0 {
+			continue
+		}
+		date, err := time.Parse(dateLayout, cell("date"))
+		if err != nil {
+			return f, fmt.Errorf("line %d: invalid date %q", n, cell("date"))
+		}
+		v := NAV{Code: code, Date: date, NAV: nav}
+		v.Repurchase, _ = strconv.ParseFloat(cell("repurchase"), 64)
+		v.Sale, _ = strconv.ParseFloat(cell("sale"), 64)
+		f.NAVs = append(f.NAVs, v)
+	}
+	return f, sc.Err()
+}
+
+// isin drops the placeholders the reports use for a missing ISIN.
+func isin(s string) string {
+	if len(s) != 12 {
+		return ""
+	}
+	return s
+}
+
+// readMaster reads the comma separated scheme master, which names the plan
+// and option in Scheme NAV Name and runs both ISINs together in its last
+// column.
+func readMaster(header string, r io.Reader) (File, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	head, err := csv.NewReader(strings.NewReader(header)).Read()
+	if err != nil {
+		return File{}, fmt.Errorf("invalid scheme master header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range head {
+		switch k := key(name); {
+		case k == "amc":
+			cols["amc"] = i
+		case k == "code":
+			cols["code"] = i
+		case k == "schemename":
+			cols["fund"] = i
+		case k == "schemenavname":
+			cols["name"] = i
+		case k == "schemetype":
+			cols["type"] = i
+		case k == "schemecategory":
+			cols["category"] = i
+		case k == "launchdate":
+			cols["launch"] = i
+		case strings.HasPrefix(k, "isin"):
+			cols["isin"] = i
+		}
+	}
+	for _, c := range []string{"code", "fund"} {
+		if _, ok := cols[c]; !ok {
+			return File{}, fmt.Errorf("scheme master header %q has no %s column", header, c)
+		}
+	}
+
+	var f File
+	for n := 2; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return f, fmt.Errorf("line %d: %w", n, err)
+		}
+		cell := func(c string) string {
+			i, ok := cols[c]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		if len(row) == 1 && cell("code") == "" {
+			continue
+		}
+		code, err := strconv.Atoi(cell("code"))
+		if err != nil {
+			return f, fmt.Errorf("line %d: invalid scheme code %q", n, cell("code"))
+		}
+		name := cell("name")
+		if name == "" {
+			name = cell("fund")
+		}
+		s := Scheme{
+			Code:     code,
+			AMC:      cell("amc"),
+			Name:     strings.Join(strings.Fields(name), " "),
+			Type:     schemeType(cell("type")),
+			Category: cell("category"),
+		}
+		isins := strings.ReplaceAll(cell("isin"), " ", "")
+		if len(isins) >= 12 {
+			s.ISINGrowth = isin(isins[:12])
+			if len(isins) >= 24 {
+				s.ISINReinvest = isin(isins[12:24])
+			}
+		}
+		if launch, err := time.Parse(dateLayout, cell("launch")); err == nil {
+			s.Launch = launch
+		}
+		f.Schemes = append(f.Schemes, s)
+	}
+	return f, nil
+}
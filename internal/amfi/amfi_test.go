@@ -0,0 +1,114 @@
+package amfi
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const navReport = "\ufeffScheme Code;Scheme Name;ISIN Div Payout/ISIN Growth;ISIN Div Reinvestment;Net Asset Value;Repurchase Price;Sale Price;Date\r\n" +
+	"\r\n" +
+	"Open Ended Schemes ( Other Scheme - Index Funds )\r\n" +
+	"\r\n" +
+	"UTI Mutual Fund\r\n" +
+	"\r\n" +
+	"120716;UTI Nifty 50 Index Fund  - Direct Plan - Growth Option;INF789F01XA0;;150.1234;;;01-Apr-2024\r\n" +
+	"120716;UTI Nifty 50 Index Fund  - Direct Plan - Growth Option;INF789F01XA0;;151.5;;;02-Apr-2024\r\n" +
+	"\r\n" +
+	"Open Ended Schemes ( Equity Scheme - Large Cap Fund )\r\n" +
+	"\r\n" +
+	"Axis Mutual Fund\r\n" +
+	"\r\n" +
+	"120465;Axis Bluechip Fund - Direct Plan - Growth;INF846K01DP8;-;55.2;54.65;;01-Apr-2024\r\n" +
+	"120466;Axis Bluechip Fund - Direct Plan - IDCW;INF846K01DQ6;INF846K01DR4;N.A.;;;01-Apr-2024\r\n"
+
+func TestReadNAVReport(t *testing.T) {
+	f, err := Read(strings.NewReader(navReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Schemes) != 3 {
+		t.Fatalf("schemes %+v", f.Schemes)
+	}
+	index := f.Schemes[0]
+	if index.Code != 120716 || index.AMC != "UTI Mutual Fund" || index.Type != "Open Ended" || index.Category != "Other Scheme - Index Funds" {
+		t.Fatalf("index fund %+v", index)
+	}
+	if index.Name != "UTI Nifty 50 Index Fund - Direct Plan - Growth Option" || index.ISINGrowth != "INF789F01XA0" || index.ISINReinvest != "" {
+		t.Fatalf("index fund %+v", index)
+	}
+	idcw := f.Schemes[2]
+	if idcw.AMC != "Axis Mutual Fund" || idcw.Category != "Equity Scheme - Large Cap Fund" || idcw.ISINReinvest != "INF846K01DR4" {
+		t.Fatalf("idcw %+v", idcw)
+	}
+
+	// the N.A. row adds its scheme but no NAV
+	if len(f.NAVs) != 3 {
+		t.Fatalf("navs %+v", f.NAVs)
+	}
+	want := NAV{Code: 120465, Date: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), NAV: 55.2, Repurchase: 54.65}
+	if f.NAVs[2] != want {
+		t.Fatalf("nav %+v, want %+v", f.NAVs[2], want)
+	}
+}
+
+func TestReadCurrentNAVs(t *testing.T) {
+	// the daily NAVAll file orders its columns differently and has no
+	// repurchase or sale prices
+	report := "Scheme Code;ISIN Div Payout/ ISIN Growth;ISIN Div Reinvestment;Scheme Name;Net Asset Value;Date\n" +
+		"Open Ended Schemes(Equity Scheme - Large Cap Fund)\n" +
+		"Axis Mutual Fund\n" +
+		"120465;INF846K01DP8;-;Axis Bluechip Fund - Direct Plan - Growth;57.01;18-Oct-2026\n"
+	f, err := Read(strings.NewReader(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Schemes) != 1 || f.Schemes[0].Name != "Axis Bluechip Fund - Direct Plan - Growth" || f.Schemes[0].ISINGrowth != "INF846K01DP8" {
+		t.Fatalf("schemes %+v", f.Schemes)
+	}
+	if len(f.NAVs) != 1 || f.NAVs[0].NAV != 57.01 || !f.NAVs[0].Date.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("navs %+v", f.NAVs)
+	}
+
+	if _, err := Read(strings.NewReader("Scheme Code;Scheme Name\n")); err == nil {
+		t.Fatal("report without NAV and date columns accepted")
+	}
+}
+
+func TestReadSchemeMaster(t *testing.T) {
+	master := "AMC,Code,Scheme Name,Scheme Type,Scheme Category,Scheme NAV Name,Scheme Minimum Amount,Launch Date, Closure Date,ISIN Div Payout/ ISIN GrowthISIN Div Reinvestment\n" +
+		"Axis Mutual Fund,120466,Axis Bluechip Fund,Open Ended,Equity Scheme - Large Cap Fund,Axis Bluechip Fund - Direct Plan - IDCW,\"5,000\",01-Jan-2013,,INF846K01DQ6INF846K01DR4\n" +
+		"UTI Mutual Fund,120716,UTI Nifty 50 Index Fund,Open Ended,Other Scheme - Index Funds,,500,,,INF789F01XA0\n"
+	f, err := Read(strings.NewReader(master))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Schemes) != 2 || len(f.NAVs) != 0 {
+		t.Fatalf("file %+v", f)
+	}
+	axis := f.Schemes[0]
+	if axis.Code != 120466 || axis.Name != "Axis Bluechip Fund - Direct Plan - IDCW" || axis.Type != "Open Ended" || axis.Category != "Equity Scheme - Large Cap Fund" {
+		t.Fatalf("axis %+v", axis)
+	}
+	if axis.ISINGrowth != "INF846K01DQ6" || axis.ISINReinvest != "INF846K01DR4" || !axis.Launch.Equal(time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("axis %+v", axis)
+	}
+	// without a NAV name the fund's name is used
+	if uti := f.Schemes[1]; uti.Name != "UTI Nifty 50 Index Fund" || uti.ISINGrowth != "INF789F01XA0" || !uti.Launch.IsZero() {
+		t.Fatalf("uti %+v", uti)
+	}
+}
+
+func TestSymbol(t *testing.T) {
+	if s := Symbol(120716); s != "MF120716" {
+		t.Fatalf("symbol %s", s)
+	}
+	if code, ok := ParseSymbol("MF120716"); !ok || code != 120716 {
+		t.Fatalf("parsed %d, %v", code, ok)
+	}
+	for _, s := range []string{"RELIANCE", "MF", "MFX1"} {
+		if _, ok := ParseSymbol(s); ok {
+			t.Fatalf("%s parsed as a scheme", s)
+		}
+	}
+}
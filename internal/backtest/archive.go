@@ -0,0 +1,387 @@
+package backtest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"fund-manager/internal/services"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DataFingerprint identifies the state of the price data a run used. A
+// backend that loads only the prices a run needs, as the csv backend does,
+// counts those, described by Window; the others count all of daily.
+type DataFingerprint struct {
+	Rows    int64  `json:"rows"`
+	Stocks  int64  `json:"stocks"`
+	MaxDate string `json:"max_date"`
+	Backend string `json:"backend,omitempty"` // empty in runs that predate it
+	Window  string `json:"window,omitempty"`
+}
+
+// Comparable reports whether f and g count the same source, so that equal
+// fingerprints mean the same data: the same backend and window.
+func (f DataFingerprint) Comparable(g DataFingerprint) bool {
+	return f.Backend == g.Backend && f.Window == g.Window
+}
+
+func (f DataFingerprint) String() string {
+	source := f.Backend
+	if source == "" {
+		source = "unknown backend"
+	}
+	if f.Window != "" {
+		source += " (" + f.Window + ")"
+	}
+	return fmt.Sprintf("%s: %d rows of %d stocks to %s", source, f.Rows, f.Stocks, f.MaxDate)
+}
+
+type RunMeta struct {
+	Name       string          `json:"name"`
+	RunID      string          `json:"run_id"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	GitCommit  string          `json:"git_commit"`
+	GitDirty   bool            `json:"git_dirty"`
+	Data       DataFingerprint `json:"data"`
+}
+
+type Metrics struct {
+	InitialCapital float64 `json:"initial_capital"`
+	FinalEquity    float64 `json:"final_equity"`
+	NetProfit      float64 `json:"net_profit"`
+	CAGR           float64 `json:"cagr"`
+	MaxDrawdown    float64 `json:"max_drawdown"`
+	TotalTrades    int     `json:"total_trades"`
+	WinningTrades  int     `json:"winning_trades"`
+	WinRate        float64 `json:"win_rate"`
+	AverageProfit  float64 `json:"average_profit"`
+	TotalCosts     float64 `json:"total_costs"`
+	RollCosts      float64 `json:"roll_costs,omitempty"`
+	CashInterest   float64 `json:"cash_interest,omitempty"`
+	MaxMarginUsed  float64 `json:"max_margin_used,omitempty"`
+	// set when money is added or taken out during the run; net profit is
+	// then over the net invested
+	NetInvested float64 `json:"net_invested,omitempty"`
+	XIRR        float64 `json:"xirr,omitempty"`
+	TWR         float64 `json:"twr,omitempty"`
+	MWR         float64 `json:"mwr,omitempty"`
+	TaxPaid     float64 `json:"tax_paid,omitempty"`
+	// set with dividends: CAGR is then the total return
+	Dividends float64 `json:"dividends,omitempty"`
+	PriceCAGR float64 `json:"price_cagr,omitempty"`
+}
+
+// Run is a backtest loaded back from its archive directory.
+type Run struct {
+	Dir         string
+	Spec        Spec
+	Meta        RunMeta
+	Metrics     Metrics
+	TradeLogs   []TradeLog
+	EquityDates []time.Time
+	EquityCurve []float64
+	Returns     []float64 // see PeriodReturns; nil in archives that predate them
+}
+
+func MetricsOf(result BacktestResult, initialCapital float64) Metrics {
+	m := Metrics{
+		InitialCapital: initialCapital,
+		FinalEquity:    result.FinalEquity,
+		NetProfit:      result.FinalEquity - initialCapital,
+		CAGR:           result.CAGR,
+		MaxDrawdown:    result.Drawdown,
+		TotalTrades:    result.TotalTrades,
+		WinningTrades:  result.WinningTrades,
+		WinRate:        result.WinRate,
+		AverageProfit:  result.AverageProfit,
+		TotalCosts:     result.TotalCosts,
+		RollCosts:      result.RollCosts,
+		CashInterest:   result.CashInterest,
+		MaxMarginUsed:  result.MaxMarginUsed,
+		TaxPaid:        result.TaxPaid,
+		Dividends:      result.Dividends,
+		PriceCAGR:      result.PriceCAGR,
+	}
+	if len(result.Flows) > 1 {
+		if perf, err := result.Returns(); err == nil {
+			m.NetInvested, m.XIRR, m.TWR, m.MWR = perf.Invested, perf.XIRR, perf.TWR, perf.MWR
+			m.NetProfit = result.FinalEquity - perf.Invested
+		}
+	}
+	return m
+}
+
+// Fingerprint counts the prices svc reads from backend. window describes
+// what svc loaded when it holds only the prices of the run, and is empty
+// when svc reads all of daily.
+func Fingerprint(ctx context.Context, svc *services.Service, backend, window string) (DataFingerprint, error) {
+	row, err := svc.GetDataFingerprint(ctx)
+	if err != nil {
+		return DataFingerprint{}, fmt.Errorf("failed to fingerprint data: %w", err)
+	}
+	fp := DataFingerprint{Rows: row.RowCount, Stocks: row.StockCount, Backend: backend, Window: window}
+	if row.MaxDate.Valid {
+		fp.MaxDate = row.MaxDate.Time.Format("2006-01-02")
+	}
+	return fp, nil
+}
+
+// GitCommit reports the HEAD commit of the working directory's repository
+// and whether it has uncommitted changes. It returns "" outside a repository.
+func GitCommit() (string, bool) {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	status, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+	dirty := err == nil && len(strings.TrimSpace(string(status))) > 0
+	return strings.TrimSpace(string(out)), dirty
+}
+
+// SaveRun writes a run to <runsDir>/<spec name>/<run id>/ and returns the
+// directory. The run id is taken from meta.StartedAt.
+func SaveRun(runsDir string, spec Spec, meta RunMeta, result BacktestResult) (string, error) {
+	if meta.RunID == "" {
+		meta.RunID = meta.StartedAt.UTC().Format("20060102-150405")
+	}
+	meta.Name = spec.Name
+	dir := filepath.Join(runsDir, spec.Name, meta.RunID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	specYAML, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "spec.yaml"), specYAML, 0644); err != nil {
+		return "", err
+	}
+	if err := writeJSON(filepath.Join(dir, "meta.json"), meta); err != nil {
+		return "", err
+	}
+	if err := writeJSON(filepath.Join(dir, "metrics.json"), MetricsOf(result, spec.InitialCapital)); err != nil {
+		return "", err
+	}
+	if err := ExportTradeLogsToCSV(filepath.Join(dir, "trades.csv"), result.TradeLogs); err != nil {
+		return "", err
+	}
+	if err := exportEquityCSV(filepath.Join(dir, "equity.csv"), result.EquityDates, result.EquityCurve, result.MonthlyReturns, result.AfterTaxEquity); err != nil {
+		return "", err
+	}
+	if result.Gains != nil {
+		if err := ExportGainsCSV(filepath.Join(dir, "capital_gains.csv"), result.Gains); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func LoadRun(dir string) (Run, error) {
+	run := Run{Dir: dir}
+
+	spec, err := LoadSpec(filepath.Join(dir, "spec.yaml"))
+	if err != nil {
+		return run, err
+	}
+	run.Spec = spec
+	if err := readJSON(filepath.Join(dir, "meta.json"), &run.Meta); err != nil {
+		return run, err
+	}
+	if err := readJSON(filepath.Join(dir, "metrics.json"), &run.Metrics); err != nil {
+		return run, err
+	}
+	if run.TradeLogs, err = ReadTradeLogsCSV(filepath.Join(dir, "trades.csv")); err != nil {
+		return run, err
+	}
+	if run.EquityDates, run.EquityCurve, run.Returns, err = readEquityCSV(filepath.Join(dir, "equity.csv")); err != nil {
+		return run, err
+	}
+	return run, nil
+}
+
+// Result rebuilds the parts of a BacktestResult that an archive keeps. The
+// portfolio log is recovered from the trades open on each equity date.
+func (r Run) Result() BacktestResult {
+	result := BacktestResult{
+		TradeLogs:     r.TradeLogs,
+		EquityCurve:   r.EquityCurve,
+		EquityDates:   r.EquityDates,
+		Drawdown:      r.Metrics.MaxDrawdown,
+		CAGR:          r.Metrics.CAGR,
+		TotalTrades:   r.Metrics.TotalTrades,
+		WinningTrades: r.Metrics.WinningTrades,
+		WinRate:       r.Metrics.WinRate,
+		AverageProfit: r.Metrics.AverageProfit,
+		Profit:        SummarizeTrades(r.TradeLogs).Profit,
+		TotalCosts:    r.Metrics.TotalCosts,
+		FinalEquity:   r.Metrics.FinalEquity,
+		RollCosts:     r.Metrics.RollCosts,
+		CashInterest:  r.Metrics.CashInterest,
+		MaxMarginUsed: r.Metrics.MaxMarginUsed,
+		TaxPaid:       r.Metrics.TaxPaid,
+	}
+
+	result.MonthlyReturns = r.PeriodReturns()
+	for i, d := range r.EquityDates {
+		// the last point is the final liquidation, nothing is held
+		if i == len(r.EquityDates)-1 {
+			break
+		}
+		var held []string
+		for _, t := range r.TradeLogs {
+			if !t.EntryDate.After(d) && t.ExitDate.After(d) {
+				held = append(held, t.Symbol)
+			}
+		}
+		sort.Strings(held)
+		result.PortfolioLog = append(result.PortfolioLog, held)
+	}
+	return result
+}
+
+// PeriodReturns is the return of every equity point over the one before,
+// the first over the initial capital, net of the money that came in or
+// went out. Archives without them fall back to the plain ratios of the
+// equity points, which are the same when nothing flowed.
+func (r Run) PeriodReturns() []float64 {
+	if len(r.Returns) == len(r.EquityCurve) {
+		return r.Returns
+	}
+	return EquityReturns(r.Metrics.InitialCapital, r.EquityCurve)
+}
+
+// EquityReturns is the ratio of every equity point to the one before, the
+// first to initial, minus one.
+func EquityReturns(initial float64, equity []float64) []float64 {
+	out := make([]float64, len(equity))
+	prev := initial
+	for i, v := range equity {
+		if prev > 0 {
+			out[i] = v/prev - 1
+		}
+		prev = v
+	}
+	return out
+}
+
+// Growth compounds period returns into the value of one unit invested
+// before the first of them.
+func Growth(returns []float64) []float64 {
+	out := make([]float64, len(returns))
+	unit := 1.0
+	for i, r := range returns {
+		unit *= 1 + r
+		out[i] = unit
+	}
+	return out
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// exportEquityCSV writes the equity curve, with the after-tax curve next
+// to it when there is one and the period returns last.
+func exportEquityCSV(filename string, dates []time.Time, equity, periodReturns, afterTax []float64) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"Date", "Equity"}
+	if afterTax != nil {
+		header = append(header, "AfterTax")
+	}
+	header = append(header, "Return")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for i, d := range dates {
+		record := []string{d.Format("2006-01-02"), fmt.Sprintf("%.2f", equity[i])}
+		if afterTax != nil {
+			record = append(record, fmt.Sprintf("%.2f", afterTax[i]))
+		}
+		record = append(record, strconv.FormatFloat(periodReturns[i], 'g', -1, 64))
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readEquityCSV reads an equity.csv; returns is nil when the file has no
+// Return column.
+func readEquityCSV(filename string) (dates []time.Time, equity, returns []float64, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	returnCol := -1
+	for i, row := range records {
+		if i == 0 {
+			for j, h := range row {
+				if h == "Return" {
+					returnCol = j
+				}
+			}
+			continue
+		}
+		d, err := time.Parse("2006-01-02", row[0])
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s line %d: %w", filename, i+1, err)
+		}
+		v, err := strconv.ParseFloat(row[1], 64)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s line %d: %w", filename, i+1, err)
+		}
+		dates = append(dates, d)
+		equity = append(equity, v)
+		if returnCol >= 0 && returnCol < len(row) {
+			r, err := strconv.ParseFloat(row[returnCol], 64)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("%s line %d: %w", filename, i+1, err)
+			}
+			returns = append(returns, r)
+		}
+	}
+	return dates, equity, returns, nil
+}
@@ -3,22 +3,57 @@ package backtest
 
 import (
 	"context"
+	"fmt"
 	"fund-manager/internal/repository"
+	"fund-manager/internal/returns"
 	"fund-manager/internal/services"
+	"fund-manager/internal/tax"
 	"log"
 	"math"
+	"sort"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type BacktestConfig struct {
-	StartDate      time.Time
-	EndDate        time.Time
-	TopN           int32
-	ScriptType     []string
-	InitialCapital float64
-	Service        *services.Service
+	StartDate       time.Time
+	EndDate         time.Time
+	TopN            int32
+	ScriptType      []string
+	Industries      []string // lower case; every industry when empty
+	InitialCapital  float64
+	LookbackMonths  int32  // momentum lookback, 12 when unset
+	RebalanceMonths int    // months between rebalances, 1 when unset
+	Weighting       string // WeightEqual (default), WeightRank or WeightMomentum
+	Costs           CostModel
+	Futures         FuturesConfig
+	Contributions   ContributionPlan
+	Tax             *tax.Rules // capital gains paid from the account; nil for none
+	Dividends       string     // DividendsCash or DividendsReinvest; ignored when empty
+	Service         *services.Service
+}
+
+const (
+	WeightEqual    = "equal"
+	WeightRank     = "rank"
+	WeightMomentum = "momentum"
+)
+
+// CostModel charges a percentage of traded value on every buy and sell.
+type CostModel struct {
+	CommissionBps float64 `yaml:"commission_bps" json:"commission_bps"` // brokerage and exchange charges
+	SlippageBps   float64 `yaml:"slippage_bps" json:"slippage_bps"`     // execution shortfall against the close
+	TaxBps        float64 `yaml:"tax_bps" json:"tax_bps"`               // STT, stamp duty and GST
+}
+
+func (c CostModel) rate() float64 {
+	return (c.CommissionBps + c.SlippageBps + c.TaxBps) / 10000
+}
+
+// Cost returns the charges for trading value worth of stock.
+func (c CostModel) Cost(value float64) float64 {
+	return math.Abs(value) * c.rate()
 }
 
 type TradeLog struct {
@@ -33,11 +68,17 @@ type TradeLog struct {
 	Quantity    float64
 	AmountUsed  float64
 	MaxDrawdown float64 // New field for individual stock drawdown
+	Costs       float64
+	// dividends earned while held, which Profit leaves out, and the return
+	// with them
+	Dividends      float64
+	TotalReturnPct float64
 }
 
 type BacktestResult struct {
 	TradeLogs      []TradeLog
 	EquityCurve    []float64
+	EquityDates    []time.Time
 	MonthlyReturns []float64
 	Drawdown       float64
 	CAGR           float64
@@ -47,153 +88,827 @@ type BacktestResult struct {
 	WinRate        float64
 	AverageProfit  float64
 	Profit         float64
+	TotalCosts     float64
+	FinalEquity    float64
+	RollCosts      float64 // part of TotalCosts
+	CashInterest   float64
+	MaxMarginUsed  float64            // highest margin blocked, as a share of equity
+	Flows          []returns.CashFlow // the initial capital and any contributions
+	// set with tax rules: the equity curve less the tax owed but not yet
+	// paid, and the gains behind it
+	TaxPaid        float64
+	AfterTaxEquity []float64
+	Gains          []tax.Gain
+	TaxYears       []tax.Year
+	// set with dividends: what the positions earned, and the CAGR of the
+	// equity curve without it
+	Dividends float64
+	PriceCAGR float64
+}
+
+type position struct {
+	quantity   float64
+	entryPrice float64
+	entryDate  time.Time
+	entryCost  float64
+	dividends  float64
 }
 
 func RunBacktest(ctx context.Context, cfg BacktestConfig) BacktestResult {
-	equity := cfg.InitialCapital
+	lookback := cfg.LookbackMonths
+	if lookback <= 0 {
+		lookback = 12
+	}
+	every := cfg.RebalanceMonths
+	if every <= 0 {
+		every = 1
+	}
+
+	cash := cfg.InitialCapital
+	prevEquity := cfg.InitialCapital
 	equityCurve := make([]float64, 0)
+	equityDates := make([]time.Time, 0)
 	monthlyReturns := make([]float64, 0)
 	portfolioLog := make([][]string, 0)
-
 	tradeLogs := make([]TradeLog, 0)
-	currentPortfolio := make(map[string]struct{})
-	entryPrices := make(map[string]float64)
-	entryDates := make(map[string]time.Time)
+	totalCosts := 0.0
+	rollCosts, cashInterest, maxMarginUsed := 0.0, 0.0, 0.0
+	fut := cfg.Futures
+	if fut.Mode != "" && fut.Mode != FuturesCash && cfg.Service.Lots.Len() == 0 {
+		log.Printf("No F&O lot sizes loaded, the basket is held in cash")
+	}
+
+	positions := make(map[string]*position)
+	futures := make(map[string]*futurePosition) // keyed by underlying + FuturesSuffix
+	prices := newPriceBook(ctx, cfg.Service)
+
+	// with tax rules every purchase is a tax lot, sold first in, first out
+	var ledger *tax.Ledger
+	if cfg.Tax != nil {
+		ledger = tax.NewLedger(*cfg.Tax, func(symbol string) (float64, bool) {
+			price, ok, err := cfg.Service.Close(ctx, symbol, tax.GrandfatherDate)
+			return price, ok && err == nil
+		})
+	}
+	buyLot := func(sym string, date time.Time, quantity, cost float64) {
+		if ledger != nil {
+			ledger.Buy(tax.Lot{Symbol: sym, Date: date, Quantity: quantity, Cost: cost})
+		}
+	}
+
+	closePosition := func(sym string, date time.Time) {
+		pos := positions[sym]
+		exitPrice := prices.at(sym, date)
+		amount := pos.quantity * pos.entryPrice
+		proceeds := pos.quantity * exitPrice
+		exitCost := cfg.Costs.Cost(proceeds)
+		profit := proceeds - amount - pos.entryCost - exitCost
+		profitPct, totalPct := 0.0, 0.0
+		if amount > 0 {
+			profitPct = (profit / amount) * 100
+			totalPct = ((profit + pos.dividends) / amount) * 100
+		}
+
+		tradeLogs = append(tradeLogs, TradeLog{
+			Symbol:         sym,
+			EntryDate:      pos.entryDate,
+			ExitDate:       date,
+			EntryPrice:     pos.entryPrice,
+			ExitPrice:      exitPrice,
+			Profit:         profit,
+			ProfitPct:      profitPct,
+			DaysHeld:       int(date.Sub(pos.entryDate).Hours() / 24),
+			Quantity:       pos.quantity,
+			AmountUsed:     amount,
+			MaxDrawdown:    getStockDrawdown(ctx, cfg, sym, pos.entryDate, date),
+			Costs:          pos.entryCost + exitCost,
+			Dividends:      pos.dividends,
+			TotalReturnPct: totalPct,
+		})
+
+		if ledger != nil {
+			if _, err := ledger.Sell(sym, date, pos.quantity, proceeds-exitCost, nil); err != nil {
+				log.Printf("Tax lots of %s: %v", sym, err)
+			}
+		}
+
+		cash += proceeds - exitCost
+		totalCosts += exitCost
+		delete(positions, sym)
+	}
+
+	margin := func(date time.Time) float64 {
+		blocked := 0.0
+		for _, f := range futures {
+			blocked += f.notional(prices.at(f.underlying, date)) * fut.margin()
+		}
+		return blocked
+	}
+
+	openFuture := func(underlying string, lotSize int32, lots int64, short bool, price float64, date time.Time) {
+		quantity := float64(lots) * float64(lotSize)
+		if short {
+			quantity = -quantity
+		}
+		cost := cfg.Costs.Cost(quantity * price)
+		cash -= cost
+		totalCosts += cost
+		futures[underlying+FuturesSuffix] = &futurePosition{
+			underlying: underlying,
+			lots:       lots,
+			quantity:   quantity,
+			entryPrice: price,
+			settled:    price,
+			entryDate:  date,
+			rolledTo:   monthOf(date),
+			costs:      cost,
+		}
+	}
+
+	// futures settle their gains and losses in cash; the trade log counts
+	// the notional as the amount used
+	closeFuture := func(key string, date time.Time) {
+		f := futures[key]
+		exitPrice := prices.at(f.underlying, date)
+		exitCost := cfg.Costs.Cost(f.quantity * exitPrice)
+		pnl := f.quantity * (exitPrice - f.entryPrice)
+		amount := f.notional(f.entryPrice)
+		profit := pnl - f.costs - exitCost
+		profitPct := 0.0
+		if amount > 0 {
+			profitPct = (profit / amount) * 100
+		}
+		drawdown := 0.0
+		if f.quantity > 0 {
+			drawdown = getStockDrawdown(ctx, cfg, f.underlying, f.entryDate, date)
+		}
+
+		tradeLogs = append(tradeLogs, TradeLog{
+			Symbol:         key,
+			EntryDate:      f.entryDate,
+			ExitDate:       date,
+			EntryPrice:     f.entryPrice,
+			ExitPrice:      exitPrice,
+			Profit:         profit,
+			ProfitPct:      profitPct,
+			DaysHeld:       int(date.Sub(f.entryDate).Hours() / 24),
+			Quantity:       f.quantity,
+			AmountUsed:     amount,
+			MaxDrawdown:    drawdown,
+			Costs:          f.costs + exitCost,
+			TotalReturnPct: profitPct,
+		})
+
+		cash += f.quantity*(exitPrice-f.settled) - exitCost
+		totalCosts += exitCost
+		delete(futures, key)
+	}
+
+	// settle pays the futures' gains and losses since the last settlement
+	// in cash, as the exchange does with variation margin
+	settle := func(date time.Time) {
+		for _, f := range futures {
+			price := prices.at(f.underlying, date)
+			cash += f.quantity * (price - f.settled)
+			f.settled = price
+		}
+	}
+
+	// rollFutures charges the roll into every expiry since the last one
+	rollFutures := func(date time.Time) {
+		month := monthOf(date)
+		for _, key := range sortedFutures(futures) {
+			f := futures[key]
+			rolls := monthsBetween(f.rolledTo, month)
+			if rolls <= 0 {
+				continue
+			}
+			cost := float64(rolls) * f.notional(prices.at(f.underlying, date)) * fut.RollCostBps / 10000
+			cash -= cost
+			f.costs += cost
+			f.rolledTo = month
+			rollCosts += cost
+			totalCosts += cost
+		}
+	}
+
+	// accrue pays the cash yield on what was not blocked as margin since
+	// the previous rebalance
+	var accruedTo time.Time
+	accrue := func(date time.Time) {
+		if fut.CashYieldPct > 0 && !accruedTo.IsZero() {
+			free := cash - margin(accruedTo)
+			days := date.Sub(accruedTo).Hours() / 24
+			if free > 0 && days > 0 {
+				interest := free * fut.CashYieldPct / 100 * days / 365
+				cash += interest
+				cashInterest += interest
+			}
+		}
+		accruedTo = date
+	}
+
+	// hedge keeps a short in the index futures against the basket's value,
+	// trading only when the number of lots changes
+	hedge := func(date time.Time) {
+		key := fut.HedgeSymbol + FuturesSuffix
+		exposure := 0.0
+		for sym, pos := range positions {
+			exposure += pos.quantity * prices.at(sym, date)
+		}
+		var lots int64
+		lot, ok := cfg.Service.FnoLot(fut.HedgeSymbol, date)
+		price := prices.at(fut.HedgeSymbol, date)
+		if ok && price > 0 {
+			lots = int64(math.Round(exposure * fut.HedgeRatio / (price * float64(lot.Size))))
+			if fut.margin() > 0 {
+				free := cash - margin(date)
+				if cur, held := futures[key]; held {
+					free += cur.notional(price) * fut.margin()
+				}
+				lots = min(lots, lot.Contracts(free/(fut.margin()+cfg.Costs.rate()), price))
+			}
+		} else {
+			log.Printf("No %s future at %s, the basket is not hedged", fut.HedgeSymbol, date.Format("2006-01-02"))
+		}
+		if cur, held := futures[key]; held {
+			if cur.lots == lots {
+				return
+			}
+			closeFuture(key, date)
+		}
+		if lots > 0 {
+			openFuture(fut.HedgeSymbol, lot.Size, lots, true, price, date)
+		}
+	}
+
+	// dividends earned on their ex-date and paid on their record date
+	var receivables []receivable
+	dividendsTotal, periodDividends := 0.0, 0.0
+
+	markToMarket := func(date time.Time) float64 {
+		equity := cash
+		for _, r := range receivables {
+			equity += r.amount
+		}
+		for sym, pos := range positions {
+			equity += pos.quantity * prices.at(sym, date)
+		}
+		for _, f := range futures {
+			equity += f.quantity * (prices.at(f.underlying, date) - f.settled)
+		}
+		return equity
+	}
+
+	// sellPart sells quantity shares of a position, logged as a trade of
+	// their own
+	sellPart := func(sym string, quantity float64, date time.Time) {
+		pos := positions[sym]
+		if quantity >= pos.quantity {
+			closePosition(sym, date)
+			return
+		}
+		part := &position{quantity: quantity, entryPrice: pos.entryPrice, entryDate: pos.entryDate, entryCost: pos.entryCost * quantity / pos.quantity, dividends: pos.dividends * quantity / pos.quantity}
+		pos.quantity -= quantity
+		pos.entryCost -= part.entryCost
+		pos.dividends -= part.dividends
+		positions[sym] = part
+		closePosition(sym, date)
+		positions[sym] = pos
+	}
+
+	// raise covers a cash shortfall by selling the same share of every
+	// position
+	raise := func(date time.Time) {
+		if cash >= 0 {
+			return
+		}
+		held := 0.0
+		for sym, pos := range positions {
+			held += pos.quantity * prices.at(sym, date)
+		}
+		if held > 0 {
+			share := -cash * (1 + cfg.Costs.rate()) / held
+			for _, sym := range sortedSymbols(positions) {
+				sellPart(sym, math.Ceil(positions[sym].quantity*share), date)
+			}
+		}
+	}
+
+	// contribute moves the plan's flows due by date into cash. Withdrawals
+	// the cash cannot cover are raised from the positions, and cut to what
+	// the account had when even that is not enough.
+	contributing := cfg.Contributions.Active()
+	scheduled := cfg.Contributions.Flows(cfg.StartDate, cfg.EndDate)
+	flows := []returns.CashFlow{{Date: cfg.StartDate, Amount: cfg.InitialCapital}}
+	periodFlows := 0.0
+	contribute := func(date time.Time) {
+		for len(scheduled) > 0 && !scheduled[0].Date.After(date) {
+			flows = append(flows, scheduled[0])
+			cash += scheduled[0].Amount
+			periodFlows += scheduled[0].Amount
+			scheduled = scheduled[1:]
+		}
+		raise(date)
+		// an emptied account pays what it has; withdrawals it could not
+		// pay at all are dropped
+		for cash < 0 && len(flows) > 1 && flows[len(flows)-1].Amount < 0 {
+			last := &flows[len(flows)-1]
+			cut := math.Min(-cash, -last.Amount)
+			last.Amount += cut
+			periodFlows += cut
+			cash += cut
+			if last.Amount == 0 {
+				flows = flows[:len(flows)-1]
+			}
+		}
+	}
+
+	// taxOwed is the tax on the gains of the financial years before fy that
+	// is not paid yet; payTax pays it from cash
+	taxPaid := 0.0
+	taxOwed := func(fy int) float64 {
+		owed := -taxPaid
+		for _, y := range cfg.Tax.Statement(ledger.Gains()) {
+			if y.FY < fy {
+				owed += y.Tax
+			}
+		}
+		return owed
+	}
+	payTax := func(date time.Time, fy int) {
+		if ledger == nil {
+			return
+		}
+		if owed := taxOwed(fy); owed > 0.005 {
+			cash -= owed
+			taxPaid += owed
+			raise(date)
+		}
+	}
+	var afterTax []float64
+	markAfterTax := func(equity float64) {
+		if ledger != nil {
+			afterTax = append(afterTax, equity-taxOwed(math.MaxInt))
+		}
+	}
+
+	// earnDividends books the dividends that went ex since the last call on
+	// the positions held, which were all bought before
+	dividendsTo := cfg.StartDate
+	earnDividends := func(date time.Time) {
+		if cfg.Dividends == "" {
+			return
+		}
+		for _, sym := range sortedSymbols(positions) {
+			pos := positions[sym]
+			for _, r := range entitled(cfg.Service.DividendsBetween(sym, dividendsTo, date), pos.quantity) {
+				pos.dividends += r.amount
+				dividendsTotal += r.amount
+				periodDividends += r.amount
+				receivables = append(receivables, r)
+			}
+		}
+		dividendsTo = date
+	}
+
+	// payDividends moves the dividends whose record date has come into
+	// cash and, when reinvesting, buys more of the paying stocks still held
+	payDividends := func(date time.Time, reinvest bool) {
+		var paid []receivable
+		paid, receivables = due(receivables, date)
+		for _, r := range paid {
+			cash += r.amount
+			pos, held := positions[r.symbol]
+			if !reinvest || !held {
+				continue
+			}
+			price := prices.at(r.symbol, date)
+			if price <= 0 {
+				continue
+			}
+			quantity := math.Floor(math.Min(r.amount, cash-margin(date)) / (price * (1 + cfg.Costs.rate())))
+			if quantity <= 0 {
+				continue
+			}
+			amount := quantity * price
+			cost := cfg.Costs.Cost(amount)
+			cash -= amount + cost
+			totalCosts += cost
+			pos.entryPrice = (pos.quantity*pos.entryPrice + amount) / (pos.quantity + quantity)
+			pos.quantity += quantity
+			pos.entryCost += cost
+			buyLot(r.symbol, date, quantity, amount+cost)
+		}
+	}
 
-	for month := 0; ; month++ {
-		monthDate := cfg.StartDate.AddDate(0, month, 0)
-		if monthDate.After(cfg.EndDate) {
+	// periodReturn is the return since the previous equity point net of
+	// the money that came in or went out; priceReturns leave out the
+	// dividends as well
+	var priceReturns []float64
+	periodReturn := func(equity float64) float64 {
+		flowed, earned := periodFlows, periodDividends
+		periodFlows, periodDividends = 0, 0
+		if prevEquity <= 0 {
+			priceReturns = append(priceReturns, 0)
+			return 0
+		}
+		priceReturns = append(priceReturns, (equity-flowed-earned)/prevEquity-1)
+		return (equity-flowed)/prevEquity - 1
+	}
+
+	for step := 0; ; step++ {
+		rebalanceDate := cfg.StartDate.AddDate(0, step*every, 0)
+		// nothing bought on the end date would be held for a day
+		if !rebalanceDate.Before(cfg.EndDate) {
 			break
 		}
 		params := repository.GetTopStocksByReturnParams{
-			Column1: toPgTimestamp(monthDate),
-			Column2: 12,
+			Column1: toPgTimestamp(rebalanceDate),
+			Column2: lookback,
 			Column3: cfg.ScriptType,
 			Limit:   cfg.TopN,
+			Column5: cfg.Industries,
 		}
 
+		accrue(rebalanceDate)
+		settle(rebalanceDate)
+		rollFutures(rebalanceDate)
+		earnDividends(rebalanceDate)
+
 		rows, err := cfg.Service.GetTopStocksByReturn(ctx, params)
 		if err != nil {
-			log.Printf("Error fetching top stocks for %s: %v", monthDate.Format("2006-01-02"), err)
+			log.Printf("Error fetching top stocks for %s: %v", rebalanceDate.Format("2006-01-02"), err)
 			continue
 		}
 
-		newPortfolio := make(map[string]struct{})
-		currentSymbols := make([]string, 0, len(rows))
-		monthProfit := 0.0
+		target := make(map[string]struct{}, len(rows))
+		for _, row := range rows {
+			target[row.Symbol] = struct{}{}
+		}
+
+		// Exit stocks that dropped out of the top N
+		for _, sym := range sortedSymbols(positions) {
+			if _, stillHeld := target[sym]; !stillHeld {
+				closePosition(sym, rebalanceDate)
+			}
+		}
+		if fut.Mode == FuturesStock {
+			for _, key := range sortedFutures(futures) {
+				if _, stillHeld := target[futures[key].underlying]; !stillHeld {
+					closeFuture(key, rebalanceDate)
+				}
+			}
+		}
 
+		payDividends(rebalanceDate, cfg.Dividends == DividendsReinvest)
+		contribute(rebalanceDate)
+		// last year's tax is due at the first rebalance of the new one
+		payTax(rebalanceDate, tax.FinancialYear(rebalanceDate))
+
+		// Enter new names; stocks still in the top N keep their quantity
+		equity := markToMarket(rebalanceDate)
+		if fut.Mode == FuturesHedge {
+			// leave room for the hedge's margin
+			equity /= 1 + fut.margin()*fut.HedgeRatio
+		}
+		weights := targetWeights(rows, cfg.Weighting)
+		if fut.Mode == FuturesStock && cfg.Service.Lots.Len() > 0 && !cfg.Service.Lots.Covers(rebalanceDate) {
+			log.Printf("No F&O lot sizes for %s, new names are bought in cash", rebalanceDate.Format("Jan 2006"))
+		}
 		for _, row := range rows {
-			price := getLatestClose(ctx, cfg.Service, row.Symbol, monthDate)
-			if _, held := currentPortfolio[row.Symbol]; !held {
-				entryPrices[row.Symbol] = price
-				entryDates[row.Symbol] = monthDate
+			if _, held := positions[row.Symbol]; held {
+				continue
+			}
+			if _, held := futures[row.Symbol+FuturesSuffix]; held {
+				continue
+			}
+			price := prices.at(row.Symbol, rebalanceDate)
+			if price <= 0 {
+				log.Printf("No price for %s at %s, skipping entry", row.Symbol, rebalanceDate.Format("2006-01-02"))
+				continue
 			}
-			newPortfolio[row.Symbol] = struct{}{}
-			currentSymbols = append(currentSymbols, row.Symbol)
-		}
-
-		// Exit stocks not in newPortfolio
-		for sym := range currentPortfolio {
-			if _, stillHeld := newPortfolio[sym]; !stillHeld {
-				exitPrice := getLatestClose(ctx, cfg.Service, sym, monthDate)
-				entryPrice := entryPrices[sym]
-				daysHeld := int(monthDate.Sub(entryDates[sym]).Hours() / 24)
-				alloc := cfg.InitialCapital / float64(cfg.TopN)
-				quantity := math.Floor(alloc / entryPrice)
-				amount := quantity * entryPrice
-				profit := (exitPrice - entryPrice) * quantity
-				profitPct := (profit / amount) * 100
-
-				tradeLogs = append(tradeLogs, TradeLog{
-					Symbol:      sym,
-					EntryDate:   entryDates[sym],
-					ExitDate:    monthDate,
-					EntryPrice:  entryPrice,
-					ExitPrice:   exitPrice,
-					Profit:      profit,
-					ProfitPct:   profitPct,
-					DaysHeld:    daysHeld,
-					Quantity:    quantity,
-					AmountUsed:  amount,
-					MaxDrawdown: getStockDrawdown(ctx, cfg, sym, entryDates[sym], monthDate),
-				})
-
-				monthProfit += profit
-				delete(entryPrices, sym)
-				delete(entryDates, sym)
+			available := cash - margin(rebalanceDate)
+			if fut.Mode == FuturesStock {
+				// names without a lot that fits their weight are bought in cash
+				if lot, ok := cfg.Service.FnoLot(row.Symbol, rebalanceDate); ok {
+					notional := math.Min(equity*weights[row.Symbol], available/(fut.margin()+cfg.Costs.rate()))
+					if lots := lot.Contracts(notional, price); lots > 0 {
+						openFuture(row.Symbol, lot.Size, lots, false, price, rebalanceDate)
+						continue
+					}
+				}
 			}
+			alloc := math.Min(equity*weights[row.Symbol], available)
+			quantity := math.Floor(alloc / (price * (1 + cfg.Costs.rate())))
+			if quantity <= 0 {
+				continue
+			}
+			amount := quantity * price
+			cost := cfg.Costs.Cost(amount)
+			cash -= amount + cost
+			totalCosts += cost
+			positions[row.Symbol] = &position{
+				quantity:   quantity,
+				entryPrice: price,
+				entryDate:  rebalanceDate,
+				entryCost:  cost,
+			}
+			buyLot(row.Symbol, rebalanceDate, quantity, amount+cost)
 		}
 
-		equity += monthProfit
-		equityCurve = append(equityCurve, equity)
-		monthlyReturns = append(monthlyReturns, monthProfit/cfg.InitialCapital)
-		portfolioLog = append(portfolioLog, currentSymbols)
-		currentPortfolio = newPortfolio
-	}
+		// money that came in since the last rebalance tops up the names
+		// still held toward their weight
+		if contributing {
+			budget := periodFlows
+			for _, row := range rows {
+				pos, held := positions[row.Symbol]
+				price := prices.at(row.Symbol, rebalanceDate)
+				if !held || price <= 0 || budget <= 0 {
+					continue
+				}
+				gap := equity*weights[row.Symbol] - pos.quantity*price
+				quantity := math.Floor(math.Min(gap, math.Min(budget, cash-margin(rebalanceDate))) / (price * (1 + cfg.Costs.rate())))
+				if quantity <= 0 {
+					continue
+				}
+				amount := quantity * price
+				cost := cfg.Costs.Cost(amount)
+				cash -= amount + cost
+				budget -= amount + cost
+				totalCosts += cost
+				pos.entryPrice = (pos.quantity*pos.entryPrice + amount) / (pos.quantity + quantity)
+				pos.quantity += quantity
+				pos.entryCost += cost
+				buyLot(row.Symbol, rebalanceDate, quantity, amount+cost)
+			}
+		}
 
-	// Final exits
-	for sym := range currentPortfolio {
-		entryPrice := entryPrices[sym]
-		exitPrice := getLatestClose(ctx, cfg.Service, sym, cfg.EndDate)
-		daysHeld := int(cfg.EndDate.Sub(entryDates[sym]).Hours() / 24)
-		alloc := cfg.InitialCapital / float64(cfg.TopN)
-		quantity := math.Floor(alloc / entryPrice)
-		amount := quantity * entryPrice
-		profit := (exitPrice - entryPrice) * quantity
-		profitPct := (profit / amount) * 100
+		if fut.Mode == FuturesHedge {
+			hedge(rebalanceDate)
+		}
 
-		tradeLogs = append(tradeLogs, TradeLog{
-			Symbol:      sym,
-			EntryDate:   entryDates[sym],
-			ExitDate:    cfg.EndDate,
-			EntryPrice:  entryPrice,
-			ExitPrice:   exitPrice,
-			Profit:      profit,
-			ProfitPct:   profitPct,
-			DaysHeld:    daysHeld,
-			Quantity:    quantity,
-			AmountUsed:  amount,
-			MaxDrawdown: getStockDrawdown(ctx, cfg, sym, entryDates[sym], cfg.EndDate),
-		})
+		currentSymbols := make([]string, 0, len(rows))
+		for _, row := range rows {
+			if _, held := positions[row.Symbol]; held {
+				currentSymbols = append(currentSymbols, row.Symbol)
+			}
+			if _, held := futures[row.Symbol+FuturesSuffix]; held {
+				currentSymbols = append(currentSymbols, row.Symbol+FuturesSuffix)
+			}
+		}
+		if _, held := futures[fut.HedgeSymbol+FuturesSuffix]; held && fut.Mode == FuturesHedge {
+			currentSymbols = append(currentSymbols, fut.HedgeSymbol+FuturesSuffix)
+		}
 
-		equity += profit
+		equity = markToMarket(rebalanceDate)
+		if len(futures) > 0 && equity > 0 {
+			maxMarginUsed = math.Max(maxMarginUsed, margin(rebalanceDate)/equity)
+		}
 		equityCurve = append(equityCurve, equity)
+		equityDates = append(equityDates, rebalanceDate)
+		monthlyReturns = append(monthlyReturns, periodReturn(equity))
+		markAfterTax(equity)
+		portfolioLog = append(portfolioLog, currentSymbols)
+		prevEquity = equity
 	}
 
-	total := len(tradeLogs)
-	wins := 0
-	sumProfits := 0.0
-	for _, t := range tradeLogs {
-		if t.Profit > 0 {
-			wins++
-		}
-		sumProfits += t.Profit
+	// Final exits
+	accrue(cfg.EndDate)
+	settle(cfg.EndDate)
+	rollFutures(cfg.EndDate)
+	earnDividends(cfg.EndDate)
+	for _, sym := range sortedSymbols(positions) {
+		closePosition(sym, cfg.EndDate)
 	}
-
-	winRate := 0.0
-	avgProfit := 0.0
-	if total > 0 {
-		winRate = float64(wins) / float64(total)
-		avgProfit = sumProfits / float64(total)
+	for _, key := range sortedFutures(futures) {
+		closeFuture(key, cfg.EndDate)
 	}
+	// dividends earned before the end are paid even if their record date
+	// is later
+	payDividends(time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC), false)
+	contribute(cfg.EndDate)
+	payTax(cfg.EndDate, math.MaxInt)
+	// rebalances stop before the end date, so this is always a new point
+	equity := cash
+	equityCurve = append(equityCurve, equity)
+	equityDates = append(equityDates, cfg.EndDate)
+	monthlyReturns = append(monthlyReturns, periodReturn(equity))
+	markAfterTax(equity)
+
+	stats := SummarizeTrades(tradeLogs)
 
 	months := int(cfg.EndDate.Sub(cfg.StartDate).Hours() / (24 * 30))
 	cagr := computeCAGR(cfg.InitialCapital, equity, months)
 	drawdown := maxDrawdown(equityCurve)
+	if contributing {
+		// money moving in and out is not growth: both follow the value of
+		// one unit of the account instead
+		units := make([]float64, len(monthlyReturns))
+		unit := 1.0
+		for i, r := range monthlyReturns {
+			unit *= 1 + r
+			units[i] = unit
+		}
+		cagr = computeCAGR(1, unit, months)
+		drawdown = maxDrawdown(append([]float64{1}, units...))
+	}
 
-	return BacktestResult{
+	result := BacktestResult{
 		TradeLogs:      tradeLogs,
 		EquityCurve:    equityCurve,
+		EquityDates:    equityDates,
 		MonthlyReturns: monthlyReturns,
 		Drawdown:       drawdown,
 		CAGR:           cagr,
 		PortfolioLog:   portfolioLog,
-		TotalTrades:    total,
-		WinningTrades:  wins,
-		WinRate:        winRate,
-		AverageProfit:  avgProfit,
-		Profit:         sumProfits,
+		TotalTrades:    stats.TotalTrades,
+		WinningTrades:  stats.WinningTrades,
+		WinRate:        stats.WinRate,
+		AverageProfit:  stats.AverageProfit,
+		Profit:         stats.Profit,
+		TotalCosts:     totalCosts,
+		FinalEquity:    equity,
+		RollCosts:      rollCosts,
+		CashInterest:   cashInterest,
+		MaxMarginUsed:  maxMarginUsed,
+		Flows:          flows,
+	}
+	if cfg.Dividends != "" {
+		unit := 1.0
+		for _, r := range priceReturns {
+			unit *= 1 + r
+		}
+		result.Dividends = dividendsTotal
+		result.PriceCAGR = computeCAGR(1, unit, months)
+	}
+	if ledger != nil {
+		result.TaxPaid = taxPaid
+		result.AfterTaxEquity = afterTax
+		result.Gains = ledger.Gains()
+		result.TaxYears = cfg.Tax.Statement(result.Gains)
+	}
+	return result
+}
+
+// Returns measures the run from its cash flows and equity curve.
+func (r BacktestResult) Returns() (returns.Summary, error) {
+	valuations := make([]returns.Valuation, len(r.EquityDates))
+	for i, d := range r.EquityDates {
+		valuations[i] = returns.Valuation{Date: d, Value: r.EquityCurve[i]}
+	}
+	return returns.Summarize(r.Flows, valuations)
+}
+
+// Target is a stock the strategy holds after a rebalance.
+type Target struct {
+	Symbol string
+	Weight float64
+	Return float64 // over the lookback, percent
+}
+
+// Targets is the portfolio the strategy rebalances into on date.
+func Targets(ctx context.Context, cfg BacktestConfig, date time.Time) ([]Target, error) {
+	lookback := cfg.LookbackMonths
+	if lookback <= 0 {
+		lookback = 12
+	}
+	rows, err := cfg.Service.GetTopStocksByReturn(ctx, repository.GetTopStocksByReturnParams{
+		Column1: toPgTimestamp(date),
+		Column2: lookback,
+		Column3: cfg.ScriptType,
+		Limit:   cfg.TopN,
+		Column5: cfg.Industries,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank stocks on %s: %w", date.Format("2006-01-02"), err)
+	}
+	weights := targetWeights(rows, cfg.Weighting)
+	targets := make([]Target, len(rows))
+	for i, row := range rows {
+		targets[i] = Target{Symbol: row.Symbol, Weight: weights[row.Symbol], Return: float64(row.ReturnPercentage)}
+	}
+	return targets, nil
+}
+
+// targetWeights splits the portfolio across the ranked rows.
+func targetWeights(rows []repository.GetTopStocksByReturnRow, weighting string) map[string]float64 {
+	weights := make(map[string]float64, len(rows))
+	if len(rows) == 0 {
+		return weights
+	}
+
+	raw := make([]float64, len(rows))
+	switch weighting {
+	case WeightRank:
+		// linear decay: the best of N gets N shares, the worst 1
+		for i := range rows {
+			raw[i] = float64(len(rows) - i)
+		}
+	case WeightMomentum:
+		for i, row := range rows {
+			raw[i] = math.Max(float64(row.ReturnPercentage), 0)
+		}
+	default:
+		for i := range rows {
+			raw[i] = 1
+		}
 	}
+
+	total := 0.0
+	for _, w := range raw {
+		total += w
+	}
+	for i, row := range rows {
+		if total == 0 {
+			weights[row.Symbol] = 1 / float64(len(rows))
+			continue
+		}
+		weights[row.Symbol] = raw[i] / total
+	}
+	return weights
+}
+
+func sortedSymbols(positions map[string]*position) []string {
+	symbols := make([]string, 0, len(positions))
+	for sym := range positions {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// priceBook looks up closes, falling back to the last known price of a
+// symbol when it stops trading so that held positions keep their value.
+type priceBook struct {
+	ctx  context.Context
+	svc  *services.Service
+	last map[string]float64
+	seen map[string]map[time.Time]float64
+}
+
+func newPriceBook(ctx context.Context, svc *services.Service) *priceBook {
+	return &priceBook{
+		ctx:  ctx,
+		svc:  svc,
+		last: make(map[string]float64),
+		seen: make(map[string]map[time.Time]float64),
+	}
+}
+
+func (p *priceBook) at(symbol string, date time.Time) float64 {
+	if byDate, ok := p.seen[symbol]; ok {
+		if price, ok := byDate[date]; ok {
+			return price
+		}
+	} else {
+		p.seen[symbol] = make(map[time.Time]float64)
+	}
+
+	price := getLatestClose(p.ctx, p.svc, symbol, date)
+	if price > 0 {
+		p.last[symbol] = price
+	} else {
+		price = p.last[symbol]
+	}
+	p.seen[symbol][date] = price
+	return price
+}
+
+type TradeStats struct {
+	TotalTrades   int
+	WinningTrades int
+	WinRate       float64
+	AverageProfit float64
+	Profit        float64
+	BestTrade     *TradeLog
+	WorstTrade    *TradeLog
+	AvgDaysHeld   float64
+}
+
+func SummarizeTrades(tradeLogs []TradeLog) TradeStats {
+	stats := TradeStats{TotalTrades: len(tradeLogs)}
+	days := 0
+	for i, t := range tradeLogs {
+		if t.Profit > 0 {
+			stats.WinningTrades++
+		}
+		stats.Profit += t.Profit
+		days += t.DaysHeld
+		if stats.BestTrade == nil || t.ProfitPct > stats.BestTrade.ProfitPct {
+			stats.BestTrade = &tradeLogs[i]
+		}
+		if stats.WorstTrade == nil || t.ProfitPct < stats.WorstTrade.ProfitPct {
+			stats.WorstTrade = &tradeLogs[i]
+		}
+	}
+
+	if stats.TotalTrades > 0 {
+		stats.WinRate = float64(stats.WinningTrades) / float64(stats.TotalTrades)
+		stats.AverageProfit = stats.Profit / float64(stats.TotalTrades)
+		stats.AvgDaysHeld = float64(days) / float64(stats.TotalTrades)
+	}
+	return stats
 }
 
 func toPgDate(t time.Time) (d pgtype.Date) {
@@ -225,6 +940,19 @@ func getLatestClose(ctx context.Context, s *services.Service, symbol string, dat
 	return f64.Float64
 }
 
+// Closes returns the close of symbol on or before each date, NaN where it
+// has no price yet. It is used to line a benchmark up with an equity curve.
+func Closes(ctx context.Context, s *services.Service, symbol string, dates []time.Time) []float64 {
+	out := make([]float64, len(dates))
+	for i, d := range dates {
+		out[i] = getLatestClose(ctx, s, symbol, d)
+		if out[i] <= 0 {
+			out[i] = math.NaN()
+		}
+	}
+	return out
+}
+
 func maxDrawdown(equity []float64) float64 {
 	peak := equity[0]
 	maxDD := 0.0
@@ -0,0 +1,707 @@
+package backtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fund-manager/internal/dividends"
+	"fund-manager/internal/fno"
+	"fund-manager/internal/returns"
+	"fund-manager/internal/services"
+	"fund-manager/internal/synthetic"
+	"fund-manager/internal/tax"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestMain(m *testing.M) {
+	// missing prices in the corporate action scenario are logged on purpose
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+type scenario struct {
+	name   string
+	market synthetic.Config
+	config BacktestConfig
+}
+
+var scenarios = []scenario{
+	{
+		name: "trending",
+		market: synthetic.Config{
+			Seed: 1, Stocks: 40, ScriptTypes: []string{"mid", "small"},
+			Start: date("2018-01-01"), End: date("2023-12-31"),
+			Drift: 0.12, DriftSpread: 0.15, Volatility: 0.25,
+		},
+		config: BacktestConfig{
+			StartDate: date("2020-01-01"), EndDate: date("2023-12-01"),
+			TopN: 5, ScriptType: []string{"mid", "small"}, InitialCapital: 1000000,
+			Costs: CostModel{CommissionBps: 3, SlippageBps: 5, TaxBps: 10},
+		},
+	},
+	{
+		name: "regimes",
+		market: synthetic.Config{
+			Seed: 2, Stocks: 30,
+			Start: date("2018-01-01"), End: date("2023-12-31"),
+			Drift: 0.10, DriftSpread: 0.10, Volatility: 0.20,
+			Regimes: []synthetic.Regime{
+				{From: date("2020-02-15"), Drift: -1.5, Volatility: 0.60},
+				{From: date("2020-05-01"), Drift: 0.60, Volatility: 0.30},
+				{From: date("2021-06-01"), Drift: 0.08, Volatility: 0.20},
+			},
+		},
+		config: BacktestConfig{
+			StartDate: date("2019-06-01"), EndDate: date("2023-06-01"),
+			TopN: 6, ScriptType: []string{"mid"}, InitialCapital: 500000,
+			LookbackMonths: 6, Weighting: WeightMomentum,
+		},
+	},
+	{
+		name: "corporate-actions",
+		market: synthetic.Config{
+			Seed: 3, Stocks: 25,
+			Start: date("2019-01-01"), End: date("2023-12-31"),
+			Drift: 0.15, DriftSpread: 0.20, Volatility: 0.30,
+			Splits: 6, Delistings: 4, GapProb: 0.03, ZeroPrices: 8,
+		},
+		config: BacktestConfig{
+			StartDate: date("2020-03-01"), EndDate: date("2023-11-01"),
+			TopN: 4, ScriptType: []string{"mid"}, InitialCapital: 1000000,
+			RebalanceMonths: 2, Weighting: WeightRank,
+			Costs: CostModel{CommissionBps: 5, SlippageBps: 10},
+		},
+	},
+}
+
+// golden is the pinned part of a BacktestResult. Floats are rounded so
+// that the files are stable across platforms.
+type golden struct {
+	CAGR        float64       `json:"cagr"`
+	MaxDrawdown float64       `json:"max_drawdown"`
+	FinalEquity float64       `json:"final_equity"`
+	TotalCosts  float64       `json:"total_costs"`
+	TotalTrades int           `json:"total_trades"`
+	WinRate     float64       `json:"win_rate"`
+	Trades      []goldenTrade `json:"trades"`
+	Equity      []goldenPoint `json:"equity"`
+}
+
+type goldenTrade struct {
+	Symbol     string  `json:"symbol"`
+	Entry      string  `json:"entry"`
+	Exit       string  `json:"exit"`
+	EntryPrice float64 `json:"entry_price"`
+	ExitPrice  float64 `json:"exit_price"`
+	Quantity   float64 `json:"quantity"`
+	Profit     float64 `json:"profit"`
+}
+
+type goldenPoint struct {
+	Date   string  `json:"date"`
+	Equity float64 `json:"equity"`
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+func goldenOf(r BacktestResult) golden {
+	g := golden{
+		CAGR:        round(r.CAGR, 6),
+		MaxDrawdown: round(r.Drawdown, 6),
+		FinalEquity: round(r.FinalEquity, 2),
+		TotalCosts:  round(r.TotalCosts, 2),
+		TotalTrades: r.TotalTrades,
+		WinRate:     round(r.WinRate, 6),
+	}
+	for _, t := range r.TradeLogs {
+		g.Trades = append(g.Trades, goldenTrade{
+			Symbol:     t.Symbol,
+			Entry:      t.EntryDate.Format("2006-01-02"),
+			Exit:       t.ExitDate.Format("2006-01-02"),
+			EntryPrice: round(t.EntryPrice, 2),
+			ExitPrice:  round(t.ExitPrice, 2),
+			Quantity:   t.Quantity,
+			Profit:     round(t.Profit, 2),
+		})
+	}
+	for i, d := range r.EquityDates {
+		g.Equity = append(g.Equity, goldenPoint{Date: d.Format("2006-01-02"), Equity: round(r.EquityCurve[i], 2)})
+	}
+	return g
+}
+
+func TestRunBacktestGolden(t *testing.T) {
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			market, err := synthetic.Generate(sc.market)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := sc.config
+			cfg.Service = services.NewService(market.Store())
+
+			got, err := json.MarshalIndent(goldenOf(RunBacktest(context.Background(), cfg)), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join("testdata", sc.name+".golden.json")
+			if *update {
+				if err := os.WriteFile(path, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("result differs from %s; if the change is intended run go test ./internal/backtest -update and review the diff", path)
+			}
+		})
+	}
+}
+
+// lotsFor lists every stock of the market in F&O with the same lot size in
+// every month from start to end.
+func lotsFor(market synthetic.Market, size int32, start, end time.Time) *fno.Table {
+	table := fno.NewTable(nil)
+	for _, st := range market.Stocks {
+		for m := monthOf(start); !m.After(end); m = m.AddDate(0, 1, 0) {
+			table.Add(st.Symbol, false, fno.Lot{Month: m, Size: size})
+		}
+	}
+	return table
+}
+
+func futuresScenario(t *testing.T, lotSize int32) BacktestConfig {
+	t.Helper()
+	sc := scenarios[0]
+	market, err := synthetic.Generate(sc.market)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := sc.config
+	cfg.Service = services.NewService(market.Store())
+	cfg.Service.Lots = lotsFor(market, lotSize, sc.market.Start, sc.market.End)
+	return cfg
+}
+
+func TestFuturesFullMarginMatchesCash(t *testing.T) {
+	// With lots of one share, the whole notional as margin and no roll
+	// costs, futures are the cash basket under another name.
+	cfg := futuresScenario(t, 1)
+	cfg.Costs = CostModel{}
+	cash := RunBacktest(context.Background(), cfg)
+	cfg.Futures = FuturesConfig{Mode: FuturesStock, MarginPct: 100}
+	futures := RunBacktest(context.Background(), cfg)
+
+	if len(futures.EquityCurve) != len(cash.EquityCurve) {
+		t.Fatalf("%d equity points, want %d", len(futures.EquityCurve), len(cash.EquityCurve))
+	}
+	for i := range cash.EquityCurve {
+		if math.Abs(futures.EquityCurve[i]-cash.EquityCurve[i]) > 1e-6*cash.EquityCurve[i] {
+			t.Fatalf("equity on %s: futures %.2f, cash %.2f", cash.EquityDates[i].Format("2006-01-02"), futures.EquityCurve[i], cash.EquityCurve[i])
+		}
+	}
+	for _, tr := range futures.TradeLogs {
+		if len(tr.Symbol) < len(FuturesSuffix) || tr.Symbol[len(tr.Symbol)-len(FuturesSuffix):] != FuturesSuffix {
+			t.Fatalf("trade %s not in futures", tr.Symbol)
+		}
+	}
+}
+
+func TestFuturesWholeLotsAndRolls(t *testing.T) {
+	cfg := futuresScenario(t, 250)
+	cfg.Futures = FuturesConfig{Mode: FuturesStock, MarginPct: 20, RollCostBps: 4, CashYieldPct: 6}
+	r := RunBacktest(context.Background(), cfg)
+
+	futures := 0
+	for _, tr := range r.TradeLogs {
+		if tr.Symbol[len(tr.Symbol)-len(FuturesSuffix):] != FuturesSuffix {
+			continue
+		}
+		futures++
+		if math.Mod(tr.Quantity, 250) != 0 || tr.Quantity <= 0 {
+			t.Fatalf("%s holds %v, not whole lots of 250", tr.Symbol, tr.Quantity)
+		}
+	}
+	if futures == 0 {
+		t.Fatal("no futures traded")
+	}
+	if r.RollCosts <= 0 || r.CashInterest <= 0 {
+		t.Fatalf("roll costs %.2f, cash interest %.2f", r.RollCosts, r.CashInterest)
+	}
+	if r.MaxMarginUsed <= 0 || r.MaxMarginUsed > 1 {
+		t.Fatalf("max margin used %.4f", r.MaxMarginUsed)
+	}
+}
+
+func TestFuturesHedge(t *testing.T) {
+	cfg := futuresScenario(t, 50)
+	cfg.Futures = FuturesConfig{Mode: FuturesHedge, HedgeSymbol: "SYN001", HedgeRatio: 1, MarginPct: 15}
+	r := RunBacktest(context.Background(), cfg)
+
+	shorts := 0
+	for _, tr := range r.TradeLogs {
+		if tr.Symbol == "SYN001"+FuturesSuffix {
+			if tr.Quantity >= 0 {
+				t.Fatalf("hedge trade is long %v", tr.Quantity)
+			}
+			shorts++
+		}
+	}
+	if shorts == 0 {
+		t.Fatal("basket never hedged")
+	}
+	// a fully hedged basket keeps only the spread to the index
+	cash := RunBacktest(context.Background(), futuresScenario(t, 50))
+	if r.CAGR >= cash.CAGR {
+		t.Fatalf("hedged CAGR %.4f not below unhedged %.4f in a rising market", r.CAGR, cash.CAGR)
+	}
+}
+
+func TestCashYield(t *testing.T) {
+	cfg := futuresScenario(t, 1)
+	base := RunBacktest(context.Background(), cfg)
+	cfg.Futures = FuturesConfig{CashYieldPct: 6}
+	r := RunBacktest(context.Background(), cfg)
+	if r.CashInterest <= 0 || r.FinalEquity <= base.FinalEquity {
+		t.Fatalf("interest %.2f, final equity %.2f vs %.2f", r.CashInterest, r.FinalEquity, base.FinalEquity)
+	}
+}
+
+func TestReturnsSingleDeposit(t *testing.T) {
+	cfg := futuresScenario(t, 1)
+	result := RunBacktest(context.Background(), cfg)
+	perf, err := result.Returns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// with the initial capital as the only flow, time- and money-weighted
+	// returns are the plain return
+	total := result.FinalEquity/cfg.InitialCapital - 1
+	if math.Abs(perf.TWR-total) > 1e-9 || math.Abs(perf.MWR-total) > 1e-9 {
+		t.Fatalf("twr %.6f, mwr %.6f, want %.6f", perf.TWR, perf.MWR, total)
+	}
+	if m := MetricsOf(result, cfg.InitialCapital); m.XIRR != 0 || m.TWR != 0 {
+		t.Fatalf("flow metrics set without contributions: %+v", m)
+	}
+}
+
+func TestContributionFlows(t *testing.T) {
+	plan := ContributionPlan{
+		SIP: 10000, StepUpPct: 10, SWP: 5000, SWPFrom: date("2021-06-01"),
+		Lumpsums: []returns.CashFlow{{Date: date("2020-03-15"), Amount: 50000}, {Date: date("2019-12-01"), Amount: 1}},
+	}
+	flows := plan.Flows(date("2020-01-01"), date("2021-08-01"))
+	sips, swps, lumpsums := 0, 0, 0
+	for i, f := range flows {
+		if i > 0 && f.Date.Before(flows[i-1].Date) {
+			t.Fatalf("flows out of order at %d", i)
+		}
+		switch {
+		case f.Amount == 50000:
+			lumpsums++
+		case f.Amount == -5000:
+			swps++
+			if f.Date.Before(plan.SWPFrom) {
+				t.Fatalf("withdrawal on %s", f.Date.Format("2006-01-02"))
+			}
+		default:
+			sips++
+			// the thirteenth instalment on 2021-02-01 is the first stepped up
+			want := 10000.0
+			if !f.Date.Before(date("2021-02-01")) {
+				want = 11000
+			}
+			if math.Abs(f.Amount-want) > 1e-9 {
+				t.Fatalf("sip %.2f on %s, want %.2f", f.Amount, f.Date.Format("2006-01-02"), want)
+			}
+		}
+	}
+	// February 2020 to July 2021; withdrawals in June and July
+	if sips != 18 || swps != 2 || lumpsums != 1 {
+		t.Fatalf("%d sips, %d withdrawals, %d lump sums", sips, swps, lumpsums)
+	}
+
+	// a month-end start pays on the last day of shorter months, every month
+	plan = ContributionPlan{SIP: 10000, StepUpPct: 10}
+	flows = plan.Flows(date("2023-01-31"), date("2024-04-01"))
+	want := []string{
+		"2023-02-28", "2023-03-31", "2023-04-30", "2023-05-31", "2023-06-30", "2023-07-31", "2023-08-31",
+		"2023-09-30", "2023-10-31", "2023-11-30", "2023-12-31", "2024-01-31", "2024-02-29", "2024-03-31",
+	}
+	if len(flows) != len(want) {
+		t.Fatalf("%d instalments, want %d: %v", len(flows), len(want), flows)
+	}
+	for i, f := range flows {
+		if got := f.Date.Format("2006-01-02"); got != want[i] {
+			t.Fatalf("instalment %d on %s, want %s", i+1, got, want[i])
+		}
+		// the step-up comes with the thirteenth, a year after the start
+		amount := 10000.0
+		if i >= 12 {
+			amount = 11000
+		}
+		if math.Abs(f.Amount-amount) > 1e-9 {
+			t.Fatalf("instalment %d of %.2f, want %.2f", i+1, f.Amount, amount)
+		}
+	}
+}
+
+func TestRunBacktestWithContributions(t *testing.T) {
+	cfg := futuresScenario(t, 1)
+	cfg.Contributions = ContributionPlan{SIP: 20000, StepUpPct: 10}
+	sip := RunBacktest(context.Background(), cfg)
+
+	if len(sip.Flows) < 2 || sip.Flows[0].Amount != cfg.InitialCapital {
+		t.Fatalf("flows %v", sip.Flows)
+	}
+	m := MetricsOf(sip, cfg.InitialCapital)
+	if m.XIRR == 0 || m.TWR == 0 || m.NetInvested <= cfg.InitialCapital {
+		t.Fatalf("metrics %+v", m)
+	}
+	if math.Abs(m.NetProfit-(sip.FinalEquity-m.NetInvested)) > 1e-6 {
+		t.Fatalf("net profit %.2f on %.2f invested, final %.2f", m.NetProfit, m.NetInvested, sip.FinalEquity)
+	}
+	// money coming in is not growth: CAGR follows the time-weighted return
+	perf, err := sip.Returns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(sip.CAGR-perf.TWRAnnual) > 0.005 {
+		t.Fatalf("CAGR %.4f, time-weighted %.4f a year", sip.CAGR, perf.TWRAnnual)
+	}
+
+	// the archive keeps the returns net of the SIPs, not the equity ratios
+	spec, err := Spec{
+		Name:           "sip",
+		Universe:       SpecUniverse{ScriptTypes: cfg.ScriptType},
+		Strategy:       SpecStrategy{TopN: int(cfg.TopN)},
+		Dates:          SpecDates{Start: "2020-01-01", End: "2023-12-01"},
+		InitialCapital: cfg.InitialCapital,
+	}.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := SaveRun(t.TempDir(), spec, RunMeta{StartedAt: time.Now()}, sip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := LoadRun(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := run.Result().MonthlyReturns
+	if len(loaded) != len(sip.MonthlyReturns) {
+		t.Fatalf("%d returns archived, %d run", len(loaded), len(sip.MonthlyReturns))
+	}
+	for i, r := range sip.MonthlyReturns {
+		if math.Abs(loaded[i]-r) > 1e-12 {
+			t.Fatalf("return %d: archived %v, run %v", i, loaded[i], r)
+		}
+	}
+	growth := Growth(loaded)
+	if unit := computeCAGR(1, growth[len(growth)-1], int(cfg.EndDate.Sub(cfg.StartDate).Hours()/(24*30))); math.Abs(unit-sip.CAGR) > 1e-9 {
+		t.Fatalf("archived growth CAGR %.4f, run %.4f", unit, sip.CAGR)
+	}
+	raw := Growth(EquityReturns(cfg.InitialCapital, sip.EquityCurve))
+	if raw[len(raw)-1] <= growth[len(growth)-1] {
+		t.Fatalf("equity ratios grew %.4f, no more than the returns' %.4f", raw[len(raw)-1], growth[len(growth)-1])
+	}
+
+	// a SIP too small to buy anything leaves the run as it was
+	cfg.Contributions = ContributionPlan{SIP: 1}
+	tiny := RunBacktest(context.Background(), cfg)
+	base := RunBacktest(context.Background(), futuresScenario(t, 1))
+	if math.Abs(tiny.CAGR-base.CAGR) > 1e-3 || len(tiny.TradeLogs) != len(base.TradeLogs) {
+		t.Fatalf("CAGR %.4f with a token SIP, %.4f without", tiny.CAGR, base.CAGR)
+	}
+}
+
+func TestRunBacktestWithdrawals(t *testing.T) {
+	cfg := futuresScenario(t, 1)
+	cfg.Contributions = ContributionPlan{SWP: 60000, SWPFrom: cfg.StartDate.AddDate(1, 0, 0)}
+	r := RunBacktest(context.Background(), cfg)
+
+	withdrawn := 0.0
+	for _, f := range r.Flows[1:] {
+		if f.Amount >= 0 {
+			t.Fatalf("flow %+v", f)
+		}
+		withdrawn -= f.Amount
+	}
+	if withdrawn == 0 {
+		t.Fatal("nothing withdrawn")
+	}
+	// withdrawals the cash cannot cover sell part of names that stay held
+	partial := false
+	for _, tr := range r.TradeLogs {
+		for i, d := range r.EquityDates {
+			if !d.Equal(tr.ExitDate) {
+				continue
+			}
+			for _, sym := range r.PortfolioLog[i] {
+				partial = partial || sym == tr.Symbol
+			}
+		}
+	}
+	if !partial {
+		t.Fatal("no positions sold to pay a withdrawal")
+	}
+	for i, e := range r.EquityCurve {
+		if e < 0 {
+			t.Fatalf("equity %.2f on %s", e, r.EquityDates[i].Format("2006-01-02"))
+		}
+	}
+}
+
+func TestRunBacktestWithTax(t *testing.T) {
+	cfg := futuresScenario(t, 1)
+	base := RunBacktest(context.Background(), cfg)
+	rules := tax.IndianEquity()
+	cfg.Tax = &rules
+	r := RunBacktest(context.Background(), cfg)
+
+	if r.TaxPaid <= 0 || r.FinalEquity >= base.FinalEquity {
+		t.Fatalf("tax paid %.2f, final equity %.2f against %.2f before tax", r.TaxPaid, r.FinalEquity, base.FinalEquity)
+	}
+	due := 0.0
+	for _, y := range r.TaxYears {
+		due += y.Tax
+	}
+	if math.Abs(due-r.TaxPaid) > 0.01 {
+		t.Fatalf("statement %.2f, paid %.2f", due, r.TaxPaid)
+	}
+	// every trade is one lot sold first in, first out
+	gains, profit := 0.0, 0.0
+	for _, g := range r.Gains {
+		gains += g.Amount()
+	}
+	for _, tr := range r.TradeLogs {
+		profit += tr.Profit
+	}
+	if math.Abs(gains-profit) > 0.01 {
+		t.Fatalf("gains %.2f, trade profit %.2f", gains, profit)
+	}
+	if len(r.AfterTaxEquity) != len(r.EquityCurve) || r.AfterTaxEquity[len(r.AfterTaxEquity)-1] != r.FinalEquity {
+		t.Fatalf("%d after-tax points for %d", len(r.AfterTaxEquity), len(r.EquityCurve))
+	}
+	for i := range r.EquityCurve {
+		if r.AfterTaxEquity[i] > r.EquityCurve[i]+1e-6 {
+			t.Fatalf("after tax %.2f above %.2f on %s", r.AfterTaxEquity[i], r.EquityCurve[i], r.EquityDates[i].Format("2006-01-02"))
+		}
+	}
+	if m := MetricsOf(r, cfg.InitialCapital); m.TaxPaid != r.TaxPaid {
+		t.Fatalf("metrics %+v", m)
+	}
+}
+
+// quarterlyDividends pays amount a share on every stock each quarter, to
+// holders of record two days after the ex-date.
+func quarterlyDividends(market synthetic.Market, amount float64, start, end time.Time) *dividends.Table {
+	var divs []dividends.Dividend
+	for _, st := range market.Stocks {
+		for ex := start; !ex.After(end); ex = ex.AddDate(0, 3, 0) {
+			divs = append(divs, dividends.Dividend{Symbol: st.Symbol, ExDate: ex, RecordDate: ex.AddDate(0, 0, 2), Amount: amount})
+		}
+	}
+	return dividends.NewTable(divs)
+}
+
+func TestRunBacktestWithDividends(t *testing.T) {
+	sc := scenarios[0]
+	market, err := synthetic.Generate(sc.market)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := sc.config
+	cfg.Service = services.NewService(market.Store())
+	base := RunBacktest(context.Background(), cfg)
+	cfg.Service.Dividends = quarterlyDividends(market, 2, date("2019-01-15"), sc.market.End)
+
+	for _, mode := range []string{DividendsCash, DividendsReinvest} {
+		cfg.Dividends = mode
+		r := RunBacktest(context.Background(), cfg)
+		if r.Dividends <= 0 || r.FinalEquity <= base.FinalEquity {
+			t.Fatalf("%s: dividends %.2f, final equity %.2f against %.2f without", mode, r.Dividends, r.FinalEquity, base.FinalEquity)
+		}
+		earned := 0.0
+		for _, tr := range r.TradeLogs {
+			earned += tr.Dividends
+			if tr.Dividends > 0 && tr.TotalReturnPct <= tr.ProfitPct {
+				t.Fatalf("%s: %s total return %.4f not above %.4f", mode, tr.Symbol, tr.TotalReturnPct, tr.ProfitPct)
+			}
+		}
+		if math.Abs(earned-r.Dividends) > 0.01 {
+			t.Fatalf("%s: trades earned %.2f, run %.2f", mode, earned, r.Dividends)
+		}
+		if r.PriceCAGR >= r.CAGR {
+			t.Fatalf("%s: price CAGR %.4f not below %.4f", mode, r.PriceCAGR, r.CAGR)
+		}
+		if m := MetricsOf(r, cfg.InitialCapital); m.Dividends != r.Dividends || m.PriceCAGR != r.PriceCAGR {
+			t.Fatalf("%s: metrics %+v", mode, m)
+		}
+	}
+	if base.Dividends != 0 || base.PriceCAGR != 0 {
+		t.Fatalf("dividends %.2f, price CAGR %.4f without a mode", base.Dividends, base.PriceCAGR)
+	}
+}
+
+func TestSpecRejectsNegativeSchedule(t *testing.T) {
+	valid := Spec{
+		Universe:       SpecUniverse{ScriptTypes: []string{"mid"}},
+		Strategy:       SpecStrategy{TopN: 5},
+		Dates:          SpecDates{Start: "2020-01-01", End: "2023-12-01"},
+		InitialCapital: 1000000,
+	}
+	spec, err := valid.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// zero takes the defaults the run uses
+	if spec.Strategy.LookbackMonths != 12 || spec.Schedule.EveryMonths != 1 {
+		t.Fatalf("resolved %+v", spec)
+	}
+	if from := spec.PriceWindow().From; !from.Equal(date("2018-12-01")) {
+		t.Fatalf("prices from %s", from.Format("2006-01-02"))
+	}
+
+	lookback := valid
+	lookback.Strategy.LookbackMonths = -3
+	every := valid
+	every.Schedule.EveryMonths = -1
+	commission := valid
+	commission.Costs.CommissionBps = -5
+	slippage := valid
+	slippage.Costs.SlippageBps = -1
+	taxBps := valid
+	taxBps.Costs = CostModel{CommissionBps: 10, TaxBps: -12}
+	for _, s := range []Spec{lookback, every, commission, slippage, taxBps} {
+		if _, err := s.Resolve(); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+			t.Errorf("lookback %d, every %d, costs %+v: error %v", s.Strategy.LookbackMonths, s.Schedule.EveryMonths, s.Costs, err)
+		}
+	}
+}
+
+func TestDataFingerprintComparable(t *testing.T) {
+	spec, err := Spec{
+		Universe:       SpecUniverse{ScriptTypes: []string{"mid", "small"}},
+		Strategy:       SpecStrategy{TopN: 5},
+		Dates:          SpecDates{Start: "2020-01-01", End: "2023-12-01"},
+		InitialCapital: 1000000,
+	}.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	window := spec.PriceWindow().String()
+	if !strings.HasPrefix(window, "2018-12-01 to ") || !strings.Contains(window, "mid,small") {
+		t.Fatalf("window %q", window)
+	}
+
+	pg := DataFingerprint{Rows: 1000, Stocks: 10, MaxDate: "2023-12-01", Backend: "postgres"}
+	csv := DataFingerprint{Rows: 400, Stocks: 10, MaxDate: "2023-12-01", Backend: "csv", Window: window}
+	later := csv
+	later.Window = "2019-12-01 to 2024-12-01, mid,small"
+	for _, c := range []struct {
+		f, g DataFingerprint
+		want bool
+	}{
+		{pg, pg, true},
+		{pg, DataFingerprint{Rows: 1200, Backend: "postgres"}, true},
+		{csv, csv, true},
+		{pg, csv, false},
+		{csv, later, false},
+		// runs archived before the backend was recorded
+		{pg, DataFingerprint{Rows: 1000}, false},
+	} {
+		if got := c.f.Comparable(c.g); got != c.want {
+			t.Errorf("%s comparable with %s: %v", c.f, c.g, got)
+		}
+	}
+	if got, want := csv.String(), "csv ("+window+"): 400 rows of 10 stocks to 2023-12-01"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// The engine compounds: entries are sized from the equity of the day, the
+// period returns chain to the final equity, and the end date only sells.
+// The original cmd/backtest sized every entry from the initial capital,
+// took the month's closed profit over the initial capital as its return and
+// also rebalanced on the end date.
+func TestRunBacktestCompounds(t *testing.T) {
+	sc := scenarios[0]
+	market, err := synthetic.Generate(sc.market)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := sc.config
+	cfg.Costs = CostModel{}
+	cfg.EndDate = cfg.StartDate.AddDate(0, 36, 0) // a rebalance date
+	cfg.Service = services.NewService(market.Store())
+	r := RunBacktest(context.Background(), cfg)
+
+	equityOn := make(map[time.Time]float64)
+	for i, d := range r.EquityDates {
+		equityOn[d] = r.EquityCurve[i]
+	}
+	fixed := cfg.InitialCapital / float64(cfg.TopN)
+	compounded := 0
+	for _, tr := range r.TradeLogs {
+		if !tr.EntryDate.Before(cfg.EndDate) {
+			t.Errorf("%s bought on the end date", tr.Symbol)
+		}
+		if !tr.ExitDate.After(tr.EntryDate) {
+			t.Errorf("%s held from %s to %s", tr.Symbol, tr.EntryDate.Format("2006-01-02"), tr.ExitDate.Format("2006-01-02"))
+		}
+		share := equityOn[tr.EntryDate] / float64(cfg.TopN)
+		if tr.AmountUsed > share+1e-6 {
+			t.Errorf("%s bought for %.2f, over its share %.2f of the equity", tr.Symbol, tr.AmountUsed, share)
+		}
+		if tr.AmountUsed > fixed {
+			compounded++
+		}
+	}
+	if compounded == 0 {
+		t.Errorf("no entry was sized above the initial %.2f per name in a rising market", fixed)
+	}
+
+	last := len(r.EquityDates) - 1
+	if !r.EquityDates[last].Equal(cfg.EndDate) || r.EquityCurve[last] != r.FinalEquity {
+		t.Errorf("last point %s %.2f, want %s %.2f", r.EquityDates[last].Format("2006-01-02"), r.EquityCurve[last], cfg.EndDate.Format("2006-01-02"), r.FinalEquity)
+	}
+	if len(r.MonthlyReturns) != len(r.EquityCurve) {
+		t.Fatalf("%d returns for %d equity points", len(r.MonthlyReturns), len(r.EquityCurve))
+	}
+	growth := cfg.InitialCapital
+	for _, ret := range r.MonthlyReturns {
+		growth *= 1 + ret
+	}
+	if math.Abs(growth-r.FinalEquity) > 1e-6*r.FinalEquity {
+		t.Errorf("returns compound to %.2f, final equity %.2f", growth, r.FinalEquity)
+	}
+}
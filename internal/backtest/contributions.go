@@ -0,0 +1,53 @@
+package backtest
+
+import (
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/returns"
+	"math"
+	"sort"
+	"time"
+)
+
+// ContributionPlan adds money to a run and takes it out on a schedule.
+// Monthly amounts fall on the start date's day of the month, from a month
+// after the start; money that comes in waits in cash for the next
+// rebalance.
+type ContributionPlan struct {
+	SIP       float64            // invested every month
+	StepUpPct float64            // yearly rise of the SIP, percent
+	SWP       float64            // withdrawn every month from SWPFrom
+	SWPFrom   time.Time          // a month after the start when zero
+	Lumpsums  []returns.CashFlow // one-off top-ups, or withdrawals when negative
+}
+
+func (p ContributionPlan) Active() bool {
+	return p.SIP > 0 || p.SWP > 0 || len(p.Lumpsums) > 0
+}
+
+// Flows lists the plan's cash flows after start and before end in date
+// order. A step-up raises the SIP after every twelve instalments. Months
+// shorter than the start date's day pay on their last day, so a plan
+// started on 31 January pays on 28 or 29 February and again on 31 March.
+func (p ContributionPlan) Flows(start, end time.Time) []returns.CashFlow {
+	var flows []returns.CashFlow
+	for m := 1; ; m++ {
+		date := pricestore.SubMonths(start, -m)
+		if !date.Before(end) {
+			break
+		}
+		if p.SIP > 0 {
+			amount := p.SIP * math.Pow(1+p.StepUpPct/100, float64((m-1)/12))
+			flows = append(flows, returns.CashFlow{Date: date, Amount: amount})
+		}
+		if p.SWP > 0 && !date.Before(p.SWPFrom) {
+			flows = append(flows, returns.CashFlow{Date: date, Amount: -p.SWP})
+		}
+	}
+	for _, f := range p.Lumpsums {
+		if f.Date.After(start) && f.Date.Before(end) {
+			flows = append(flows, f)
+		}
+	}
+	sort.SliceStable(flows, func(i, j int) bool { return flows[i].Date.Before(flows[j].Date) })
+	return flows
+}
@@ -0,0 +1,49 @@
+package backtest
+
+import (
+	"fund-manager/internal/dividends"
+	"sort"
+	"time"
+)
+
+// How a run treats the cash dividends of the stocks it holds. Without a
+// mode dividends are ignored and returns are price returns only.
+const (
+	// DividendsCash credits dividends to cash on the record date, to be
+	// invested at the next rebalance.
+	DividendsCash = "cash"
+	// DividendsReinvest buys more of the paying stock with its dividend at
+	// the first rebalance on or after the record date, if still held.
+	DividendsReinvest = "reinvest"
+)
+
+// receivable is a dividend a position earned on its ex-date and that has
+// not reached cash yet.
+type receivable struct {
+	symbol string
+	paidOn time.Time
+	amount float64
+}
+
+// due splits the receivables into those paid by date and the rest, each in
+// payment order.
+func due(pending []receivable, date time.Time) (paid, rest []receivable) {
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].paidOn.Before(pending[j].paidOn) })
+	for _, r := range pending {
+		if r.paidOn.After(date) {
+			rest = append(rest, r)
+		} else {
+			paid = append(paid, r)
+		}
+	}
+	return paid, rest
+}
+
+// entitled is what quantity shares earn from divs.
+func entitled(divs []dividends.Dividend, quantity float64) []receivable {
+	out := make([]receivable, 0, len(divs))
+	for _, d := range divs {
+		out = append(out, receivable{symbol: d.Symbol, paidOn: d.PaidOn(), amount: quantity * d.Amount})
+	}
+	return out
+}
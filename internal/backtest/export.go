@@ -0,0 +1,171 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"fund-manager/internal/tax"
+	"os"
+	"strconv"
+	"time"
+)
+
+var tradeLogHeaders = []string{"Symbol", "EntryDate", "ExitDate", "EntryPrice", "ExitPrice", "Profit", "ProfitPct", "DaysHeld", "Quantity", "AmountUsed", "MaxDrawDown", "Costs", "Dividends", "TotalReturnPct"}
+
+func ExportTradeLogsToCSV(filename string, trades []TradeLog) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write(tradeLogHeaders); err != nil {
+		return err
+	}
+
+	for _, trade := range trades {
+		record := []string{
+			trade.Symbol,
+			trade.EntryDate.Format("2006-01-02"),
+			trade.ExitDate.Format("2006-01-02"),
+			fmt.Sprintf("%.2f", trade.EntryPrice),
+			fmt.Sprintf("%.2f", trade.ExitPrice),
+			fmt.Sprintf("%.2f", trade.Profit),
+			fmt.Sprintf("%.2f", trade.ProfitPct),
+			strconv.Itoa(trade.DaysHeld),
+			fmt.Sprintf("%.0f", trade.Quantity),
+			fmt.Sprintf("%.2f", trade.AmountUsed),
+			fmt.Sprintf("%.2f", trade.MaxDrawdown),
+			fmt.Sprintf("%.2f", trade.Costs),
+			fmt.Sprintf("%.2f", trade.Dividends),
+			fmt.Sprintf("%.2f", trade.TotalReturnPct),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadTradeLogsCSV reads a file written by ExportTradeLogsToCSV. Columns are
+// matched by header, so logs written before a column was added still load.
+func ReadTradeLogsCSV(filename string) ([]TradeLog, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	index := make(map[string]int, len(records[0]))
+	for i, h := range records[0] {
+		index[h] = i
+	}
+	for _, h := range tradeLogHeaders[:11] {
+		if _, ok := index[h]; !ok {
+			return nil, fmt.Errorf("%s: missing column %s", filename, h)
+		}
+	}
+
+	trades := make([]TradeLog, 0, len(records)-1)
+	for line, row := range records[1:] {
+		var perr error
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return row[i]
+		}
+		parseFloat := func(name string) float64 {
+			s := field(name)
+			if s == "" {
+				return 0
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil && perr == nil {
+				perr = err
+			}
+			return v
+		}
+		parseDate := func(name string) time.Time {
+			v, err := time.Parse("2006-01-02", field(name))
+			if err != nil && perr == nil {
+				perr = err
+			}
+			return v
+		}
+
+		t := TradeLog{
+			Symbol:      field("Symbol"),
+			EntryDate:   parseDate("EntryDate"),
+			ExitDate:    parseDate("ExitDate"),
+			EntryPrice:  parseFloat("EntryPrice"),
+			ExitPrice:   parseFloat("ExitPrice"),
+			Profit:      parseFloat("Profit"),
+			ProfitPct:   parseFloat("ProfitPct"),
+			DaysHeld:    int(parseFloat("DaysHeld")),
+			Quantity:    parseFloat("Quantity"),
+			AmountUsed:  parseFloat("AmountUsed"),
+			MaxDrawdown: parseFloat("MaxDrawDown"),
+			Costs:       parseFloat("Costs"),
+			Dividends:   parseFloat("Dividends"),
+		}
+		// logs from before dividends count price change only
+		t.TotalReturnPct = t.ProfitPct
+		if field("TotalReturnPct") != "" {
+			t.TotalReturnPct = parseFloat("TotalReturnPct")
+		}
+		if perr != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filename, line+2, perr)
+		}
+		trades = append(trades, t)
+	}
+	return trades, nil
+}
+
+// ExportGainsCSV writes the capital gains of a run, one row per lot sold.
+func ExportGainsCSV(filename string, gains []tax.Gain) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"Symbol", "Bought", "Sold", "Quantity", "Cost", "Proceeds", "Gain", "Term", "Grandfathered", "FY"}); err != nil {
+		return err
+	}
+	for _, g := range gains {
+		record := []string{
+			g.Symbol,
+			g.Bought.Format("2006-01-02"),
+			g.Sold.Format("2006-01-02"),
+			fmt.Sprintf("%.0f", g.Quantity),
+			fmt.Sprintf("%.2f", g.Cost),
+			fmt.Sprintf("%.2f", g.Proceeds),
+			fmt.Sprintf("%.2f", g.Amount()),
+			g.Term,
+			strconv.FormatBool(g.Grandfathered),
+			tax.FYLabel(tax.FinancialYear(g.Sold)),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
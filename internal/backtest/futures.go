@@ -0,0 +1,75 @@
+package backtest
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// FuturesConfig overlays futures on the momentum basket. The zero value is a
+// plain cash-equity backtest.
+//
+// Futures are priced at the close of their underlying; the basis is left to
+// RollCostBps, which is charged on the notional for every month a position
+// is rolled into the next contract. Positions are whole lots of the
+// near-month contract as listed in the service's lot sizes.
+type FuturesConfig struct {
+	Mode         string  // FuturesCash (default), FuturesStock or FuturesHedge
+	HedgeSymbol  string  // index whose futures hedge the basket in FuturesHedge
+	HedgeRatio   float64 // share of the basket's value hedged
+	MarginPct    float64 // initial margin, percent of notional, blocked from cash
+	RollCostBps  float64 // per roll, basis points of notional
+	CashYieldPct float64 // annual yield on cash not blocked as margin
+}
+
+const (
+	// FuturesCash holds the basket in cash equities.
+	FuturesCash = "cash"
+	// FuturesStock holds F&O names through single-stock futures, in as
+	// many whole lots as their weight allows, and the rest in cash.
+	FuturesStock = "stock"
+	// FuturesHedge holds the basket in cash equities, short index futures
+	// against its value.
+	FuturesHedge = "hedge"
+
+	// FuturesSuffix marks futures positions in trade and portfolio logs.
+	FuturesSuffix = "-FUT"
+)
+
+func (f FuturesConfig) margin() float64 {
+	return f.MarginPct / 100
+}
+
+type futurePosition struct {
+	underlying string
+	lots       int64
+	quantity   float64 // lots × lot size, negative when short
+	entryPrice float64
+	settled    float64 // price at the last settlement
+	entryDate  time.Time
+	rolledTo   time.Time // expiry month of the contract held
+	costs      float64   // trading and roll costs so far
+}
+
+func (f *futurePosition) notional(price float64) float64 {
+	return math.Abs(f.quantity) * price
+}
+
+func monthOf(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthsBetween counts the expiries from the contract of month from to the
+// one of month to.
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+func sortedFutures(futures map[string]*futurePosition) []string {
+	keys := make([]string, 0, len(futures))
+	for key := range futures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
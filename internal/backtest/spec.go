@@ -0,0 +1,412 @@
+package backtest
+
+import (
+	"bytes"
+	"fmt"
+	"fund-manager/internal/instrument"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/returns"
+	"fund-manager/internal/services"
+	"fund-manager/internal/tax"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the versioned, file-based description of a backtest. Resolve fills
+// in defaults so that the saved copy of a spec pins every setting used.
+type Spec struct {
+	Name           string             `yaml:"name"`
+	Description    string             `yaml:"description,omitempty"`
+	Version        int                `yaml:"version"`
+	Universe       SpecUniverse       `yaml:"universe"`
+	Strategy       SpecStrategy       `yaml:"strategy"`
+	Weighting      string             `yaml:"weighting"`
+	Costs          CostModel          `yaml:"costs"`
+	Schedule       SpecSchedule       `yaml:"schedule"`
+	Dates          SpecDates          `yaml:"dates"`
+	InitialCapital float64            `yaml:"initial_capital"`
+	Futures        *SpecFutures       `yaml:"futures,omitempty"`
+	Contributions  *SpecContributions `yaml:"contributions,omitempty"`
+	Tax            *SpecTax           `yaml:"tax,omitempty"`
+	Dividends      string             `yaml:"dividends,omitempty"` // cash or reinvest; ignored when empty
+}
+
+type SpecUniverse struct {
+	ScriptTypes []string `yaml:"script_types"`
+	Industries  []string `yaml:"industries,omitempty"` // or fund categories
+}
+
+type SpecStrategy struct {
+	Type           string `yaml:"type"`
+	LookbackMonths int    `yaml:"lookback_months"`
+	TopN           int    `yaml:"top_n"`
+}
+
+type SpecSchedule struct {
+	EveryMonths int `yaml:"every_months"`
+}
+
+// SpecFutures is the futures overlay; see FuturesConfig.
+type SpecFutures struct {
+	Mode         string  `yaml:"mode"`
+	HedgeSymbol  string  `yaml:"hedge_symbol,omitempty"`
+	HedgeRatio   float64 `yaml:"hedge_ratio,omitempty"`
+	MarginPct    float64 `yaml:"margin_pct"`
+	RollCostBps  float64 `yaml:"roll_cost_bps"`
+	CashYieldPct float64 `yaml:"cash_yield_pct"`
+}
+
+// SpecContributions is the contribution plan; see ContributionPlan.
+type SpecContributions struct {
+	SIP       float64       `yaml:"sip,omitempty"`
+	StepUpPct float64       `yaml:"step_up_pct,omitempty"`
+	SWP       float64       `yaml:"swp,omitempty"`
+	SWPFrom   string        `yaml:"swp_from,omitempty"`
+	Lumpsums  []SpecLumpsum `yaml:"lumpsums,omitempty"`
+}
+
+// SpecLumpsum is a one-off top-up, or a withdrawal when negative.
+type SpecLumpsum struct {
+	Date   string  `yaml:"date"`
+	Amount float64 `yaml:"amount"`
+}
+
+// SpecTax is the capital gains tax the account pays; an empty section is
+// the Indian equity rules.
+type SpecTax struct {
+	Regimes        []SpecTaxRegime `yaml:"regimes"`
+	CessPct        float64         `yaml:"cess_pct"`
+	LongTermMonths int             `yaml:"long_term_months"`
+}
+
+// SpecTaxRegime is a set of rates in force from a date.
+type SpecTaxRegime struct {
+	From          string  `yaml:"from"`
+	STCGPct       float64 `yaml:"stcg_pct"`
+	LTCGPct       float64 `yaml:"ltcg_pct"`
+	LTCGExemption float64 `yaml:"ltcg_exemption"`
+}
+
+type SpecDates struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+const StrategyMomentum = "momentum"
+
+// LoadSpec reads a spec file, rejecting unknown keys so that typos do not
+// silently fall back to defaults.
+func LoadSpec(path string) (Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Spec{}, fmt.Errorf("failed to read spec: %w", err)
+	}
+
+	var spec Spec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil {
+		return Spec{}, fmt.Errorf("failed to parse spec %s: %w", path, err)
+	}
+	if spec.Name == "" {
+		spec.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return spec.Resolve()
+}
+
+// FindSpec resolves a spec given either as a path or as a name inside dir.
+func FindSpec(dir, nameOrPath string) (string, error) {
+	if _, err := os.Stat(nameOrPath); err == nil {
+		return nameOrPath, nil
+	}
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(dir, nameOrPath+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("backtest spec %q not found in %s", nameOrPath, dir)
+}
+
+// Resolve validates the spec and returns a copy with defaults filled in.
+func (s Spec) Resolve() (Spec, error) {
+	if s.Name == "" {
+		s.Name = "adhoc"
+	}
+	if s.Version == 0 {
+		s.Version = 1
+	}
+	if s.Strategy.Type == "" {
+		s.Strategy.Type = StrategyMomentum
+	}
+	if s.Strategy.Type != StrategyMomentum {
+		return s, fmt.Errorf("unknown strategy type %q", s.Strategy.Type)
+	}
+	if s.Strategy.LookbackMonths < 0 {
+		return s, fmt.Errorf("strategy.lookback_months must not be negative")
+	}
+	if s.Strategy.LookbackMonths == 0 {
+		s.Strategy.LookbackMonths = 12
+	}
+	if s.Strategy.TopN <= 0 {
+		return s, fmt.Errorf("strategy.top_n must be positive")
+	}
+	if s.Weighting == "" {
+		s.Weighting = WeightEqual
+	}
+	switch s.Weighting {
+	case WeightEqual, WeightRank, WeightMomentum:
+	default:
+		return s, fmt.Errorf("unknown weighting %q (want %s, %s or %s)", s.Weighting, WeightEqual, WeightRank, WeightMomentum)
+	}
+	if s.Schedule.EveryMonths < 0 {
+		return s, fmt.Errorf("schedule.every_months must not be negative")
+	}
+	if s.Schedule.EveryMonths == 0 {
+		s.Schedule.EveryMonths = 1
+	}
+	if len(s.Universe.ScriptTypes) == 0 {
+		return s, fmt.Errorf("universe.script_types must not be empty")
+	}
+	for _, t := range s.Universe.ScriptTypes {
+		if !instrument.Tradable(instrument.OfScriptType(t)) {
+			return s, fmt.Errorf("universe.script_types: %s series are not tradable; use them as a benchmark or hedge", t)
+		}
+	}
+	if s.InitialCapital <= 0 {
+		return s, fmt.Errorf("initial_capital must be positive")
+	}
+	if s.Costs.CommissionBps < 0 || s.Costs.SlippageBps < 0 || s.Costs.TaxBps < 0 {
+		return s, fmt.Errorf("costs.commission_bps, slippage_bps and tax_bps must not be negative")
+	}
+
+	if s.Futures != nil {
+		f, err := s.Futures.resolve()
+		if err != nil {
+			return s, err
+		}
+		s.Futures = &f
+	}
+
+	if s.Contributions != nil {
+		if _, err := s.Contributions.plan(); err != nil {
+			return s, err
+		}
+	}
+
+	switch s.Dividends {
+	case "", DividendsCash, DividendsReinvest:
+	default:
+		return s, fmt.Errorf("unknown dividends %q (want %s or %s)", s.Dividends, DividendsCash, DividendsReinvest)
+	}
+
+	if s.Tax != nil {
+		if s.Overlay() {
+			return s, fmt.Errorf("tax covers cash equities; futures gains are business income")
+		}
+		t, err := s.Tax.resolve()
+		if err != nil {
+			return s, err
+		}
+		s.Tax = &t
+	}
+
+	start, end, err := s.dates()
+	if err != nil {
+		return s, err
+	}
+	if !start.Before(end) {
+		return s, fmt.Errorf("dates.start must be before dates.end")
+	}
+	return s, nil
+}
+
+func (f SpecFutures) resolve() (SpecFutures, error) {
+	if f.Mode == "" {
+		f.Mode = FuturesCash
+	}
+	switch f.Mode {
+	case FuturesCash:
+	case FuturesStock, FuturesHedge:
+		if f.MarginPct == 0 {
+			f.MarginPct = 20
+		}
+	default:
+		return f, fmt.Errorf("unknown futures.mode %q (want %s, %s or %s)", f.Mode, FuturesCash, FuturesStock, FuturesHedge)
+	}
+	if f.Mode == FuturesHedge {
+		if f.HedgeSymbol == "" {
+			f.HedgeSymbol = "NIFTY"
+		}
+		if f.HedgeRatio == 0 {
+			f.HedgeRatio = 1
+		}
+	}
+	if f.HedgeRatio < 0 || f.MarginPct < 0 || f.RollCostBps < 0 || f.CashYieldPct < 0 {
+		return f, fmt.Errorf("futures settings must not be negative")
+	}
+	return f, nil
+}
+
+func (c SpecContributions) plan() (ContributionPlan, error) {
+	p := ContributionPlan{SIP: c.SIP, StepUpPct: c.StepUpPct, SWP: c.SWP}
+	if c.SIP < 0 || c.StepUpPct < 0 || c.SWP < 0 {
+		return p, fmt.Errorf("contributions.sip, step_up_pct and swp must not be negative")
+	}
+	if c.SWPFrom != "" {
+		from, err := time.Parse("2006-01-02", c.SWPFrom)
+		if err != nil {
+			return p, fmt.Errorf("invalid contributions.swp_from %q: want YYYY-MM-DD", c.SWPFrom)
+		}
+		p.SWPFrom = from
+	}
+	for _, l := range c.Lumpsums {
+		date, err := time.Parse("2006-01-02", l.Date)
+		if err != nil {
+			return p, fmt.Errorf("invalid contributions.lumpsums date %q: want YYYY-MM-DD", l.Date)
+		}
+		p.Lumpsums = append(p.Lumpsums, returns.CashFlow{Date: date, Amount: l.Amount})
+	}
+	return p, nil
+}
+
+func (t SpecTax) resolve() (SpecTax, error) {
+	india := tax.IndianEquity()
+	if len(t.Regimes) == 0 {
+		for _, g := range india.Regimes {
+			t.Regimes = append(t.Regimes, SpecTaxRegime{
+				From:          g.From.Format("2006-01-02"),
+				STCGPct:       g.STCGPct,
+				LTCGPct:       g.LTCGPct,
+				LTCGExemption: g.LTCGExemption,
+			})
+		}
+	}
+	if t.CessPct == 0 {
+		t.CessPct = india.CessPct
+	}
+	if t.LongTermMonths == 0 {
+		t.LongTermMonths = india.LongTermMonths
+	}
+	_, err := t.rules()
+	return t, err
+}
+
+func (t SpecTax) rules() (tax.Rules, error) {
+	rules := tax.Rules{CessPct: t.CessPct, LongTermMonths: t.LongTermMonths}
+	for _, g := range t.Regimes {
+		from, err := time.Parse("2006-01-02", g.From)
+		if err != nil {
+			return rules, fmt.Errorf("invalid tax.regimes from %q: want YYYY-MM-DD", g.From)
+		}
+		rules.Regimes = append(rules.Regimes, tax.Regime{From: from, STCGPct: g.STCGPct, LTCGPct: g.LTCGPct, LTCGExemption: g.LTCGExemption})
+	}
+	return rules, rules.Validate()
+}
+
+// Overlay reports whether the spec trades futures.
+func (s Spec) Overlay() bool {
+	return s.Futures != nil && s.Futures.Mode != FuturesCash
+}
+
+// CashVariant is the spec with its futures replaced by the cash equities
+// they stand for. Idle cash keeps its yield so that the two compare like
+// for like.
+func (s Spec) CashVariant() Spec {
+	c := s
+	c.Name = s.Name + "-cash"
+	if s.Futures != nil {
+		c.Futures = &SpecFutures{Mode: FuturesCash, CashYieldPct: s.Futures.CashYieldPct}
+	}
+	return c
+}
+
+func (s Spec) dates() (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01-02", s.Dates.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid dates.start %q: want YYYY-MM-DD", s.Dates.Start)
+	}
+	end, err := time.Parse("2006-01-02", s.Dates.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid dates.end %q: want YYYY-MM-DD", s.Dates.End)
+	}
+	return start, end, nil
+}
+
+// PriceWindow is the data a run of the spec reads: its universe from one
+// month before the first lookback to the end date.
+func (s Spec) PriceWindow() pricestore.Options {
+	start, end, _ := s.dates()
+	opts := pricestore.Options{
+		ScriptTypes: s.Universe.ScriptTypes,
+		From:        start.AddDate(0, -s.Strategy.LookbackMonths-1, 0),
+		To:          end,
+	}
+	if s.Futures != nil && s.Futures.Mode == FuturesHedge {
+		opts.Symbols = []string{s.Futures.HedgeSymbol}
+	}
+	return opts
+}
+
+// Config turns a resolved spec into the parameters of RunBacktest.
+func (s Spec) Config(svc *services.Service) (BacktestConfig, error) {
+	start, end, err := s.dates()
+	if err != nil {
+		return BacktestConfig{}, err
+	}
+	var futures FuturesConfig
+	if f := s.Futures; f != nil {
+		futures = FuturesConfig{
+			Mode:         f.Mode,
+			HedgeSymbol:  f.HedgeSymbol,
+			HedgeRatio:   f.HedgeRatio,
+			MarginPct:    f.MarginPct,
+			RollCostBps:  f.RollCostBps,
+			CashYieldPct: f.CashYieldPct,
+		}
+	}
+	var contributions ContributionPlan
+	if c := s.Contributions; c != nil {
+		if contributions, err = c.plan(); err != nil {
+			return BacktestConfig{}, err
+		}
+	}
+	var rules *tax.Rules
+	if t := s.Tax; t != nil {
+		r, err := t.rules()
+		if err != nil {
+			return BacktestConfig{}, err
+		}
+		rules = &r
+	}
+	return BacktestConfig{
+		StartDate:       start,
+		EndDate:         end,
+		TopN:            int32(s.Strategy.TopN),
+		ScriptType:      s.Universe.ScriptTypes,
+		Industries:      lower(s.Universe.Industries),
+		InitialCapital:  s.InitialCapital,
+		LookbackMonths:  int32(s.Strategy.LookbackMonths),
+		RebalanceMonths: s.Schedule.EveryMonths,
+		Weighting:       s.Weighting,
+		Costs:           s.Costs,
+		Futures:         futures,
+		Contributions:   contributions,
+		Tax:             rules,
+		Dividends:       s.Dividends,
+		Service:         svc,
+	}, nil
+}
+
+// lower lower-cases industries, which are matched regardless of case.
+func lower(names []string) []string {
+	var out []string
+	for _, n := range names {
+		out = append(out, strings.ToLower(strings.TrimSpace(n)))
+	}
+	return out
+}
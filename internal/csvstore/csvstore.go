@@ -0,0 +1,170 @@
+// Package csvstore serves the stock lists and daily OHLC CSVs straight from
+// disk, for research and tests without a database server.
+package csvstore
+
+import (
+	"encoding/csv"
+	"fmt"
+	"fund-manager/internal/indices"
+	"fund-manager/internal/instrument"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/repository"
+	"fund-manager/internal/universe"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Open reads <stocksDir>/<scriptType>.csv and <dailyDir>/<symbol>.csv into
+// an in-memory store, along with the index series in indexDir when it
+// exists. Rows are accepted and rejected exactly as 'fundmgr universe' and
+// 'fundmgr ingest' would, so the store answers like a Postgres database
+// loaded from the same files. Zero From or To dates leave that end of the
+// range open.
+func Open(stocksDir, dailyDir, indexDir, fnoPath string, opts pricestore.Options) (*pricestore.Store, error) {
+	stocks, err := universe.ReadStocks(stocksDir, fnoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stock lists: %w", err)
+	}
+
+	start := time.Now()
+	b := pricestore.NewBuilder()
+	rows, missing := 0, 0
+	for _, stock := range stocks {
+		if !opts.Includes(stock.Symbol, stock.Scripttype) {
+			continue
+		}
+		b.AddStock(stock)
+
+		bars, err := ReadDaily(filepath.Join(dailyDir, strings.ToLower(stock.Symbol)+".csv"))
+		if os.IsNotExist(err) {
+			missing++
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", stock.Symbol, err)
+		}
+		for _, bar := range bars {
+			if !opts.From.IsZero() && bar.Date.Before(opts.From) {
+				continue
+			}
+			if !opts.To.IsZero() && bar.Date.After(opts.To) {
+				continue
+			}
+			if err := b.AddBar(stock.Symbol, bar); err != nil {
+				return nil, err
+			}
+			rows++
+		}
+	}
+	n, err := addIndices(b, indexDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	rows += n
+	if missing > 0 {
+		log.Printf("⚠️ No daily CSV for %d stocks in %s", missing, dailyDir)
+	}
+	log.Printf("📦 Read %d bars from %s in %s", rows, dailyDir, time.Since(start).Round(time.Millisecond))
+	return b.Build(), nil
+}
+
+// addIndices adds the series of indexDir as stocks of script type index
+// and returns how many bars it added. A missing or empty indexDir adds
+// none.
+func addIndices(b *pricestore.Builder, indexDir string, opts pricestore.Options) (int, error) {
+	if indexDir == "" {
+		return 0, nil
+	}
+	all, err := indices.ReadDir(indexDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	for _, s := range all {
+		if !opts.Includes(s.Symbol, indices.ScriptType) {
+			continue
+		}
+		b.AddStock(repository.Stock{
+			ID:             pgtype.UUID{Bytes: uuid.NewSHA1(uuid.NameSpaceOID, []byte(s.Symbol)), Valid: true},
+			Name:           s.Name,
+			Symbol:         s.Symbol,
+			Scripttype:     indices.ScriptType,
+			InstrumentType: instrument.Index,
+		})
+		for _, bar := range s.Bars {
+			if !opts.From.IsZero() && bar.Date.Before(opts.From) {
+				continue
+			}
+			if !opts.To.IsZero() && bar.Date.After(opts.To) {
+				continue
+			}
+			if err := b.AddBar(s.Symbol, bar); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+	}
+	return rows, nil
+}
+
+// ReadDaily parses one Date,Open,High,Low,Close,Volume file, skipping the
+// rows the Postgres import would skip.
+func ReadDaily(path string) ([]pricestore.Bar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	bars := make([]pricestore.Bar, 0, len(records))
+	for idx, row := range records {
+		if idx == 0 && strings.ToLower(row[0]) == "date" {
+			continue
+		}
+		if len(row) < 6 {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", row[0])
+		if err != nil {
+			continue
+		}
+		var values [4]float64
+		valid := true
+		for i := range values {
+			if values[i], err = strconv.ParseFloat(row[i+1], 64); err != nil {
+				valid = false
+				break
+			}
+		}
+		volume, err := strconv.ParseInt(row[5], 10, 64)
+		if !valid || err != nil {
+			continue
+		}
+		bars = append(bars, pricestore.Bar{
+			Date:   date,
+			Open:   values[0],
+			High:   values[1],
+			Low:    values[2],
+			Close:  values[3],
+			Volume: volume,
+		})
+	}
+	return bars, nil
+}
@@ -0,0 +1,235 @@
+// Package dividends reads cash dividends from NSE corporate action files
+// and looks them up by symbol and ex-date for backtests.
+package dividends
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dividend is the cash paid per share to holders of record on RecordDate.
+// Shares bought on or after ExDate do not get it.
+type Dividend struct {
+	Symbol     string
+	ExDate     time.Time
+	RecordDate time.Time // zero when not announced
+	Amount     float64
+	Purpose    string
+}
+
+// PaidOn is the date the dividend reaches the holder's cash: the record
+// date, or the ex-date when there is none.
+func (d Dividend) PaidOn() time.Time {
+	if d.RecordDate.IsZero() || d.RecordDate.Before(d.ExDate) {
+		return d.ExDate
+	}
+	return d.RecordDate
+}
+
+var dateLayouts = []string{"02-Jan-2006", "2006-01-02", "02 Jan 2006", "02/01/2006", "02-01-2006"}
+
+func parseDate(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// amounts in a purpose, e.g. "Final Dividend - Rs 6 Per Share/Special
+// Dividend - Rs 2 Per Share" or "Dividend - 50%" of the face value
+var (
+	rupeesRe  = regexp.MustCompile(`(?i)dividend[^/]*?\bR[se]\.?\s*([0-9]+(?:\.[0-9]+)?)`)
+	percentRe = regexp.MustCompile(`(?i)dividend[^/]*?\b([0-9]+(?:\.[0-9]+)?)\s*%`)
+)
+
+// AmountOf is the cash per share a corporate action's purpose declares,
+// summing every dividend it names; ok is false when it names none, as for
+// bonuses and splits.
+func AmountOf(purpose string, faceValue float64) (amount float64, ok bool) {
+	for _, m := range rupeesRe.FindAllStringSubmatch(purpose, -1) {
+		v, _ := strconv.ParseFloat(m[1], 64)
+		amount += v
+		ok = true
+	}
+	if !ok && faceValue > 0 {
+		for _, m := range percentRe.FindAllStringSubmatch(purpose, -1) {
+			v, _ := strconv.ParseFloat(m[1], 64)
+			amount += v / 100 * faceValue
+			ok = true
+		}
+	}
+	return amount, ok && amount > 0
+}
+
+func key(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "-", "", "\ufeff", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// Read parses a corporate actions CSV as NSE serves it (SYMBOL, SERIES,
+// PURPOSE, FACE VALUE, EX-DATE, RECORD DATE) or a plain Symbol, ExDate,
+// RecordDate, Amount file. Actions other than dividends are skipped, as
+// are rows repeated for another series. Dividends of a stock with the same
+// ex-date are summed.
+func Read(r io.Reader) ([]Dividend, error) {
+	// a byte order mark before a quoted header would unbalance its quotes
+	br := bufio.NewReader(r)
+	if b, _ := br.Peek(3); string(b) == "\ufeff" {
+		br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		switch k := key(h); k {
+		case "symbol", "purpose", "amount":
+			cols[k] = i
+		case "facevalue", "facevalue(rs.)":
+			cols["face"] = i
+		case "exdate":
+			cols["ex"] = i
+		case "recorddate":
+			cols["record"] = i
+		}
+	}
+	_, hasSymbol := cols["symbol"]
+	_, hasEx := cols["ex"]
+	_, hasPurpose := cols["purpose"]
+	_, hasAmount := cols["amount"]
+	if !hasSymbol || !hasEx || !hasPurpose && !hasAmount {
+		return nil, fmt.Errorf("header %q needs symbol, ex-date and purpose or amount columns", strings.Join(header, ","))
+	}
+
+	type dayKey struct {
+		symbol string
+		ex     time.Time
+	}
+	var out []Dividend
+	index := make(map[dayKey]int)
+	seen := make(map[string]bool)
+	for n := 2; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		cell := func(c string) string {
+			i, ok := cols[c]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		symbol := strings.ToUpper(cell("symbol"))
+		ex, ok := parseDate(cell("ex"))
+		if symbol == "" || !ok {
+			continue
+		}
+		d := Dividend{Symbol: symbol, ExDate: ex, Purpose: strings.Join(strings.Fields(cell("purpose")), " ")}
+		d.RecordDate, _ = parseDate(cell("record"))
+		if hasAmount && cell("amount") != "" {
+			if d.Amount, err = strconv.ParseFloat(cell("amount"), 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid amount %q", n, cell("amount"))
+			}
+		} else {
+			face, _ := strconv.ParseFloat(cell("face"), 64)
+			if d.Amount, ok = AmountOf(d.Purpose, face); !ok {
+				continue
+			}
+		}
+		if d.Amount <= 0 {
+			continue
+		}
+		// the same action is listed once per series the stock trades in
+		action := fmt.Sprintf("%s|%s|%s|%g", symbol, ex.Format("2006-01-02"), d.Purpose, d.Amount)
+		if seen[action] {
+			continue
+		}
+		seen[action] = true
+		k := dayKey{symbol, ex}
+		if i, ok := index[k]; ok {
+			out[i].Amount += d.Amount
+			out[i].Purpose += "/" + d.Purpose
+			continue
+		}
+		index[k] = len(out)
+		out = append(out, d)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Symbol != out[j].Symbol {
+			return out[i].Symbol < out[j].Symbol
+		}
+		return out[i].ExDate.Before(out[j].ExDate)
+	})
+	return out, nil
+}
+
+// ReadFile reads the dividends of one file.
+func ReadFile(path string) ([]Dividend, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	divs, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return divs, nil
+}
+
+// Table holds dividends by symbol, each sorted by ex-date.
+type Table struct {
+	bySymbol map[string][]Dividend
+}
+
+func NewTable(divs []Dividend) *Table {
+	t := &Table{bySymbol: make(map[string][]Dividend)}
+	for _, d := range divs {
+		t.bySymbol[d.Symbol] = append(t.bySymbol[d.Symbol], d)
+	}
+	for _, list := range t.bySymbol {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].ExDate.Before(list[j].ExDate) })
+	}
+	return t
+}
+
+// Len is the number of symbols with dividends.
+func (t *Table) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.bySymbol)
+}
+
+// Between returns the dividends of symbol that go ex after after and on or
+// before through.
+func (t *Table) Between(symbol string, after, through time.Time) []Dividend {
+	if t == nil {
+		return nil
+	}
+	list := t.bySymbol[symbol]
+	i := sort.Search(len(list), func(i int) bool { return list[i].ExDate.After(after) })
+	j := sort.Search(len(list), func(i int) bool { return list[i].ExDate.After(through) })
+	if i >= j {
+		return nil
+	}
+	return list[i:j]
+}
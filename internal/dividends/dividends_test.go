@@ -0,0 +1,105 @@
+package dividends
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestAmountOf(t *testing.T) {
+	for _, c := range []struct {
+		purpose string
+		face    float64
+		want    float64
+		ok      bool
+	}{
+		{"Dividend - Rs 6 Per Share", 1, 6, true},
+		{"Final Dividend - Rs 6 Per Share/Special Dividend - Re 1.50 Per Share", 1, 7.5, true},
+		{"Interim Dividend - Rs.3.25 Per Share", 1, 3.25, true},
+		{"Dividend - 50%", 10, 5, true},
+		{"Bonus 1:1", 10, 0, false},
+		{"Face Value Split (Sub-Division) - From Rs 10/- Per Share To Rs 2/- Per Share", 10, 0, false},
+	} {
+		got, ok := AmountOf(c.purpose, c.face)
+		if ok != c.ok || got != c.want {
+			t.Errorf("AmountOf(%q) = %v, %v, want %v, %v", c.purpose, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestReadCorporateActions(t *testing.T) {
+	// the NSE download: one row per series, a bonus, two dividends on one day
+	file := "\ufeff\"SYMBOL \",\"COMPANY NAME \",\"SERIES \",\"PURPOSE \",\"FACE VALUE \",\"EX-DATE \",\"RECORD DATE \"\n" +
+		"\"ITC\",\"ITC Limited\",\"EQ\",\"Final Dividend - Rs 6.75 Per Share\",\"1\",\"04-Jun-2024\",\"04-Jun-2024\"\n" +
+		"\"ITC\",\"ITC Limited\",\"BE\",\"Final Dividend - Rs 6.75 Per Share\",\"1\",\"04-Jun-2024\",\"04-Jun-2024\"\n" +
+		"\"TCS\",\"Tata Consultancy Services\",\"EQ\",\"Final Dividend - Rs 28 Per Share\",\"1\",\"16-May-2024\",\"-\"\n" +
+		"\"TCS\",\"Tata Consultancy Services\",\"EQ\",\"Special Dividend - Rs 66 Per Share\",\"1\",\"16-May-2024\",\"-\"\n" +
+		"\"INFY\",\"Infosys\",\"EQ\",\"Bonus 1:1\",\"5\",\"25-Oct-2018\",\"26-Oct-2018\"\n"
+	divs, err := Read(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(divs) != 2 {
+		t.Fatalf("dividends %+v", divs)
+	}
+	itc, tcs := divs[0], divs[1]
+	if itc.Symbol != "ITC" || itc.Amount != 6.75 || !itc.PaidOn().Equal(date("2024-06-04")) {
+		t.Fatalf("itc %+v", itc)
+	}
+	// no record date pays on the ex-date
+	if tcs.Symbol != "TCS" || tcs.Amount != 94 || !tcs.RecordDate.IsZero() || !tcs.PaidOn().Equal(date("2024-05-16")) {
+		t.Fatalf("tcs %+v", tcs)
+	}
+}
+
+func TestReadPlain(t *testing.T) {
+	file := "Symbol,ExDate,RecordDate,Amount\nHDFCBANK,2024-05-10,2024-05-11,19.5\nHDFCBANK,2023-05-16,,19\n"
+	divs, err := Read(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(divs) != 2 || !divs[0].ExDate.Equal(date("2023-05-16")) || divs[1].Amount != 19.5 || !divs[1].PaidOn().Equal(date("2024-05-11")) {
+		t.Fatalf("dividends %+v", divs)
+	}
+
+	if _, err := Read(strings.NewReader("Symbol,Amount\nX,1\n")); err == nil {
+		t.Fatal("file without ex-dates accepted")
+	}
+	if _, err := Read(strings.NewReader("Symbol,ExDate,Amount\nX,2024-01-01,one\n")); err == nil {
+		t.Fatal("invalid amount accepted")
+	}
+}
+
+func TestTableBetween(t *testing.T) {
+	table := NewTable([]Dividend{
+		{Symbol: "ITC", ExDate: date("2024-06-04"), Amount: 6.75},
+		{Symbol: "ITC", ExDate: date("2024-02-08"), Amount: 6.25},
+		{Symbol: "TCS", ExDate: date("2024-05-16"), Amount: 94},
+	})
+	if table.Len() != 2 {
+		t.Fatalf("%d symbols", table.Len())
+	}
+	// after is excluded, through included
+	got := table.Between("ITC", date("2024-02-08"), date("2024-06-04"))
+	if len(got) != 1 || got[0].Amount != 6.75 {
+		t.Fatalf("between %+v", got)
+	}
+	if got := table.Between("ITC", date("2023-12-31"), date("2024-12-31")); len(got) != 2 || got[0].Amount != 6.25 {
+		t.Fatalf("year %+v", got)
+	}
+	if got := table.Between("NONE", date("2023-12-31"), date("2024-12-31")); got != nil {
+		t.Fatalf("unknown symbol %+v", got)
+	}
+	var none *Table
+	if none.Len() != 0 || none.Between("ITC", date("2023-12-31"), date("2024-12-31")) != nil {
+		t.Fatal("nil table not empty")
+	}
+}
@@ -0,0 +1,77 @@
+package dividends
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Import stores the dividends of known stocks, replacing what is stored
+// for the same ex-date, and returns how many it stored and the symbols it
+// did not know.
+func Import(ctx context.Context, q *repository.Queries, divs []Dividend) (int, []string, error) {
+	stocks, err := q.GetStocks(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get stocks: %w", err)
+	}
+	ids := make(map[string]pgtype.UUID, len(stocks))
+	for _, st := range stocks {
+		ids[st.Symbol] = st.ID
+	}
+
+	stored := 0
+	var unknown []string
+	missing := make(map[string]bool)
+	for _, d := range divs {
+		id, ok := ids[d.Symbol]
+		if !ok {
+			if !missing[d.Symbol] {
+				missing[d.Symbol] = true
+				unknown = append(unknown, d.Symbol)
+			}
+			continue
+		}
+		if err := q.UpsertDividend(ctx, repository.UpsertDividendParams{
+			StockID:    id,
+			ExDate:     pgtype.Date{Time: d.ExDate, Valid: true},
+			RecordDate: pgtype.Date{Time: d.RecordDate, Valid: !d.RecordDate.IsZero()},
+			Amount:     d.Amount,
+			Purpose:    clip(d.Purpose, 200),
+		}); err != nil {
+			return stored, unknown, fmt.Errorf("failed to store the dividend of %s on %s: %w", d.Symbol, d.ExDate.Format("2006-01-02"), err)
+		}
+		stored++
+	}
+	return stored, unknown, nil
+}
+
+func clip(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+type Queries interface {
+	GetDividends(ctx context.Context) ([]repository.GetDividendsRow, error)
+}
+
+// LoadTable reads every stored dividend.
+func LoadTable(ctx context.Context, q Queries) (*Table, error) {
+	rows, err := q.GetDividends(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dividends: %w", err)
+	}
+	divs := make([]Dividend, 0, len(rows))
+	for _, r := range rows {
+		d := Dividend{Symbol: r.Symbol, ExDate: r.ExDate.Time, Amount: r.Amount}
+		if r.RecordDate.Valid {
+			d.RecordDate = r.RecordDate.Time
+		}
+		divs = append(divs, d)
+	}
+	return NewTable(divs), nil
+}
@@ -0,0 +1,373 @@
+// Package download fetches files from the NSE archives the way a browser
+// session would: cookies from the home page first, a request at a time with
+// a pause in between, retries with backoff, and conditional requests so that
+// unchanged files are not downloaded again.
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	// HomeURL is visited before the first download, and again after a
+	// 401 or 403, to collect session cookies. Empty skips it.
+	HomeURL   string
+	UserAgent string
+	Referer   string
+
+	Retries   int           // attempts after the first one
+	BaseDelay time.Duration // first backoff, doubled on every retry
+	MaxDelay  time.Duration
+	Interval  time.Duration // minimum time between two requests
+	Timeout   time.Duration // per request
+}
+
+const (
+	NSEHome   = "https://www.nseindia.com/"
+	browserUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:120.0) Gecko/20100101 Firefox/120.0"
+)
+
+// DefaultOptions are polite settings for the NSE archives.
+func DefaultOptions() Options {
+	return Options{
+		HomeURL:   NSEHome,
+		UserAgent: browserUA,
+		Referer:   NSEHome,
+		Retries:   4,
+		BaseDelay: time.Second,
+		MaxDelay:  30 * time.Second,
+		Interval:  time.Second,
+		Timeout:   30 * time.Second,
+	}
+}
+
+type Client struct {
+	opts   Options
+	client *http.Client
+
+	mu     sync.Mutex
+	last   time.Time
+	primed bool
+}
+
+// Result describes one Fetch.
+type Result struct {
+	Path        string
+	NotModified bool // the cached file was still current
+	Bytes       int64
+	SHA256      string
+	Changed     bool // the content differs from the previous download
+	Attempts    int
+}
+
+// cacheEntry is stored next to every downloaded file as <file>.meta.json.
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	SHA256       string    `json:"sha256"`
+	Bytes        int64     `json:"bytes"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+func New(opts Options) (*Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		opts: opts,
+		client: &http.Client{
+			Jar:     jar,
+			Timeout: opts.Timeout,
+			// NSE resets HTTP/2 connections from non-browser clients
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				ForceAttemptHTTP2: false,
+			},
+		},
+	}, nil
+}
+
+// statusError is a response that may succeed when retried.
+type statusError struct {
+	resp *http.Response
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("received HTTP status %s", e.resp.Status)
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusUnauthorized || status == http.StatusForbidden || status >= 500
+}
+
+// Fetch downloads url to path. The file is replaced only once the whole
+// body has arrived, so a failed download leaves the previous copy intact.
+func (c *Client) Fetch(ctx context.Context, url, path string) (Result, error) {
+	result := Result{Path: path}
+	cached := c.cached(url, path)
+
+	var lastErr error
+	for attempt := 0; attempt <= c.opts.Retries; attempt++ {
+		if attempt > 0 {
+			var resp *http.Response
+			var se *statusError
+			if errors.As(lastErr, &se) {
+				resp = se.resp
+			}
+			delay := c.backoff(attempt, resp)
+			log.Printf("🔁 Retrying %s in %s (attempt %d of %d): %v", url, delay.Round(time.Millisecond), attempt+1, c.opts.Retries+1, lastErr)
+			if err := sleep(ctx, delay); err != nil {
+				return result, err
+			}
+		}
+		result.Attempts = attempt + 1
+
+		if err := c.prime(ctx); err != nil {
+			lastErr = err
+			continue
+		}
+		resp, err := c.get(ctx, url, cached)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				return result, err
+			}
+			continue
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusNotModified && cached != nil:
+			resp.Body.Close()
+			result.NotModified = true
+			result.Bytes = cached.Bytes
+			result.SHA256 = cached.SHA256
+			log.Printf("✔️ %s not modified (sha256 %s)", path, short(cached.SHA256))
+			return result, nil
+		case resp.StatusCode == http.StatusOK:
+			entry, err := writeAtomic(resp, path)
+			resp.Body.Close()
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			entry.URL = url
+			if err := saveCache(path, entry); err != nil {
+				log.Printf("⚠️ Failed to save cache entry for %s: %v", path, err)
+			}
+			result.Bytes = entry.Bytes
+			result.SHA256 = entry.SHA256
+			result.Changed = cached == nil || cached.SHA256 != entry.SHA256
+			log.Printf("✅ Downloaded %s → %s (%d bytes, sha256 %s)", url, path, entry.Bytes, short(entry.SHA256))
+			return result, nil
+		default:
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			lastErr = &statusError{resp: resp}
+			if !retryable(resp.StatusCode) {
+				return result, lastErr
+			}
+			if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+				c.mu.Lock()
+				c.primed = false
+				c.mu.Unlock()
+			}
+		}
+	}
+	return result, fmt.Errorf("giving up on %s after %d attempts: %w", url, result.Attempts, lastErr)
+}
+
+// cached returns the cache entry of path if it belongs to url and the file
+// still has the recorded content.
+func (c *Client) cached(url, path string) *cacheEntry {
+	data, err := os.ReadFile(path + ".meta.json")
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil
+	}
+	sum, _, err := hashFile(path)
+	if err != nil || sum != entry.SHA256 {
+		return nil
+	}
+	return &entry
+}
+
+func (c *Client) prime(ctx context.Context) error {
+	c.mu.Lock()
+	primed := c.primed
+	c.mu.Unlock()
+	if primed || c.opts.HomeURL == "" {
+		return nil
+	}
+	resp, err := c.get(ctx, c.opts.HomeURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open session: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("failed to open session: %w", &statusError{resp: resp})
+	}
+	c.mu.Lock()
+	c.primed = true
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, url string, cached *cacheEntry) (*http.Response, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request creation failed: %w", err)
+	}
+	if c.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", c.opts.UserAgent)
+	}
+	if c.opts.Referer != "" {
+		req.Header.Set("Referer", c.opts.Referer)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// wait blocks until Interval has passed since the previous request.
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	next := c.last.Add(c.opts.Interval)
+	now := time.Now()
+	if next.Before(now) {
+		next = now
+	}
+	c.last = next
+	c.mu.Unlock()
+	return sleep(ctx, time.Until(next))
+}
+
+// backoff doubles BaseDelay on every attempt up to MaxDelay with up to 50%
+// jitter, unless the server asked for a delay with Retry-After.
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, c.opts.MaxDelay)
+		}
+	}
+	delay := c.opts.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.opts.MaxDelay {
+		delay = c.opts.MaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func writeAtomic(resp *http.Response, path string) (cacheEntry, error) {
+	entry := cacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now().UTC(),
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return entry, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return entry, fmt.Errorf("file creation failed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	if err != nil {
+		return entry, fmt.Errorf("download interrupted after %d bytes: %w", n, err)
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return entry, fmt.Errorf("download truncated: got %d of %d bytes", n, resp.ContentLength)
+	}
+	if err := tmp.Sync(); err != nil {
+		return entry, err
+	}
+	if err := tmp.Close(); err != nil {
+		return entry, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return entry, fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	entry.Bytes = n
+	return entry, nil
+}
+
+func saveCache(path string, entry cacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".meta.json", append(data, '\n'), 0644)
+}
+
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	n, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), n, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func short(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
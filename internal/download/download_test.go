@@ -0,0 +1,235 @@
+package download
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+const body = "Company Name,Industry,Symbol,Series,ISIN Code\nABC Ltd,Chemicals,ABC,EQ,INE000A01010\n"
+
+// exchange stands in for the NSE archives: the home page hands out a
+// session cookie, files need it, and failures can be scripted per path.
+type exchange struct {
+	mu       sync.Mutex
+	failures map[string][]int // status codes returned before succeeding
+	requests []request
+	truncate bool
+}
+
+type request struct {
+	path string
+	at   time.Time
+	etag string
+}
+
+func (e *exchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = append(e.requests, request{r.URL.Path, time.Now(), r.Header.Get("If-None-Match")})
+
+	if r.URL.Path == "/" {
+		http.SetCookie(w, &http.Cookie{Name: "nsit", Value: "session", Path: "/"})
+		return
+	}
+	if c, err := r.Cookie("nsit"); err != nil || c.Value != "session" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if codes := e.failures[r.URL.Path]; len(codes) > 0 {
+		e.failures[r.URL.Path] = codes[1:]
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(codes[0])
+		return
+	}
+	if r.Header.Get("If-None-Match") == `"v1"` {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", `"v1"`)
+	if e.truncate {
+		w.Header().Set("Content-Length", "1000")
+		io.WriteString(w, body[:20])
+		return
+	}
+	io.WriteString(w, body)
+}
+
+func (e *exchange) paths() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var paths []string
+	for _, r := range e.requests {
+		paths = append(paths, r.path)
+	}
+	return paths
+}
+
+func testClient(t *testing.T, srv *httptest.Server, retries int, interval time.Duration) *Client {
+	t.Helper()
+	c, err := New(Options{
+		HomeURL:   srv.URL + "/",
+		Retries:   retries,
+		BaseDelay: time.Millisecond,
+		MaxDelay:  5 * time.Millisecond,
+		Interval:  interval,
+		Timeout:   5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestFetchSessionAndCache(t *testing.T) {
+	ex := &exchange{}
+	srv := httptest.NewServer(ex)
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "stocks", "mid.csv")
+	ctx := context.Background()
+
+	c := testClient(t, srv, 0, 0)
+	res, err := c.Fetch(ctx, srv.URL+"/mid.csv", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != body || res.Bytes != int64(len(body)) || !res.Changed || res.NotModified {
+		t.Fatalf("first fetch: %+v, file %q", res, data)
+	}
+	if len(res.SHA256) != 64 {
+		t.Fatalf("missing checksum: %+v", res)
+	}
+
+	// A new client revalidates with the stored ETag.
+	c = testClient(t, srv, 0, 0)
+	again, err := c.Fetch(ctx, srv.URL+"/mid.csv", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.NotModified || again.SHA256 != res.SHA256 {
+		t.Fatalf("second fetch: %+v", again)
+	}
+	if got := ex.requests[len(ex.requests)-1].etag; got != `"v1"` {
+		t.Fatalf("If-None-Match = %q", got)
+	}
+
+	// An edited file no longer matches its cache entry and is fetched again.
+	os.WriteFile(path, []byte("edited"), 0644)
+	third, err := c.Fetch(ctx, srv.URL+"/mid.csv", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.NotModified || third.Bytes != int64(len(body)) {
+		t.Fatalf("third fetch: %+v", third)
+	}
+
+	want := []string{"/", "/mid.csv", "/", "/mid.csv", "/mid.csv"}
+	if got := ex.paths(); !slices.Equal(got, want) {
+		t.Fatalf("requests %v, want %v", got, want)
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	ex := &exchange{failures: map[string][]int{
+		"/fo.csv":  {http.StatusServiceUnavailable, http.StatusTooManyRequests},
+		"/bad.csv": {http.StatusNotFound},
+	}}
+	srv := httptest.NewServer(ex)
+	defer srv.Close()
+	dir := t.TempDir()
+	ctx := context.Background()
+	c := testClient(t, srv, 3, 0)
+
+	res, err := c.Fetch(ctx, srv.URL+"/fo.csv", filepath.Join(dir, "fo.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", res.Attempts)
+	}
+
+	if _, err := c.Fetch(ctx, srv.URL+"/bad.csv", filepath.Join(dir, "bad.csv")); err == nil {
+		t.Fatal("404 did not fail")
+	}
+	if n := len(ex.paths()); n != 5 {
+		t.Fatalf("%d requests, want 5 (404 is not retried)", n)
+	}
+}
+
+func TestFetchReprimesExpiredSession(t *testing.T) {
+	ex := &exchange{}
+	srv := httptest.NewServer(ex)
+	defer srv.Close()
+	c := testClient(t, srv, 2, 0)
+	ctx := context.Background()
+
+	if _, err := c.Fetch(ctx, srv.URL+"/a.csv", filepath.Join(t.TempDir(), "a.csv")); err != nil {
+		t.Fatal(err)
+	}
+	// The session expires; the next 403 opens a new one.
+	c.client.Jar, _ = cookiejar.New(nil)
+	if _, err := c.Fetch(ctx, srv.URL+"/b.csv", filepath.Join(t.TempDir(), "b.csv")); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/", "/a.csv", "/b.csv", "/", "/b.csv"}
+	if got := ex.paths(); !slices.Equal(got, want) {
+		t.Fatalf("requests %v, want %v", got, want)
+	}
+}
+
+func TestFetchKeepsFileOnFailure(t *testing.T) {
+	ex := &exchange{truncate: true}
+	srv := httptest.NewServer(ex)
+	defer srv.Close()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mid.csv")
+	os.WriteFile(path, []byte("previous"), 0644)
+
+	c := testClient(t, srv, 1, 0)
+	if _, err := c.Fetch(context.Background(), srv.URL+"/mid.csv", path); err == nil {
+		t.Fatal("truncated download did not fail")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "previous" {
+		t.Fatalf("file replaced with %q", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("left behind %d files", len(entries))
+	}
+}
+
+func TestFetchRateLimit(t *testing.T) {
+	ex := &exchange{}
+	srv := httptest.NewServer(ex)
+	defer srv.Close()
+	dir := t.TempDir()
+	interval := 40 * time.Millisecond
+	c := testClient(t, srv, 0, interval)
+
+	for _, name := range []string{"a.csv", "b.csv", "c.csv"} {
+		if _, err := c.Fetch(context.Background(), srv.URL+"/"+name, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i < len(ex.requests); i++ {
+		if gap := ex.requests[i].at.Sub(ex.requests[i-1].at); gap < interval-5*time.Millisecond {
+			t.Fatalf("requests %d and %d only %s apart", i-1, i, gap)
+		}
+	}
+}
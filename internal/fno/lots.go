@@ -0,0 +1,228 @@
+// Package fno models the F&O underlyings and their lot size per expiry
+// month, as published by NSE in fo_mktlots.csv.
+package fno
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Underlying struct {
+	Symbol string
+	Name   string
+	Index  bool
+	Lots   []Lot // by month
+}
+
+// Lot is the lot size of the contracts expiring in Month, the first day of
+// the expiry month.
+type Lot struct {
+	Month time.Time
+	Size  int32
+}
+
+// Contracts is the number of whole lots that amount buys at price.
+func (l Lot) Contracts(amount, price float64) int64 {
+	if l.Size <= 0 || price <= 0 || amount <= 0 {
+		return 0
+	}
+	return int64(amount / (price * float64(l.Size)))
+}
+
+// ReadMarketLots parses the fo_mktlots.csv file at path.
+func ReadMarketLots(path string) ([]Underlying, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FNO list: %w", err)
+	}
+	defer file.Close()
+	return ParseMarketLots(file)
+}
+
+// ParseMarketLots reads the UNDERLYING,SYMBOL,<MON-YY>... layout. Rows
+// whose symbol is SYMBOL are section titles repeating the header with the
+// months of their section; the underlyings before "Derivatives on
+// Individual Securities" are indices. Rows without a symbol are skipped.
+// An empty cell means no contract expires in that month.
+func ParseMarketLots(r io.Reader) ([]Underlying, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FNO CSV: %w", err)
+	}
+
+	var months []time.Time
+	var underlyings []Underlying
+	index := true
+	for _, record := range records {
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		if len(record) < 2 {
+			continue
+		}
+		if strings.EqualFold(record[1], "SYMBOL") {
+			if strings.Contains(strings.ToLower(record[0]), "individual securities") {
+				index = false
+			}
+			months = make([]time.Time, 0, len(record)-2)
+			for _, cell := range record[2:] {
+				var month time.Time // zero for a blank column
+				if cell != "" {
+					if month, err = time.Parse("Jan-06", cell); err != nil {
+						return nil, fmt.Errorf("invalid expiry month %q in FNO header", cell)
+					}
+				}
+				months = append(months, month)
+			}
+			continue
+		}
+		if record[1] == "" {
+			if strings.Contains(strings.ToLower(record[0]), "individual securities") {
+				index = false
+			}
+			continue
+		}
+		if months == nil {
+			return nil, fmt.Errorf("FNO list has no UNDERLYING header")
+		}
+
+		u := Underlying{Symbol: record[1], Name: record[0], Index: index}
+		for i, cell := range record[2:] {
+			if cell == "" || i >= len(months) || months[i].IsZero() {
+				continue
+			}
+			size, err := strconv.ParseInt(cell, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid lot size %q for %s", cell, u.Symbol)
+			}
+			u.Lots = append(u.Lots, Lot{Month: months[i], Size: int32(size)})
+		}
+		underlyings = append(underlyings, u)
+	}
+	return underlyings, nil
+}
+
+// Table answers point-in-time lot size questions for a set of
+// underlyings.
+type Table struct {
+	lots map[string][]Lot
+	idx  map[string]bool
+}
+
+func NewTable(underlyings []Underlying) *Table {
+	t := &Table{lots: make(map[string][]Lot), idx: make(map[string]bool)}
+	for _, u := range underlyings {
+		t.Add(u.Symbol, u.Index, u.Lots...)
+	}
+	return t
+}
+
+// Add records lots of symbol, replacing any already known for the same
+// months.
+func (t *Table) Add(symbol string, index bool, lots ...Lot) {
+	t.idx[symbol] = index
+	byMonth := make(map[time.Time]int32)
+	for _, l := range t.lots[symbol] {
+		byMonth[l.Month] = l.Size
+	}
+	for _, l := range lots {
+		byMonth[l.Month] = l.Size
+	}
+	merged := make([]Lot, 0, len(byMonth))
+	for m, size := range byMonth {
+		merged = append(merged, Lot{Month: m, Size: size})
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Month.Before(merged[j].Month) })
+	t.lots[symbol] = merged
+}
+
+func (t *Table) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.lots)
+}
+
+// Symbols lists the underlyings, indices included, in order.
+func (t *Table) Symbols() []string {
+	symbols := make([]string, 0, len(t.lots))
+	for s := range t.lots {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+func (t *Table) IsIndex(symbol string) bool {
+	return t.idx[symbol]
+}
+
+// Lot returns the lot of the near-month contract of symbol on date, the one
+// expiring in date's calendar month, and whether there is one.
+func (t *Table) Lot(symbol string, date time.Time) (Lot, bool) {
+	if t == nil {
+		return Lot{}, false
+	}
+	month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	lots := t.lots[symbol]
+	i := sort.Search(len(lots), func(i int) bool { return !lots[i].Month.Before(month) })
+	if i < len(lots) && lots[i].Month.Equal(month) {
+		return lots[i], true
+	}
+	return Lot{}, false
+}
+
+// Covers reports whether the table lists stock lots for date's month. The
+// market lots list only the next few expiries, so outside them the table
+// cannot tell whether a stock was in F&O.
+func (t *Table) Covers(date time.Time) bool {
+	if t == nil {
+		return false
+	}
+	for symbol := range t.lots {
+		if t.idx[symbol] {
+			continue
+		}
+		if _, ok := t.Lot(symbol, date); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// InFno reports whether symbol has a contract expiring in date's month.
+func (t *Table) InFno(symbol string, date time.Time) bool {
+	_, ok := t.Lot(symbol, date)
+	return ok
+}
+
+// Change is a lot size that differs from the previous expiry month's.
+type Change struct {
+	Symbol   string
+	Month    time.Time
+	From, To int32
+}
+
+// Changes lists the months in which a lot size changed, per symbol in
+// order.
+func (t *Table) Changes() []Change {
+	var changes []Change
+	for _, symbol := range t.Symbols() {
+		lots := t.lots[symbol]
+		for i := 1; i < len(lots); i++ {
+			if lots[i].Size != lots[i-1].Size {
+				changes = append(changes, Change{Symbol: symbol, Month: lots[i].Month, From: lots[i-1].Size, To: lots[i].Size})
+			}
+		}
+	}
+	return changes
+}
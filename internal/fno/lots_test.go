@@ -0,0 +1,179 @@
+package fno
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// laid out like NSE's fo_mktlots.csv: cells padded to a fixed width, index
+// options running further out than the stock months, and the stock section
+// repeating the header with only its own months
+const marketLots = `UNDERLYING                          ,SYMBOL    ,JUL-25     ,AUG-25     ,SEP-25     ,DEC-25     ,MAR-26
+NIFTY BANK                          ,BANKNIFTY ,35         ,35         ,35         ,35         ,30
+NIFTY 50                            ,NIFTY     ,75         ,75         ,75         ,75         ,75
+                                    ,          ,           ,           ,           ,           ,
+Derivatives on Individual Securities,Symbol    ,JUL-25     ,AUG-25     ,SEP-25     ,           ,
+AARTI INDUSTRIES LTD                ,AARTIIND  ,1325       ,           ,           ,           ,
+ABB INDIA LIMITED                   ,ABB       ,125        ,125        ,125        ,           ,
+ANGEL ONE LIMITED                   ,ANGELONE  ,250        ,           ,200        ,           ,
+`
+
+func month(s string) time.Time {
+	t, err := time.Parse("2006-01", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseMarketLots(t *testing.T) {
+	underlyings, err := ParseMarketLots(strings.NewReader(marketLots))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		symbol, name string
+		index        bool
+		lots         string
+	}{
+		{"BANKNIFTY", "NIFTY BANK", true, "2025-07:35 2025-08:35 2025-09:35 2025-12:35 2026-03:30"},
+		{"NIFTY", "NIFTY 50", true, "2025-07:75 2025-08:75 2025-09:75 2025-12:75 2026-03:75"},
+		// a blank cell is a month without a contract
+		{"AARTIIND", "AARTI INDUSTRIES LTD", false, "2025-07:1325"},
+		{"ABB", "ABB INDIA LIMITED", false, "2025-07:125 2025-08:125 2025-09:125"},
+		{"ANGELONE", "ANGEL ONE LIMITED", false, "2025-07:250 2025-09:200"},
+	}
+	if len(underlyings) != len(want) {
+		t.Fatalf("got %d underlyings, want %d: %+v", len(underlyings), len(want), underlyings)
+	}
+	for i, w := range want {
+		u := underlyings[i]
+		var lots []string
+		for _, l := range u.Lots {
+			lots = append(lots, fmt.Sprintf("%s:%d", l.Month.Format("2006-01"), l.Size))
+		}
+		if u.Symbol != w.symbol || u.Name != w.name || u.Index != w.index || strings.Join(lots, " ") != w.lots {
+			t.Errorf("underlying %d = %s %q index %v lots %v, want %s %q index %v lots %s",
+				i, u.Symbol, u.Name, u.Index, lots, w.symbol, w.name, w.index, w.lots)
+		}
+	}
+}
+
+func TestParseMarketLotsErrors(t *testing.T) {
+	for _, c := range []struct {
+		src, want string
+	}{
+		{"UNDERLYING,SYMBOL,JULY-25\n", `invalid expiry month "JULY-25"`},
+		{"UNDERLYING,SYMBOL,JUL-25\nABB INDIA LIMITED,ABB,12a\n", `invalid lot size "12a" for ABB`},
+		{"ABB INDIA LIMITED,ABB,125\n", "no UNDERLYING header"},
+	} {
+		if _, err := ParseMarketLots(strings.NewReader(c.src)); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%q: error %v, want %q", c.src, err, c.want)
+		}
+	}
+}
+
+func TestTableLot(t *testing.T) {
+	underlyings, err := ParseMarketLots(strings.NewReader(marketLots))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := NewTable(underlyings)
+	for _, c := range []struct {
+		symbol string
+		date   string
+		size   int32 // 0 for no contract
+	}{
+		{"ABB", "2025-06-30", 0}, // before the list
+		{"ABB", "2025-07-01", 125},
+		{"ABB", "2025-09-30", 125},
+		{"ABB", "2025-10-01", 0}, // after the stock months
+		{"AARTIIND", "2025-07-15", 1325},
+		{"AARTIIND", "2025-08-15", 0}, // dropped out
+		{"ANGELONE", "2025-08-15", 0},
+		{"ANGELONE", "2025-09-15", 200},
+		{"BANKNIFTY", "2025-12-24", 35},
+		{"BANKNIFTY", "2026-03-02", 30},
+		{"BANKNIFTY", "2026-01-15", 0}, // between quarterly expiries
+		{"UNKNOWN", "2025-07-15", 0},
+	} {
+		date, err := time.Parse("2006-01-02", c.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lot, ok := table.Lot(c.symbol, date)
+		if ok != (c.size != 0) || lot.Size != c.size {
+			t.Errorf("Lot(%s, %s) = %d, %v, want %d", c.symbol, c.date, lot.Size, ok, c.size)
+		}
+		if ok && !lot.Month.Equal(month(c.date[:7])) {
+			t.Errorf("Lot(%s, %s) month %s", c.symbol, c.date, lot.Month.Format("2006-01"))
+		}
+		if table.InFno(c.symbol, date) != ok {
+			t.Errorf("InFno(%s, %s) disagrees with Lot", c.symbol, c.date)
+		}
+	}
+
+	// only months with stock lots count as covered; the index months that
+	// run further out say nothing about stocks
+	for _, c := range []struct {
+		month string
+		want  bool
+	}{
+		{"2025-06", false},
+		{"2025-07", true},
+		{"2025-09", true},
+		{"2025-12", false},
+		{"2026-03", false},
+	} {
+		if got := table.Covers(month(c.month)); got != c.want {
+			t.Errorf("Covers(%s) = %v, want %v", c.month, got, c.want)
+		}
+	}
+	var empty *Table
+	if _, ok := empty.Lot("ABB", month("2025-07")); ok || empty.Covers(month("2025-07")) || empty.Len() != 0 {
+		t.Errorf("a nil table has lots")
+	}
+}
+
+func TestTableChanges(t *testing.T) {
+	underlyings, err := ParseMarketLots(strings.NewReader(marketLots))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := NewTable(underlyings)
+	// a later list revising a month and adding one
+	table.Add("ABB", false, Lot{Month: month("2025-09"), Size: 100}, Lot{Month: month("2025-10"), Size: 100})
+
+	var got []string
+	for _, c := range table.Changes() {
+		got = append(got, fmt.Sprintf("%s %s %d->%d", c.Symbol, c.Month.Format("2006-01"), c.From, c.To))
+	}
+	want := "ABB 2025-09 125->100, ANGELONE 2025-09 250->200, BANKNIFTY 2026-03 35->30"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("changes %s, want %s", strings.Join(got, ", "), want)
+	}
+	if lot, ok := table.Lot("ABB", month("2025-10")); !ok || lot.Size != 100 {
+		t.Errorf("ABB October lot %d, %v", lot.Size, ok)
+	}
+	if lot := (Lot{Size: 125}); lot.Contracts(100000, 300) != 2 || lot.Contracts(30000, 300) != 0 {
+		t.Errorf("contracts %d, %d", lot.Contracts(100000, 300), lot.Contracts(30000, 300))
+	}
+}
+
+func TestReadShippedList(t *testing.T) {
+	underlyings, err := ReadMarketLots("../../data/fnoList.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stocks := 0
+	for _, u := range underlyings {
+		if !u.Index {
+			stocks++
+		}
+	}
+	if stocks == 0 || stocks == len(underlyings) {
+		t.Errorf("%d of %d underlyings are stocks", stocks, len(underlyings))
+	}
+}
@@ -0,0 +1,70 @@
+package fno
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/repository"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Revision is a stored lot size that a newer list replaced.
+type Revision struct {
+	Symbol   string
+	Month    time.Time
+	From, To int32
+}
+
+// Import upserts the underlyings and their lot sizes recorded on the given
+// date. Months already stored keep their rows; a different size replaces
+// the old one and is returned as a revision. Months that have dropped out
+// of the list stay, which is how the history builds up.
+func Import(ctx context.Context, pool *pgxpool.Pool, underlyings []Underlying, on time.Time) ([]Revision, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	q := repository.New(tx)
+
+	var revisions []Revision
+	for _, u := range underlyings {
+		if err := q.UpsertFnoUnderlying(ctx, repository.UpsertFnoUnderlyingParams{Symbol: u.Symbol, Name: u.Name, IsIndex: u.Index}); err != nil {
+			return nil, fmt.Errorf("failed to store %s: %w", u.Symbol, err)
+		}
+		for _, l := range u.Lots {
+			previous, err := q.UpsertFnoLotSize(ctx, repository.UpsertFnoLotSizeParams{
+				Symbol:      u.Symbol,
+				ExpiryMonth: pgtype.Date{Time: l.Month, Valid: true},
+				LotSize:     l.Size,
+				RecordedOn:  pgtype.Date{Time: on, Valid: true},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to store lot size of %s: %w", u.Symbol, err)
+			}
+			if previous != 0 && previous != l.Size {
+				revisions = append(revisions, Revision{Symbol: u.Symbol, Month: l.Month, From: previous, To: l.Size})
+			}
+		}
+	}
+	return revisions, tx.Commit(ctx)
+}
+
+type LotQueries interface {
+	GetFnoLotSizes(ctx context.Context) ([]repository.GetFnoLotSizesRow, error)
+}
+
+// LoadTable reads every stored lot size.
+func LoadTable(ctx context.Context, q LotQueries) (*Table, error) {
+	rows, err := q.GetFnoLotSizes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lot sizes: %w", err)
+	}
+	t := NewTable(nil)
+	for _, r := range rows {
+		t.Add(r.Symbol, r.IsIndex, Lot{Month: r.ExpiryMonth.Time, Size: r.LotSize})
+	}
+	return t, nil
+}
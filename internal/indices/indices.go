@@ -0,0 +1,261 @@
+// Package indices reads the historical index CSVs NSE and niftyindices.com
+// publish, price series and total return indices (TRIs) alike. A series is
+// stored as an instrument of its own, of script type index, so that
+// benchmarks, hedges and filters look it up by symbol like any stock.
+package indices
+
+import (
+	"encoding/csv"
+	"fmt"
+	"fund-manager/internal/instrument"
+	"fund-manager/internal/pricestore"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScriptType is the script type of index series in stocks.
+const ScriptType = instrument.ScriptIndex
+
+// Source marks daily rows imported from index files.
+const Source = "index"
+
+// Series is one index's daily values, sorted by date. A TRI has only
+// closes.
+type Series struct {
+	Name   string // e.g. NIFTY 50, or NIFTY 50 TRI
+	Symbol string
+	TRI    bool
+	Bars   []pricestore.Bar
+}
+
+// aliases are the symbols the exchange trades an index's derivatives
+// under, keyed by the index's name without spaces.
+var aliases = map[string]string{
+	"NIFTY50":                "NIFTY",
+	"NIFTYBANK":              "BANKNIFTY",
+	"NIFTYFINANCIALSERVICES": "FINNIFTY",
+	"NIFTYMIDCAPSELECT":      "MIDCPNIFTY",
+}
+
+var nonAlnum = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// Symbol is the symbol of the index series name: its derivatives symbol
+// where it has one, else the name in upper case without spaces, so NIFTY
+// 50 is NIFTY and NIFTY MIDCAP 150 is NIFTYMIDCAP150. A TRI's symbol ends
+// in TRI, as in NIFTYTRI.
+func Symbol(name string, tri bool) string {
+	key := nonAlnum.ReplaceAllString(strings.ToUpper(name), "")
+	key = strings.TrimSuffix(key, "TRI")
+	key = strings.ReplaceAll(key, "SMLCAP", "SMALLCAP")
+	if alias, ok := aliases[key]; ok {
+		key = alias
+	}
+	if tri {
+		key += "TRI"
+	}
+	return key
+}
+
+// date layouts seen in index files
+var dateLayouts = []string{"02 Jan 2006", "02-Jan-2006", "2006-01-02", "02-Jan-06", "02/01/2006", "02-01-2006", "Jan 02, 2006"}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func key(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "\"", "", "\ufeff", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// Read parses one index CSV, finding its columns by name. The index is
+// named by its Index Name column, else by name. A Total Returns Index
+// column makes the series a TRI. Rows without a close are skipped; a
+// missing open, high or low is NaN and a missing volume -1.
+func Read(r io.Reader, name string) (Series, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return Series{}, fmt.Errorf("failed to read header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		switch k := key(h); {
+		case k == "indexname":
+			cols["name"] = i
+		case k == "date" || k == "historicaldate" || k == "timestamp":
+			cols["date"] = i
+		case k == "open" || k == "high" || k == "low" || k == "close":
+			cols[k] = i
+		case k == "tri" || strings.HasPrefix(k, "totalreturn"):
+			cols["tri"] = i
+		case k == "sharestraded" || k == "volume":
+			cols["volume"] = i
+		}
+	}
+	s := Series{Name: name}
+	if _, ok := cols["tri"]; ok {
+		s.TRI = true
+		cols["close"] = cols["tri"]
+	}
+	for _, c := range []string{"date", "close"} {
+		if _, ok := cols[c]; !ok {
+			return s, fmt.Errorf("header %q has no %s column", strings.Join(header, ","), c)
+		}
+	}
+
+	for n := 2; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return s, fmt.Errorf("line %d: %w", n, err)
+		}
+		cell := func(c string) string {
+			i, ok := cols[c]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		if cell("date") == "" {
+			continue
+		}
+		if v := cell("name"); v != "" && s.Name == name {
+			s.Name = strings.Join(strings.Fields(v), " ")
+		}
+		date, err := parseDate(cell("date"))
+		if err != nil {
+			return s, fmt.Errorf("line %d: %w", n, err)
+		}
+		bar := pricestore.Bar{Date: date, Open: value(cell("open")), High: value(cell("high")), Low: value(cell("low")), Close: value(cell("close")), Volume: -1}
+		if math.IsNaN(bar.Close) {
+			continue
+		}
+		if s.TRI {
+			bar.Open, bar.High, bar.Low = math.NaN(), math.NaN(), math.NaN()
+		} else if v, err := strconv.ParseFloat(strings.ReplaceAll(cell("volume"), ",", ""), 64); err == nil {
+			bar.Volume = int64(v)
+		}
+		s.Bars = append(s.Bars, bar)
+	}
+	if s.Name == "" {
+		return s, fmt.Errorf("no index name")
+	}
+	if s.TRI && !strings.HasSuffix(strings.ToUpper(s.Name), "TRI") {
+		s.Name += " TRI"
+	}
+	s.Symbol = Symbol(s.Name, s.TRI)
+	sort.SliceStable(s.Bars, func(i, j int) bool { return s.Bars[i].Date.Before(s.Bars[j].Date) })
+	// a date given twice keeps its last row
+	bars := s.Bars[:0]
+	for _, bar := range s.Bars {
+		if len(bars) > 0 && bars[len(bars)-1].Date.Equal(bar.Date) {
+			bars[len(bars)-1] = bar
+			continue
+		}
+		bars = append(bars, bar)
+	}
+	s.Bars = bars
+	return s, nil
+}
+
+// value parses a number written with thousands separators; "-" and empty
+// cells are NaN.
+func value(s string) float64 {
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	if err != nil || v <= 0 {
+		return math.NaN()
+	}
+	return v
+}
+
+// the date range NSE appends to downloaded file names
+var rangeSuffix = regexp.MustCompile(`[-_ ]*\d{2}-\d{2}-\d{4}-to-\d{2}-\d{2}-\d{4}$`)
+
+// NameOf is the index a file is named after, e.g. NIFTY 50 for
+// "NIFTY 50-01-04-2023-to-31-03-2024.csv".
+func NameOf(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.TrimSpace(rangeSuffix.ReplaceAllString(base, ""))
+}
+
+// ReadFile reads one index CSV, named by NameOf when it has no Index Name
+// column.
+func ReadFile(path string) (Series, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Series{}, err
+	}
+	defer file.Close()
+	s, err := Read(file, NameOf(path))
+	if err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// ReadDir reads every .csv in dir and joins the files of one index, as NSE
+// serves a year per download; on a date two files share, the later file in
+// name order wins.
+func ReadDir(dir string) ([]Series, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var all []Series
+	index := make(map[string]int)
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.EqualFold(filepath.Ext(e.Name()), ".csv") {
+			continue
+		}
+		s, err := ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[s.Symbol]
+		if !ok {
+			index[s.Symbol] = len(all)
+			all = append(all, s)
+			continue
+		}
+		all[i].Bars = merge(all[i].Bars, s.Bars)
+	}
+	return all, nil
+}
+
+// merge joins two sorted bar slices, taking b's bar on a date both have.
+func merge(a, b []pricestore.Bar) []pricestore.Bar {
+	out := make([]pricestore.Bar, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i].Date.Before(b[j].Date):
+			out = append(out, a[i])
+			i++
+		case i == len(a) || b[j].Date.Before(a[i].Date):
+			out = append(out, b[j])
+			j++
+		default:
+			out = append(out, b[j])
+			i++
+			j++
+		}
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package indices
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadPriceIndex(t *testing.T) {
+	// the NSE download: padded headers, upper case months, a missing open
+	file := "\ufeffDate ,Open ,High ,Low ,Close ,Shares Traded ,Turnover (₹ Cr)\n" +
+		"02-APR-2024,\"22,458.75\",\"22,601.80\",\"22,433.85\",\"22,453.30\",\"2,80,31,556\",\"29,146.08\"\n" +
+		"01-APR-2024,-,\"22,529.95\",\"22,270.85\",\"22,462.00\",-,-\n"
+	s, err := Read(strings.NewReader(file), "NIFTY 50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "NIFTY 50" || s.Symbol != "NIFTY" || s.TRI {
+		t.Fatalf("series %+v", s)
+	}
+	if len(s.Bars) != 2 || !s.Bars[0].Date.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("bars %+v", s.Bars)
+	}
+	first, second := s.Bars[0], s.Bars[1]
+	if !math.IsNaN(first.Open) || first.Close != 22462 || first.Volume != -1 {
+		t.Fatalf("first bar %+v", first)
+	}
+	if second.Open != 22458.75 || second.Close != 22453.3 || second.Volume != 28031556 {
+		t.Fatalf("second bar %+v", second)
+	}
+}
+
+func TestReadTRI(t *testing.T) {
+	// niftyindices.com names the index in every row
+	file := "Index Name,Date,Total Returns Index\n" +
+		"Nifty Smallcap 250,01 Apr 2024,\"19,012.10\"\n" +
+		"Nifty Smallcap 250,01 Apr 2024,\"19,020.55\"\n" +
+		"Nifty Smallcap 250,03 Apr 2024,\n"
+	s, err := Read(strings.NewReader(file), "ignored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "Nifty Smallcap 250 TRI" || s.Symbol != "NIFTYSMALLCAP250TRI" || !s.TRI {
+		t.Fatalf("series %+v", s)
+	}
+	// the repeated date keeps its last row and the empty close is skipped
+	if len(s.Bars) != 1 || s.Bars[0].Close != 19020.55 || !math.IsNaN(s.Bars[0].High) {
+		t.Fatalf("bars %+v", s.Bars)
+	}
+
+	if _, err := Read(strings.NewReader("Date,Open\n"), "NIFTY 50"); err == nil {
+		t.Fatal("file without closes accepted")
+	}
+}
+
+func TestSymbol(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		tri  bool
+		want string
+	}{
+		{"NIFTY 50", false, "NIFTY"},
+		{"NIFTY 50 TRI", true, "NIFTYTRI"},
+		{"Nifty Bank", false, "BANKNIFTY"},
+		{"NIFTY MIDCAP 150", false, "NIFTYMIDCAP150"},
+		{"NIFTY SMLCAP 250", false, "NIFTYSMALLCAP250"},
+		{"Nifty Midcap 150", true, "NIFTYMIDCAP150TRI"},
+	} {
+		if got := Symbol(c.name, c.tri); got != c.want {
+			t.Errorf("Symbol(%q, %v) = %s, want %s", c.name, c.tri, got, c.want)
+		}
+	}
+	if name := NameOf("data/indices/NIFTY MIDCAP 150-01-04-2023-to-31-03-2024.csv"); name != "NIFTY MIDCAP 150" {
+		t.Fatalf("name %q", name)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"NIFTY 50-01-01-2023-to-31-12-2023.csv": "Date,Close\n29-DEC-2023,21731.40\n01-JAN-2024,21700\n",
+		"NIFTY 50-01-01-2024-to-31-12-2024.csv": "Date,Close\n01-JAN-2024,21741.90\n02-JAN-2024,21665.80\n",
+		"NIFTY 50 TRI.csv":                      "Date,Total Returns Index\n01-JAN-2024,31912.59\n",
+		"notes.txt":                             "not an index",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("series %+v", all)
+	}
+	price, tri := all[1], all[0]
+	if tri.Symbol != "NIFTYTRI" || len(tri.Bars) != 1 {
+		t.Fatalf("tri %+v", tri)
+	}
+	// the later file wins on the date both have
+	if price.Symbol != "NIFTY" || len(price.Bars) != 3 || price.Bars[1].Close != 21741.9 {
+		t.Fatalf("price %+v", price)
+	}
+}
@@ -0,0 +1,245 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/amfi"
+	"fund-manager/internal/instrument"
+	"fund-manager/internal/repository"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ImportAMFI loads AMFI scheme masters and NAV reports. Every scheme
+// becomes a stock, MF<code> of script type mf with its category as the
+// industry, and its NAVs that stock's daily closes. A report replaces the
+// NAVs its schemes already have in its date range, so that files can be
+// imported again and in any order.
+func ImportAMFI(ctx context.Context, queries *repository.Queries, paths []string) error {
+	rows, err := queries.GetMfSchemes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get schemes: %w", err)
+	}
+	known := make(map[int]repository.MfScheme, len(rows))
+	for _, row := range rows {
+		known[int(row.Code)] = row
+	}
+
+	schemes, navs := 0, 0
+	for _, path := range paths {
+		f, err := readAMFI(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		for _, s := range f.Schemes {
+			row, err := saveScheme(ctx, queries, known, s)
+			if err != nil {
+				return fmt.Errorf("failed to save scheme %d: %w", s.Code, err)
+			}
+			known[s.Code] = row
+		}
+		n, err := saveNAVs(ctx, queries, known, f.NAVs)
+		if err != nil {
+			return fmt.Errorf("failed to save the NAVs of %s: %w", path, err)
+		}
+		fmt.Printf("%s: %d schemes, %d NAVs\n", filepath.Base(path), len(f.Schemes), n)
+		schemes += len(f.Schemes)
+		navs += n
+	}
+
+	if err := queries.RefreshMonthlyCloses(ctx); err != nil {
+		return fmt.Errorf("failed to refresh monthly closes: %w", err)
+	}
+	fmt.Printf("✅ Imported %d schemes and %d NAVs from %d files.\n", schemes, navs, len(paths))
+	return nil
+}
+
+func readAMFI(path string) (amfi.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return amfi.File{}, err
+	}
+	defer file.Close()
+	return amfi.Read(file)
+}
+
+// clip cuts s to the n characters a column holds.
+func clip(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+func schemeOf(row repository.MfScheme) amfi.Scheme {
+	return amfi.Scheme{
+		Code:         int(row.Code),
+		AMC:          row.Amc,
+		Name:         row.Name,
+		Type:         row.SchemeType,
+		Category:     row.Category,
+		ISINGrowth:   row.IsinGrowth,
+		ISINReinvest: row.IsinReinvest,
+		Launch:       row.LaunchDate.Time,
+	}
+}
+
+// saveScheme stores s, filling what its file does not say from the stored
+// scheme, and creates or updates its stock. Unchanged schemes are left
+// alone.
+func saveScheme(ctx context.Context, queries *repository.Queries, known map[int]repository.MfScheme, s amfi.Scheme) (repository.MfScheme, error) {
+	old, exists := known[s.Code]
+	if exists {
+		if s = s.Merge(schemeOf(old)); s == schemeOf(old) {
+			return old, nil
+		}
+	}
+	name := clip(s.Name, 100)
+	industry := pgtype.Text{String: clip(s.Category, 50), Valid: s.Category != ""}
+
+	stockID := old.StockID
+	if !exists {
+		stockID = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+		if _, err := queries.CreateStock(ctx, repository.CreateStockParams{
+			ID:             stockID,
+			Name:           name,
+			Symbol:         amfi.Symbol(s.Code),
+			Scripttype:     amfi.ScriptType,
+			Industry:       industry,
+			Isin:           pgtype.Text{String: s.ISINGrowth, Valid: s.ISINGrowth != ""},
+			InstrumentType: instrument.OfFundCategory(s.Category),
+		}); err != nil {
+			return old, err
+		}
+	} else if s.Name != old.Name || s.Category != old.Category {
+		if err := queries.UpdateStock(ctx, repository.UpdateStockParams{
+			ID:             stockID,
+			Name:           name,
+			Symbol:         amfi.Symbol(s.Code),
+			Scripttype:     amfi.ScriptType,
+			Industry:       industry,
+			InstrumentType: instrument.OfFundCategory(s.Category),
+		}); err != nil {
+			return old, err
+		}
+	}
+
+	row := repository.MfScheme{
+		Code:         int32(s.Code),
+		StockID:      stockID,
+		Amc:          s.AMC,
+		Name:         s.Name,
+		SchemeType:   s.Type,
+		Category:     s.Category,
+		IsinGrowth:   s.ISINGrowth,
+		IsinReinvest: s.ISINReinvest,
+		LaunchDate:   pgtype.Date{Time: s.Launch, Valid: !s.Launch.IsZero()},
+	}
+	return row, queries.UpsertMfScheme(ctx, repository.UpsertMfSchemeParams{
+		Code:         row.Code,
+		StockID:      row.StockID,
+		Amc:          row.Amc,
+		Name:         row.Name,
+		SchemeType:   row.SchemeType,
+		Category:     row.Category,
+		IsinGrowth:   row.IsinGrowth,
+		IsinReinvest: row.IsinReinvest,
+		LaunchDate:   row.LaunchDate,
+	})
+}
+
+// saveNAVs replaces the stored NAVs of the schemes in navs between the
+// first and last date in navs, in mf_navs and as daily rows, and returns
+// how many it wrote. A later NAV of a scheme on the same date wins.
+func saveNAVs(ctx context.Context, queries *repository.Queries, known map[int]repository.MfScheme, navs []amfi.NAV) (int, error) {
+	if len(navs) == 0 {
+		return 0, nil
+	}
+	type key struct {
+		code int
+		date time.Time
+	}
+	latest := make(map[key]amfi.NAV, len(navs))
+	for _, v := range navs {
+		latest[key{v.Code, v.Date}] = v
+	}
+	navs = navs[:0:0]
+	for _, v := range latest {
+		navs = append(navs, v)
+	}
+	sort.Slice(navs, func(i, j int) bool {
+		if navs[i].Code != navs[j].Code {
+			return navs[i].Code < navs[j].Code
+		}
+		return navs[i].Date.Before(navs[j].Date)
+	})
+
+	from, to := navs[0].Date, navs[0].Date
+	var codes []int32
+	var stockIDs []pgtype.UUID
+	for i, v := range navs {
+		if v.Date.Before(from) {
+			from = v.Date
+		}
+		if v.Date.After(to) {
+			to = v.Date
+		}
+		if i == 0 || navs[i-1].Code != v.Code {
+			codes = append(codes, int32(v.Code))
+			stockIDs = append(stockIDs, known[v.Code].StockID)
+		}
+	}
+	fromDate, toDate := pgtype.Date{Time: from, Valid: true}, pgtype.Date{Time: to, Valid: true}
+	source := pgtype.Text{String: amfi.Source, Valid: true}
+	if err := queries.DeleteMfNavs(ctx, repository.DeleteMfNavsParams{Codes: codes, FromDate: fromDate, ToDate: toDate}); err != nil {
+		return 0, err
+	}
+	if err := queries.DeleteDailyBySource(ctx, repository.DeleteDailyBySourceParams{StockIds: stockIDs, Source: source, FromDate: fromDate, ToDate: toDate}); err != nil {
+		return 0, err
+	}
+
+	price := func(f float64) pgtype.Float8 {
+		return pgtype.Float8{Float64: f, Valid: f > 0}
+	}
+	mf := make([]repository.BulkCreateMfNavsParams, 0, len(navs))
+	daily := make([]repository.BulkCreateDailyParams, 0, len(navs))
+	for _, v := range navs {
+		date := pgtype.Date{Time: v.Date, Valid: true}
+		mf = append(mf, repository.BulkCreateMfNavsParams{
+			SchemeCode:      int32(v.Code),
+			NavDate:         date,
+			Nav:             v.NAV,
+			RepurchasePrice: price(v.Repurchase),
+			SalePrice:       price(v.Sale),
+		})
+		nav, err := parseToPgNumeric(strconv.FormatFloat(v.NAV, 'f', -1, 64))
+		if err != nil {
+			return 0, err
+		}
+		daily = append(daily, repository.BulkCreateDailyParams{
+			ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			Stockid:   known[v.Code].StockID,
+			Open:      nav,
+			High:      nav,
+			Low:       nav,
+			Close:     nav,
+			Timestamp: date,
+			AdjClose:  nav,
+			Source:    source,
+		})
+	}
+	if _, err := queries.BulkCreateMfNavs(ctx, mf); err != nil {
+		return 0, err
+	}
+	if _, err := queries.BulkCreateDaily(ctx, daily); err != nil {
+		return 0, err
+	}
+	return len(navs), nil
+}
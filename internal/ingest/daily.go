@@ -0,0 +1,216 @@
+package ingest
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"fund-manager/internal/amfi"
+	"fund-manager/internal/indices"
+	"fund-manager/internal/repository"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SourceCSV marks daily rows imported from the per-stock CSVs.
+const SourceCSV = "csv"
+
+// ImportDaily loads <dailyDir>/<symbol>.csv for every stock in the database.
+func ImportDaily(ctx context.Context, queries *repository.Queries, dailyDir string) error {
+	stocks, err := queries.GetStocks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stocks: %w", err)
+	}
+	// schemes get their NAVs from ImportAMFI and indices from ImportIndices
+	listed := stocks[:0]
+	for _, stock := range stocks {
+		if stock.Scripttype != amfi.ScriptType && stock.Scripttype != indices.ScriptType {
+			listed = append(listed, stock)
+		}
+	}
+	stocks = listed
+
+	fmt.Printf("Importing daily OHLC for %d stocks...\n", len(stocks))
+	for i, stock := range stocks {
+		fmt.Printf("[%d/%d] %s\n", i+1, len(stocks), stock.Symbol)
+		if err := importDailyCSVData(ctx, queries, dailyDir, stock); err != nil {
+			log.Printf("Error processing %s: %v", stock.Symbol, err)
+		}
+	}
+
+	if err := queries.RefreshMonthlyCloses(ctx); err != nil {
+		return fmt.Errorf("failed to refresh monthly closes: %w", err)
+	}
+
+	fmt.Println("✅ All daily OHLC data imported and stored.")
+	return nil
+}
+
+func importDailyCSVData(ctx context.Context, queries *repository.Queries, dailyDir string, stock repository.Stock) error {
+	filePath := filepath.Join(dailyDir, strings.ToLower(stock.Symbol)+".csv")
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open CSV: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	var ohlcRecords []repository.BulkCreateDailyParams
+	extra := findOptionalColumns(nil)
+	for idx, row := range records {
+		// Skip header if present
+		if idx == 0 && strings.ToLower(row[0]) == "date" {
+			extra = findOptionalColumns(row)
+			continue
+		}
+		if len(row) < 6 {
+			log.Printf("Skipping malformed row in %s: %v", stock.Symbol, row)
+			continue
+		}
+
+		date, err := time.Parse("2006-01-02", row[0])
+		if err != nil {
+			log.Printf("Invalid date for %s: %v", stock.Symbol, err)
+			continue
+		}
+
+		open, err := parseToPgNumeric(row[1])
+		if err != nil {
+			log.Printf("Invalid open for %s: %v", stock.Symbol, err)
+			continue
+		}
+		high, err := parseToPgNumeric(row[2])
+		if err != nil {
+			log.Printf("Invalid high for %s: %v", stock.Symbol, err)
+			continue
+		}
+		low, err := parseToPgNumeric(row[3])
+		if err != nil {
+			log.Printf("Invalid low for %s: %v", stock.Symbol, err)
+			continue
+		}
+		closePrice, err := parseToPgNumeric(row[4])
+		if err != nil {
+			log.Printf("Invalid close for %s: %v", stock.Symbol, err)
+			continue
+		}
+		volume, err := strconv.ParseInt(row[5], 10, 64)
+		if err != nil {
+			log.Printf("Invalid volume for %s: %v", stock.Symbol, err)
+			continue
+		}
+
+		record := repository.BulkCreateDailyParams{
+			ID:      pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			Stockid: stock.ID,
+			Open:    open,
+			High:    high,
+			Low:     low,
+			Close:   closePrice,
+			Volume: pgtype.Int8{
+				Int64: volume,
+				Valid: true,
+			},
+			Timestamp: pgtype.Date{
+				Time:  date,
+				Valid: true,
+			},
+			AdjClose: closePrice,
+			Source:   pgtype.Text{String: SourceCSV, Valid: true},
+		}
+		if err := extra.apply(row, &record); err != nil {
+			log.Printf("Invalid %v for %s", err, stock.Symbol)
+			continue
+		}
+		ohlcRecords = append(ohlcRecords, record)
+	}
+
+	if len(ohlcRecords) == 0 {
+		log.Printf("No valid rows for %s", stock.Symbol)
+		return nil
+	}
+
+	_, err = queries.BulkCreateDaily(ctx, ohlcRecords)
+	if err != nil {
+		return fmt.Errorf("failed to insert OHLC: %w", err)
+	}
+	return nil
+}
+
+func parseToPgNumeric(value string) (pgtype.Numeric, error) {
+	var n pgtype.Numeric
+	err := n.Scan(value)
+	return n, err
+}
+
+// optionalColumns are the positions of the columns after Date,Open,High,
+// Low,Close,Volume that a daily CSV may carry, found by header name; -1
+// when absent.
+type optionalColumns struct {
+	tradedValue, trades, deliverable, adjClose int
+}
+
+func findOptionalColumns(header []string) optionalColumns {
+	cols := optionalColumns{-1, -1, -1, -1}
+	for i, name := range header {
+		switch strings.NewReplacer(" ", "", "_", "").Replace(strings.ToLower(name)) {
+		case "tradedvalue", "turnover", "value":
+			cols.tradedValue = i
+		case "trades", "numtrades":
+			cols.trades = i
+		case "deliverableqty", "deliverablequantity", "delivery":
+			cols.deliverable = i
+		case "adjclose", "adjustedclose":
+			cols.adjClose = i
+		}
+	}
+	return cols
+}
+
+// apply fills the optional fields of record from row. Empty cells are left
+// NULL, except adjusted close, which then stays equal to close.
+func (c optionalColumns) apply(row []string, record *repository.BulkCreateDailyParams) error {
+	cell := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	var err error
+	if v := cell(c.tradedValue); v != "" {
+		if record.TradedValue, err = parseToPgNumeric(v); err != nil {
+			return fmt.Errorf("traded value %q", v)
+		}
+	}
+	if v := cell(c.trades); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("trades %q", v)
+		}
+		record.NumTrades = pgtype.Int8{Int64: n, Valid: true}
+	}
+	if v := cell(c.deliverable); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("deliverable quantity %q", v)
+		}
+		record.DeliverableQty = pgtype.Int8{Int64: n, Valid: true}
+	}
+	if v := cell(c.adjClose); v != "" {
+		if record.AdjClose, err = parseToPgNumeric(v); err != nil {
+			return fmt.Errorf("adjusted close %q", v)
+		}
+	}
+	return nil
+}
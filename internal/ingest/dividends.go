@@ -0,0 +1,27 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/dividends"
+	"fund-manager/internal/repository"
+	"strings"
+)
+
+// ImportDividends stores the cash dividends of a corporate actions CSV for
+// the stocks already in the database.
+func ImportDividends(ctx context.Context, queries *repository.Queries, path string) error {
+	divs, err := dividends.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	stored, unknown, err := dividends.Import(ctx, queries, divs)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		fmt.Printf("⚠️ Skipped dividends of %d unknown symbols: %s\n", len(unknown), strings.Join(unknown, ", "))
+	}
+	fmt.Printf("✅ Imported %d dividends from %s.\n", stored, path)
+	return nil
+}
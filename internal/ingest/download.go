@@ -0,0 +1,44 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/download"
+	"log"
+	"path/filepath"
+)
+
+type StockSegment struct {
+	Cap string
+	URL string
+}
+
+var StockLists = []StockSegment{
+	{Cap: "large", URL: "https://nsearchives.nseindia.com/content/indices/ind_nifty100list.csv"},
+	{Cap: "mid", URL: "https://nsearchives.nseindia.com/content/indices/ind_niftymidcap150list.csv"},
+	{Cap: "small", URL: "https://nsearchives.nseindia.com/content/indices/ind_niftysmallcap250list.csv"},
+	{Cap: "micro", URL: "https://nsearchives.nseindia.com/content/indices/ind_niftymicrocap250_list.csv"},
+}
+
+const FnoListURL = "https://nsearchives.nseindia.com/content/fo/fo_mktlots.csv"
+
+// DownloadStockLists fetches the index constituent lists into stocksDir and
+// the F&O market lots file into fnoPath.
+func DownloadStockLists(ctx context.Context, client *download.Client, stocksDir, fnoPath string) error {
+	for _, segment := range StockLists {
+		filePath := filepath.Join(stocksDir, segment.Cap+".csv")
+		log.Printf("📥 Fetching %s cap stock list...", segment.Cap)
+		if _, err := client.Fetch(ctx, segment.URL, filePath); err != nil {
+			log.Printf("❌ Failed to download %s cap list: %v", segment.Cap, err)
+			continue
+		}
+	}
+
+	log.Println("📥 Fetching FNO list...")
+	if _, err := client.Fetch(ctx, FnoListURL, fnoPath); err != nil {
+		return fmt.Errorf("failed to download FNO list: %w", err)
+	}
+
+	log.Println("🎉 Done fetching all CSV files.")
+	return nil
+}
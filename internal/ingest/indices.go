@@ -0,0 +1,116 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/indices"
+	"fund-manager/internal/instrument"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/repository"
+	"math"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ImportIndices loads the index CSVs of indexDir. Every index becomes a
+// stock of script type index, e.g. NIFTY or NIFTYTRI, and its values that
+// stock's daily rows; a series replaces the rows it already has in its date
+// range.
+func ImportIndices(ctx context.Context, queries *repository.Queries, indexDir string) error {
+	all, err := indices.ReadDir(indexDir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", indexDir, err)
+	}
+	stocks, err := queries.GetStocks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stocks: %w", err)
+	}
+	known := make(map[string]repository.Stock)
+	for _, st := range stocks {
+		if st.Scripttype == indices.ScriptType {
+			known[st.Symbol] = st
+		}
+	}
+
+	rows := 0
+	for _, s := range all {
+		if len(s.Bars) == 0 {
+			continue
+		}
+		st, ok := known[s.Symbol]
+		if !ok {
+			st = repository.Stock{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}}
+			if _, err := queries.CreateStock(ctx, repository.CreateStockParams{
+				ID:             st.ID,
+				Name:           clip(s.Name, 100),
+				Symbol:         s.Symbol,
+				Scripttype:     indices.ScriptType,
+				InstrumentType: instrument.Index,
+			}); err != nil {
+				return fmt.Errorf("failed to create %s: %w", s.Symbol, err)
+			}
+		}
+		n, err := saveIndex(ctx, queries, st.ID, s.Bars)
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %w", s.Symbol, err)
+		}
+		fmt.Printf("%s (%s): %d days\n", s.Symbol, s.Name, n)
+		rows += n
+	}
+
+	if err := queries.RefreshMonthlyCloses(ctx); err != nil {
+		return fmt.Errorf("failed to refresh monthly closes: %w", err)
+	}
+	fmt.Printf("✅ Imported %d index days for %d series.\n", rows, len(all))
+	return nil
+}
+
+// saveIndex replaces the daily rows of an index between the first and last
+// of bars, which are sorted by date.
+func saveIndex(ctx context.Context, queries *repository.Queries, stockID pgtype.UUID, bars []pricestore.Bar) (int, error) {
+	source := pgtype.Text{String: indices.Source, Valid: true}
+	if err := queries.DeleteDailyBySource(ctx, repository.DeleteDailyBySourceParams{
+		StockIds: []pgtype.UUID{stockID},
+		Source:   source,
+		FromDate: pgtype.Date{Time: bars[0].Date, Valid: true},
+		ToDate:   pgtype.Date{Time: bars[len(bars)-1].Date, Valid: true},
+	}); err != nil {
+		return 0, err
+	}
+
+	numeric := func(f float64) (pgtype.Numeric, error) {
+		if math.IsNaN(f) {
+			return pgtype.Numeric{}, nil
+		}
+		return parseToPgNumeric(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	daily := make([]repository.BulkCreateDailyParams, 0, len(bars))
+	for _, bar := range bars {
+		var values [4]pgtype.Numeric
+		for i, f := range []float64{bar.Open, bar.High, bar.Low, bar.Close} {
+			v, err := numeric(f)
+			if err != nil {
+				return 0, err
+			}
+			values[i] = v
+		}
+		daily = append(daily, repository.BulkCreateDailyParams{
+			ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			Stockid:   stockID,
+			Open:      values[0],
+			High:      values[1],
+			Low:       values[2],
+			Close:     values[3],
+			Volume:    pgtype.Int8{Int64: bar.Volume, Valid: bar.Volume >= 0},
+			Timestamp: pgtype.Date{Time: bar.Date, Valid: true},
+			AdjClose:  values[3],
+			Source:    source,
+		})
+	}
+	if _, err := queries.BulkCreateDaily(ctx, daily); err != nil {
+		return 0, err
+	}
+	return len(daily), nil
+}
@@ -0,0 +1,69 @@
+// Package instrument names the kinds of instrument the stocks table holds.
+// The script type is the universe a stock is ranked in; the instrument
+// type says what it is.
+package instrument
+
+import "strings"
+
+const (
+	Equity     = "equity"
+	ETF        = "etf"
+	Index      = "index" // a price or total return series, not tradable
+	MutualFund = "mf"
+	Cash       = "cash" // liquid, overnight and money market funds and ETFs
+)
+
+// Types lists every instrument type.
+var Types = []string{Equity, ETF, Index, MutualFund, Cash}
+
+func Valid(t string) bool {
+	for _, v := range Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Tradable reports whether instruments of type t can be bought.
+func Tradable(t string) bool {
+	return t != Index
+}
+
+// Script types of the instruments that are not equities. The stock lists
+// are named by script type, so an etf.csv or cash.csv list holds ETFs.
+const (
+	ScriptETF   = "etf"
+	ScriptCash  = "cash"
+	ScriptIndex = "index"
+	ScriptMF    = "mf"
+)
+
+// OfScriptType is the type of the instruments of a script type.
+func OfScriptType(scriptType string) string {
+	switch scriptType {
+	case ScriptETF:
+		return ETF
+	case ScriptCash:
+		return Cash
+	case ScriptIndex:
+		return Index
+	case ScriptMF:
+		return MutualFund
+	}
+	return Equity
+}
+
+// cashCategories are the AMFI categories of funds that stand in for cash.
+var cashCategories = []string{"liquid fund", "overnight fund", "money market fund"}
+
+// OfFundCategory is the type of a mutual fund scheme of an AMFI category.
+func OfFundCategory(category string) string {
+	c := strings.ToLower(category)
+	for _, cash := range cashCategories {
+		if strings.Contains(c, cash) {
+			return Cash
+		}
+	}
+	return MutualFund
+}
@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Check builds the schema once from the migrations and once from schemaSQL
+// in two scratch schemas and returns the differences between them. It all
+// happens in a transaction that is rolled back, so the database is left as
+// it was.
+func Check(ctx context.Context, pool *pgxpool.Pool, migrations []Migration, schemaSQL string) ([]string, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	build := func(schema string, statements ...string) ([]string, error) {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("CREATE SCHEMA %s; SET LOCAL search_path TO %s", schema, schema)); err != nil {
+			return nil, err
+		}
+		for _, sql := range statements {
+			if _, err := tx.Exec(ctx, sql); err != nil {
+				return nil, fmt.Errorf("%s: %w", schema, err)
+			}
+		}
+		return describe(ctx, tx, schema)
+	}
+
+	ups := make([]string, len(migrations))
+	for i, m := range migrations {
+		ups[i] = m.Up
+	}
+	fromMigrations, err := build("fundmgr_check_migrations", ups...)
+	if err != nil {
+		return nil, err
+	}
+	fromSchema, err := build("fundmgr_check_schema", schemaSQL)
+	if err != nil {
+		return nil, err
+	}
+	return diff(fromMigrations, fromSchema), nil
+}
+
+// describe lists the tables' columns, constraints and indexes and the
+// views of a schema as comparable lines with the schema name removed.
+func describe(ctx context.Context, tx pgx.Tx, schema string) ([]string, error) {
+	queries := []string{
+		`SELECT 'column ' || table_name || '.' || column_name || ' ' || data_type || ' null=' || is_nullable || ' default=' || COALESCE(column_default, '')
+		 FROM information_schema.columns WHERE table_schema = $1`,
+		`SELECT 'constraint ' || rel.relname || ' ' || con.conname || ' ' || pg_get_constraintdef(con.oid)
+		 FROM pg_constraint con JOIN pg_class rel ON rel.oid = con.conrelid JOIN pg_namespace n ON n.oid = rel.relnamespace
+		 WHERE n.nspname = $1`,
+		`SELECT 'index ' || tablename || ' ' || indexdef FROM pg_indexes WHERE schemaname = $1`,
+		`SELECT 'view ' || viewname || ' ' || definition FROM pg_views WHERE schemaname = $1`,
+		`SELECT 'matview ' || matviewname || ' ' || definition FROM pg_matviews WHERE schemaname = $1`,
+	}
+
+	var lines []string
+	for _, q := range queries {
+		rows, err := tx.Query(ctx, q, schema)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			lines = append(lines, strings.ReplaceAll(line, schema+".", ""))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(lines)
+	return lines, nil
+}
+
+func diff(migrations, schema []string) []string {
+	inSchema := make(map[string]bool, len(schema))
+	for _, l := range schema {
+		inSchema[l] = true
+	}
+	inMigrations := make(map[string]bool, len(migrations))
+	var out []string
+	for _, l := range migrations {
+		inMigrations[l] = true
+		if !inSchema[l] {
+			out = append(out, "only in migrations: "+l)
+		}
+	}
+	for _, l := range schema {
+		if !inMigrations[l] {
+			out = append(out, "only in schema.sql: "+l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,292 @@
+// Package migrate applies the goose-style migrations in sql/migrations and
+// records them in the schema_migrations table.
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// Load parses every <version>_<name>.sql file in fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int64]string)
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		versionText, name, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(versionText, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.sql", file)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, file, version)
+		}
+		seen[version] = file
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", file, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, Up: up, Down: down})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// parse splits a file at its "-- +goose Up" and "-- +goose Down" markers.
+// Statement markers are dropped since each section runs as one batch.
+func parse(src string) (string, string, error) {
+	var up, down strings.Builder
+	var section *strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	for scanner.Scan() {
+		line := scanner.Text()
+		annotation, isAnnotation := strings.CutPrefix(strings.TrimSpace(line), "-- +goose ")
+		if isAnnotation {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				section = &up
+			case "Down":
+				section = &down
+			case "StatementBegin", "StatementEnd":
+			default:
+				return "", "", fmt.Errorf("unsupported annotation %q", line)
+			}
+			continue
+		}
+		if section != nil {
+			section.WriteString(line)
+			section.WriteByte('\n')
+		}
+	}
+	if strings.TrimSpace(up.String()) == "" {
+		return "", "", errors.New("empty Up section")
+	}
+	if strings.TrimSpace(down.String()) == "" {
+		return "", "", errors.New("empty Down section")
+	}
+	return up.String(), down.String(), scanner.Err()
+}
+
+const createVersionTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+type State struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Status reports every known migration and whether it has been applied.
+// Versions recorded in the database without a file are returned as
+// applied migrations with empty SQL.
+func Status(ctx context.Context, pool *pgxpool.Pool, migrations []Migration) ([]State, error) {
+	applied, err := appliedVersions(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]State, 0, len(migrations))
+	known := make(map[int64]bool)
+	for _, m := range migrations {
+		at, ok := applied[m.Version]
+		states = append(states, State{Migration: m, Applied: ok, AppliedAt: at.time})
+		known[m.Version] = true
+	}
+	for v, at := range applied {
+		if !known[v] {
+			states = append(states, State{Migration: Migration{Version: v, Name: at.name}, Applied: true, AppliedAt: at.time})
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// Up applies pending migrations up to and including target, or all of them
+// when target is 0. Each migration runs in its own transaction.
+func Up(ctx context.Context, pool *pgxpool.Pool, migrations []Migration, target int64) ([]Migration, error) {
+	applied, err := appliedVersions(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		if err := checkUnmanaged(ctx, pool); err != nil {
+			return nil, err
+		}
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if target != 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, m.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("failed to apply %s: %w", m, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down rolls back the latest steps applied migrations, newest first.
+func Down(ctx context.Context, pool *pgxpool.Pool, migrations []Migration, steps int) ([]Migration, error) {
+	applied, err := appliedVersions(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+	versions := make([]int64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	var done []Migration
+	for _, v := range versions {
+		if len(done) == steps {
+			break
+		}
+		m, ok := byVersion[v]
+		if !ok {
+			return done, fmt.Errorf("version %d is applied but has no migration file", v)
+		}
+		err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, m.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("failed to roll back %s: %w", m, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Redo rolls back the latest migration and applies it again.
+func Redo(ctx context.Context, pool *pgxpool.Pool, migrations []Migration) (Migration, error) {
+	down, err := Down(ctx, pool, migrations, 1)
+	if err != nil {
+		return Migration{}, err
+	}
+	if len(down) == 0 {
+		return Migration{}, errors.New("no migration has been applied")
+	}
+	if _, err := Up(ctx, pool, migrations, down[0].Version); err != nil {
+		return down[0], err
+	}
+	return down[0], nil
+}
+
+// Baseline records the migrations up to target as applied without running
+// them, for databases created from schema.sql before migrations were tracked.
+func Baseline(ctx context.Context, pool *pgxpool.Pool, migrations []Migration, target int64) ([]Migration, error) {
+	if _, err := pool.Exec(ctx, createVersionTable); err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, m := range migrations {
+		if m.Version > target {
+			break
+		}
+		tag, err := pool.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT DO NOTHING`, m.Version, m.Name)
+		if err != nil {
+			return done, err
+		}
+		if tag.RowsAffected() > 0 {
+			done = append(done, m)
+		}
+	}
+	return done, nil
+}
+
+type appliedAt struct {
+	name string
+	time time.Time
+}
+
+func appliedVersions(ctx context.Context, pool *pgxpool.Pool) (map[int64]appliedAt, error) {
+	if _, err := pool.Exec(ctx, createVersionTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	rows, err := pool.Query(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedAt)
+	for rows.Next() {
+		var v int64
+		var at appliedAt
+		if err := rows.Scan(&v, &at.name, &at.time); err != nil {
+			return nil, err
+		}
+		applied[v] = at
+	}
+	return applied, rows.Err()
+}
+
+// checkUnmanaged refuses to migrate a database whose tables were created
+// without the runner, which would otherwise fail half way through.
+func checkUnmanaged(ctx context.Context, pool *pgxpool.Pool) error {
+	var exists bool
+	err := pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'stocks')`).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("tables exist but schema_migrations is empty; record the versions they match with 'fundmgr migrate baseline -to <version>'")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package migrate
+
+import (
+	"context"
+	"fund-manager/sql/migrations"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	ms, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range ms {
+		if i > 0 && ms[i-1].Version >= m.Version {
+			t.Errorf("%s is not after %s", m, ms[i-1])
+		}
+		if strings.Contains(m.Down, "SELECT 'down SQL query'") {
+			t.Errorf("%s has a placeholder Down section", m)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_a.sql": {Data: []byte("-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE a (id int);\n-- +goose StatementEnd\n\n-- +goose Down\nDROP TABLE a;\n")},
+		"2_b.sql": {Data: []byte("-- +goose Up\nCREATE TABLE b (id int);\n")},
+	}
+	if _, err := Load(fsys); err == nil || !strings.Contains(err.Error(), "2_b.sql") {
+		t.Fatalf("want an error for the missing Down of 2_b.sql, got %v", err)
+	}
+
+	delete(fsys, "2_b.sql")
+	ms, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms[0].Version != 1 || ms[0].Name != "a" {
+		t.Errorf("got %s", ms[0])
+	}
+	if strings.TrimSpace(ms[0].Up) != "CREATE TABLE a (id int);" || strings.TrimSpace(ms[0].Down) != "DROP TABLE a;" {
+		t.Errorf("got up %q down %q", ms[0].Up, ms[0].Down)
+	}
+}
+
+// TestCheckSchema compares the migrations with sql/schema.sql on the
+// database in POSTGRES. Nothing is left behind.
+func TestCheckSchema(t *testing.T) {
+	url := os.Getenv("POSTGRES")
+	if url == "" {
+		t.Skip("POSTGRES not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ms, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := os.ReadFile("../../sql/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := Check(ctx, pool, ms, string(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diffs {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,89 @@
+package orders
+
+import (
+	"encoding/csv"
+	"fmt"
+	"fund-manager/internal/backtest"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readRows reads a CSV of a symbol and an optional number per row. A
+// first row whose number does not parse is taken as a header.
+func readRows(path string) ([]string, []float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var symbols []string
+	var values []float64
+	for i, record := range records {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		symbol := strings.ToUpper(strings.TrimSpace(record[0]))
+		value := 0.0
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			v, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+			if err != nil {
+				if i == 0 {
+					continue
+				}
+				return nil, nil, fmt.Errorf("%s line %d: invalid number %q", path, i+1, record[1])
+			}
+			value = v
+		} else if i == 0 && strings.EqualFold(symbol, "symbol") {
+			continue
+		}
+		symbols = append(symbols, symbol)
+		values = append(values, value)
+	}
+	return symbols, values, nil
+}
+
+// ReadHoldings reads symbol,quantity rows.
+func ReadHoldings(path string) ([]Holding, error) {
+	symbols, quantities, err := readRows(path)
+	if err != nil {
+		return nil, err
+	}
+	holdings := make([]Holding, len(symbols))
+	for i := range symbols {
+		holdings[i] = Holding{Symbol: symbols[i], Quantity: quantities[i]}
+	}
+	return holdings, nil
+}
+
+// ReadTargets reads symbol[,weight] rows such as stockList.csv. Weights
+// are scaled to add up to 1; without any the symbols share equally.
+func ReadTargets(path string) ([]backtest.Target, error) {
+	symbols, weights, err := readRows(path)
+	if err != nil {
+		return nil, err
+	}
+	total := 0.0
+	for _, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("%s: negative weight", path)
+		}
+		total += w
+	}
+	targets := make([]backtest.Target, len(symbols))
+	for i, symbol := range symbols {
+		targets[i] = backtest.Target{Symbol: symbol, Weight: 1 / float64(len(symbols))}
+		if total > 0 {
+			targets[i].Weight = weights[i] / total
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,138 @@
+// Package orders works out the trades that take a portfolio from its
+// current holdings to the strategy's target weights.
+package orders
+
+import (
+	"fmt"
+	"fund-manager/internal/backtest"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	Buy  = "BUY"
+	Sell = "SELL"
+)
+
+type Order struct {
+	Symbol   string
+	Side     string
+	Quantity int64
+	Price    float64 // reference price the estimate is based on
+	Value    float64
+	Cost     float64 // estimated charges
+	Exit     bool    // sells the whole holding
+}
+
+type Holding struct {
+	Symbol   string
+	Quantity float64
+}
+
+type Options struct {
+	Costs backtest.CostModel
+	// MinTrade is the smallest order value. Smaller adjustments are left
+	// alone; exits of stocks no longer targeted always go through.
+	MinTrade float64
+}
+
+type Plan struct {
+	Date     time.Time // rebalance date, set by the caller
+	Equity   float64   // cash plus the holdings at their prices
+	Cash     float64   // before the orders
+	Orders   []Order   // sells first, then buys in target order
+	Skipped  []Order   // below MinTrade or not affordable
+	Costs    float64
+	Leftover float64 // cash after every order
+}
+
+func (p Plan) Total(side string) float64 {
+	total := 0.0
+	for _, o := range p.Orders {
+		if o.Side == side {
+			total += o.Value
+		}
+	}
+	return total
+}
+
+// Generate turns holdings and targets into orders in whole shares. Every
+// holding and target needs a price. Target quantities leave room for the
+// charges of buying them, and buys are cut back to the cash the sells
+// leave.
+func Generate(holdings []Holding, targets []backtest.Target, prices map[string]float64, cash float64, opts Options) (Plan, error) {
+	plan := Plan{Cash: cash, Equity: cash}
+	price := func(symbol string) (float64, error) {
+		p, ok := prices[symbol]
+		if !ok || p <= 0 {
+			return 0, fmt.Errorf("no price for %s", symbol)
+		}
+		return p, nil
+	}
+
+	held := make(map[string]int64, len(holdings))
+	for _, h := range holdings {
+		p, err := price(h.Symbol)
+		if err != nil {
+			return plan, err
+		}
+		plan.Equity += h.Quantity * p
+		held[h.Symbol] += int64(math.Floor(h.Quantity))
+	}
+
+	rate := opts.Costs.Cost(1)
+	desired := make(map[string]int64, len(targets))
+	for _, t := range targets {
+		p, err := price(t.Symbol)
+		if err != nil {
+			return plan, err
+		}
+		desired[t.Symbol] = int64(math.Floor(plan.Equity * t.Weight / (p * (1 + rate))))
+	}
+
+	order := func(symbol, side string, quantity int64, exit bool) Order {
+		p := prices[symbol]
+		value := float64(quantity) * p
+		return Order{Symbol: symbol, Side: side, Quantity: quantity, Price: p, Value: value, Cost: opts.Costs.Cost(value), Exit: exit}
+	}
+
+	symbols := make([]string, 0, len(held))
+	for symbol := range held {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		want, targeted := desired[symbol]
+		if held[symbol] <= want {
+			continue
+		}
+		o := order(symbol, Sell, held[symbol]-want, !targeted)
+		if !o.Exit && o.Value < opts.MinTrade {
+			plan.Skipped = append(plan.Skipped, o)
+			continue
+		}
+		plan.Orders = append(plan.Orders, o)
+		cash += o.Value - o.Cost
+		plan.Costs += o.Cost
+	}
+
+	for _, t := range targets {
+		if desired[t.Symbol] <= held[t.Symbol] {
+			continue
+		}
+		o := order(t.Symbol, Buy, desired[t.Symbol]-held[t.Symbol], false)
+		if affordable := int64(math.Floor(cash / (o.Price * (1 + rate)))); affordable < o.Quantity {
+			o = order(t.Symbol, Buy, max(affordable, 0), false)
+		}
+		if o.Quantity == 0 || o.Value < opts.MinTrade {
+			plan.Skipped = append(plan.Skipped, order(t.Symbol, Buy, desired[t.Symbol]-held[t.Symbol], false))
+			continue
+		}
+		plan.Orders = append(plan.Orders, o)
+		cash -= o.Value + o.Cost
+		plan.Costs += o.Cost
+	}
+	plan.Leftover = cash
+	return plan, nil
+}
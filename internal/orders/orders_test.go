@@ -0,0 +1,79 @@
+package orders
+
+import (
+	"fund-manager/internal/backtest"
+	"math"
+	"testing"
+)
+
+var prices = map[string]float64{"AAA": 100, "BBB": 250, "CCC": 40, "DDD": 1000}
+
+func TestGenerate(t *testing.T) {
+	holdings := []Holding{{"AAA", 300}, {"BBB", 100}, {"CCC", 500}}
+	targets := []backtest.Target{{Symbol: "BBB", Weight: 0.5}, {Symbol: "DDD", Weight: 0.3}, {Symbol: "AAA", Weight: 0.2}}
+	plan, err := Generate(holdings, targets, prices, 15000, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// equity 15000 + 30000 + 25000 + 20000 = 90000
+	if plan.Equity != 90000 {
+		t.Fatalf("equity %.2f", plan.Equity)
+	}
+	want := []Order{
+		{Symbol: "AAA", Side: Sell, Quantity: 120},
+		{Symbol: "CCC", Side: Sell, Quantity: 500, Exit: true},
+		{Symbol: "BBB", Side: Buy, Quantity: 80},
+		{Symbol: "DDD", Side: Buy, Quantity: 27},
+	}
+	if len(plan.Orders) != len(want) {
+		t.Fatalf("orders %+v", plan.Orders)
+	}
+	for i, w := range want {
+		o := plan.Orders[i]
+		if o.Symbol != w.Symbol || o.Side != w.Side || o.Quantity != w.Quantity || o.Exit != w.Exit {
+			t.Errorf("order %d: got %+v, want %+v", i, o, w)
+		}
+	}
+	if plan.Leftover != 15000+12000+20000-20000-27000 {
+		t.Fatalf("leftover %.2f", plan.Leftover)
+	}
+}
+
+func TestGenerateMinTradeAndCosts(t *testing.T) {
+	holdings := []Holding{{"AAA", 498}, {"CCC", 10}}
+	targets := []backtest.Target{{Symbol: "AAA", Weight: 1}}
+	costs := backtest.CostModel{CommissionBps: 10, TaxBps: 10}
+	plan, err := Generate(holdings, targets, prices, 400, Options{Costs: costs, MinTrade: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the 400 exit of CCC goes through, the buy of 2 or 3 AAA does not
+	if len(plan.Orders) != 1 || plan.Orders[0].Symbol != "CCC" || !plan.Orders[0].Exit {
+		t.Fatalf("orders %+v", plan.Orders)
+	}
+	if len(plan.Skipped) != 1 || plan.Skipped[0].Symbol != "AAA" {
+		t.Fatalf("skipped %+v", plan.Skipped)
+	}
+	if math.Abs(plan.Costs-0.8) > 1e-9 || math.Abs(plan.Leftover-(400+400-0.8)) > 1e-9 {
+		t.Fatalf("costs %.4f, leftover %.4f", plan.Costs, plan.Leftover)
+	}
+}
+
+func TestGenerateCutsBuysToCash(t *testing.T) {
+	targets := []backtest.Target{{Symbol: "DDD", Weight: 0.6}, {Symbol: "AAA", Weight: 0.4}}
+	costs := backtest.CostModel{CommissionBps: 50}
+	plan, err := Generate(nil, targets, prices, 10000, Options{Costs: costs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spent := 0.0
+	for _, o := range plan.Orders {
+		spent += o.Value + o.Cost
+	}
+	if spent > 10000 || plan.Leftover < 0 {
+		t.Fatalf("spent %.2f of 10000", spent)
+	}
+	if _, err := Generate([]Holding{{"ZZZ", 1}}, targets, prices, 0, Options{}); err == nil {
+		t.Fatal("missing price not reported")
+	}
+}
@@ -0,0 +1,229 @@
+package orders
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// OrderWriter renders a plan's orders in one file format.
+type OrderWriter interface {
+	WriteOrders(w io.Writer, plan Plan) error
+}
+
+const (
+	Market = "MARKET"
+	Limit  = "LIMIT"
+)
+
+// Basket holds the order details the broker formats need that a plan does
+// not carry.
+type Basket struct {
+	Exchange  string // NSE or BSE
+	Product   string // CNC for delivery
+	OrderType string // MARKET, or LIMIT at the reference price
+}
+
+var writers = map[string]func(Basket) OrderWriter{
+	"sheet":  func(Basket) OrderWriter { return SheetWriter{} },
+	"kite":   func(b Basket) OrderWriter { return KiteWriter{b} },
+	"basket": func(b Basket) OrderWriter { return BasketCSVWriter{b} },
+	"fix":    func(b Basket) OrderWriter { return FIXWriter{b} },
+}
+
+// Formats lists the names NewWriter accepts.
+func Formats() []string {
+	names := make([]string, 0, len(writers))
+	for name := range writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func NewWriter(format string, b Basket) (OrderWriter, error) {
+	b.Exchange = strings.ToUpper(b.Exchange)
+	b.Product = strings.ToUpper(b.Product)
+	b.OrderType = strings.ToUpper(b.OrderType)
+	if b.Exchange == "" {
+		b.Exchange = "NSE"
+	}
+	if b.Product == "" {
+		b.Product = "CNC"
+	}
+	switch b.OrderType {
+	case "":
+		b.OrderType = Market
+	case Market, Limit:
+	default:
+		return nil, fmt.Errorf("unknown order type %q (want MARKET or LIMIT)", b.OrderType)
+	}
+	newWriter, ok := writers[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown order format %q (want %s)", format, strings.Join(Formats(), ", "))
+	}
+	return newWriter(b), nil
+}
+
+// limitPrice is the reference price on the exchange's 5 paise tick.
+func limitPrice(price float64) float64 {
+	return math.Round(price*20) / 20
+}
+
+func (b Basket) price(o Order) float64 {
+	if b.OrderType == Limit {
+		return limitPrice(o.Price)
+	}
+	return 0
+}
+
+// SheetWriter prints the plan as a table for checking the orders off by
+// hand, with the skipped adjustments and the cash summary below it.
+type SheetWriter struct{}
+
+func (SheetWriter) WriteOrders(w io.Writer, plan Plan) error {
+	if !plan.Date.IsZero() {
+		fmt.Fprintf(w, "Orders for %s\n\n", plan.Date.Format("2006-01-02"))
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "#\tSide\tSymbol\tQuantity\tPrice\tValue\tEst. Cost\tDone\t")
+	for i, o := range plan.Orders {
+		side := o.Side
+		if o.Exit {
+			side += " (exit)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%.2f\t%.2f\t%.2f\t[ ]\t\n", i+1, side, o.Symbol, o.Quantity, o.Price, o.Value, o.Cost)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(plan.Skipped) > 0 {
+		fmt.Fprintln(w, "\nSkipped (below the minimum trade or not affordable):")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		for _, o := range plan.Skipped {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%.2f\t\n", o.Side, o.Symbol, o.Quantity, o.Value)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, row := range []struct {
+		label string
+		value float64
+	}{
+		{"Equity", plan.Equity},
+		{"Cash", plan.Cash},
+		{"Sells", plan.Total(Sell)},
+		{"Buys", plan.Total(Buy)},
+		{"Est. Costs", plan.Costs},
+		{"Leftover Cash", plan.Leftover},
+	} {
+		fmt.Fprintf(tw, "%s\t%.2f\t\n", row.label, row.value)
+	}
+	return tw.Flush()
+}
+
+// KiteWriter writes the JSON basket Zerodha Kite imports, an array of
+// regular orders.
+type KiteWriter struct{ Basket }
+
+type kiteOrder struct {
+	Variety         string  `json:"variety"`
+	Exchange        string  `json:"exchange"`
+	TradingSymbol   string  `json:"tradingsymbol"`
+	TransactionType string  `json:"transaction_type"`
+	OrderType       string  `json:"order_type"`
+	Product         string  `json:"product"`
+	Quantity        int64   `json:"quantity"`
+	Price           float64 `json:"price,omitempty"`
+}
+
+func (k KiteWriter) WriteOrders(w io.Writer, plan Plan) error {
+	basket := make([]kiteOrder, len(plan.Orders))
+	for i, o := range plan.Orders {
+		basket[i] = kiteOrder{
+			Variety:         "regular",
+			Exchange:        k.Exchange,
+			TradingSymbol:   o.Symbol,
+			TransactionType: o.Side,
+			OrderType:       k.OrderType,
+			Product:         k.Product,
+			Quantity:        o.Quantity,
+			Price:           k.price(o),
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(basket)
+}
+
+// BasketCSVWriter writes the exchange, symbol, side, quantity, order
+// type, price and product columns the basket upload of most Indian
+// brokers takes, one order per row.
+type BasketCSVWriter struct{ Basket }
+
+func (b BasketCSVWriter) WriteOrders(w io.Writer, plan Plan) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Exchange", "Symbol", "Transaction Type", "Quantity", "Order Type", "Price", "Product", "Validity"})
+	for _, o := range plan.Orders {
+		writer.Write([]string{
+			b.Exchange,
+			o.Symbol,
+			o.Side,
+			strconv.FormatInt(o.Quantity, 10),
+			b.OrderType,
+			strconv.FormatFloat(b.price(o), 'f', 2, 64),
+			b.Product,
+			"DAY",
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// FIXWriter writes one row per order with columns named after the FIX
+// 4.2 NewOrderSingle tags and their codes: Side 1 buy and 2 sell, OrdType
+// 1 market and 2 limit, TimeInForce 0 day. ClOrdIDs are the plan date
+// and the order's position.
+type FIXWriter struct{ Basket }
+
+func (f FIXWriter) WriteOrders(w io.Writer, plan Plan) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"ClOrdID(11)", "Symbol(55)", "SecurityExchange(207)", "Side(54)", "OrderQty(38)", "OrdType(40)", "Price(44)", "TimeInForce(59)"})
+	ordType := "1"
+	if f.OrderType == Limit {
+		ordType = "2"
+	}
+	for i, o := range plan.Orders {
+		side := "1"
+		if o.Side == Sell {
+			side = "2"
+		}
+		price := ""
+		if f.OrderType == Limit {
+			price = strconv.FormatFloat(f.price(o), 'f', 2, 64)
+		}
+		writer.Write([]string{
+			fmt.Sprintf("%s-%03d", plan.Date.Format("20060102"), i+1),
+			o.Symbol,
+			f.Exchange,
+			side,
+			strconv.FormatInt(o.Quantity, 10),
+			ordType,
+			price,
+			"0",
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
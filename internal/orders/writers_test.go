@@ -0,0 +1,68 @@
+package orders
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var plan = Plan{
+	Date: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+	Orders: []Order{
+		{Symbol: "CCC", Side: Sell, Quantity: 500, Price: 40, Value: 20000, Exit: true},
+		{Symbol: "DDD", Side: Buy, Quantity: 27, Price: 1000.03, Value: 27000.81},
+	},
+}
+
+func write(t *testing.T, format string, b Basket) string {
+	t.Helper()
+	w, err := NewWriter(format, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := w.WriteOrders(&buf, plan); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestKiteWriter(t *testing.T) {
+	var basket []map[string]any
+	if err := json.Unmarshal([]byte(write(t, "kite", Basket{OrderType: "limit"})), &basket); err != nil {
+		t.Fatal(err)
+	}
+	if len(basket) != 2 {
+		t.Fatalf("basket %v", basket)
+	}
+	buy := basket[1]
+	if buy["tradingsymbol"] != "DDD" || buy["transaction_type"] != "BUY" || buy["exchange"] != "NSE" ||
+		buy["product"] != "CNC" || buy["order_type"] != "LIMIT" || buy["quantity"] != 27.0 || buy["price"] != 1000.05 {
+		t.Fatalf("buy %v", buy)
+	}
+	if _, ok := basket[0]["price"]; !ok {
+		t.Fatalf("limit sell without a price: %v", basket[0])
+	}
+}
+
+func TestCSVWriters(t *testing.T) {
+	basket := strings.Split(strings.TrimSpace(write(t, "basket", Basket{Exchange: "bse"})), "\n")
+	if len(basket) != 3 || basket[1] != "BSE,CCC,SELL,500,MARKET,0.00,CNC,DAY" {
+		t.Fatalf("basket %q", basket)
+	}
+	fix := strings.Split(strings.TrimSpace(write(t, "fix", Basket{})), "\n")
+	if len(fix) != 3 || fix[1] != "20250801-001,CCC,NSE,2,500,1,,0" || fix[2] != "20250801-002,DDD,NSE,1,27,1,,0" {
+		t.Fatalf("fix %q", fix)
+	}
+}
+
+func TestNewWriterRejectsUnknown(t *testing.T) {
+	if _, err := NewWriter("xls", Basket{}); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+	if _, err := NewWriter("kite", Basket{OrderType: "SL"}); err == nil {
+		t.Fatal("unknown order type accepted")
+	}
+}
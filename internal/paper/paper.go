@@ -0,0 +1,296 @@
+// Package paper runs a strategy forward on a simulated account one trading
+// day at a time and compares the track record with the backtest replayed
+// over the same days.
+package paper
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/backtest"
+	"fund-manager/internal/orders"
+	"fund-manager/internal/services"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Fill struct {
+	Date     time.Time
+	Symbol   string
+	Side     string // orders.Buy or orders.Sell
+	Quantity float64
+	Price    float64
+	Cost     float64
+}
+
+// Day is the account at the close of a processed trading day.
+type Day struct {
+	Date       time.Time
+	Cash       float64
+	Equity     float64
+	Rebalanced bool
+}
+
+type Account struct {
+	ID      pgtype.UUID
+	Name    string
+	Spec    backtest.Spec // pinned when the account is opened
+	Start   time.Time     // first scheduled rebalance
+	Capital float64
+	Fills   []Fill
+	Days    []Day
+}
+
+// Positions is the cash and the shares held after every fill.
+func (a *Account) Positions() (float64, map[string]float64) {
+	cash := a.Capital
+	holdings := make(map[string]float64)
+	for _, f := range a.Fills {
+		value := f.Quantity * f.Price
+		if f.Side == orders.Buy {
+			cash -= value + f.Cost
+			holdings[f.Symbol] += f.Quantity
+		} else {
+			cash += value - f.Cost
+			holdings[f.Symbol] -= f.Quantity
+		}
+		if holdings[f.Symbol] == 0 {
+			delete(holdings, f.Symbol)
+		}
+	}
+	return cash, holdings
+}
+
+// LastDay is the last processed trading day.
+func (a *Account) LastDay() (time.Time, bool) {
+	if len(a.Days) == 0 {
+		return time.Time{}, false
+	}
+	return a.Days[len(a.Days)-1].Date, true
+}
+
+// due reports whether date reaches the first scheduled rebalance (start,
+// start + every months, ...) after the last one traded. A schedule date
+// that is not a trading day is traded on the next one.
+func (a *Account) due(date time.Time) bool {
+	var last time.Time
+	for _, d := range a.Days {
+		if d.Rebalanced {
+			last = d.Date
+		}
+	}
+	every := max(a.Spec.Schedule.EveryMonths, 1)
+	for k := 0; ; k++ {
+		scheduled := a.Start.AddDate(0, k*every, 0)
+		if last.IsZero() || scheduled.After(last) {
+			return !date.Before(scheduled)
+		}
+	}
+}
+
+func (a *Account) config(svc *services.Service) (backtest.BacktestConfig, error) {
+	cfg, err := a.Spec.Config(svc)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.StartDate, cfg.InitialCapital = a.Start, a.Capital
+	// the paper account's equity is before tax and without dividends,
+	// also for accounts opened before dividend specs were refused
+	cfg.Tax = nil
+	cfg.Dividends = ""
+	return cfg, nil
+}
+
+// Step runs the account through date. On a rebalance day the strategy's
+// targets are traded at the close with the spec's costs; every day the
+// holdings are valued at the close.
+func (a *Account) Step(ctx context.Context, svc *services.Service, date time.Time) ([]Fill, Day, error) {
+	if last, ok := a.LastDay(); ok && !date.After(last) {
+		return nil, Day{}, fmt.Errorf("%s already ran through %s", a.Name, last.Format("2006-01-02"))
+	}
+	cash, held := a.Positions()
+	closes := make(map[string]float64, len(held))
+	for symbol := range held {
+		price, ok, err := svc.Close(ctx, symbol, date)
+		if err != nil {
+			return nil, Day{}, err
+		}
+		if !ok {
+			return nil, Day{}, fmt.Errorf("no close for %s on or before %s", symbol, date.Format("2006-01-02"))
+		}
+		closes[symbol] = price
+	}
+
+	day := Day{Date: date, Rebalanced: a.due(date)}
+	var fills []Fill
+	if day.Rebalanced {
+		cfg, err := a.config(svc)
+		if err != nil {
+			return nil, Day{}, err
+		}
+		targets, err := backtest.Targets(ctx, cfg, date)
+		if err != nil {
+			return nil, Day{}, err
+		}
+		fills, err = a.rebalance(ctx, svc, date, targets, cash, held, closes)
+		if err != nil {
+			return nil, Day{}, err
+		}
+	}
+
+	a.Fills = append(a.Fills, fills...)
+	day.Cash, held = a.Positions()
+	day.Equity = day.Cash
+	for symbol, quantity := range held {
+		day.Equity += quantity * closes[symbol]
+	}
+	a.Days = append(a.Days, day)
+	return fills, day, nil
+}
+
+// rebalance trades the way the backtest does: names that left the targets
+// are sold, names that joined are bought at their weight of the equity as
+// far as the cash goes, and names kept keep their quantity.
+func (a *Account) rebalance(ctx context.Context, svc *services.Service, date time.Time, targets []backtest.Target, cash float64, held, closes map[string]float64) ([]Fill, error) {
+	trade := func(symbol, side string, quantity float64) Fill {
+		price := closes[symbol]
+		return Fill{Date: date, Symbol: symbol, Side: side, Quantity: quantity, Price: price, Cost: a.Spec.Costs.Cost(quantity * price)}
+	}
+
+	targeted := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		targeted[t.Symbol] = true
+	}
+	var fills []Fill
+	for _, symbol := range sortedSymbols(held) {
+		if targeted[symbol] {
+			continue
+		}
+		f := trade(symbol, orders.Sell, held[symbol])
+		cash += f.Quantity*f.Price - f.Cost
+		fills = append(fills, f)
+		delete(held, symbol)
+	}
+
+	equity := cash
+	for symbol, quantity := range held {
+		equity += quantity * closes[symbol]
+	}
+	rate := a.Spec.Costs.Cost(1)
+	for _, t := range targets {
+		if _, ok := held[t.Symbol]; ok {
+			continue
+		}
+		price, ok, err := svc.Close(ctx, t.Symbol, date)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			log.Printf("No price for %s at %s, skipping entry", t.Symbol, date.Format("2006-01-02"))
+			continue
+		}
+		closes[t.Symbol] = price
+		quantity := math.Floor(math.Min(equity*t.Weight, cash) / (price * (1 + rate)))
+		if quantity <= 0 {
+			continue
+		}
+		f := trade(t.Symbol, orders.Buy, quantity)
+		cash -= f.Quantity*f.Price + f.Cost
+		fills = append(fills, f)
+	}
+	return fills, nil
+}
+
+func sortedSymbols(holdings map[string]float64) []string {
+	symbols := make([]string, 0, len(holdings))
+	for symbol := range holdings {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Point pairs the paper and backtest equity on one of the backtest's
+// equity dates.
+type Point struct {
+	Date     time.Time
+	Paper    float64
+	Backtest float64
+}
+
+// Gap is the paper equity against the backtest's, percent.
+func (p Point) Gap() float64 {
+	return (p.Paper/p.Backtest - 1) * 100
+}
+
+type Divergence struct {
+	Points         []Point
+	PaperReturn    float64 // percent
+	BacktestReturn float64 // percent
+	MaxGap         float64 // largest absolute Gap, percent
+	TrackingError  float64 // standard deviation of the difference in period returns, percent
+}
+
+// Compare replays the backtest from the account's start through its last
+// day and lines the paper equity up with the backtest's at each of the
+// backtest's equity dates. The backtest's last point is after selling
+// everything, so it carries the exit costs the paper account has not paid.
+func (a *Account) Compare(ctx context.Context, svc *services.Service) (Divergence, error) {
+	last, ok := a.LastDay()
+	if !ok || !last.After(a.Start) {
+		return Divergence{}, fmt.Errorf("%s has no trading days after its start to compare", a.Name)
+	}
+	cfg, err := a.config(svc)
+	if err != nil {
+		return Divergence{}, err
+	}
+	cfg.EndDate = last
+	result := backtest.RunBacktest(ctx, cfg)
+
+	var div Divergence
+	prevPaper, prevBacktest := a.Capital, a.Capital
+	var diffs []float64
+	for i, date := range result.EquityDates {
+		p := Point{Date: date, Paper: a.equityOn(date), Backtest: result.EquityCurve[i]}
+		div.Points = append(div.Points, p)
+		div.MaxGap = math.Max(div.MaxGap, math.Abs(p.Gap()))
+		diffs = append(diffs, (p.Paper/prevPaper-p.Backtest/prevBacktest)*100)
+		prevPaper, prevBacktest = p.Paper, p.Backtest
+	}
+	div.PaperReturn = (a.Days[len(a.Days)-1].Equity/a.Capital - 1) * 100
+	div.BacktestReturn = (result.FinalEquity/a.Capital - 1) * 100
+	div.TrackingError = stdDev(diffs)
+	return div, nil
+}
+
+// equityOn is the equity at the close of the last processed day on or
+// before date.
+func (a *Account) equityOn(date time.Time) float64 {
+	equity := a.Capital
+	for _, d := range a.Days {
+		if d.Date.After(date) {
+			break
+		}
+		equity = d.Equity
+	}
+	return equity
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)-1))
+}
@@ -0,0 +1,142 @@
+package paper
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/backtest"
+	"fund-manager/internal/dividends"
+	"fund-manager/internal/services"
+	"fund-manager/internal/synthetic"
+	"math"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func newAccount(t *testing.T) (*Account, *services.Service) {
+	t.Helper()
+	market, err := synthetic.Generate(synthetic.Config{
+		Seed: 1, Stocks: 40, ScriptTypes: []string{"mid", "small"},
+		Start: date("2018-01-01"), End: date("2021-12-31"),
+		Drift: 0.12, DriftSpread: 0.15, Volatility: 0.25,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := backtest.Spec{
+		Name:           "paper",
+		Universe:       backtest.SpecUniverse{ScriptTypes: []string{"mid", "small"}},
+		Strategy:       backtest.SpecStrategy{TopN: 5},
+		Costs:          backtest.CostModel{CommissionBps: 3, SlippageBps: 5, TaxBps: 10},
+		Dates:          backtest.SpecDates{Start: "2020-01-01", End: "2021-12-01"},
+		InitialCapital: 1000000,
+	}.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2020-02-01 is a Saturday, so February trades on Monday the 3rd
+	return &Account{Name: "paper", Spec: spec, Start: date("2020-01-01"), Capital: 1000000}, services.NewService(market.Store())
+}
+
+func run(t *testing.T, a *Account, svc *services.Service, from, to time.Time) {
+	t.Helper()
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if wd := d.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			continue
+		}
+		if _, _, err := a.Step(context.Background(), svc, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStepRebalancesOnSchedule(t *testing.T) {
+	a, svc := newAccount(t)
+	run(t, a, svc, date("2020-01-01"), date("2020-06-30"))
+
+	var rebalanced []string
+	for _, d := range a.Days {
+		if d.Rebalanced {
+			rebalanced = append(rebalanced, d.Date.Format("2006-01-02"))
+		}
+		if d.Cash < 0 {
+			t.Fatalf("cash %.2f on %s", d.Cash, d.Date.Format("2006-01-02"))
+		}
+	}
+	want := []string{"2020-01-01", "2020-02-03", "2020-03-02", "2020-04-01", "2020-05-01", "2020-06-01"}
+	if len(rebalanced) != len(want) {
+		t.Fatalf("rebalanced on %v, want %v", rebalanced, want)
+	}
+	for i := range want {
+		if rebalanced[i] != want[i] {
+			t.Fatalf("rebalanced on %v, want %v", rebalanced, want)
+		}
+	}
+
+	cash, held := a.Positions()
+	if len(held) == 0 || len(held) > 5 || cash != a.Days[len(a.Days)-1].Cash {
+		t.Fatalf("%d holdings, cash %.2f", len(held), cash)
+	}
+	if _, _, err := a.Step(context.Background(), svc, date("2020-06-30")); err == nil {
+		t.Fatal("a processed day ran again")
+	}
+}
+
+func TestCompareTracksBacktest(t *testing.T) {
+	a, svc := newAccount(t)
+	run(t, a, svc, date("2020-01-01"), date("2021-06-30"))
+
+	div, err := a.Compare(context.Background(), svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(div.Points) != 19 {
+		t.Fatalf("%d points", len(div.Points))
+	}
+	last := div.Points[len(div.Points)-1]
+	if !last.Date.Equal(date("2021-06-30")) || last.Paper != a.Days[len(a.Days)-1].Equity {
+		t.Fatalf("last point %+v", last)
+	}
+	// the same ranking and rule; only the trading day of a weekend
+	// schedule date and the backtest's closing exit costs differ
+	if math.Abs(div.PaperReturn-div.BacktestReturn) > 2 || div.MaxGap > 2 {
+		t.Fatalf("paper %.2f%%, backtest %.2f%%, max gap %.2f%%", div.PaperReturn, div.BacktestReturn, div.MaxGap)
+	}
+	t.Logf("paper %.2f%%, backtest %.2f%%, max gap %.2f%%, tracking error %.2f%%", div.PaperReturn, div.BacktestReturn, div.MaxGap, div.TrackingError)
+}
+
+func TestCompareIgnoresDividends(t *testing.T) {
+	a, svc := newAccount(t)
+	run(t, a, svc, date("2020-01-01"), date("2020-12-31"))
+	base, err := a.Compare(context.Background(), svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Step books no dividends, so the replayed backtest must not either
+	var divs []dividends.Dividend
+	for i := 1; i <= 40; i++ {
+		divs = append(divs, dividends.Dividend{Symbol: fmt.Sprintf("SYN%03d", i), ExDate: date("2020-06-15"), Amount: 5})
+	}
+	svc.Dividends = dividends.NewTable(divs)
+	a.Spec.Dividends = backtest.DividendsReinvest
+	div, err := a.Compare(context.Background(), svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(div.BacktestReturn-base.BacktestReturn) > 1e-6 {
+		t.Fatalf("backtest %.4f%% with dividends, %.4f%% without", div.BacktestReturn, base.BacktestReturn)
+	}
+	for i, p := range div.Points {
+		if math.Abs(p.Backtest-base.Points[i].Backtest) > 1e-6 {
+			t.Fatalf("backtest equity %.2f on %s with dividends, %.2f without", p.Backtest, p.Date.Format("2006-01-02"), base.Points[i].Backtest)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fund-manager/internal/backtest"
+	"fund-manager/internal/repository"
+	"fund-manager/internal/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gopkg.in/yaml.v3"
+)
+
+// Open starts a paper account that trades spec from start with capital.
+func Open(ctx context.Context, q *repository.Queries, name string, spec backtest.Spec, start time.Time, capital float64) (*Account, error) {
+	if name == "" {
+		return nil, fmt.Errorf("account name must not be empty")
+	}
+	if spec.Overlay() {
+		return nil, fmt.Errorf("spec %s has a futures overlay; paper accounts hold the basket in cash", spec.Name)
+	}
+	if spec.Contributions != nil {
+		return nil, fmt.Errorf("spec %s has contributions; paper accounts trade a fixed capital", spec.Name)
+	}
+	if spec.Dividends != "" {
+		return nil, fmt.Errorf("spec %s takes dividends; paper accounts do not book them", spec.Name)
+	}
+	if capital <= 0 {
+		return nil, fmt.Errorf("capital must be positive")
+	}
+	specYAML, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.CreatePaperAccount(ctx, repository.CreatePaperAccountParams{
+		ID:             pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Name:           name,
+		Spec:           string(specYAML),
+		StartDate:      pgtype.Date{Time: start, Valid: true},
+		InitialCapital: capital,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open paper account %s: %w", name, err)
+	}
+	return account(row)
+}
+
+func account(row repository.PaperAccount) (*Account, error) {
+	var spec backtest.Spec
+	if err := yaml.Unmarshal([]byte(row.Spec), &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse the spec of paper account %s: %w", row.Name, err)
+	}
+	spec, err := spec.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("paper account %s: %w", row.Name, err)
+	}
+	return &Account{
+		ID:      row.ID,
+		Name:    row.Name,
+		Spec:    spec,
+		Start:   row.StartDate.Time,
+		Capital: row.InitialCapital,
+	}, nil
+}
+
+// Load reads an account with its fills and days.
+func Load(ctx context.Context, q *repository.Queries, name string) (*Account, error) {
+	row, err := q.GetPaperAccountByName(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("no paper account %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a, err := account(row)
+	if err != nil {
+		return nil, err
+	}
+	fills, err := q.GetPaperFills(ctx, a.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fills: %w", err)
+	}
+	for _, f := range fills {
+		a.Fills = append(a.Fills, Fill{Date: f.TradeDate.Time, Symbol: f.Symbol, Side: f.Side, Quantity: f.Quantity, Price: f.Price, Cost: f.Cost})
+	}
+	days, err := q.GetPaperDays(ctx, a.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load days: %w", err)
+	}
+	for _, d := range days {
+		a.Days = append(a.Days, Day{Date: d.TradeDate.Time, Cash: d.Cash, Equity: d.Equity, Rebalanced: d.Rebalanced})
+	}
+	return a, nil
+}
+
+// LoadAll reads every paper account.
+func LoadAll(ctx context.Context, q *repository.Queries) ([]*Account, error) {
+	rows, err := q.ListPaperAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list paper accounts: %w", err)
+	}
+	accounts := make([]*Account, 0, len(rows))
+	for _, row := range rows {
+		a, err := Load(ctx, q, row.Name)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, nil
+}
+
+func Delete(ctx context.Context, q *repository.Queries, name string) error {
+	n, err := q.DeletePaperAccount(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete paper account %s: %w", name, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("no paper account %q", name)
+	}
+	return nil
+}
+
+// Run steps the account through every trading day in daily after its last
+// processed day, up to until, storing each day's fills and value. It
+// returns the days processed.
+func Run(ctx context.Context, q *repository.Queries, svc *services.Service, a *Account, until time.Time) ([]Day, error) {
+	after := a.Start.AddDate(0, 0, -1)
+	if last, ok := a.LastDay(); ok {
+		after = last
+	}
+	dates, err := q.GetTradingDays(ctx, repository.GetTradingDaysParams{
+		After: pgtype.Date{Time: after, Valid: true},
+		Until: pgtype.Date{Time: until, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trading days: %w", err)
+	}
+
+	var days []Day
+	for _, date := range dates {
+		fills, day, err := a.Step(ctx, svc, date.Time)
+		if err != nil {
+			return days, fmt.Errorf("%s on %s: %w", a.Name, date.Time.Format("2006-01-02"), err)
+		}
+		for _, f := range fills {
+			if err := q.CreatePaperFill(ctx, repository.CreatePaperFillParams{
+				ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+				AccountID: a.ID,
+				TradeDate: pgtype.Date{Time: f.Date, Valid: true},
+				Symbol:    f.Symbol,
+				Side:      f.Side,
+				Quantity:  f.Quantity,
+				Price:     f.Price,
+				Cost:      f.Cost,
+			}); err != nil {
+				return days, fmt.Errorf("failed to store fill: %w", err)
+			}
+		}
+		if err := q.UpsertPaperDay(ctx, repository.UpsertPaperDayParams{
+			AccountID:  a.ID,
+			TradeDate:  pgtype.Date{Time: day.Date, Valid: true},
+			Cash:       day.Cash,
+			Equity:     day.Equity,
+			Rebalanced: day.Rebalanced,
+		}); err != nil {
+			return days, fmt.Errorf("failed to store day: %w", err)
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
@@ -0,0 +1,303 @@
+// Package portfolio tracks real accounts: their transactions, and the
+// holdings, cash and profit derived from them.
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/returns"
+	"fund-manager/internal/services"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	Buy        = "buy"
+	Sell       = "sell"
+	Dividend   = "dividend"
+	Fee        = "fee"
+	Deposit    = "deposit"
+	Withdrawal = "withdrawal"
+)
+
+// Kinds lists the transaction kinds in the order they are documented.
+var Kinds = []string{Buy, Sell, Dividend, Fee, Deposit, Withdrawal}
+
+type Transaction struct {
+	ID       string
+	Date     time.Time
+	Kind     string
+	Symbol   string
+	Quantity float64
+	Price    float64
+	Amount   float64 // cash of a dividend, fee, deposit or withdrawal
+	Fees     float64 // charges on a buy or sell
+	Note     string
+	Lots     []string // buys a sale takes its shares from; first in, first out when empty
+}
+
+// Validate checks that a transaction has the fields its kind needs.
+func (t Transaction) Validate() error {
+	switch t.Kind {
+	case Buy, Sell:
+		if t.Symbol == "" || t.Quantity <= 0 || t.Price <= 0 || t.Fees < 0 {
+			return fmt.Errorf("a %s needs a symbol, a positive quantity and price and no negative fees", t.Kind)
+		}
+		if t.Kind == Buy && len(t.Lots) > 0 {
+			return fmt.Errorf("only a sale names lots")
+		}
+	case Dividend:
+		if t.Symbol == "" || t.Amount <= 0 {
+			return fmt.Errorf("a dividend needs a symbol and a positive amount")
+		}
+	case Fee, Deposit, Withdrawal:
+		if t.Amount <= 0 {
+			return fmt.Errorf("a %s needs a positive amount", t.Kind)
+		}
+	default:
+		return fmt.Errorf("unknown transaction kind %q", t.Kind)
+	}
+	if t.Date.IsZero() {
+		return fmt.Errorf("transaction has no date")
+	}
+	return nil
+}
+
+// CashFlow is the change in the account's cash.
+func (t Transaction) CashFlow() float64 {
+	switch t.Kind {
+	case Buy:
+		return -(t.Quantity*t.Price + t.Fees)
+	case Sell:
+		return t.Quantity*t.Price - t.Fees
+	case Dividend, Deposit:
+		return t.Amount
+	case Fee, Withdrawal:
+		return -t.Amount
+	}
+	return 0
+}
+
+// Holding is the position in one symbol. Sales are matched against the
+// average cost of the shares held.
+type Holding struct {
+	Symbol    string
+	Quantity  float64
+	CostBasis float64 // of the shares held, buy fees included
+	Realised  float64 // on sales, net of all fees
+	Dividends float64
+	Price     float64 // last close, 0 until valued or when there is none
+}
+
+func (h Holding) AvgCost() float64 {
+	if h.Quantity == 0 {
+		return 0
+	}
+	return h.CostBasis / h.Quantity
+}
+
+func (h Holding) Value() float64 {
+	return h.Quantity * h.Price
+}
+
+// Unrealised is the gain on the shares held, 0 without a price.
+func (h Holding) Unrealised() float64 {
+	if h.Price == 0 {
+		return 0
+	}
+	return h.Value() - h.CostBasis
+}
+
+// Book is an account as of a date.
+type Book struct {
+	AsOf        time.Time
+	Holdings    map[string]*Holding // every symbol ever held
+	Cash        float64
+	Deposits    float64
+	Withdrawals float64
+	Fees        float64 // fee transactions; trade charges are in the holdings
+}
+
+// quantities below this are rounding left over from fractional units
+const dust = 1e-9
+
+// Build replays the transactions dated up to asOf. Selling more than is
+// held is an error.
+func Build(txns []Transaction, asOf time.Time) (*Book, error) {
+	sorted := make([]Transaction, 0, len(txns))
+	for _, t := range txns {
+		if !t.Date.After(asOf) {
+			sorted = append(sorted, t)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	b := &Book{AsOf: asOf, Holdings: make(map[string]*Holding)}
+	holding := func(symbol string) *Holding {
+		h, ok := b.Holdings[symbol]
+		if !ok {
+			h = &Holding{Symbol: symbol}
+			b.Holdings[symbol] = h
+		}
+		return h
+	}
+
+	for _, t := range sorted {
+		if err := t.Validate(); err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", t.ID, err)
+		}
+		b.Cash += t.CashFlow()
+		switch t.Kind {
+		case Buy:
+			h := holding(t.Symbol)
+			h.Quantity += t.Quantity
+			h.CostBasis += t.Quantity*t.Price + t.Fees
+		case Sell:
+			h := holding(t.Symbol)
+			if t.Quantity > h.Quantity+dust {
+				return nil, fmt.Errorf("sale of %g %s on %s exceeds the %g held", t.Quantity, t.Symbol, t.Date.Format("2006-01-02"), h.Quantity)
+			}
+			cost := h.AvgCost() * t.Quantity
+			h.Realised += t.Quantity*t.Price - t.Fees - cost
+			h.CostBasis -= cost
+			h.Quantity -= t.Quantity
+			if math.Abs(h.Quantity) < dust {
+				h.Quantity, h.CostBasis = 0, 0
+			}
+		case Dividend:
+			holding(t.Symbol).Dividends += t.Amount
+		case Fee:
+			b.Fees += t.Amount
+		case Deposit:
+			b.Deposits += t.Amount
+		case Withdrawal:
+			b.Withdrawals += t.Amount
+		}
+	}
+	return b, nil
+}
+
+// Open lists the holdings with shares, by symbol.
+func (b *Book) Open() []*Holding {
+	var open []*Holding
+	for _, h := range b.sorted() {
+		if h.Quantity > 0 {
+			open = append(open, h)
+		}
+	}
+	return open
+}
+
+// All lists every holding, closed ones included, by symbol.
+func (b *Book) All() []*Holding {
+	return b.sorted()
+}
+
+func (b *Book) sorted() []*Holding {
+	holdings := make([]*Holding, 0, len(b.Holdings))
+	for _, h := range b.Holdings {
+		holdings = append(holdings, h)
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].Symbol < holdings[j].Symbol })
+	return holdings
+}
+
+func (b *Book) MarketValue() float64 {
+	total := 0.0
+	for _, h := range b.Holdings {
+		total += h.Value()
+	}
+	return total
+}
+
+// Equity is the cash plus the market value of the holdings.
+func (b *Book) Equity() float64 {
+	return b.Cash + b.MarketValue()
+}
+
+func (b *Book) Realised() float64 {
+	total := 0.0
+	for _, h := range b.Holdings {
+		total += h.Realised
+	}
+	return total
+}
+
+func (b *Book) Unrealised() float64 {
+	total := 0.0
+	for _, h := range b.Holdings {
+		total += h.Unrealised()
+	}
+	return total
+}
+
+func (b *Book) Dividends() float64 {
+	total := 0.0
+	for _, h := range b.Holdings {
+		total += h.Dividends
+	}
+	return total
+}
+
+// Value prices the open holdings at their last close on or before the
+// book's date and returns the symbols that have none.
+func (b *Book) Value(ctx context.Context, svc *services.Service) ([]string, error) {
+	var missing []string
+	for _, h := range b.Open() {
+		price, ok, err := svc.Close(ctx, h.Symbol, b.AsOf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to price %s: %w", h.Symbol, err)
+		}
+		if !ok {
+			missing = append(missing, h.Symbol)
+		}
+		h.Price = price
+	}
+	return missing, nil
+}
+
+// Flows are the deposits and withdrawals up to asOf, the money that moved
+// in and out of the account from outside.
+func Flows(txns []Transaction, asOf time.Time) []returns.CashFlow {
+	var flows []returns.CashFlow
+	for _, t := range txns {
+		if t.Date.After(asOf) || (t.Kind != Deposit && t.Kind != Withdrawal) {
+			continue
+		}
+		flows = append(flows, returns.CashFlow{Date: t.Date, Amount: t.CashFlow()})
+	}
+	sort.SliceStable(flows, func(i, j int) bool { return flows[i].Date.Before(flows[j].Date) })
+	return flows
+}
+
+// Performance measures the account from its first deposit to asOf. It is
+// valued at the close of every deposit and withdrawal date, so the
+// time-weighted return is exact.
+func Performance(ctx context.Context, svc *services.Service, txns []Transaction, asOf time.Time) (returns.Summary, error) {
+	flows := Flows(txns, asOf)
+	if len(flows) == 0 {
+		return returns.Summary{}, fmt.Errorf("no deposits to measure returns from; record the money put into the account with deposit")
+	}
+	dates := make([]time.Time, 0, len(flows)+1)
+	for _, f := range flows {
+		if len(dates) == 0 || !dates[len(dates)-1].Equal(f.Date) {
+			dates = append(dates, f.Date)
+		}
+	}
+	if dates[len(dates)-1].Before(asOf) {
+		dates = append(dates, asOf)
+	}
+	valuations := make([]returns.Valuation, 0, len(dates))
+	for _, date := range dates {
+		book, err := Build(txns, date)
+		if err != nil {
+			return returns.Summary{}, err
+		}
+		if _, err := book.Value(ctx, svc); err != nil {
+			return returns.Summary{}, err
+		}
+		valuations = append(valuations, returns.Valuation{Date: date, Value: book.Equity()})
+	}
+	return returns.Summarize(flows, valuations)
+}
@@ -0,0 +1,150 @@
+package portfolio
+
+import (
+	"context"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/repository"
+	"fund-manager/internal/services"
+	"fund-manager/internal/tax"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+var history = []Transaction{
+	{ID: "1", Date: day("2025-01-02"), Kind: Deposit, Amount: 100000},
+	{ID: "2", Date: day("2025-01-03"), Kind: Buy, Symbol: "ABC", Quantity: 100, Price: 200, Fees: 20},
+	{ID: "3", Date: day("2025-02-03"), Kind: Buy, Symbol: "ABC", Quantity: 100, Price: 300, Fees: 30},
+	{ID: "4", Date: day("2025-03-03"), Kind: Sell, Symbol: "ABC", Quantity: 50, Price: 400, Fees: 10},
+	{ID: "5", Date: day("2025-03-10"), Kind: Dividend, Symbol: "ABC", Amount: 300},
+	{ID: "6", Date: day("2025-03-31"), Kind: Fee, Amount: 118},
+	{ID: "7", Date: day("2025-04-01"), Kind: Withdrawal, Amount: 5000},
+}
+
+func TestBuildAverageCost(t *testing.T) {
+	b, err := Build(history, day("2025-12-31"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := b.Holdings["ABC"]
+	// 200 shares cost 50050; 50 sold at the average of 250.25
+	if h.Quantity != 150 || !near(h.CostBasis, 37537.5) || !near(h.AvgCost(), 250.25) {
+		t.Fatalf("holding %+v", *h)
+	}
+	if !near(h.Realised, 50*400-10-50*250.25) {
+		t.Fatalf("realised %.2f", h.Realised)
+	}
+	wantCash := 100000 - 20020 - 30030 + 19990 + 300 - 118 - 5000.0
+	if !near(b.Cash, wantCash) {
+		t.Fatalf("cash %.2f, want %.2f", b.Cash, wantCash)
+	}
+
+	h.Price = 350
+	if !near(h.Unrealised(), 150*350-37537.5) || !near(b.Equity(), wantCash+150*350) {
+		t.Fatalf("unrealised %.2f, equity %.2f", h.Unrealised(), b.Equity())
+	}
+	if b.Dividends() != 300 || b.Fees != 118 || b.Deposits-b.Withdrawals != 95000 {
+		t.Fatalf("dividends %.2f, fees %.2f, net deposits %.2f", b.Dividends(), b.Fees, b.Deposits-b.Withdrawals)
+	}
+}
+
+func TestBuildAsOf(t *testing.T) {
+	b, err := Build(history, day("2025-02-28"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := b.Holdings["ABC"]; h.Quantity != 200 || h.Realised != 0 {
+		t.Fatalf("holding on 2025-02-28: %+v", *h)
+	}
+}
+
+func TestBuildRejectsOverselling(t *testing.T) {
+	txns := append(history[:len(history):len(history)], Transaction{ID: "8", Date: day("2025-05-01"), Kind: Sell, Symbol: "ABC", Quantity: 151, Price: 100})
+	if _, err := Build(txns, day("2025-12-31")); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("want an overselling error, got %v", err)
+	}
+}
+
+func TestBuildClosesPosition(t *testing.T) {
+	txns := append(history[:len(history):len(history)], Transaction{ID: "8", Date: day("2025-05-01"), Kind: Sell, Symbol: "ABC", Quantity: 150, Price: 100})
+	b, err := Build(txns, day("2025-12-31"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Open()) != 0 || len(b.All()) != 1 || b.Holdings["ABC"].CostBasis != 0 {
+		t.Fatalf("open %d, all %d, basis %.2f", len(b.Open()), len(b.All()), b.Holdings["ABC"].CostBasis)
+	}
+}
+
+func TestPerformance(t *testing.T) {
+	b := pricestore.NewBuilder()
+	b.AddStock(repository.Stock{Symbol: "ABC", Scripttype: "mid"})
+	for _, bar := range []pricestore.Bar{{Date: day("2025-01-03"), Close: 200}, {Date: day("2025-07-01"), Close: 400}, {Date: day("2025-12-31"), Close: 200}} {
+		if err := b.AddBar("ABC", bar); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := services.NewService(b.Build())
+
+	// 20000 doubles, 40000 more goes in at the top, then everything halves
+	txns := []Transaction{
+		{Date: day("2025-01-03"), Kind: Deposit, Amount: 20000},
+		{Date: day("2025-01-03"), Kind: Buy, Symbol: "ABC", Quantity: 100, Price: 200},
+		{Date: day("2025-07-01"), Kind: Deposit, Amount: 40000},
+		{Date: day("2025-07-01"), Kind: Buy, Symbol: "ABC", Quantity: 100, Price: 400},
+	}
+	perf, err := Performance(context.Background(), svc, txns, day("2025-12-31"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(perf.TWR, 0) || perf.MWR >= 0 || perf.XIRR >= 0 || perf.Invested != 60000 || perf.Final != 40000 {
+		t.Fatalf("performance %+v", perf)
+	}
+	if _, err := Performance(context.Background(), svc, txns[1:2], day("2025-12-31")); err == nil {
+		t.Fatal("returns without deposits")
+	}
+}
+
+func TestTaxLots(t *testing.T) {
+	rules := tax.IndianEquity()
+	ledger, err := TaxLots(history, day("2025-12-31"), rules, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// first in, first out: the 50 sold come from the first buy
+	gains := ledger.Gains()
+	if len(gains) != 1 || gains[0].LotID != "2" || !near(gains[0].Cost, 10010) || !near(gains[0].Amount(), 19990-10010) || gains[0].Term != tax.ShortTerm {
+		t.Fatalf("gains %+v", gains)
+	}
+
+	named := append([]Transaction(nil), history...)
+	named[3].Lots = []string{"3"}
+	ledger, err = TaxLots(named, day("2025-12-31"), rules, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := ledger.Gains()[0]; g.LotID != "3" || !near(g.Cost, 15015) {
+		t.Fatalf("gain %+v", g)
+	}
+	if open := ledger.Open(); len(open) != 2 || open[0].Quantity != 100 || open[1].Quantity != 50 {
+		t.Fatalf("open lots %+v", open)
+	}
+
+	named[3].Lots = []string{"1"}
+	if _, err := TaxLots(named, day("2025-12-31"), rules, nil); err == nil {
+		t.Fatal("sold from a deposit")
+	}
+}
@@ -0,0 +1,176 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fund-manager/internal/repository"
+	"fund-manager/internal/tax"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func OpenAccount(ctx context.Context, q *repository.Queries, name, broker string) (repository.PortfolioAccount, error) {
+	if name == "" {
+		return repository.PortfolioAccount{}, fmt.Errorf("account name must not be empty")
+	}
+	account, err := q.CreatePortfolioAccount(ctx, repository.CreatePortfolioAccountParams{
+		ID:     pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Name:   name,
+		Broker: broker,
+	})
+	if err != nil {
+		return account, fmt.Errorf("failed to open account %s: %w", name, err)
+	}
+	return account, nil
+}
+
+func FindAccount(ctx context.Context, q *repository.Queries, name string) (repository.PortfolioAccount, error) {
+	account, err := q.GetPortfolioAccountByName(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return account, fmt.Errorf("no portfolio account %q", name)
+	}
+	return account, err
+}
+
+// Transactions loads the transactions of an account in date order.
+func Transactions(ctx context.Context, q *repository.Queries, accountID pgtype.UUID) ([]Transaction, error) {
+	rows, err := q.GetPortfolioTransactions(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+	txns := make([]Transaction, len(rows))
+	for i, r := range rows {
+		txns[i] = Transaction{
+			ID:       FormatID(r.ID),
+			Date:     r.TradeDate.Time,
+			Kind:     r.Kind,
+			Symbol:   r.Symbol,
+			Quantity: r.Quantity,
+			Price:    r.Price,
+			Amount:   r.Amount,
+			Fees:     r.Fees,
+			Note:     r.Note,
+			Lots:     splitLots(r.Lots),
+		}
+	}
+	return txns, nil
+}
+
+// Record stores a transaction after checking that the account's history
+// still adds up with it, so that a sale cannot exceed the shares held or
+// the lots it names. Lots may be given as id prefixes.
+func Record(ctx context.Context, q *repository.Queries, accountID pgtype.UUID, t Transaction) (Transaction, error) {
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	t.Symbol = strings.ToUpper(t.Symbol)
+	txns, err := Transactions(ctx, q, accountID)
+	if err != nil {
+		return t, err
+	}
+	if t.Lots, err = resolveLots(txns, t); err != nil {
+		return t, err
+	}
+	if err := check(append(txns, t)); err != nil {
+		return t, err
+	}
+
+	id := uuid.New()
+	if _, err := q.CreatePortfolioTransaction(ctx, repository.CreatePortfolioTransactionParams{
+		ID:        pgtype.UUID{Bytes: id, Valid: true},
+		AccountID: accountID,
+		TradeDate: pgtype.Date{Time: t.Date, Valid: true},
+		Kind:      t.Kind,
+		Symbol:    t.Symbol,
+		Quantity:  t.Quantity,
+		Price:     t.Price,
+		Amount:    t.Amount,
+		Fees:      t.Fees,
+		Note:      t.Note,
+		Lots:      strings.Join(t.Lots, ","),
+	}); err != nil {
+		return t, fmt.Errorf("failed to record %s: %w", t.Kind, err)
+	}
+	t.ID = id.String()
+	return t, nil
+}
+
+// Delete removes the transaction whose id starts with prefix. It refuses
+// when the prefix is ambiguous or the history would no longer add up.
+func Delete(ctx context.Context, q *repository.Queries, accountID pgtype.UUID, prefix string) (Transaction, error) {
+	txns, err := Transactions(ctx, q, accountID)
+	if err != nil {
+		return Transaction{}, err
+	}
+	var match []int
+	for i, t := range txns {
+		if strings.HasPrefix(t.ID, strings.ToLower(prefix)) {
+			match = append(match, i)
+		}
+	}
+	if len(match) != 1 {
+		return Transaction{}, fmt.Errorf("%d transactions match %q", len(match), prefix)
+	}
+	t := txns[match[0]]
+	rest := append(txns[:match[0]:match[0]], txns[match[0]+1:]...)
+	if err := check(rest); err != nil {
+		return t, fmt.Errorf("cannot delete %s: %w", t.ID, err)
+	}
+
+	id, err := uuid.Parse(t.ID)
+	if err != nil {
+		return t, err
+	}
+	if _, err := q.DeletePortfolioTransaction(ctx, repository.DeletePortfolioTransactionParams{
+		AccountID: accountID,
+		ID:        pgtype.UUID{Bytes: id, Valid: true},
+	}); err != nil {
+		return t, fmt.Errorf("failed to delete %s: %w", t.ID, err)
+	}
+	return t, nil
+}
+
+// check replays a whole history, holdings and tax lots.
+func check(txns []Transaction) error {
+	end := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	if _, err := Build(txns, end); err != nil {
+		return err
+	}
+	_, err := TaxLots(txns, end, tax.IndianEquity(), nil)
+	return err
+}
+
+// resolveLots turns the id prefixes a sale names into the ids of buys of
+// the same symbol.
+func resolveLots(txns []Transaction, sale Transaction) ([]string, error) {
+	ids := make([]string, 0, len(sale.Lots))
+	for _, prefix := range sale.Lots {
+		var match []string
+		for _, t := range txns {
+			if t.Kind == Buy && t.Symbol == sale.Symbol && strings.HasPrefix(t.ID, strings.ToLower(prefix)) {
+				match = append(match, t.ID)
+			}
+		}
+		if len(match) != 1 {
+			return nil, fmt.Errorf("%d buys of %s match lot %q", len(match), sale.Symbol, prefix)
+		}
+		ids = append(ids, match[0])
+	}
+	return ids, nil
+}
+
+func splitLots(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func FormatID(id pgtype.UUID) string {
+	return uuid.UUID(id.Bytes).String()
+}
@@ -0,0 +1,43 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/services"
+	"fund-manager/internal/tax"
+	"sort"
+	"time"
+)
+
+// TaxLots replays the trades dated up to asOf into tax lots, one for every
+// buy with the buy's id. Sales take their shares from the lots they name,
+// or first in, first out.
+func TaxLots(txns []Transaction, asOf time.Time, rules tax.Rules, fmv func(symbol string) (float64, bool)) (*tax.Ledger, error) {
+	sorted := make([]Transaction, 0, len(txns))
+	for _, t := range txns {
+		if !t.Date.After(asOf) && (t.Kind == Buy || t.Kind == Sell) {
+			sorted = append(sorted, t)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	ledger := tax.NewLedger(rules, fmv)
+	for _, t := range sorted {
+		if t.Kind == Buy {
+			ledger.Buy(tax.Lot{ID: t.ID, Symbol: t.Symbol, Date: t.Date, Quantity: t.Quantity, Cost: t.Quantity*t.Price + t.Fees})
+			continue
+		}
+		if _, err := ledger.Sell(t.Symbol, t.Date, t.Quantity, t.Quantity*t.Price-t.Fees, t.Lots); err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", t.ID, err)
+		}
+	}
+	return ledger, nil
+}
+
+// FairValue looks up the closes that grandfathered shares are costed at.
+func FairValue(ctx context.Context, svc *services.Service) func(symbol string) (float64, bool) {
+	return func(symbol string) (float64, bool) {
+		price, ok, err := svc.Close(ctx, symbol, tax.GrandfatherDate)
+		return price, ok && err == nil
+	}
+}
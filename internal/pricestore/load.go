@@ -0,0 +1,129 @@
+package pricestore
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/repository"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Source is the part of services.QueryInterface a store loads from.
+type Source interface {
+	GetStocks(ctx context.Context) ([]repository.Stock, error)
+	GetDailyBarsBetween(ctx context.Context, input repository.GetDailyBarsBetweenParams) ([]repository.GetDailyBarsBetweenRow, error)
+}
+
+type Options struct {
+	ScriptTypes []string // empty loads every stock
+	Symbols     []string // loaded whatever their script type
+	From, To    time.Time
+}
+
+// String describes the selection, e.g. "2019-12-01 to 2024-12-31, mid,small
+// and NIFTY", or "all" for none.
+func (o Options) String() string {
+	var parts []string
+	if !o.From.IsZero() || !o.To.IsZero() {
+		from, to := "start", "end"
+		if !o.From.IsZero() {
+			from = o.From.Format("2006-01-02")
+		}
+		if !o.To.IsZero() {
+			to = o.To.Format("2006-01-02")
+		}
+		parts = append(parts, from+" to "+to)
+	}
+	if len(o.ScriptTypes) > 0 {
+		universe := strings.Join(o.ScriptTypes, ",")
+		if len(o.Symbols) > 0 {
+			universe += " and " + strings.Join(o.Symbols, ",")
+		}
+		parts = append(parts, universe)
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Includes reports whether a stock is selected by the options.
+func (o Options) Includes(symbol, scriptType string) bool {
+	if len(o.ScriptTypes) == 0 || slices.Contains(o.ScriptTypes, scriptType) {
+		return true
+	}
+	return slices.Contains(o.Symbols, symbol)
+}
+
+// symbols per GetDailyBarsBetween call, to keep result sets moderate
+const loadBatch = 200
+
+// Load copies the stocks of the requested script types and their bars from
+// From to To into a new store. Queries about earlier dates only see what
+// was loaded, so From must cover any lookback the caller needs.
+func Load(ctx context.Context, src Source, opts Options) (*Store, error) {
+	stocks, err := src.GetStocks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stocks: %w", err)
+	}
+
+	b := NewBuilder()
+	var symbols []string
+	for _, st := range stocks {
+		if !opts.Includes(st.Symbol, st.Scripttype) {
+			continue
+		}
+		b.AddStock(st)
+		symbols = append(symbols, st.Symbol)
+	}
+
+	start := time.Now()
+	rows := 0
+	for i := 0; i < len(symbols); i += loadBatch {
+		batch := symbols[i:min(i+loadBatch, len(symbols))]
+		bars, err := src.GetDailyBarsBetween(ctx, repository.GetDailyBarsBetweenParams{
+			Symbols:   batch,
+			StartDate: pgtype.Date{Time: opts.From, Valid: true},
+			EndDate:   pgtype.Date{Time: opts.To, Valid: true},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prices: %w", err)
+		}
+		for _, bar := range bars {
+			if err := b.AddBar(bar.Symbol, barOf(bar)); err != nil {
+				return nil, err
+			}
+		}
+		rows += len(bars)
+	}
+	log.Printf("📦 Loaded %d bars of %d stocks in %s", rows, len(symbols), time.Since(start).Round(time.Millisecond))
+	return b.Build(), nil
+}
+
+func barOf(row repository.GetDailyBarsBetweenRow) Bar {
+	bar := Bar{
+		Date:   row.Timestamp.Time,
+		Open:   numericValue(row.Open),
+		High:   numericValue(row.High),
+		Low:    numericValue(row.Low),
+		Close:  numericValue(row.Close),
+		Volume: -1,
+	}
+	if row.Volume.Valid {
+		bar.Volume = row.Volume.Int64
+	}
+	return bar
+}
+
+func numericValue(n pgtype.Numeric) float64 {
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return math.NaN()
+	}
+	return f.Float64
+}
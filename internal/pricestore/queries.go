@@ -0,0 +1,157 @@
+package pricestore
+
+import (
+	"context"
+	"fund-manager/internal/repository"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The methods below give Store the semantics of the matching queries in
+// sql/query.sql so that it satisfies services.QueryInterface.
+
+func (s *Store) GetStocks(ctx context.Context) ([]repository.Stock, error) {
+	out := make([]repository.Stock, len(s.stocks))
+	copy(out, s.stocks)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, ctx.Err()
+}
+
+func (s *Store) GetTopStocksByReturn(ctx context.Context, arg repository.GetTopStocksByReturnParams) ([]repository.GetTopStocksByReturnRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	types := make(map[string]bool, len(arg.Column3))
+	for _, t := range arg.Column3 {
+		types[t] = true
+	}
+	industries := make(map[string]bool, len(arg.Column5))
+	for _, ind := range arg.Column5 {
+		industries[ind] = true
+	}
+	// timestamps compare against dates at midnight, so a time of day on
+	// the rebalance date still includes that day's close
+	now := dayNumber(arg.Column1.Time)
+	then := dayNumber(SubMonths(arg.Column1.Time, int(arg.Column2)))
+
+	var rows []repository.GetTopStocksByReturnRow
+	for i, st := range s.stocks {
+		if !types[st.Scripttype] {
+			continue
+		}
+		if len(industries) > 0 && !industries[strings.ToLower(st.Industry.String)] {
+			continue
+		}
+		old := s.latestAt(i, then, true)
+		latest := s.latestAt(i, now, true)
+		if old < 0 || latest < 0 {
+			continue
+		}
+		ret := (s.close[latest] - s.close[old]) / s.close[old] * 100
+		rows = append(rows, repository.GetTopStocksByReturnRow{
+			ID:               st.ID,
+			Name:             st.Name,
+			Symbol:           st.Symbol,
+			ReturnPercentage: int32(math.Round(ret)),
+		})
+	}
+	// Postgres leaves ties unordered; break them by symbol to be repeatable
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].ReturnPercentage != rows[j].ReturnPercentage {
+			return rows[i].ReturnPercentage > rows[j].ReturnPercentage
+		}
+		return rows[i].Symbol < rows[j].Symbol
+	})
+	if arg.Limit >= 0 && int(arg.Limit) < len(rows) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+func (s *Store) GetLatestClosePrice(ctx context.Context, arg repository.GetLatestClosePriceParams) (pgtype.Numeric, error) {
+	if err := ctx.Err(); err != nil {
+		return pgtype.Numeric{}, err
+	}
+	i, ok := s.bySymbol[arg.Symbol]
+	if !ok {
+		return pgtype.Numeric{}, pgx.ErrNoRows
+	}
+	j := s.latestAt(i, dayNumber(arg.Timestamp.Time), false)
+	if j < 0 {
+		return pgtype.Numeric{}, pgx.ErrNoRows
+	}
+	return toNumeric(s.close[j]), nil
+}
+
+func (s *Store) GetHistoricalStockPrices(ctx context.Context, arg repository.GetHistoricalStockPricesParams) ([]repository.GetHistoricalStockPricesRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	i, ok := s.bySymbol[arg.Symbol]
+	if !ok {
+		return nil, nil
+	}
+	a, b := s.between(i, dayNumber(arg.Timestamp.Time), dayNumber(arg.Timestamp_2.Time))
+	var rows []repository.GetHistoricalStockPricesRow
+	for j := a; j < b; j++ {
+		rows = append(rows, repository.GetHistoricalStockPricesRow{Timestamp: pgDate(s.days[j]), Close: toNumeric(s.close[j])})
+	}
+	return rows, nil
+}
+
+func (s *Store) GetDailyBarsBetween(ctx context.Context, arg repository.GetDailyBarsBetweenParams) ([]repository.GetDailyBarsBetweenRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	symbols := append([]string(nil), arg.Symbols...)
+	sort.Strings(symbols)
+	from, to := dayNumber(arg.StartDate.Time), dayNumber(arg.EndDate.Time)
+
+	var rows []repository.GetDailyBarsBetweenRow
+	for k, sym := range symbols {
+		if k > 0 && symbols[k-1] == sym {
+			continue
+		}
+		i, ok := s.bySymbol[sym]
+		if !ok {
+			continue
+		}
+		a, b := s.between(i, from, to)
+		for j := a; j < b; j++ {
+			row := repository.GetDailyBarsBetweenRow{
+				Symbol:    sym,
+				Timestamp: pgDate(s.days[j]),
+				Open:      toNumeric(s.open[j]),
+				High:      toNumeric(s.high[j]),
+				Low:       toNumeric(s.low[j]),
+				Close:     toNumeric(s.close[j]),
+			}
+			if v := s.volume[j]; v >= 0 {
+				row.Volume = pgtype.Int8{Int64: v, Valid: true}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (s *Store) GetDataFingerprint(ctx context.Context) (repository.GetDataFingerprintRow, error) {
+	fp := repository.GetDataFingerprintRow{RowCount: int64(len(s.days))}
+	maxDay := int32(math.MinInt32)
+	for i := range s.stocks {
+		if s.end[i] > s.start[i] {
+			fp.StockCount++
+			if d := s.days[s.end[i]-1]; d > maxDay {
+				maxDay = d
+			}
+		}
+	}
+	if fp.StockCount > 0 {
+		fp.MaxDate = pgDate(maxDay)
+	}
+	return fp, ctx.Err()
+}
@@ -0,0 +1,203 @@
+// Package pricestore holds daily prices in memory so that backtests and
+// screens can run without a database round trip per lookup.
+package pricestore
+
+import (
+	"fmt"
+	"fund-manager/internal/repository"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Bar is one daily row. Missing open, high and low are NaN; a missing
+// volume is -1.
+type Bar struct {
+	Date   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume int64
+}
+
+// Store is a read-only, columnar copy of the stocks and daily tables. All
+// bars live in shared slices sorted by stock and date, and each stock owns
+// the range [start, end) of them. It is safe for concurrent use, so one
+// loaded store can serve many backtests.
+type Store struct {
+	stocks   []repository.Stock
+	bySymbol map[string]int
+	start    []int
+	end      []int
+
+	days   []int32 // days since the Unix epoch
+	open   []float64
+	high   []float64
+	low    []float64
+	close  []float64
+	volume []int64
+}
+
+// Builder collects stocks and bars in any order and sorts them into a Store.
+type Builder struct {
+	stocks   []repository.Stock
+	bySymbol map[string]int
+	bars     [][]Bar
+}
+
+func NewBuilder() *Builder {
+	return &Builder{bySymbol: make(map[string]int)}
+}
+
+// AddStock registers a stock; adding a symbol twice keeps the first.
+func (b *Builder) AddStock(stock repository.Stock) {
+	if _, ok := b.bySymbol[stock.Symbol]; ok {
+		return
+	}
+	b.bySymbol[stock.Symbol] = len(b.stocks)
+	b.stocks = append(b.stocks, stock)
+	b.bars = append(b.bars, nil)
+}
+
+// AddBar appends a bar for a registered symbol. Bars without a close are
+// dropped, as every query the store answers ignores them.
+func (b *Builder) AddBar(symbol string, bar Bar) error {
+	i, ok := b.bySymbol[symbol]
+	if !ok {
+		return fmt.Errorf("unknown symbol %q", symbol)
+	}
+	if math.IsNaN(bar.Close) {
+		return nil
+	}
+	b.bars[i] = append(b.bars[i], bar)
+	return nil
+}
+
+func (b *Builder) Build() *Store {
+	s := &Store{
+		stocks:   b.stocks,
+		bySymbol: b.bySymbol,
+		start:    make([]int, len(b.stocks)),
+		end:      make([]int, len(b.stocks)),
+	}
+	total := 0
+	for _, bars := range b.bars {
+		total += len(bars)
+	}
+	s.days = make([]int32, 0, total)
+	s.open = make([]float64, 0, total)
+	s.high = make([]float64, 0, total)
+	s.low = make([]float64, 0, total)
+	s.close = make([]float64, 0, total)
+	s.volume = make([]int64, 0, total)
+
+	for i, bars := range b.bars {
+		sort.SliceStable(bars, func(x, y int) bool { return bars[x].Date.Before(bars[y].Date) })
+		s.start[i] = len(s.days)
+		for _, bar := range bars {
+			s.days = append(s.days, dayNumber(bar.Date))
+			s.open = append(s.open, bar.Open)
+			s.high = append(s.high, bar.High)
+			s.low = append(s.low, bar.Low)
+			s.close = append(s.close, bar.Close)
+			s.volume = append(s.volume, bar.Volume)
+		}
+		s.end[i] = len(s.days)
+	}
+	return s
+}
+
+// Rows is the number of bars held.
+func (s *Store) Rows() int {
+	return len(s.days)
+}
+
+// Symbols lists the loaded stocks in load order.
+func (s *Store) Symbols() []string {
+	out := make([]string, len(s.stocks))
+	for i, st := range s.stocks {
+		out[i] = st.Symbol
+	}
+	return out
+}
+
+// Bars returns a copy of the bars of symbol in date order.
+func (s *Store) Bars(symbol string) []Bar {
+	i, ok := s.bySymbol[symbol]
+	if !ok {
+		return nil
+	}
+	bars := make([]Bar, 0, s.end[i]-s.start[i])
+	for j := s.start[i]; j < s.end[i]; j++ {
+		bars = append(bars, Bar{
+			Date:   dayTime(s.days[j]),
+			Open:   s.open[j],
+			High:   s.high[j],
+			Low:    s.low[j],
+			Close:  s.close[j],
+			Volume: s.volume[j],
+		})
+	}
+	return bars
+}
+
+// latestAt returns the index of the last bar of stock i on or before day,
+// or -1. With nonZero set bars closing at 0 are skipped as well.
+func (s *Store) latestAt(i int, day int32, nonZero bool) int {
+	lo, hi := s.start[i], s.end[i]
+	j := lo + sort.Search(hi-lo, func(k int) bool { return s.days[lo+k] > day }) - 1
+	for ; j >= lo; j-- {
+		if !nonZero || s.close[j] != 0 {
+			return j
+		}
+	}
+	return -1
+}
+
+// between returns the bar range of stock i within [from, to].
+func (s *Store) between(i int, from, to int32) (int, int) {
+	lo, hi := s.start[i], s.end[i]
+	a := lo + sort.Search(hi-lo, func(k int) bool { return s.days[lo+k] >= from })
+	b := lo + sort.Search(hi-lo, func(k int) bool { return s.days[lo+k] > to })
+	return a, b
+}
+
+var epoch = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func dayNumber(t time.Time) int32 {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return int32(t.Sub(epoch).Hours() / 24)
+}
+
+func dayTime(d int32) time.Time {
+	return epoch.AddDate(0, 0, int(d))
+}
+
+// SubMonths steps back like Postgres interval arithmetic, clamping to the
+// end of a shorter month (31 March minus one month is 28 or 29 February).
+func SubMonths(t time.Time, months int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m-time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	if d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
+func toNumeric(f float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return n
+	}
+	_ = n.Scan(strconv.FormatFloat(f, 'f', -1, 64))
+	return n
+}
+
+func pgDate(d int32) pgtype.Date {
+	return pgtype.Date{Time: dayTime(d), Valid: true}
+}
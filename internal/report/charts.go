@@ -0,0 +1,335 @@
+package report
+
+import (
+	"fmt"
+	"fund-manager/internal/backtest"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth   = 900
+	chartHeight  = 280
+	marginLeft   = 70
+	marginRight  = 16
+	marginTop    = 12
+	marginBottom = 28
+)
+
+type tick struct {
+	Pos   float64
+	Label string
+}
+
+type line struct {
+	Name   string
+	Color  string
+	Points string
+}
+
+// lineChart is the geometry of a chart with a shared date axis. The
+// template only places the precomputed shapes.
+type lineChart struct {
+	Width, Height  int
+	Left, Right    float64
+	Top, Bottom    float64
+	Lines          []line
+	Area           string
+	XTicks, YTicks []tick
+}
+
+type plotArea struct {
+	first, last time.Time
+	lo, hi      float64
+}
+
+func (p plotArea) x(d time.Time) float64 {
+	span := p.last.Sub(p.first).Hours()
+	if span <= 0 {
+		return marginLeft
+	}
+	return round1(marginLeft + d.Sub(p.first).Hours()/span*float64(chartWidth-marginLeft-marginRight))
+}
+
+func (p plotArea) y(v float64) float64 {
+	if p.hi == p.lo {
+		return marginTop
+	}
+	return round1(marginTop + (p.hi-v)/(p.hi-p.lo)*float64(chartHeight-marginTop-marginBottom))
+}
+
+func (p plotArea) chart() lineChart {
+	c := lineChart{
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   marginLeft,
+		Right:  chartWidth - marginRight,
+		Top:    marginTop,
+		Bottom: chartHeight - marginBottom,
+	}
+	for _, d := range dateTicks(p.first, p.last, 6) {
+		c.XTicks = append(c.XTicks, tick{Pos: p.x(d), Label: d.Format("Jan 2006")})
+	}
+	return c
+}
+
+func (p plotArea) points(dates []time.Time, values []float64) string {
+	var b strings.Builder
+	for i, d := range dates {
+		if math.IsNaN(values[i]) {
+			continue
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f ", p.x(d), p.y(values[i]))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// equityChart plots equity and, when given, the benchmark rebased to the
+// same starting value.
+func equityChart(dates []time.Time, equity, benchmark []float64, benchmarkName string) lineChart {
+	area := plotArea{first: dates[0], last: dates[len(dates)-1], lo: math.Inf(1), hi: math.Inf(-1)}
+	series := [][]float64{equity}
+	if len(benchmark) == len(equity) {
+		series = append(series, benchmark)
+	}
+	for _, s := range series {
+		for _, v := range s {
+			if !math.IsNaN(v) {
+				area.lo, area.hi = math.Min(area.lo, v), math.Max(area.hi, v)
+			}
+		}
+	}
+	area.lo, area.hi = padRange(area.lo, area.hi)
+
+	c := area.chart()
+	c.Lines = append(c.Lines, line{Name: "Strategy", Color: "#1f6feb", Points: area.points(dates, equity)})
+	if len(series) > 1 {
+		c.Lines = append(c.Lines, line{Name: benchmarkName, Color: "#8b949e", Points: area.points(dates, benchmark)})
+	}
+	for _, v := range valueTicks(area.lo, area.hi, 5) {
+		c.YTicks = append(c.YTicks, tick{Pos: area.y(v), Label: formatAmount(v)})
+	}
+	return c
+}
+
+// underwaterChart shades the distance of the account's growth, compounded
+// from its period returns, below its running peak.
+func underwaterChart(dates []time.Time, periodReturns []float64) lineChart {
+	dd := drawdowns(backtest.Growth(periodReturns))
+	area := plotArea{first: dates[0], last: dates[len(dates)-1], lo: 0, hi: 0}
+	for _, v := range dd {
+		area.lo = math.Min(area.lo, v)
+	}
+	if area.lo == 0 {
+		area.lo = -0.01
+	}
+
+	c := area.chart()
+	pts := area.points(dates, dd)
+	c.Lines = []line{{Name: "Drawdown", Color: "#cf222e", Points: pts}}
+	c.Area = fmt.Sprintf("%.1f,%.1f %s %.1f,%.1f", area.x(dates[0]), area.y(0), pts, area.x(dates[len(dates)-1]), area.y(0))
+	for _, v := range valueTicks(area.lo, 0, 4) {
+		c.YTicks = append(c.YTicks, tick{Pos: area.y(v), Label: fmt.Sprintf("%.0f%%", v*100)})
+	}
+	return c
+}
+
+func drawdowns(equity []float64) []float64 {
+	out := make([]float64, len(equity))
+	peak := math.Inf(-1)
+	for i, v := range equity {
+		peak = math.Max(peak, v)
+		if peak > 0 {
+			out[i] = v/peak - 1
+		}
+	}
+	return out
+}
+
+type heatCell struct {
+	Label string
+	Color string
+	Title string
+}
+
+type heatRow struct {
+	Year  int
+	Cells [12]heatCell
+	Total heatCell
+}
+
+// monthlyHeatmap assigns the return between two equity points, net of
+// flows, to the month in which the period started, compounding periods in
+// the same month.
+func monthlyHeatmap(dates []time.Time, periodReturns []float64) []heatRow {
+	type key struct {
+		year  int
+		month time.Month
+	}
+	growth := make(map[key]float64)
+	yearly := make(map[int]float64)
+	for i := 1; i < len(periodReturns); i++ {
+		r := 1 + periodReturns[i]
+		k := key{dates[i-1].Year(), dates[i-1].Month()}
+		if _, ok := growth[k]; !ok {
+			growth[k] = 1
+		}
+		growth[k] *= r
+		if _, ok := yearly[k.year]; !ok {
+			yearly[k.year] = 1
+		}
+		yearly[k.year] *= r
+	}
+
+	years := make([]int, 0, len(yearly))
+	for y := range yearly {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+
+	rows := make([]heatRow, 0, len(years))
+	for _, y := range years {
+		row := heatRow{Year: y}
+		for m := time.January; m <= time.December; m++ {
+			g, ok := growth[key{y, m}]
+			if !ok {
+				row.Cells[m-1] = heatCell{Color: "#f6f8fa"}
+				continue
+			}
+			row.Cells[m-1] = returnCell(g-1, fmt.Sprintf("%s %d", m, y), 0.10)
+		}
+		row.Total = returnCell(yearly[y]-1, fmt.Sprintf("%d", y), 0.50)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func returnCell(r float64, title string, full float64) heatCell {
+	return heatCell{
+		Label: fmt.Sprintf("%.1f", r*100),
+		Color: returnColor(r, full),
+		Title: fmt.Sprintf("%s: %+.2f%%", title, r*100),
+	}
+}
+
+// returnColor fades from white to green or red, saturating at ±full.
+func returnColor(r, full float64) string {
+	t := math.Min(math.Abs(r)/full, 1)
+	fade := func(c int) int { return int(math.Round(255 - t*float64(255-c))) }
+	if r >= 0 {
+		return fmt.Sprintf("#%02x%02x%02x", fade(46), fade(160), fade(67))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", fade(207), fade(34), fade(46))
+}
+
+type holdingBar struct {
+	X, Width float64
+	Title    string
+}
+
+type holdingRow struct {
+	Symbol string
+	Y      float64
+	Bars   []holdingBar
+}
+
+type holdingsChart struct {
+	Width, Height int
+	Left, Right   float64
+	RowHeight     float64
+	Rows          []holdingRow
+	XTicks        []tick
+}
+
+// holdingsTimeline draws one row per symbol with a bar for every stretch
+// of consecutive rebalances in which the portfolio log lists it.
+func holdingsTimeline(dates []time.Time, portfolio [][]string) holdingsChart {
+	const rowHeight = 16
+	area := plotArea{first: dates[0], last: dates[len(dates)-1]}
+
+	var order []string
+	rowOf := make(map[string]*holdingRow)
+	for i, held := range portfolio {
+		if i+1 >= len(dates) {
+			break
+		}
+		for _, sym := range held {
+			row := rowOf[sym]
+			if row == nil {
+				order = append(order, sym)
+				row = &holdingRow{Symbol: sym}
+				rowOf[sym] = row
+			}
+			x0, x1 := area.x(dates[i]), area.x(dates[i+1])
+			if n := len(row.Bars); n > 0 && math.Abs(row.Bars[n-1].X+row.Bars[n-1].Width-x0) < 0.01 {
+				row.Bars[n-1].Width = round1(x1 - row.Bars[n-1].X)
+				continue
+			}
+			row.Bars = append(row.Bars, holdingBar{X: x0, Width: round1(x1 - x0), Title: sym + " from " + dates[i].Format("2006-01-02")})
+		}
+	}
+
+	c := holdingsChart{
+		Width:     chartWidth,
+		Left:      marginLeft,
+		Right:     chartWidth - marginRight,
+		RowHeight: rowHeight - 4,
+	}
+	for i, sym := range order {
+		row := rowOf[sym]
+		row.Y = float64(marginTop + i*rowHeight)
+		c.Rows = append(c.Rows, *row)
+	}
+	c.Height = marginTop + len(order)*rowHeight + marginBottom
+	for _, d := range dateTicks(area.first, area.last, 6) {
+		c.XTicks = append(c.XTicks, tick{Pos: area.x(d), Label: d.Format("Jan 2006")})
+	}
+	return c
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+func padRange(lo, hi float64) (float64, float64) {
+	if math.IsInf(lo, 0) || math.IsInf(hi, 0) {
+		return 0, 1
+	}
+	pad := (hi - lo) * 0.05
+	if pad == 0 {
+		pad = math.Max(math.Abs(hi)*0.05, 1)
+	}
+	return lo - pad, hi + pad
+}
+
+func valueTicks(lo, hi float64, n int) []float64 {
+	ticks := make([]float64, n)
+	for i := range ticks {
+		ticks[i] = lo + (hi-lo)*float64(i)/float64(n-1)
+	}
+	return ticks
+}
+
+func dateTicks(first, last time.Time, n int) []time.Time {
+	if !last.After(first) {
+		return []time.Time{first}
+	}
+	ticks := make([]time.Time, n)
+	span := last.Sub(first)
+	for i := range ticks {
+		ticks[i] = first.Add(span * time.Duration(i) / time.Duration(n-1))
+	}
+	return ticks
+}
+
+func formatAmount(v float64) string {
+	switch a := math.Abs(v); {
+	case a >= 1e7:
+		return fmt.Sprintf("%.2fCr", v/1e7)
+	case a >= 1e5:
+		return fmt.Sprintf("%.2fL", v/1e5)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
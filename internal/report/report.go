@@ -0,0 +1,133 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"fund-manager/internal/backtest"
+	"html/template"
+	"io"
+	"math"
+	"time"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+var tmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date":   func(t time.Time) string { return t.Format("2006-01-02") },
+	"amount": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"pct":    func(v float64) string { return fmt.Sprintf("%.2f%%", v) },
+	"mul100": func(v float64) float64 { return v * 100 },
+	"months": func() []string {
+		return []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	},
+}).Parse(reportTemplate))
+
+// Input is everything a report shows. Benchmark, when set, holds the
+// benchmark's closes on Result.EquityDates.
+type Input struct {
+	Title         string
+	Subtitle      string
+	Result        backtest.BacktestResult
+	Metrics       backtest.Metrics
+	Benchmark     []float64
+	BenchmarkName string
+	GeneratedAt   time.Time
+}
+
+type metric struct {
+	Label string
+	Value string
+}
+
+type page struct {
+	Input
+	Summary    []metric
+	Equity     lineChart
+	Underwater lineChart
+	Heatmap    []heatRow
+	Holdings   holdingsChart
+	Trades     []backtest.TradeLog
+	Dividends  bool // the run tracked dividends
+}
+
+// WriteHTML renders a single self-contained HTML file: all charts are
+// inline SVG and the page loads no scripts, fonts or stylesheets.
+func WriteHTML(w io.Writer, in Input) error {
+	r := in.Result
+	if len(r.EquityDates) == 0 {
+		return fmt.Errorf("backtest has no equity curve to report")
+	}
+	if in.GeneratedAt.IsZero() {
+		in.GeneratedAt = time.Now()
+	}
+	// drawdowns and monthly returns leave out the money that came in or
+	// went out
+	periodReturns := r.MonthlyReturns
+	if len(periodReturns) != len(r.EquityCurve) {
+		periodReturns = backtest.EquityReturns(in.Metrics.InitialCapital, r.EquityCurve)
+	}
+
+	p := page{
+		Input:      in,
+		Summary:    summary(in),
+		Equity:     equityChart(r.EquityDates, r.EquityCurve, rebase(in.Benchmark, r.EquityCurve[0]), in.BenchmarkName),
+		Underwater: underwaterChart(r.EquityDates, periodReturns),
+		Heatmap:    monthlyHeatmap(r.EquityDates, periodReturns),
+		Holdings:   holdingsTimeline(r.EquityDates, r.PortfolioLog),
+		Trades:     r.TradeLogs,
+		Dividends:  in.Metrics.Dividends != 0,
+	}
+	return tmpl.Execute(w, p)
+}
+
+func summary(in Input) []metric {
+	m := in.Metrics
+	out := []metric{
+		{"CAGR", fmt.Sprintf("%.2f%%", m.CAGR*100)},
+		{"Max Drawdown", fmt.Sprintf("%.2f%%", m.MaxDrawdown*100)},
+		{"Initial Capital", formatAmount(m.InitialCapital)},
+		{"Final Equity", formatAmount(m.FinalEquity)},
+		{"Net Profit", formatAmount(m.NetProfit)},
+		{"Total Trades", fmt.Sprintf("%d", m.TotalTrades)},
+		{"Win Rate", fmt.Sprintf("%.2f%%", m.WinRate*100)},
+		{"Average Profit", fmt.Sprintf("%.2f", m.AverageProfit)},
+		{"Total Costs", fmt.Sprintf("%.2f", m.TotalCosts)},
+	}
+	if m.Dividends != 0 {
+		out = append(out, metric{"Dividends", formatAmount(m.Dividends)}, metric{"Price CAGR", fmt.Sprintf("%.2f%%", m.PriceCAGR*100)})
+	}
+	if m.RollCosts != 0 || m.MaxMarginUsed != 0 {
+		out = append(out, metric{"Roll Costs", fmt.Sprintf("%.2f", m.RollCosts)}, metric{"Max Margin Used", fmt.Sprintf("%.2f%%", m.MaxMarginUsed*100)})
+	}
+	if m.CashInterest != 0 {
+		out = append(out, metric{"Cash Interest", formatAmount(m.CashInterest)})
+	}
+	if m.TaxPaid != 0 {
+		out = append(out, metric{"Tax Paid", formatAmount(m.TaxPaid)})
+	}
+	if m.XIRR != 0 || m.TWR != 0 {
+		out = append(out,
+			metric{"Net Invested", formatAmount(m.NetInvested)},
+			metric{"XIRR", fmt.Sprintf("%.2f%%", m.XIRR*100)},
+			metric{"Time-Weighted Return", fmt.Sprintf("%.2f%%", m.TWR*100)},
+			metric{"Money-Weighted Return", fmt.Sprintf("%.2f%%", m.MWR*100)},
+		)
+	}
+	if b := in.Benchmark; len(b) > 1 && b[0] > 0 && !math.IsNaN(b[len(b)-1]) {
+		out = append(out, metric{in.BenchmarkName + " Return", fmt.Sprintf("%.2f%%", (b[len(b)-1]/b[0]-1)*100)})
+	}
+	return out
+}
+
+// rebase scales prices so the series starts at base.
+func rebase(prices []float64, base float64) []float64 {
+	if len(prices) == 0 || prices[0] <= 0 {
+		return nil
+	}
+	out := make([]float64, len(prices))
+	for i, p := range prices {
+		out[i] = p / prices[0] * base
+	}
+	return out
+}
@@ -9,6 +9,156 @@ import (
 	"context"
 )
 
+// iteratorForBulkCreateBacktestEquity implements pgx.CopyFromSource.
+type iteratorForBulkCreateBacktestEquity struct {
+	rows                 []BulkCreateBacktestEquityParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForBulkCreateBacktestEquity) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForBulkCreateBacktestEquity) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].RunID,
+		r.rows[0].Date,
+		r.rows[0].Equity,
+		r.rows[0].PeriodReturn,
+	}, nil
+}
+
+func (r iteratorForBulkCreateBacktestEquity) Err() error {
+	return nil
+}
+
+func (q *Queries) BulkCreateBacktestEquity(ctx context.Context, arg []BulkCreateBacktestEquityParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"backtest_equity"}, []string{"run_id", "date", "equity", "period_return"}, &iteratorForBulkCreateBacktestEquity{rows: arg})
+}
+
+// iteratorForBulkCreateBacktestMetrics implements pgx.CopyFromSource.
+type iteratorForBulkCreateBacktestMetrics struct {
+	rows                 []BulkCreateBacktestMetricsParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForBulkCreateBacktestMetrics) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForBulkCreateBacktestMetrics) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].RunID,
+		r.rows[0].Name,
+		r.rows[0].Value,
+	}, nil
+}
+
+func (r iteratorForBulkCreateBacktestMetrics) Err() error {
+	return nil
+}
+
+func (q *Queries) BulkCreateBacktestMetrics(ctx context.Context, arg []BulkCreateBacktestMetricsParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"backtest_metrics"}, []string{"run_id", "name", "value"}, &iteratorForBulkCreateBacktestMetrics{rows: arg})
+}
+
+// iteratorForBulkCreateBacktestParams implements pgx.CopyFromSource.
+type iteratorForBulkCreateBacktestParams struct {
+	rows                 []BulkCreateBacktestParamsParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForBulkCreateBacktestParams) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForBulkCreateBacktestParams) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].RunID,
+		r.rows[0].Key,
+		r.rows[0].Value,
+	}, nil
+}
+
+func (r iteratorForBulkCreateBacktestParams) Err() error {
+	return nil
+}
+
+func (q *Queries) BulkCreateBacktestParams(ctx context.Context, arg []BulkCreateBacktestParamsParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"backtest_params"}, []string{"run_id", "key", "value"}, &iteratorForBulkCreateBacktestParams{rows: arg})
+}
+
+// iteratorForBulkCreateBacktestTrades implements pgx.CopyFromSource.
+type iteratorForBulkCreateBacktestTrades struct {
+	rows                 []BulkCreateBacktestTradesParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForBulkCreateBacktestTrades) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForBulkCreateBacktestTrades) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].ID,
+		r.rows[0].RunID,
+		r.rows[0].Symbol,
+		r.rows[0].EntryDate,
+		r.rows[0].ExitDate,
+		r.rows[0].EntryPrice,
+		r.rows[0].ExitPrice,
+		r.rows[0].Quantity,
+		r.rows[0].AmountUsed,
+		r.rows[0].Profit,
+		r.rows[0].ProfitPct,
+		r.rows[0].DaysHeld,
+		r.rows[0].MaxDrawdown,
+		r.rows[0].Costs,
+		r.rows[0].Dividends,
+		r.rows[0].TotalReturnPct,
+	}, nil
+}
+
+func (r iteratorForBulkCreateBacktestTrades) Err() error {
+	return nil
+}
+
+func (q *Queries) BulkCreateBacktestTrades(ctx context.Context, arg []BulkCreateBacktestTradesParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"backtest_trades"}, []string{"id", "run_id", "symbol", "entry_date", "exit_date", "entry_price", "exit_price", "quantity", "amount_used", "profit", "profit_pct", "days_held", "max_drawdown", "costs", "dividends", "total_return_pct"}, &iteratorForBulkCreateBacktestTrades{rows: arg})
+}
+
 // iteratorForBulkCreateDaily implements pgx.CopyFromSource.
 type iteratorForBulkCreateDaily struct {
 	rows                 []BulkCreateDailyParams
@@ -37,6 +187,11 @@ func (r iteratorForBulkCreateDaily) Values() ([]interface{}, error) {
 		r.rows[0].Close,
 		r.rows[0].Volume,
 		r.rows[0].Timestamp,
+		r.rows[0].TradedValue,
+		r.rows[0].NumTrades,
+		r.rows[0].DeliverableQty,
+		r.rows[0].AdjClose,
+		r.rows[0].Source,
 	}, nil
 }
 
@@ -45,7 +200,80 @@ func (r iteratorForBulkCreateDaily) Err() error {
 }
 
 func (q *Queries) BulkCreateDaily(ctx context.Context, arg []BulkCreateDailyParams) (int64, error) {
-	return q.db.CopyFrom(ctx, []string{"daily"}, []string{"id", "stockid", "open", "high", "low", "close", "volume", "timestamp"}, &iteratorForBulkCreateDaily{rows: arg})
+	return q.db.CopyFrom(ctx, []string{"daily"}, []string{"id", "stockid", "open", "high", "low", "close", "volume", "timestamp", "traded_value", "num_trades", "deliverable_qty", "adj_close", "source"}, &iteratorForBulkCreateDaily{rows: arg})
+}
+
+// iteratorForBulkCreateMfNavs implements pgx.CopyFromSource.
+type iteratorForBulkCreateMfNavs struct {
+	rows                 []BulkCreateMfNavsParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForBulkCreateMfNavs) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForBulkCreateMfNavs) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].SchemeCode,
+		r.rows[0].NavDate,
+		r.rows[0].Nav,
+		r.rows[0].RepurchasePrice,
+		r.rows[0].SalePrice,
+	}, nil
+}
+
+func (r iteratorForBulkCreateMfNavs) Err() error {
+	return nil
+}
+
+func (q *Queries) BulkCreateMfNavs(ctx context.Context, arg []BulkCreateMfNavsParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"mf_navs"}, []string{"scheme_code", "nav_date", "nav", "repurchase_price", "sale_price"}, &iteratorForBulkCreateMfNavs{rows: arg})
+}
+
+// iteratorForBulkCreateStockChanges implements pgx.CopyFromSource.
+type iteratorForBulkCreateStockChanges struct {
+	rows                 []BulkCreateStockChangesParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForBulkCreateStockChanges) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForBulkCreateStockChanges) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].ID,
+		r.rows[0].StockID,
+		r.rows[0].ChangedOn,
+		r.rows[0].Field,
+		r.rows[0].OldValue,
+		r.rows[0].NewValue,
+	}, nil
+}
+
+func (r iteratorForBulkCreateStockChanges) Err() error {
+	return nil
+}
+
+func (q *Queries) BulkCreateStockChanges(ctx context.Context, arg []BulkCreateStockChangesParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"stock_changes"}, []string{"id", "stock_id", "changed_on", "field", "old_value", "new_value"}, &iteratorForBulkCreateStockChanges{rows: arg})
 }
 
 // iteratorForBulkCreateStocks implements pgx.CopyFromSource.
@@ -75,6 +303,7 @@ func (r iteratorForBulkCreateStocks) Values() ([]interface{}, error) {
 		r.rows[0].Industry,
 		r.rows[0].Isin,
 		r.rows[0].Fno,
+		r.rows[0].InstrumentType,
 	}, nil
 }
 
@@ -83,5 +312,5 @@ func (r iteratorForBulkCreateStocks) Err() error {
 }
 
 func (q *Queries) BulkCreateStocks(ctx context.Context, arg []BulkCreateStocksParams) (int64, error) {
-	return q.db.CopyFrom(ctx, []string{"stocks"}, []string{"id", "name", "symbol", "scripttype", "industry", "isin", "fno"}, &iteratorForBulkCreateStocks{rows: arg})
+	return q.db.CopyFrom(ctx, []string{"stocks"}, []string{"id", "name", "symbol", "scripttype", "industry", "isin", "fno", "instrument_type"}, &iteratorForBulkCreateStocks{rows: arg})
 }
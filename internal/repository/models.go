@@ -8,27 +8,199 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type BacktestEquity struct {
+	RunID        pgtype.UUID
+	Date         pgtype.Date
+	Equity       float64
+	PeriodReturn pgtype.Float8
+}
+
+type BacktestMetric struct {
+	RunID pgtype.UUID
+	Name  string
+	Value float64
+}
+
+type BacktestParam struct {
+	RunID pgtype.UUID
+	Key   string
+	Value string
+}
+
+type BacktestRun struct {
+	ID          pgtype.UUID
+	CreatedAt   pgtype.Timestamptz
+	Name        string
+	RunKey      string
+	StartedAt   pgtype.Timestamptz
+	FinishedAt  pgtype.Timestamptz
+	GitCommit   string
+	GitDirty    bool
+	DataRows    int64
+	DataStocks  int64
+	DataMaxDate pgtype.Date
+	Spec        string
+	DataBackend string
+	DataWindow  string
+}
+
+type BacktestTrade struct {
+	ID             pgtype.UUID
+	RunID          pgtype.UUID
+	Symbol         string
+	EntryDate      pgtype.Date
+	ExitDate       pgtype.Date
+	EntryPrice     float64
+	ExitPrice      float64
+	Quantity       float64
+	AmountUsed     float64
+	Profit         float64
+	ProfitPct      float64
+	DaysHeld       int32
+	MaxDrawdown    float64
+	Costs          float64
+	Dividends      float64
+	TotalReturnPct float64
+}
+
 type Daily struct {
+	ID             pgtype.UUID
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Stockid        pgtype.UUID
+	Open           pgtype.Numeric
+	High           pgtype.Numeric
+	Low            pgtype.Numeric
+	Close          pgtype.Numeric
+	Volume         pgtype.Int8
+	Timestamp      pgtype.Date
+	TradedValue    pgtype.Numeric
+	NumTrades      pgtype.Int8
+	DeliverableQty pgtype.Int8
+	AdjClose       pgtype.Numeric
+	Source         pgtype.Text
+}
+
+type Dividend struct {
+	StockID    pgtype.UUID
+	ExDate     pgtype.Date
+	RecordDate pgtype.Date
+	Amount     float64
+	Purpose    string
+}
+
+type FnoLotSize struct {
+	Symbol      string
+	ExpiryMonth pgtype.Date
+	LotSize     int32
+	RecordedOn  pgtype.Date
+}
+
+type FnoUnderlying struct {
+	Symbol    string
+	Name      string
+	IsIndex   bool
+	UpdatedAt pgtype.Timestamptz
+}
+
+type MfNav struct {
+	SchemeCode      int32
+	NavDate         pgtype.Date
+	Nav             float64
+	RepurchasePrice pgtype.Float8
+	SalePrice       pgtype.Float8
+}
+
+type MfScheme struct {
+	Code         int32
+	StockID      pgtype.UUID
+	Amc          string
+	Name         string
+	SchemeType   string
+	Category     string
+	IsinGrowth   string
+	IsinReinvest string
+	LaunchDate   pgtype.Date
+	UpdatedAt    pgtype.Timestamptz
+}
+
+type MonthlyClose struct {
+	Stockid  pgtype.UUID
+	Month    pgtype.Date
+	LastDate pgtype.Date
+	Close    pgtype.Numeric
+}
+
+type PaperAccount struct {
+	ID             pgtype.UUID
+	Name           string
+	Spec           string
+	StartDate      pgtype.Date
+	InitialCapital float64
+	CreatedAt      pgtype.Timestamptz
+}
+
+type PaperDay struct {
+	AccountID  pgtype.UUID
+	TradeDate  pgtype.Date
+	Cash       float64
+	Equity     float64
+	Rebalanced bool
+}
+
+type PaperFill struct {
 	ID        pgtype.UUID
+	AccountID pgtype.UUID
+	TradeDate pgtype.Date
+	Symbol    string
+	Side      string
+	Quantity  float64
+	Price     float64
+	Cost      float64
 	CreatedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
-	Stockid   pgtype.UUID
-	Open      pgtype.Numeric
-	High      pgtype.Numeric
-	Low       pgtype.Numeric
-	Close     pgtype.Numeric
-	Volume    pgtype.Int4
-	Timestamp pgtype.Date
+}
+
+type PortfolioAccount struct {
+	ID        pgtype.UUID
+	Name      string
+	Broker    string
+	CreatedAt pgtype.Timestamptz
+}
+
+type PortfolioTransaction struct {
+	ID        pgtype.UUID
+	AccountID pgtype.UUID
+	TradeDate pgtype.Date
+	Kind      string
+	Symbol    string
+	Quantity  float64
+	Price     float64
+	Amount    float64
+	Fees      float64
+	Note      string
+	CreatedAt pgtype.Timestamptz
+	Lots      string
 }
 
 type Stock struct {
-	ID         pgtype.UUID
-	CreatedAt  pgtype.Timestamptz
-	UpdatedAt  pgtype.Timestamptz
-	Name       string
-	Symbol     string
-	Scripttype string
-	Industry   pgtype.Text
-	Isin       pgtype.Text
-	Fno        bool
+	ID             pgtype.UUID
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Name           string
+	Symbol         string
+	Scripttype     string
+	Industry       pgtype.Text
+	Isin           pgtype.Text
+	Fno            bool
+	InstrumentType string
+}
+
+type StockChange struct {
+	ID        pgtype.UUID
+	StockID   pgtype.UUID
+	ChangedOn pgtype.Date
+	Field     string
+	OldValue  pgtype.Text
+	NewValue  pgtype.Text
+	CreatedAt pgtype.Timestamptz
 }
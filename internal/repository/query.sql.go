@@ -11,44 +11,311 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type BulkCreateBacktestEquityParams struct {
+	RunID        pgtype.UUID
+	Date         pgtype.Date
+	Equity       float64
+	PeriodReturn pgtype.Float8
+}
+
+type BulkCreateBacktestMetricsParams struct {
+	RunID pgtype.UUID
+	Name  string
+	Value float64
+}
+
+type BulkCreateBacktestParamsParams struct {
+	RunID pgtype.UUID
+	Key   string
+	Value string
+}
+
+type BulkCreateBacktestTradesParams struct {
+	ID             pgtype.UUID
+	RunID          pgtype.UUID
+	Symbol         string
+	EntryDate      pgtype.Date
+	ExitDate       pgtype.Date
+	EntryPrice     float64
+	ExitPrice      float64
+	Quantity       float64
+	AmountUsed     float64
+	Profit         float64
+	ProfitPct      float64
+	DaysHeld       int32
+	MaxDrawdown    float64
+	Costs          float64
+	Dividends      float64
+	TotalReturnPct float64
+}
+
 type BulkCreateDailyParams struct {
+	ID             pgtype.UUID
+	Stockid        pgtype.UUID
+	Open           pgtype.Numeric
+	High           pgtype.Numeric
+	Low            pgtype.Numeric
+	Close          pgtype.Numeric
+	Volume         pgtype.Int8
+	Timestamp      pgtype.Date
+	TradedValue    pgtype.Numeric
+	NumTrades      pgtype.Int8
+	DeliverableQty pgtype.Int8
+	AdjClose       pgtype.Numeric
+	Source         pgtype.Text
+}
+
+type BulkCreateMfNavsParams struct {
+	SchemeCode      int32
+	NavDate         pgtype.Date
+	Nav             float64
+	RepurchasePrice pgtype.Float8
+	SalePrice       pgtype.Float8
+}
+
+type BulkCreateStockChangesParams struct {
 	ID        pgtype.UUID
-	Stockid   pgtype.UUID
-	Open      pgtype.Numeric
-	High      pgtype.Numeric
-	Low       pgtype.Numeric
-	Close     pgtype.Numeric
-	Volume    pgtype.Int4
-	Timestamp pgtype.Date
+	StockID   pgtype.UUID
+	ChangedOn pgtype.Date
+	Field     string
+	OldValue  pgtype.Text
+	NewValue  pgtype.Text
 }
 
 type BulkCreateStocksParams struct {
-	ID         pgtype.UUID
-	Name       string
-	Symbol     string
-	Scripttype string
-	Industry   pgtype.Text
-	Isin       pgtype.Text
-	Fno        bool
+	ID             pgtype.UUID
+	Name           string
+	Symbol         string
+	Scripttype     string
+	Industry       pgtype.Text
+	Isin           pgtype.Text
+	Fno            bool
+	InstrumentType string
+}
+
+const createBacktestRun = `-- name: CreateBacktestRun :one
+INSERT INTO backtest_runs (
+    id, name, run_key, started_at, finished_at, git_commit, git_dirty,
+    data_rows, data_stocks, data_max_date, spec, data_backend, data_window
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+RETURNING id, created_at, name, run_key, started_at, finished_at, git_commit, git_dirty, data_rows, data_stocks, data_max_date, spec, data_backend, data_window
+`
+
+type CreateBacktestRunParams struct {
+	ID          pgtype.UUID
+	Name        string
+	RunKey      string
+	StartedAt   pgtype.Timestamptz
+	FinishedAt  pgtype.Timestamptz
+	GitCommit   string
+	GitDirty    bool
+	DataRows    int64
+	DataStocks  int64
+	DataMaxDate pgtype.Date
+	Spec        string
+	DataBackend string
+	DataWindow  string
+}
+
+func (q *Queries) CreateBacktestRun(ctx context.Context, arg CreateBacktestRunParams) (BacktestRun, error) {
+	row := q.db.QueryRow(ctx, createBacktestRun,
+		arg.ID,
+		arg.Name,
+		arg.RunKey,
+		arg.StartedAt,
+		arg.FinishedAt,
+		arg.GitCommit,
+		arg.GitDirty,
+		arg.DataRows,
+		arg.DataStocks,
+		arg.DataMaxDate,
+		arg.Spec,
+		arg.DataBackend,
+		arg.DataWindow,
+	)
+	var i BacktestRun
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Name,
+		&i.RunKey,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.GitCommit,
+		&i.GitDirty,
+		&i.DataRows,
+		&i.DataStocks,
+		&i.DataMaxDate,
+		&i.Spec,
+		&i.DataBackend,
+		&i.DataWindow,
+	)
+	return i, err
+}
+
+const createPaperAccount = `-- name: CreatePaperAccount :one
+INSERT INTO paper_accounts (id, name, spec, start_date, initial_capital)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, name, spec, start_date, initial_capital, created_at
+`
+
+type CreatePaperAccountParams struct {
+	ID             pgtype.UUID
+	Name           string
+	Spec           string
+	StartDate      pgtype.Date
+	InitialCapital float64
+}
+
+func (q *Queries) CreatePaperAccount(ctx context.Context, arg CreatePaperAccountParams) (PaperAccount, error) {
+	row := q.db.QueryRow(ctx, createPaperAccount,
+		arg.ID,
+		arg.Name,
+		arg.Spec,
+		arg.StartDate,
+		arg.InitialCapital,
+	)
+	var i PaperAccount
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Spec,
+		&i.StartDate,
+		&i.InitialCapital,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPaperFill = `-- name: CreatePaperFill :exec
+INSERT INTO paper_fills (id, account_id, trade_date, symbol, side, quantity, price, cost)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type CreatePaperFillParams struct {
+	ID        pgtype.UUID
+	AccountID pgtype.UUID
+	TradeDate pgtype.Date
+	Symbol    string
+	Side      string
+	Quantity  float64
+	Price     float64
+	Cost      float64
+}
+
+func (q *Queries) CreatePaperFill(ctx context.Context, arg CreatePaperFillParams) error {
+	_, err := q.db.Exec(ctx, createPaperFill,
+		arg.ID,
+		arg.AccountID,
+		arg.TradeDate,
+		arg.Symbol,
+		arg.Side,
+		arg.Quantity,
+		arg.Price,
+		arg.Cost,
+	)
+	return err
+}
+
+const createPortfolioAccount = `-- name: CreatePortfolioAccount :one
+INSERT INTO portfolio_accounts (id, name, broker)
+VALUES ($1, $2, $3)
+RETURNING id, name, broker, created_at
+`
+
+type CreatePortfolioAccountParams struct {
+	ID     pgtype.UUID
+	Name   string
+	Broker string
+}
+
+func (q *Queries) CreatePortfolioAccount(ctx context.Context, arg CreatePortfolioAccountParams) (PortfolioAccount, error) {
+	row := q.db.QueryRow(ctx, createPortfolioAccount, arg.ID, arg.Name, arg.Broker)
+	var i PortfolioAccount
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Broker,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPortfolioTransaction = `-- name: CreatePortfolioTransaction :one
+INSERT INTO portfolio_transactions (
+    id, account_id, trade_date, kind, symbol, quantity, price, amount, fees, note, lots
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+RETURNING id, account_id, trade_date, kind, symbol, quantity, price, amount, fees, note, created_at, lots
+`
+
+type CreatePortfolioTransactionParams struct {
+	ID        pgtype.UUID
+	AccountID pgtype.UUID
+	TradeDate pgtype.Date
+	Kind      string
+	Symbol    string
+	Quantity  float64
+	Price     float64
+	Amount    float64
+	Fees      float64
+	Note      string
+	Lots      string
+}
+
+func (q *Queries) CreatePortfolioTransaction(ctx context.Context, arg CreatePortfolioTransactionParams) (PortfolioTransaction, error) {
+	row := q.db.QueryRow(ctx, createPortfolioTransaction,
+		arg.ID,
+		arg.AccountID,
+		arg.TradeDate,
+		arg.Kind,
+		arg.Symbol,
+		arg.Quantity,
+		arg.Price,
+		arg.Amount,
+		arg.Fees,
+		arg.Note,
+		arg.Lots,
+	)
+	var i PortfolioTransaction
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.TradeDate,
+		&i.Kind,
+		&i.Symbol,
+		&i.Quantity,
+		&i.Price,
+		&i.Amount,
+		&i.Fees,
+		&i.Note,
+		&i.CreatedAt,
+		&i.Lots,
+	)
+	return i, err
 }
 
 const createStock = `-- name: CreateStock :one
 INSERT INTO stocks (
-    id, name, symbol, scriptType, industry, isin, fno
+    id, name, symbol, scriptType, industry, isin, fno, instrument_type
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8
 )
-RETURNING id, created_at, updated_at, name, symbol, scripttype, industry, isin, fno
+RETURNING id, created_at, updated_at, name, symbol, scripttype, industry, isin, fno, instrument_type
 `
 
 type CreateStockParams struct {
-	ID         pgtype.UUID
-	Name       string
-	Symbol     string
-	Scripttype string
-	Industry   pgtype.Text
-	Isin       pgtype.Text
-	Fno        bool
+	ID             pgtype.UUID
+	Name           string
+	Symbol         string
+	Scripttype     string
+	Industry       pgtype.Text
+	Isin           pgtype.Text
+	Fno            bool
+	InstrumentType string
 }
 
 func (q *Queries) CreateStock(ctx context.Context, arg CreateStockParams) (Stock, error) {
@@ -60,54 +327,649 @@ func (q *Queries) CreateStock(ctx context.Context, arg CreateStockParams) (Stock
 		arg.Industry,
 		arg.Isin,
 		arg.Fno,
+		arg.InstrumentType,
 	)
 	var i Stock
 	err := row.Scan(
 		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Symbol,
+		&i.Scripttype,
+		&i.Industry,
+		&i.Isin,
+		&i.Fno,
+		&i.InstrumentType,
+	)
+	return i, err
+}
+
+const deleteDailyBySource = `-- name: DeleteDailyBySource :exec
+DELETE FROM daily
+WHERE stockid = ANY($1::uuid[])
+  AND source = $2
+  AND timestamp >= $3::date
+  AND timestamp <= $4::date
+`
+
+type DeleteDailyBySourceParams struct {
+	StockIds []pgtype.UUID
+	Source   pgtype.Text
+	FromDate pgtype.Date
+	ToDate   pgtype.Date
+}
+
+func (q *Queries) DeleteDailyBySource(ctx context.Context, arg DeleteDailyBySourceParams) error {
+	_, err := q.db.Exec(ctx, deleteDailyBySource,
+		arg.StockIds,
+		arg.Source,
+		arg.FromDate,
+		arg.ToDate,
+	)
+	return err
+}
+
+const deleteMfNavs = `-- name: DeleteMfNavs :exec
+DELETE FROM mf_navs
+WHERE scheme_code = ANY($1::int[])
+  AND nav_date >= $2::date
+  AND nav_date <= $3::date
+`
+
+type DeleteMfNavsParams struct {
+	Codes    []int32
+	FromDate pgtype.Date
+	ToDate   pgtype.Date
+}
+
+func (q *Queries) DeleteMfNavs(ctx context.Context, arg DeleteMfNavsParams) error {
+	_, err := q.db.Exec(ctx, deleteMfNavs, arg.Codes, arg.FromDate, arg.ToDate)
+	return err
+}
+
+const deletePaperAccount = `-- name: DeletePaperAccount :execrows
+DELETE FROM paper_accounts
+WHERE name = $1
+`
+
+func (q *Queries) DeletePaperAccount(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePaperAccount, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deletePortfolioTransaction = `-- name: DeletePortfolioTransaction :execrows
+DELETE FROM portfolio_transactions
+WHERE account_id = $1 AND id = $2
+`
+
+type DeletePortfolioTransactionParams struct {
+	AccountID pgtype.UUID
+	ID        pgtype.UUID
+}
+
+func (q *Queries) DeletePortfolioTransaction(ctx context.Context, arg DeletePortfolioTransactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePortfolioTransaction, arg.AccountID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getBacktestEquity = `-- name: GetBacktestEquity :many
+SELECT date, equity, period_return FROM backtest_equity
+WHERE run_id = $1
+ORDER BY date
+`
+
+type GetBacktestEquityRow struct {
+	Date         pgtype.Date
+	Equity       float64
+	PeriodReturn pgtype.Float8
+}
+
+func (q *Queries) GetBacktestEquity(ctx context.Context, runID pgtype.UUID) ([]GetBacktestEquityRow, error) {
+	rows, err := q.db.Query(ctx, getBacktestEquity, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBacktestEquityRow
+	for rows.Next() {
+		var i GetBacktestEquityRow
+		if err := rows.Scan(&i.Date, &i.Equity, &i.PeriodReturn); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBacktestMetrics = `-- name: GetBacktestMetrics :many
+SELECT name, value FROM backtest_metrics
+WHERE run_id = $1
+ORDER BY name
+`
+
+type GetBacktestMetricsRow struct {
+	Name  string
+	Value float64
+}
+
+func (q *Queries) GetBacktestMetrics(ctx context.Context, runID pgtype.UUID) ([]GetBacktestMetricsRow, error) {
+	rows, err := q.db.Query(ctx, getBacktestMetrics, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBacktestMetricsRow
+	for rows.Next() {
+		var i GetBacktestMetricsRow
+		if err := rows.Scan(&i.Name, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBacktestParams = `-- name: GetBacktestParams :many
+SELECT key, value FROM backtest_params
+WHERE run_id = $1
+ORDER BY key
+`
+
+type GetBacktestParamsRow struct {
+	Key   string
+	Value string
+}
+
+func (q *Queries) GetBacktestParams(ctx context.Context, runID pgtype.UUID) ([]GetBacktestParamsRow, error) {
+	rows, err := q.db.Query(ctx, getBacktestParams, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBacktestParamsRow
+	for rows.Next() {
+		var i GetBacktestParamsRow
+		if err := rows.Scan(&i.Key, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBacktestRun = `-- name: GetBacktestRun :one
+SELECT id, created_at, name, run_key, started_at, finished_at, git_commit, git_dirty, data_rows, data_stocks, data_max_date, spec, data_backend, data_window FROM backtest_runs
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetBacktestRun(ctx context.Context, id pgtype.UUID) (BacktestRun, error) {
+	row := q.db.QueryRow(ctx, getBacktestRun, id)
+	var i BacktestRun
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Name,
+		&i.RunKey,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.GitCommit,
+		&i.GitDirty,
+		&i.DataRows,
+		&i.DataStocks,
+		&i.DataMaxDate,
+		&i.Spec,
+		&i.DataBackend,
+		&i.DataWindow,
+	)
+	return i, err
+}
+
+const getBacktestTrades = `-- name: GetBacktestTrades :many
+SELECT id, run_id, symbol, entry_date, exit_date, entry_price, exit_price, quantity, amount_used, profit, profit_pct, days_held, max_drawdown, costs FROM backtest_trades
+WHERE run_id = $1
+ORDER BY exit_date, symbol
+`
+
+func (q *Queries) GetBacktestTrades(ctx context.Context, runID pgtype.UUID) ([]BacktestTrade, error) {
+	rows, err := q.db.Query(ctx, getBacktestTrades, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BacktestTrade
+	for rows.Next() {
+		var i BacktestTrade
+		if err := rows.Scan(
+			&i.ID,
+			&i.RunID,
+			&i.Symbol,
+			&i.EntryDate,
+			&i.ExitDate,
+			&i.EntryPrice,
+			&i.ExitPrice,
+			&i.Quantity,
+			&i.AmountUsed,
+			&i.Profit,
+			&i.ProfitPct,
+			&i.DaysHeld,
+			&i.MaxDrawdown,
+			&i.Costs,
+			&i.Dividends,
+			&i.TotalReturnPct,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDailyBarsBetween = `-- name: GetDailyBarsBetween :many
+SELECT s.symbol, d.timestamp, d.open, d.high, d.low, d.close, d.volume
+FROM daily d
+JOIN stocks s ON d.stockid = s.id
+WHERE s.symbol = ANY($1::text[])
+  AND d.timestamp >= $2
+  AND d.timestamp <= $3
+  AND d.close IS NOT NULL
+ORDER BY s.symbol, d.timestamp
+`
+
+type GetDailyBarsBetweenParams struct {
+	Symbols   []string
+	StartDate pgtype.Date
+	EndDate   pgtype.Date
+}
+
+type GetDailyBarsBetweenRow struct {
+	Symbol    string
+	Timestamp pgtype.Date
+	Open      pgtype.Numeric
+	High      pgtype.Numeric
+	Low       pgtype.Numeric
+	Close     pgtype.Numeric
+	Volume    pgtype.Int8
+}
+
+func (q *Queries) GetDailyBarsBetween(ctx context.Context, arg GetDailyBarsBetweenParams) ([]GetDailyBarsBetweenRow, error) {
+	rows, err := q.db.Query(ctx, getDailyBarsBetween, arg.Symbols, arg.StartDate, arg.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDailyBarsBetweenRow
+	for rows.Next() {
+		var i GetDailyBarsBetweenRow
+		if err := rows.Scan(
+			&i.Symbol,
+			&i.Timestamp,
+			&i.Open,
+			&i.High,
+			&i.Low,
+			&i.Close,
+			&i.Volume,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDataFingerprint = `-- name: GetDataFingerprint :one
+SELECT
+    COUNT(*)::bigint AS row_count,
+    COUNT(DISTINCT stockid)::bigint AS stock_count,
+    MAX(timestamp)::date AS max_date
+FROM daily
+`
+
+type GetDataFingerprintRow struct {
+	RowCount   int64
+	StockCount int64
+	MaxDate    pgtype.Date
+}
+
+func (q *Queries) GetDataFingerprint(ctx context.Context) (GetDataFingerprintRow, error) {
+	row := q.db.QueryRow(ctx, getDataFingerprint)
+	var i GetDataFingerprintRow
+	err := row.Scan(&i.RowCount, &i.StockCount, &i.MaxDate)
+	return i, err
+}
+
+const getDividends = `-- name: GetDividends :many
+SELECT s.symbol, v.ex_date, v.record_date, v.amount
+FROM dividends v
+JOIN stocks s ON s.id = v.stock_id
+ORDER BY s.symbol, v.ex_date
+`
+
+type GetDividendsRow struct {
+	Symbol     string
+	ExDate     pgtype.Date
+	RecordDate pgtype.Date
+	Amount     float64
+}
+
+func (q *Queries) GetDividends(ctx context.Context) ([]GetDividendsRow, error) {
+	rows, err := q.db.Query(ctx, getDividends)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDividendsRow
+	for rows.Next() {
+		var i GetDividendsRow
+		if err := rows.Scan(
+			&i.Symbol,
+			&i.ExDate,
+			&i.RecordDate,
+			&i.Amount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFnoLotSizes = `-- name: GetFnoLotSizes :many
+SELECT l.symbol, u.is_index, l.expiry_month, l.lot_size
+FROM fno_lot_sizes l
+JOIN fno_underlyings u ON u.symbol = l.symbol
+ORDER BY l.symbol, l.expiry_month
+`
+
+type GetFnoLotSizesRow struct {
+	Symbol      string
+	IsIndex     bool
+	ExpiryMonth pgtype.Date
+	LotSize     int32
+}
+
+func (q *Queries) GetFnoLotSizes(ctx context.Context) ([]GetFnoLotSizesRow, error) {
+	rows, err := q.db.Query(ctx, getFnoLotSizes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFnoLotSizesRow
+	for rows.Next() {
+		var i GetFnoLotSizesRow
+		if err := rows.Scan(
+			&i.Symbol,
+			&i.IsIndex,
+			&i.ExpiryMonth,
+			&i.LotSize,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHistoricalStockPrices = `-- name: GetHistoricalStockPrices :many
+SELECT d.timestamp, d.close
+FROM daily d
+JOIN stocks s ON d.stockid = s.id
+WHERE s.symbol = $1
+  AND d.timestamp >= $2
+  AND d.timestamp <= $3
+  AND d.close IS NOT NULL
+ORDER BY d.timestamp
+`
+
+type GetHistoricalStockPricesParams struct {
+	Symbol      string
+	Timestamp   pgtype.Date
+	Timestamp_2 pgtype.Date
+}
+
+type GetHistoricalStockPricesRow struct {
+	Timestamp pgtype.Date
+	Close     pgtype.Numeric
+}
+
+func (q *Queries) GetHistoricalStockPrices(ctx context.Context, arg GetHistoricalStockPricesParams) ([]GetHistoricalStockPricesRow, error) {
+	rows, err := q.db.Query(ctx, getHistoricalStockPrices, arg.Symbol, arg.Timestamp, arg.Timestamp_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetHistoricalStockPricesRow
+	for rows.Next() {
+		var i GetHistoricalStockPricesRow
+		if err := rows.Scan(&i.Timestamp, &i.Close); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestClosePrice = `-- name: GetLatestClosePrice :one
+SELECT close
+FROM daily d
+JOIN stocks s ON d.stockid = s.id
+WHERE s.symbol = $1 AND d.timestamp <= $2
+AND d.close IS NOT NULL
+ORDER BY d.timestamp DESC
+LIMIT 1
+`
+
+type GetLatestClosePriceParams struct {
+	Symbol    string
+	Timestamp pgtype.Date
+}
+
+func (q *Queries) GetLatestClosePrice(ctx context.Context, arg GetLatestClosePriceParams) (pgtype.Numeric, error) {
+	row := q.db.QueryRow(ctx, getLatestClosePrice, arg.Symbol, arg.Timestamp)
+	var close pgtype.Numeric
+	err := row.Scan(&close)
+	return close, err
+}
+
+const getMfSchemes = `-- name: GetMfSchemes :many
+SELECT code, stock_id, amc, name, scheme_type, category, isin_growth, isin_reinvest, launch_date, updated_at FROM mf_schemes
+ORDER BY code
+`
+
+func (q *Queries) GetMfSchemes(ctx context.Context) ([]MfScheme, error) {
+	rows, err := q.db.Query(ctx, getMfSchemes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MfScheme
+	for rows.Next() {
+		var i MfScheme
+		if err := rows.Scan(
+			&i.Code,
+			&i.StockID,
+			&i.Amc,
+			&i.Name,
+			&i.SchemeType,
+			&i.Category,
+			&i.IsinGrowth,
+			&i.IsinReinvest,
+			&i.LaunchDate,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPaperAccountByName = `-- name: GetPaperAccountByName :one
+SELECT id, name, spec, start_date, initial_capital, created_at FROM paper_accounts
+WHERE name = $1 LIMIT 1
+`
+
+func (q *Queries) GetPaperAccountByName(ctx context.Context, name string) (PaperAccount, error) {
+	row := q.db.QueryRow(ctx, getPaperAccountByName, name)
+	var i PaperAccount
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Spec,
+		&i.StartDate,
+		&i.InitialCapital,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPaperDays = `-- name: GetPaperDays :many
+SELECT account_id, trade_date, cash, equity, rebalanced FROM paper_days
+WHERE account_id = $1
+ORDER BY trade_date
+`
+
+func (q *Queries) GetPaperDays(ctx context.Context, accountID pgtype.UUID) ([]PaperDay, error) {
+	rows, err := q.db.Query(ctx, getPaperDays, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaperDay
+	for rows.Next() {
+		var i PaperDay
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.TradeDate,
+			&i.Cash,
+			&i.Equity,
+			&i.Rebalanced,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPaperFills = `-- name: GetPaperFills :many
+SELECT id, account_id, trade_date, symbol, side, quantity, price, cost, created_at FROM paper_fills
+WHERE account_id = $1
+ORDER BY trade_date, created_at, id
+`
+
+func (q *Queries) GetPaperFills(ctx context.Context, accountID pgtype.UUID) ([]PaperFill, error) {
+	rows, err := q.db.Query(ctx, getPaperFills, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaperFill
+	for rows.Next() {
+		var i PaperFill
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.TradeDate,
+			&i.Symbol,
+			&i.Side,
+			&i.Quantity,
+			&i.Price,
+			&i.Cost,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPortfolioAccountByName = `-- name: GetPortfolioAccountByName :one
+SELECT id, name, broker, created_at FROM portfolio_accounts
+WHERE name = $1 LIMIT 1
+`
+
+func (q *Queries) GetPortfolioAccountByName(ctx context.Context, name string) (PortfolioAccount, error) {
+	row := q.db.QueryRow(ctx, getPortfolioAccountByName, name)
+	var i PortfolioAccount
+	err := row.Scan(
+		&i.ID,
 		&i.Name,
-		&i.Symbol,
-		&i.Scripttype,
-		&i.Industry,
-		&i.Isin,
-		&i.Fno,
+		&i.Broker,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getHistoricalStockPrices = `-- name: GetHistoricalStockPrices :many
-SELECT d.timestamp, d.close
-FROM daily d
-JOIN stocks s ON d.stockid = s.id
-WHERE s.symbol = $1
-  AND d.timestamp >= $2
-  AND d.timestamp <= $3
-  AND d.close IS NOT NULL
-ORDER BY d.timestamp
+const getPortfolioTransactions = `-- name: GetPortfolioTransactions :many
+SELECT id, account_id, trade_date, kind, symbol, quantity, price, amount, fees, note, created_at, lots FROM portfolio_transactions
+WHERE account_id = $1
+ORDER BY trade_date, created_at, id
 `
 
-type GetHistoricalStockPricesParams struct {
-	Symbol      string
-	Timestamp   pgtype.Date
-	Timestamp_2 pgtype.Date
-}
-
-type GetHistoricalStockPricesRow struct {
-	Timestamp pgtype.Date
-	Close     pgtype.Numeric
-}
-
-func (q *Queries) GetHistoricalStockPrices(ctx context.Context, arg GetHistoricalStockPricesParams) ([]GetHistoricalStockPricesRow, error) {
-	rows, err := q.db.Query(ctx, getHistoricalStockPrices, arg.Symbol, arg.Timestamp, arg.Timestamp_2)
+func (q *Queries) GetPortfolioTransactions(ctx context.Context, accountID pgtype.UUID) ([]PortfolioTransaction, error) {
+	rows, err := q.db.Query(ctx, getPortfolioTransactions, accountID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetHistoricalStockPricesRow
+	var items []PortfolioTransaction
 	for rows.Next() {
-		var i GetHistoricalStockPricesRow
-		if err := rows.Scan(&i.Timestamp, &i.Close); err != nil {
+		var i PortfolioTransaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.TradeDate,
+			&i.Kind,
+			&i.Symbol,
+			&i.Quantity,
+			&i.Price,
+			&i.Amount,
+			&i.Fees,
+			&i.Note,
+			&i.CreatedAt,
+			&i.Lots,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -118,30 +980,39 @@ func (q *Queries) GetHistoricalStockPrices(ctx context.Context, arg GetHistorica
 	return items, nil
 }
 
-const getLatestClosePrice = `-- name: GetLatestClosePrice :one
-SELECT close
-FROM daily d
-JOIN stocks s ON d.stockid = s.id
-WHERE s.symbol = $1 AND d.timestamp <= $2
-AND d.close IS NOT NULL
-ORDER BY d.timestamp DESC
-LIMIT 1
+const getRemovedStockIDs = `-- name: GetRemovedStockIDs :many
+SELECT c.stock_id
+FROM (
+    SELECT DISTINCT ON (stock_id) stock_id, field
+    FROM stock_changes
+    WHERE field IN ('added', 'removed')
+    ORDER BY stock_id, changed_on DESC, created_at DESC
+) c
+WHERE c.field = 'removed'
 `
 
-type GetLatestClosePriceParams struct {
-	Symbol    string
-	Timestamp pgtype.Date
-}
-
-func (q *Queries) GetLatestClosePrice(ctx context.Context, arg GetLatestClosePriceParams) (pgtype.Numeric, error) {
-	row := q.db.QueryRow(ctx, getLatestClosePrice, arg.Symbol, arg.Timestamp)
-	var close pgtype.Numeric
-	err := row.Scan(&close)
-	return close, err
+func (q *Queries) GetRemovedStockIDs(ctx context.Context) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, getRemovedStockIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var stock_id pgtype.UUID
+		if err := rows.Scan(&stock_id); err != nil {
+			return nil, err
+		}
+		items = append(items, stock_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getStock = `-- name: GetStock :one
-SELECT id, created_at, updated_at, name, symbol, scripttype, industry, isin, fno FROM stocks
+SELECT id, created_at, updated_at, name, symbol, scripttype, industry, isin, fno, instrument_type FROM stocks
 WHERE id = $1 LIMIT 1
 `
 
@@ -158,12 +1029,55 @@ func (q *Queries) GetStock(ctx context.Context, id pgtype.UUID) (Stock, error) {
 		&i.Industry,
 		&i.Isin,
 		&i.Fno,
+		&i.InstrumentType,
 	)
 	return i, err
 }
 
+const getStockChanges = `-- name: GetStockChanges :many
+SELECT s.symbol, c.changed_on, c.field, c.old_value, c.new_value
+FROM stock_changes c
+JOIN stocks s ON c.stock_id = s.id
+WHERE c.changed_on >= $1::date
+ORDER BY c.changed_on, s.symbol, c.field
+`
+
+type GetStockChangesRow struct {
+	Symbol    string
+	ChangedOn pgtype.Date
+	Field     string
+	OldValue  pgtype.Text
+	NewValue  pgtype.Text
+}
+
+func (q *Queries) GetStockChanges(ctx context.Context, since pgtype.Date) ([]GetStockChangesRow, error) {
+	rows, err := q.db.Query(ctx, getStockChanges, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStockChangesRow
+	for rows.Next() {
+		var i GetStockChangesRow
+		if err := rows.Scan(
+			&i.Symbol,
+			&i.ChangedOn,
+			&i.Field,
+			&i.OldValue,
+			&i.NewValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getStocks = `-- name: GetStocks :many
-SELECT id, created_at, updated_at, name, symbol, scripttype, industry, isin, fno FROM stocks
+SELECT id, created_at, updated_at, name, symbol, scripttype, industry, isin, fno, instrument_type FROM stocks
 ORDER BY name
 `
 
@@ -186,6 +1100,7 @@ func (q *Queries) GetStocks(ctx context.Context) ([]Stock, error) {
 			&i.Industry,
 			&i.Isin,
 			&i.Fno,
+			&i.InstrumentType,
 		); err != nil {
 			return nil, err
 		}
@@ -198,49 +1113,55 @@ func (q *Queries) GetStocks(ctx context.Context) ([]Stock, error) {
 }
 
 const getTopStocksByReturn = `-- name: GetTopStocksByReturn :many
-WITH one_year_ago_prices AS (
-    SELECT DISTINCT ON (d.stockid)
-        d.stockid,
-        d.close
-    FROM
-        daily d
-    JOIN stocks s ON d.stockid = s.id
-    WHERE
-        d.timestamp <= ($1::timestamp - make_interval(months => $2::int))
-        AND d.close IS NOT NULL
-        AND d.close != 0
-        AND s.scriptType = ANY($3::text[])
-    ORDER BY d.stockid, d.timestamp DESC
-),
-latest_prices AS (
-    SELECT DISTINCT ON (d.stockid)
-        d.stockid,
-        d.close
-    FROM
-        daily d
-    JOIN stocks s ON d.stockid = s.id
-    WHERE
-        d.timestamp <= $1::timestamp
-        AND d.close IS NOT NULL
-        AND d.close != 0
-        AND s.scriptType = ANY($3::text[])
-    ORDER BY d.stockid, d.timestamp DESC
-),
-stock_returns AS (
-    SELECT
-        l.stockid,
-        ROUND((l.close - o.close) / o.close * 100)::int AS return_percentage
-    FROM latest_prices l
-    JOIN one_year_ago_prices o ON l.stockid = o.stockid
-)
 SELECT
     s.id,
     s.name,
     s.symbol,
-    sr.return_percentage
-FROM stock_returns sr
-JOIN stocks s ON sr.stockid = s.id
-ORDER BY sr.return_percentage DESC
+    ROUND((l.close - o.close) / o.close * 100)::int AS return_percentage
+FROM stocks s
+CROSS JOIN LATERAL (
+    SELECT d.close
+    FROM daily d
+    WHERE
+        d.stockid = s.id
+        AND d.timestamp <= $1::timestamp
+        AND d.close IS NOT NULL
+        AND d.close != 0
+    ORDER BY d.timestamp DESC
+    LIMIT 1
+) l
+CROSS JOIN LATERAL (
+    SELECT c.close
+    FROM (
+        (
+            SELECT d.timestamp, d.close
+            FROM daily d
+            WHERE
+                d.stockid = s.id
+                AND d.timestamp <= ($1::timestamp - make_interval(months => $2::int))
+                AND d.timestamp >= date_trunc('month', $1::timestamp - make_interval(months => $2::int))
+                AND d.close IS NOT NULL
+                AND d.close != 0
+            ORDER BY d.timestamp DESC
+            LIMIT 1
+        )
+        UNION ALL
+        (
+            SELECT m.last_date, m.close
+            FROM monthly_closes m
+            WHERE
+                m.stockid = s.id
+                AND m.month < date_trunc('month', $1::timestamp - make_interval(months => $2::int))
+            ORDER BY m.month DESC
+            LIMIT 1
+        )
+    ) c
+    ORDER BY c.timestamp DESC
+    LIMIT 1
+) o
+WHERE s.scriptType = ANY($3::text[])
+  AND (COALESCE(cardinality($5::text[]), 0) = 0 OR lower(s.industry) = ANY($5::text[]))
+ORDER BY return_percentage DESC, s.symbol
 LIMIT $4
 `
 
@@ -249,6 +1170,7 @@ type GetTopStocksByReturnParams struct {
 	Column2 int32
 	Column3 []string
 	Limit   int32
+	Column5 []string
 }
 
 type GetTopStocksByReturnRow struct {
@@ -264,6 +1186,7 @@ func (q *Queries) GetTopStocksByReturn(ctx context.Context, arg GetTopStocksByRe
 		arg.Column2,
 		arg.Column3,
 		arg.Limit,
+		arg.Column5,
 	)
 	if err != nil {
 		return nil, err
@@ -287,3 +1210,320 @@ func (q *Queries) GetTopStocksByReturn(ctx context.Context, arg GetTopStocksByRe
 	}
 	return items, nil
 }
+
+const getTradingDays = `-- name: GetTradingDays :many
+SELECT DISTINCT d.timestamp::date AS trading_day
+FROM daily d
+WHERE d.timestamp > $1::date
+  AND d.timestamp <= $2::date
+  AND d.close IS NOT NULL
+ORDER BY trading_day
+`
+
+type GetTradingDaysParams struct {
+	After pgtype.Date
+	Until pgtype.Date
+}
+
+func (q *Queries) GetTradingDays(ctx context.Context, arg GetTradingDaysParams) ([]pgtype.Date, error) {
+	rows, err := q.db.Query(ctx, getTradingDays, arg.After, arg.Until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Date
+	for rows.Next() {
+		var trading_day pgtype.Date
+		if err := rows.Scan(&trading_day); err != nil {
+			return nil, err
+		}
+		items = append(items, trading_day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBacktestRuns = `-- name: ListBacktestRuns :many
+SELECT id, created_at, name, run_key, started_at, finished_at, git_commit, git_dirty, data_rows, data_stocks, data_max_date, spec, data_backend, data_window FROM backtest_runs
+ORDER BY started_at DESC
+LIMIT $1
+`
+
+func (q *Queries) ListBacktestRuns(ctx context.Context, limit int32) ([]BacktestRun, error) {
+	rows, err := q.db.Query(ctx, listBacktestRuns, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BacktestRun
+	for rows.Next() {
+		var i BacktestRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Name,
+			&i.RunKey,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.GitCommit,
+			&i.GitDirty,
+			&i.DataRows,
+			&i.DataStocks,
+			&i.DataMaxDate,
+			&i.Spec,
+			&i.DataBackend,
+			&i.DataWindow,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaperAccounts = `-- name: ListPaperAccounts :many
+SELECT id, name, spec, start_date, initial_capital, created_at FROM paper_accounts
+ORDER BY name
+`
+
+func (q *Queries) ListPaperAccounts(ctx context.Context) ([]PaperAccount, error) {
+	rows, err := q.db.Query(ctx, listPaperAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaperAccount
+	for rows.Next() {
+		var i PaperAccount
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Spec,
+			&i.StartDate,
+			&i.InitialCapital,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPortfolioAccounts = `-- name: ListPortfolioAccounts :many
+SELECT id, name, broker, created_at FROM portfolio_accounts
+ORDER BY name
+`
+
+func (q *Queries) ListPortfolioAccounts(ctx context.Context) ([]PortfolioAccount, error) {
+	rows, err := q.db.Query(ctx, listPortfolioAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PortfolioAccount
+	for rows.Next() {
+		var i PortfolioAccount
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Broker,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const refreshMonthlyCloses = `-- name: RefreshMonthlyCloses :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY monthly_closes
+`
+
+func (q *Queries) RefreshMonthlyCloses(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, refreshMonthlyCloses)
+	return err
+}
+
+const updateStock = `-- name: UpdateStock :exec
+UPDATE stocks
+SET name = $2, symbol = $3, scriptType = $4, industry = $5, fno = $6, instrument_type = $7, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdateStockParams struct {
+	ID             pgtype.UUID
+	Name           string
+	Symbol         string
+	Scripttype     string
+	Industry       pgtype.Text
+	Fno            bool
+	InstrumentType string
+}
+
+func (q *Queries) UpdateStock(ctx context.Context, arg UpdateStockParams) error {
+	_, err := q.db.Exec(ctx, updateStock,
+		arg.ID,
+		arg.Name,
+		arg.Symbol,
+		arg.Scripttype,
+		arg.Industry,
+		arg.Fno,
+		arg.InstrumentType,
+	)
+	return err
+}
+
+const upsertDividend = `-- name: UpsertDividend :exec
+INSERT INTO dividends (stock_id, ex_date, record_date, amount, purpose)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (stock_id, ex_date) DO UPDATE
+SET record_date = EXCLUDED.record_date, amount = EXCLUDED.amount, purpose = EXCLUDED.purpose
+`
+
+type UpsertDividendParams struct {
+	StockID    pgtype.UUID
+	ExDate     pgtype.Date
+	RecordDate pgtype.Date
+	Amount     float64
+	Purpose    string
+}
+
+func (q *Queries) UpsertDividend(ctx context.Context, arg UpsertDividendParams) error {
+	_, err := q.db.Exec(ctx, upsertDividend,
+		arg.StockID,
+		arg.ExDate,
+		arg.RecordDate,
+		arg.Amount,
+		arg.Purpose,
+	)
+	return err
+}
+
+const upsertFnoLotSize = `-- name: UpsertFnoLotSize :one
+WITH previous AS (
+    SELECT lot_size FROM fno_lot_sizes
+    WHERE symbol = $1 AND expiry_month = $2
+)
+INSERT INTO fno_lot_sizes (symbol, expiry_month, lot_size, recorded_on)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (symbol, expiry_month) DO UPDATE
+SET lot_size = EXCLUDED.lot_size, recorded_on = EXCLUDED.recorded_on
+RETURNING COALESCE((SELECT lot_size FROM previous), 0)::int AS previous_lot_size
+`
+
+type UpsertFnoLotSizeParams struct {
+	Symbol      string
+	ExpiryMonth pgtype.Date
+	LotSize     int32
+	RecordedOn  pgtype.Date
+}
+
+// Returns the lot size stored before, 0 for a new month.
+func (q *Queries) UpsertFnoLotSize(ctx context.Context, arg UpsertFnoLotSizeParams) (int32, error) {
+	row := q.db.QueryRow(ctx, upsertFnoLotSize,
+		arg.Symbol,
+		arg.ExpiryMonth,
+		arg.LotSize,
+		arg.RecordedOn,
+	)
+	var previous_lot_size int32
+	err := row.Scan(&previous_lot_size)
+	return previous_lot_size, err
+}
+
+const upsertFnoUnderlying = `-- name: UpsertFnoUnderlying :exec
+INSERT INTO fno_underlyings (symbol, name, is_index)
+VALUES ($1, $2, $3)
+ON CONFLICT (symbol) DO UPDATE
+SET name = EXCLUDED.name, is_index = EXCLUDED.is_index, updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertFnoUnderlyingParams struct {
+	Symbol  string
+	Name    string
+	IsIndex bool
+}
+
+func (q *Queries) UpsertFnoUnderlying(ctx context.Context, arg UpsertFnoUnderlyingParams) error {
+	_, err := q.db.Exec(ctx, upsertFnoUnderlying, arg.Symbol, arg.Name, arg.IsIndex)
+	return err
+}
+
+const upsertMfScheme = `-- name: UpsertMfScheme :exec
+INSERT INTO mf_schemes (
+    code, stock_id, amc, name, scheme_type, category, isin_growth, isin_reinvest, launch_date
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (code) DO UPDATE
+SET amc = EXCLUDED.amc, name = EXCLUDED.name, scheme_type = EXCLUDED.scheme_type,
+    category = EXCLUDED.category, isin_growth = EXCLUDED.isin_growth,
+    isin_reinvest = EXCLUDED.isin_reinvest, launch_date = EXCLUDED.launch_date,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertMfSchemeParams struct {
+	Code         int32
+	StockID      pgtype.UUID
+	Amc          string
+	Name         string
+	SchemeType   string
+	Category     string
+	IsinGrowth   string
+	IsinReinvest string
+	LaunchDate   pgtype.Date
+}
+
+func (q *Queries) UpsertMfScheme(ctx context.Context, arg UpsertMfSchemeParams) error {
+	_, err := q.db.Exec(ctx, upsertMfScheme,
+		arg.Code,
+		arg.StockID,
+		arg.Amc,
+		arg.Name,
+		arg.SchemeType,
+		arg.Category,
+		arg.IsinGrowth,
+		arg.IsinReinvest,
+		arg.LaunchDate,
+	)
+	return err
+}
+
+const upsertPaperDay = `-- name: UpsertPaperDay :exec
+INSERT INTO paper_days (account_id, trade_date, cash, equity, rebalanced)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (account_id, trade_date) DO UPDATE
+SET cash = EXCLUDED.cash, equity = EXCLUDED.equity, rebalanced = EXCLUDED.rebalanced
+`
+
+type UpsertPaperDayParams struct {
+	AccountID  pgtype.UUID
+	TradeDate  pgtype.Date
+	Cash       float64
+	Equity     float64
+	Rebalanced bool
+}
+
+func (q *Queries) UpsertPaperDay(ctx context.Context, arg UpsertPaperDayParams) error {
+	_, err := q.db.Exec(ctx, upsertPaperDay,
+		arg.AccountID,
+		arg.TradeDate,
+		arg.Cash,
+		arg.Equity,
+		arg.Rebalanced,
+	)
+	return err
+}
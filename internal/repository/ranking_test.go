@@ -0,0 +1,189 @@
+package repository_test
+
+import (
+	"context"
+	"fund-manager/internal/repository"
+	"fund-manager/internal/synthetic"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// scanRanking is GetTopStocksByReturn as it was before the indexes and
+// monthly_closes: two DISTINCT ON passes over daily.
+const scanRanking = `
+WITH one_year_ago_prices AS (
+    SELECT DISTINCT ON (d.stockid) d.stockid, d.close
+    FROM daily d
+    JOIN stocks s ON d.stockid = s.id
+    WHERE d.timestamp <= ($1::timestamp - make_interval(months => $2::int))
+        AND d.close IS NOT NULL AND d.close != 0
+        AND s.scriptType = ANY($3::text[])
+    ORDER BY d.stockid, d.timestamp DESC
+),
+latest_prices AS (
+    SELECT DISTINCT ON (d.stockid) d.stockid, d.close
+    FROM daily d
+    JOIN stocks s ON d.stockid = s.id
+    WHERE d.timestamp <= $1::timestamp
+        AND d.close IS NOT NULL AND d.close != 0
+        AND s.scriptType = ANY($3::text[])
+    ORDER BY d.stockid, d.timestamp DESC
+),
+stock_returns AS (
+    SELECT l.stockid, ROUND((l.close - o.close) / o.close * 100)::int AS return_percentage
+    FROM latest_prices l
+    JOIN one_year_ago_prices o ON l.stockid = o.stockid
+)
+SELECT s.id, s.name, s.symbol, sr.return_percentage
+FROM stock_returns sr
+JOIN stocks s ON sr.stockid = s.id
+ORDER BY sr.return_percentage DESC
+LIMIT $4`
+
+var market = synthetic.Config{
+	Seed: 11, Stocks: 400, ScriptTypes: []string{"large", "mid", "small"},
+	Start: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC),
+	End:   time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+	Drift: 0.1, DriftSpread: 0.2, Volatility: 0.3,
+	Delistings: 20, GapProb: 0.02, ZeroPrices: 50,
+}
+
+// withMarket loads the synthetic market into a scratch schema built from
+// sql/schema.sql on the database in POSTGRES, inside a transaction that is
+// rolled back afterwards.
+func withMarket(tb testing.TB, fn func(ctx context.Context, tx pgx.Tx)) {
+	url := os.Getenv("POSTGRES")
+	if url == "" {
+		tb.Skip("POSTGRES not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer pool.Close()
+
+	schema, err := os.ReadFile("../../sql/schema.sql")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	m, err := synthetic.Generate(market)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "CREATE SCHEMA fundmgr_bench; SET LOCAL search_path TO fundmgr_bench"); err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := tx.Exec(ctx, string(schema)); err != nil {
+		tb.Fatal(err)
+	}
+	if err := m.LoadPostgres(ctx, repository.New(tx)); err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := tx.Exec(ctx, "ANALYZE stocks; ANALYZE daily; ANALYZE monthly_closes"); err != nil {
+		tb.Fatal(err)
+	}
+	fn(ctx, tx)
+}
+
+func rankingParams(date time.Time, limit int32) repository.GetTopStocksByReturnParams {
+	return repository.GetTopStocksByReturnParams{
+		Column1: pgtype.Timestamp{Time: date, Valid: true},
+		Column2: 12,
+		Column3: market.ScriptTypes,
+		Limit:   limit,
+	}
+}
+
+func scan(ctx context.Context, db repository.DBTX, arg repository.GetTopStocksByReturnParams) (map[string]int32, error) {
+	rows, err := db.Query(ctx, scanRanking, arg.Column1, arg.Column2, arg.Column3, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	returns := make(map[string]int32)
+	for rows.Next() {
+		var r repository.GetTopStocksByReturnRow
+		if err := rows.Scan(&r.ID, &r.Name, &r.Symbol, &r.ReturnPercentage); err != nil {
+			return nil, err
+		}
+		returns[r.Symbol] = r.ReturnPercentage
+	}
+	return returns, rows.Err()
+}
+
+// The rewritten ranking must return what the full scan returned, including
+// on lookback dates inside a month, at month ends and before any data.
+func TestTopStocksByReturnMatchesScan(t *testing.T) {
+	withMarket(t, func(ctx context.Context, tx pgx.Tx) {
+		q := repository.New(tx)
+		for _, date := range []string{"2015-06-30", "2016-01-01", "2016-02-29", "2019-07-17", "2022-03-31", "2024-12-31"} {
+			d, _ := time.Parse("2006-01-02", date)
+			arg := rankingParams(d, 1000)
+			want, err := scan(ctx, tx, arg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := q.GetTopStocksByReturn(ctx, arg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != len(want) {
+				t.Errorf("%s: got %d stocks, want %d", date, len(rows), len(want))
+			}
+			for _, r := range rows {
+				if ret, ok := want[r.Symbol]; !ok || ret != r.ReturnPercentage {
+					t.Errorf("%s: %s returned %d, want %d (present %v)", date, r.Symbol, r.ReturnPercentage, ret, ok)
+				}
+			}
+		}
+	})
+}
+
+// BenchmarkTopStocksByReturn ranks the top 30 of the synthetic market with
+// the old scan on a daily table without the new indexes ("before") and with
+// the current query ("after"):
+//
+//	POSTGRES=postgres://... go test ./internal/repository -run '^$' -bench TopStocksByReturn
+func BenchmarkTopStocksByReturn(b *testing.B) {
+	withMarket(b, func(ctx context.Context, tx pgx.Tx) {
+		arg := rankingParams(market.End, 30)
+
+		b.Run("before", func(b *testing.B) {
+			sp, err := tx.Begin(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer sp.Rollback(ctx)
+			if _, err := sp.Exec(ctx, "DROP INDEX daily_stockid_timestamp_idx; DROP INDEX stocks_scripttype_idx"); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := scan(ctx, sp, arg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run("after", func(b *testing.B) {
+			q := repository.New(tx)
+			for i := 0; i < b.N; i++ {
+				if _, err := q.GetTopStocksByReturn(ctx, arg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,237 @@
+// Package returns measures the performance of an account that money moves
+// in and out of, from its dated cash flows and valuations.
+package returns
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// CashFlow is money put into the account (positive) or taken out of it
+// (negative) on a date.
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// Valuation is the account's value at the end of a day, after that day's
+// cash flows.
+type Valuation struct {
+	Date  time.Time
+	Value float64
+}
+
+const daysPerYear = 365.0
+
+func years(from, to time.Time) float64 {
+	return to.Sub(from).Hours() / 24 / daysPerYear
+}
+
+func sortFlows(flows []CashFlow) []CashFlow {
+	sorted := append([]CashFlow(nil), flows...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+	return sorted
+}
+
+func sortValuations(valuations []Valuation) []Valuation {
+	sorted := append([]Valuation(nil), valuations...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+	return sorted
+}
+
+// XIRR is the annualised rate at which the flows grow into the final
+// value: the rate that makes the net present value of the flows, seen
+// from the investor, and the final value zero.
+func XIRR(flows []CashFlow, final Valuation) (float64, error) {
+	flows = sortFlows(flows)
+	if len(flows) == 0 {
+		return 0, fmt.Errorf("no cash flows")
+	}
+	invested := false
+	for _, f := range flows {
+		invested = invested || f.Amount > 0
+	}
+	if !invested {
+		return 0, fmt.Errorf("no money was put in")
+	}
+	start := flows[0].Date
+	npv := func(rate float64) (value, slope float64) {
+		for _, f := range flows {
+			t := years(start, f.Date)
+			value -= f.Amount / math.Pow(1+rate, t)
+			slope += t * f.Amount / math.Pow(1+rate, t+1)
+		}
+		t := years(start, final.Date)
+		value += final.Value / math.Pow(1+rate, t)
+		slope -= t * final.Value / math.Pow(1+rate, t+1)
+		return value, slope
+	}
+
+	// Newton from 10%, falling back to bisection when it wanders off
+	rate := 0.1
+	for i := 0; i < 50; i++ {
+		value, slope := npv(rate)
+		if math.Abs(value) < 1e-7 {
+			return rate, nil
+		}
+		if slope == 0 {
+			break
+		}
+		next := rate - value/slope
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < 1e-12 {
+			return next, nil
+		}
+		rate = next
+	}
+
+	lo, hi := -0.999999, 1.0
+	for v, _ := npv(hi); v > 0 && hi < 1e6; v, _ = npv(hi) {
+		hi *= 2
+	}
+	vlo, _ := npv(lo)
+	vhi, _ := npv(hi)
+	if (vlo > 0) == (vhi > 0) {
+		return 0, fmt.Errorf("no rate between %.0f%% and %.0f%% matches the flows", lo*100, hi*100)
+	}
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		v, _ := npv(mid)
+		if (v > 0) == (vlo > 0) {
+			lo, vlo = mid, v
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2, nil
+}
+
+// dietz is the Modified Dietz return from start to end: the gain over the
+// opening value plus the flows weighted by the share of the period they
+// were invested for. Flows on the end date carry no weight, unless the
+// period is a single day.
+func dietz(start, end Valuation, flows []CashFlow) (float64, error) {
+	span := end.Date.Sub(start.Date).Hours()
+	net, weighted := 0.0, 0.0
+	for _, f := range flows {
+		net += f.Amount
+		if span > 0 {
+			weighted += f.Amount * end.Date.Sub(f.Date).Hours() / span
+		} else {
+			weighted += f.Amount
+		}
+	}
+	base := start.Value + weighted
+	if base <= 0 {
+		return 0, fmt.Errorf("nothing invested from %s to %s", start.Date.Format("2006-01-02"), end.Date.Format("2006-01-02"))
+	}
+	return (end.Value - start.Value - net) / base, nil
+}
+
+// TWR is the time-weighted return over the valuations: the returns of the
+// sub-periods between consecutive valuations, linked by compounding, so
+// that the size and timing of the flows does not count. Flows between two
+// valuations enter their sub-period Modified Dietz style; value the
+// account on every flow date for the exact figure. Flows on or before the
+// first valuation are part of its value.
+func TWR(flows []CashFlow, valuations []Valuation) (float64, error) {
+	flows = sortFlows(flows)
+	valuations = sortValuations(valuations)
+	if len(valuations) < 2 {
+		return 0, fmt.Errorf("need at least two valuations")
+	}
+	growth := 1.0
+	i := 0
+	for i < len(flows) && !flows[i].Date.After(valuations[0].Date) {
+		i++
+	}
+	for k := 1; k < len(valuations); k++ {
+		var period []CashFlow
+		for i < len(flows) && !flows[i].Date.After(valuations[k].Date) {
+			period = append(period, flows[i])
+			i++
+		}
+		start := valuations[k-1]
+		if start.Value == 0 {
+			if len(period) == 0 {
+				continue
+			}
+			// money put into an empty account starts the sub-period
+			start = Valuation{Date: period[0].Date}
+		}
+		r, err := dietz(start, valuations[k], period)
+		if err != nil {
+			return 0, err
+		}
+		growth *= 1 + r
+	}
+	return growth - 1, nil
+}
+
+// MWR is the money-weighted return over the whole period, Modified Dietz
+// on the first flow date: gains over the money invested, each flow
+// weighted by how long it was in. XIRR is its exact, annualised
+// counterpart.
+func MWR(flows []CashFlow, final Valuation) (float64, error) {
+	flows = sortFlows(flows)
+	if len(flows) == 0 {
+		return 0, fmt.Errorf("no cash flows")
+	}
+	return dietz(Valuation{Date: flows[0].Date}, final, flows)
+}
+
+// Annualise turns a return over from..to into a yearly rate.
+func Annualise(r float64, from, to time.Time) float64 {
+	y := years(from, to)
+	if y <= 0 || r <= -1 {
+		return 0
+	}
+	return math.Pow(1+r, 1/y) - 1
+}
+
+// Summary is the performance of an account from its first flow to its
+// last valuation. Rates are fractions.
+type Summary struct {
+	Start     time.Time
+	End       time.Time
+	Invested  float64 // net of withdrawals
+	Final     float64
+	XIRR      float64 // annualised
+	TWR       float64 // over the period
+	TWRAnnual float64
+	MWR       float64 // over the period
+}
+
+func Summarize(flows []CashFlow, valuations []Valuation) (Summary, error) {
+	flows = sortFlows(flows)
+	valuations = sortValuations(valuations)
+	if len(flows) == 0 || len(valuations) == 0 {
+		return Summary{}, fmt.Errorf("need cash flows and valuations")
+	}
+	final := valuations[len(valuations)-1]
+	s := Summary{Start: flows[0].Date, End: final.Date, Final: final.Value}
+	for _, f := range flows {
+		s.Invested += f.Amount
+	}
+	var err error
+	if s.XIRR, err = XIRR(flows, final); err != nil {
+		return s, err
+	}
+	if s.MWR, err = MWR(flows, final); err != nil {
+		return s, err
+	}
+	// the account is empty before the first flow, so that the first day's
+	// return counts as well
+	if first := valuations[0]; !first.Date.Before(s.Start) {
+		valuations = append([]Valuation{{Date: s.Start.AddDate(0, 0, -1)}}, valuations...)
+	}
+	if s.TWR, err = TWR(flows, valuations); err != nil {
+		return s, err
+	}
+	s.TWRAnnual = Annualise(s.TWR, s.Start, s.End)
+	return s, nil
+}
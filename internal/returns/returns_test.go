@@ -0,0 +1,79 @@
+package returns
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) < tolerance
+}
+
+func TestXIRR(t *testing.T) {
+	// the spreadsheet XIRR example: 10000 in, four amounts back
+	flows := []CashFlow{
+		{day("2008-01-01"), 10000},
+		{day("2008-03-01"), -2750},
+		{day("2008-10-30"), -4250},
+		{day("2009-02-15"), -3250},
+	}
+	r, err := XIRR(flows, Valuation{day("2009-04-01"), 2750})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(r, 0.373362535, 1e-6) {
+		t.Fatalf("xirr %.9f", r)
+	}
+
+	r, err = XIRR([]CashFlow{{day("2020-01-01"), 100}}, Valuation{day("2021-12-31"), 50})
+	if err != nil || !near(r, math.Pow(0.5, 365.0/730)-1, 1e-9) {
+		t.Fatalf("losing xirr %.9f, %v", r, err)
+	}
+	if _, err := XIRR([]CashFlow{{day("2020-01-01"), -100}}, Valuation{day("2021-01-01"), 0}); err == nil {
+		t.Fatal("withdrawals only accepted")
+	}
+}
+
+func TestTWRIgnoresFlowTiming(t *testing.T) {
+	// 100 doubles, 200 more goes in at the top, then everything halves
+	flows := []CashFlow{{day("2024-01-01"), 100}, {day("2024-07-01"), 200}}
+	valuations := []Valuation{{day("2024-01-01"), 100}, {day("2024-07-01"), 400}, {day("2024-12-31"), 200}}
+	twr, err := TWR(flows, valuations)
+	if err != nil || !near(twr, 0, 1e-12) {
+		t.Fatalf("twr %.6f, %v", twr, err)
+	}
+	mwr, err := MWR(flows, valuations[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the investor lost 100 on money that was in for about 1.5 years' worth
+	if mwr >= 0 || !near(mwr, -100/(100+200*183.0/365), 1e-3) {
+		t.Fatalf("mwr %.6f", mwr)
+	}
+}
+
+func TestSummarizeSingleDeposit(t *testing.T) {
+	flows := []CashFlow{{day("2020-01-01"), 100000}}
+	valuations := []Valuation{{day("2020-06-30"), 110000}, {day("2022-01-01"), 121000}}
+	s, err := Summarize(flows, valuations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// with one deposit every measure is the plain return
+	annual := math.Pow(1.21, 365.0/731) - 1
+	if !near(s.TWR, 0.21, 1e-9) || !near(s.MWR, 0.21, 1e-9) || !near(s.XIRR, annual, 1e-7) || !near(s.TWRAnnual, annual, 1e-9) {
+		t.Fatalf("summary %+v", s)
+	}
+	if s.Invested != 100000 || s.Final != 121000 {
+		t.Fatalf("invested %.2f, final %.2f", s.Invested, s.Final)
+	}
+}
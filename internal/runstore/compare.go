@@ -0,0 +1,184 @@
+package runstore
+
+import (
+	"fmt"
+	"fund-manager/internal/backtest"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type metricRow struct {
+	label string
+	unit  string // "%", "#" for counts, or "" for amounts
+	value func(m backtest.Metrics) float64
+}
+
+var compareMetrics = []metricRow{
+	{"CAGR", "%", func(m backtest.Metrics) float64 { return m.CAGR }},
+	{"XIRR", "%", func(m backtest.Metrics) float64 { return m.XIRR }},
+	{"Time-Weighted Return", "%", func(m backtest.Metrics) float64 { return m.TWR }},
+	{"Money-Weighted Return", "%", func(m backtest.Metrics) float64 { return m.MWR }},
+	{"Max Drawdown", "%", func(m backtest.Metrics) float64 { return m.MaxDrawdown }},
+	{"Final Equity", "", func(m backtest.Metrics) float64 { return m.FinalEquity }},
+	{"Net Invested", "", func(m backtest.Metrics) float64 { return m.NetInvested }},
+	{"Net Profit", "", func(m backtest.Metrics) float64 { return m.NetProfit }},
+	{"Total Trades", "#", func(m backtest.Metrics) float64 { return float64(m.TotalTrades) }},
+	{"Win Rate", "%", func(m backtest.Metrics) float64 { return m.WinRate }},
+	{"Average Profit", "", func(m backtest.Metrics) float64 { return m.AverageProfit }},
+	{"Total Costs", "", func(m backtest.Metrics) float64 { return m.TotalCosts }},
+	{"Tax Paid", "", func(m backtest.Metrics) float64 { return m.TaxPaid }},
+	{"Roll Costs", "", func(m backtest.Metrics) float64 { return m.RollCosts }},
+	{"Dividends", "", func(m backtest.Metrics) float64 { return m.Dividends }},
+	{"Price CAGR", "%", func(m backtest.Metrics) float64 { return m.PriceCAGR }},
+	{"Cash Interest", "", func(m backtest.Metrics) float64 { return m.CashInterest }},
+	{"Max Margin Used", "%", func(m backtest.Metrics) float64 { return m.MaxMarginUsed }},
+}
+
+// Label names a run as "<name>/<run key>".
+func Label(run backtest.Run) string {
+	return run.Meta.Name + "/" + run.Meta.RunID
+}
+
+// WriteComparison prints the metrics of the runs side by side. Every run
+// after the first also gets a column with its difference to the first.
+// The data each run read follows, with a warning where two fingerprints
+// count different sources and so cannot show whether the data was the same.
+func WriteComparison(w io.Writer, runs []backtest.Run) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	header := []string{"Metric"}
+	for i, r := range runs {
+		header = append(header, Label(r))
+		if i > 0 {
+			header = append(header, "Δ")
+		}
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+
+	for _, m := range compareMetrics {
+		cells := []string{m.label}
+		base := m.value(runs[0].Metrics)
+		for i, r := range runs {
+			v := m.value(r.Metrics)
+			cells = append(cells, formatMetric(v, m.unit, false))
+			if i > 0 {
+				cells = append(cells, formatMetric(v-base, m.unit, true))
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t")+"\t")
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	base := runs[0].Meta.Data
+	for i, r := range runs {
+		data := r.Meta.Data
+		fmt.Fprintf(w, "Data %s: %s\n", Label(r), data)
+		switch {
+		case i == 0:
+		case !data.Comparable(base):
+			fmt.Fprintf(w, "⚠️  %s and %s fingerprint different sources, so their data cannot be compared\n", Label(runs[0]), Label(r))
+		case data != base:
+			fmt.Fprintf(w, "⚠️  %s read different data from %s\n", Label(r), Label(runs[0]))
+		}
+	}
+	return nil
+}
+
+func formatMetric(v float64, unit string, signed bool) string {
+	switch {
+	case unit == "%" && signed:
+		return fmt.Sprintf("%+.2fpp", v*100)
+	case unit == "%":
+		return fmt.Sprintf("%.2f%%", v*100)
+	case unit == "#" && signed:
+		return fmt.Sprintf("%+.0f", v)
+	case unit == "#":
+		return fmt.Sprintf("%.0f", v)
+	case signed:
+		return fmt.Sprintf("%+.2f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+var plotMarks = []byte{'*', '+', 'o', 'x', '#', '@'}
+
+// PlotEquity draws the growth of the runs on one ASCII chart, each rebased
+// to 100 at its first point so runs with different capital line up. Growth
+// compounds the period returns, so contributions and withdrawals do not
+// show as gains and losses.
+func PlotEquity(w io.Writer, runs []backtest.Run, width, height int) error {
+	var first, last time.Time
+	lo, hi := math.Inf(1), math.Inf(-1)
+	curves := make([][]float64, len(runs))
+	for i, r := range runs {
+		growth := backtest.Growth(r.PeriodReturns())
+		if len(growth) == 0 || growth[0] <= 0 {
+			continue
+		}
+		curves[i] = make([]float64, len(growth))
+		for j, g := range growth {
+			curves[i][j] = g / growth[0] * 100
+			lo, hi = math.Min(lo, curves[i][j]), math.Max(hi, curves[i][j])
+		}
+		if first.IsZero() || r.EquityDates[0].Before(first) {
+			first = r.EquityDates[0]
+		}
+		if end := r.EquityDates[len(r.EquityDates)-1]; end.After(last) {
+			last = end
+		}
+	}
+	if first.IsZero() || !last.After(first) {
+		_, err := fmt.Fprintln(w, "(not enough equity points to plot)")
+		return err
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+
+	grid := make([][]byte, height)
+	for i := range grid {
+		grid[i] = []byte(strings.Repeat(" ", width))
+	}
+	span := last.Sub(first).Hours()
+	for i, r := range runs {
+		if curves[i] == nil {
+			continue
+		}
+		mark := plotMarks[i%len(plotMarks)]
+		for j, d := range r.EquityDates {
+			v := curves[i][j]
+			x := int(math.Round(d.Sub(first).Hours() / span * float64(width-1)))
+			y := height - 1 - int(math.Round((v-lo)/(hi-lo)*float64(height-1)))
+			grid[y][x] = mark
+		}
+	}
+
+	for i, line := range grid {
+		label := "       "
+		switch i {
+		case 0:
+			label = fmt.Sprintf("%7.1f", hi)
+		case height - 1:
+			label = fmt.Sprintf("%7.1f", lo)
+		}
+		if _, err := fmt.Fprintf(w, "%s |%s\n", label, line); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "%s +%s\n", strings.Repeat(" ", 7), strings.Repeat("-", width))
+	gap := width - 20
+	if gap < 1 {
+		gap = 1
+	}
+	fmt.Fprintf(w, "%s  %s%*s\n", strings.Repeat(" ", 7), first.Format("2006-01-02"), gap+10, last.Format("2006-01-02"))
+	for i, r := range runs {
+		fmt.Fprintf(w, "  %c %s\n", plotMarks[i%len(plotMarks)], Label(r))
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package runstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"fund-manager/internal/backtest"
+	"fund-manager/internal/repository"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gopkg.in/yaml.v3"
+)
+
+// Save stores a finished run with its parameters, trades, equity curve and
+// metrics in one transaction and returns the new run id.
+func Save(ctx context.Context, pool *pgxpool.Pool, spec backtest.Spec, meta backtest.RunMeta, result backtest.BacktestResult) (pgtype.UUID, error) {
+	runID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	specYAML, err := yaml.Marshal(spec)
+	if err != nil {
+		return runID, err
+	}
+	params, err := flattenSpec(spec)
+	if err != nil {
+		return runID, err
+	}
+	metrics, err := metricsToMap(backtest.MetricsOf(result, spec.InitialCapital))
+	if err != nil {
+		return runID, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return runID, err
+	}
+	defer tx.Rollback(ctx)
+	q := repository.New(tx)
+
+	runKey := meta.RunID
+	if runKey == "" {
+		runKey = meta.StartedAt.UTC().Format("20060102-150405")
+	}
+	var maxDate pgtype.Date
+	if d, err := time.Parse("2006-01-02", meta.Data.MaxDate); err == nil {
+		maxDate = pgtype.Date{Time: d, Valid: true}
+	}
+
+	if _, err := q.CreateBacktestRun(ctx, repository.CreateBacktestRunParams{
+		ID:          runID,
+		Name:        spec.Name,
+		RunKey:      runKey,
+		StartedAt:   pgtype.Timestamptz{Time: meta.StartedAt, Valid: true},
+		FinishedAt:  pgtype.Timestamptz{Time: meta.FinishedAt, Valid: true},
+		GitCommit:   meta.GitCommit,
+		GitDirty:    meta.GitDirty,
+		DataRows:    meta.Data.Rows,
+		DataStocks:  meta.Data.Stocks,
+		DataMaxDate: maxDate,
+		Spec:        string(specYAML),
+		DataBackend: meta.Data.Backend,
+		DataWindow:  meta.Data.Window,
+	}); err != nil {
+		return runID, fmt.Errorf("failed to create run: %w", err)
+	}
+
+	paramRows := make([]repository.BulkCreateBacktestParamsParams, 0, len(params))
+	for _, key := range sortedKeys(params) {
+		paramRows = append(paramRows, repository.BulkCreateBacktestParamsParams{RunID: runID, Key: key, Value: params[key]})
+	}
+	if _, err := q.BulkCreateBacktestParams(ctx, paramRows); err != nil {
+		return runID, fmt.Errorf("failed to store params: %w", err)
+	}
+
+	tradeRows := make([]repository.BulkCreateBacktestTradesParams, 0, len(result.TradeLogs))
+	for _, t := range result.TradeLogs {
+		tradeRows = append(tradeRows, repository.BulkCreateBacktestTradesParams{
+			ID:             pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			RunID:          runID,
+			Symbol:         t.Symbol,
+			EntryDate:      pgtype.Date{Time: t.EntryDate, Valid: true},
+			ExitDate:       pgtype.Date{Time: t.ExitDate, Valid: true},
+			EntryPrice:     t.EntryPrice,
+			ExitPrice:      t.ExitPrice,
+			Quantity:       t.Quantity,
+			AmountUsed:     t.AmountUsed,
+			Profit:         t.Profit,
+			ProfitPct:      t.ProfitPct,
+			DaysHeld:       int32(t.DaysHeld),
+			MaxDrawdown:    t.MaxDrawdown,
+			Costs:          t.Costs,
+			Dividends:      t.Dividends,
+			TotalReturnPct: t.TotalReturnPct,
+		})
+	}
+	if _, err := q.BulkCreateBacktestTrades(ctx, tradeRows); err != nil {
+		return runID, fmt.Errorf("failed to store trades: %w", err)
+	}
+
+	equityRows := make([]repository.BulkCreateBacktestEquityParams, 0, len(result.EquityCurve))
+	for i, d := range result.EquityDates {
+		row := repository.BulkCreateBacktestEquityParams{
+			RunID:  runID,
+			Date:   pgtype.Date{Time: d, Valid: true},
+			Equity: result.EquityCurve[i],
+		}
+		if i < len(result.MonthlyReturns) {
+			row.PeriodReturn = pgtype.Float8{Float64: result.MonthlyReturns[i], Valid: true}
+		}
+		equityRows = append(equityRows, row)
+	}
+	if _, err := q.BulkCreateBacktestEquity(ctx, equityRows); err != nil {
+		return runID, fmt.Errorf("failed to store equity: %w", err)
+	}
+
+	metricRows := make([]repository.BulkCreateBacktestMetricsParams, 0, len(metrics))
+	for _, name := range sortedKeys(metrics) {
+		metricRows = append(metricRows, repository.BulkCreateBacktestMetricsParams{RunID: runID, Name: name, Value: metrics[name]})
+	}
+	if _, err := q.BulkCreateBacktestMetrics(ctx, metricRows); err != nil {
+		return runID, fmt.Errorf("failed to store metrics: %w", err)
+	}
+
+	return runID, tx.Commit(ctx)
+}
+
+// Load reads a stored run back into the same shape as an archived run.
+func Load(ctx context.Context, q *repository.Queries, id pgtype.UUID) (backtest.Run, error) {
+	var run backtest.Run
+
+	row, err := q.GetBacktestRun(ctx, id)
+	if err != nil {
+		return run, fmt.Errorf("failed to load run %s: %w", FormatID(id), err)
+	}
+	if err := yaml.Unmarshal([]byte(row.Spec), &run.Spec); err != nil {
+		return run, fmt.Errorf("failed to parse spec of run %s: %w", FormatID(id), err)
+	}
+	run.Meta = backtest.RunMeta{
+		Name:       row.Name,
+		RunID:      row.RunKey,
+		StartedAt:  row.StartedAt.Time,
+		FinishedAt: row.FinishedAt.Time,
+		GitCommit:  row.GitCommit,
+		GitDirty:   row.GitDirty,
+		Data:       backtest.DataFingerprint{Rows: row.DataRows, Stocks: row.DataStocks, Backend: row.DataBackend, Window: row.DataWindow},
+	}
+	if row.DataMaxDate.Valid {
+		run.Meta.Data.MaxDate = row.DataMaxDate.Time.Format("2006-01-02")
+	}
+
+	metricRows, err := q.GetBacktestMetrics(ctx, id)
+	if err != nil {
+		return run, err
+	}
+	metrics := make(map[string]float64, len(metricRows))
+	for _, m := range metricRows {
+		metrics[m.Name] = m.Value
+	}
+	if run.Metrics, err = metricsFromMap(metrics); err != nil {
+		return run, err
+	}
+
+	trades, err := q.GetBacktestTrades(ctx, id)
+	if err != nil {
+		return run, err
+	}
+	for _, t := range trades {
+		run.TradeLogs = append(run.TradeLogs, backtest.TradeLog{
+			Symbol:         t.Symbol,
+			EntryDate:      t.EntryDate.Time,
+			ExitDate:       t.ExitDate.Time,
+			EntryPrice:     t.EntryPrice,
+			ExitPrice:      t.ExitPrice,
+			Profit:         t.Profit,
+			ProfitPct:      t.ProfitPct,
+			DaysHeld:       int(t.DaysHeld),
+			Quantity:       t.Quantity,
+			AmountUsed:     t.AmountUsed,
+			MaxDrawdown:    t.MaxDrawdown,
+			Costs:          t.Costs,
+			Dividends:      t.Dividends,
+			TotalReturnPct: t.TotalReturnPct,
+		})
+	}
+
+	equity, err := q.GetBacktestEquity(ctx, id)
+	if err != nil {
+		return run, err
+	}
+	for _, e := range equity {
+		run.EquityDates = append(run.EquityDates, e.Date.Time)
+		run.EquityCurve = append(run.EquityCurve, e.Equity)
+		// runs stored before period returns were kept have none
+		if e.PeriodReturn.Valid {
+			run.Returns = append(run.Returns, e.PeriodReturn.Float64)
+		}
+	}
+	return run, nil
+}
+
+func List(ctx context.Context, q *repository.Queries, limit int32) ([]repository.BacktestRun, error) {
+	return q.ListBacktestRuns(ctx, limit)
+}
+
+// Resolve loads a run given as an archive directory, a run id or id prefix,
+// "<name>/<run key>", or a spec name (meaning its latest run).
+func Resolve(ctx context.Context, q *repository.Queries, ref string) (backtest.Run, error) {
+	if info, err := os.Stat(ref); err == nil && info.IsDir() {
+		return backtest.LoadRun(ref)
+	}
+
+	runs, err := q.ListBacktestRuns(ctx, 10000)
+	if err != nil {
+		return backtest.Run{}, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	var matches []repository.BacktestRun
+	for _, r := range runs {
+		id := FormatID(r.ID)
+		switch {
+		case strings.HasPrefix(id, strings.ToLower(ref)):
+			matches = append(matches, r)
+		case ref == r.Name+"/"+r.RunKey:
+			matches = append(matches, r)
+		}
+	}
+	if len(matches) == 0 {
+		// runs are newest first, so the first name match is the latest
+		for _, r := range runs {
+			if r.Name == ref {
+				matches = append(matches, r)
+				break
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return backtest.Run{}, fmt.Errorf("no backtest run matches %q", ref)
+	case 1:
+		return Load(ctx, q, matches[0].ID)
+	}
+	return backtest.Run{}, fmt.Errorf("%q matches %d runs, use a longer id", ref, len(matches))
+}
+
+func FormatID(id pgtype.UUID) string {
+	return uuid.UUID(id.Bytes).String()
+}
+
+// flattenSpec turns the spec into dotted key/value pairs such as
+// "strategy.top_n" = "10" so runs can be filtered on single parameters.
+func flattenSpec(spec backtest.Spec) (map[string]string, error) {
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		switch val := v.(type) {
+		case map[string]any:
+			for k, child := range val {
+				key := k
+				if prefix != "" {
+					key = prefix + "." + k
+				}
+				walk(key, child)
+			}
+		case []any:
+			parts := make([]string, len(val))
+			for i, p := range val {
+				parts[i] = fmt.Sprint(p)
+			}
+			out[prefix] = strings.Join(parts, ",")
+		default:
+			out[prefix] = fmt.Sprint(val)
+		}
+	}
+	walk("", tree)
+	return out, nil
+}
+
+func metricsToMap(m backtest.Metrics) (map[string]float64, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]float64)
+	return out, json.Unmarshal(data, &out)
+}
+
+func metricsFromMap(values map[string]float64) (backtest.Metrics, error) {
+	var m backtest.Metrics
+	data, err := json.Marshal(values)
+	if err != nil {
+		return m, err
+	}
+	return m, json.Unmarshal(data, &m)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,277 @@
+package screener
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// series holds the daily bars of one stock up to the screening date, oldest
+// first.
+type series struct {
+	dates  []time.Time
+	open   []float64
+	high   []float64
+	low    []float64
+	close  []float64
+	volume []float64
+}
+
+func (s *series) len() int { return len(s.close) }
+
+// column computes one named value from a stock's series. lookback reports how
+// far back before the screening date data must be loaded.
+type column struct {
+	name     string
+	lookback func(date time.Time) time.Time
+	compute  func(s *series, date time.Time) float64
+}
+
+var (
+	reReturn = regexp.MustCompile(`^ret_(\d+)([dwmy])$`)
+	reSMA    = regexp.MustCompile(`^sma_(\d+)$`)
+	reEMA    = regexp.MustCompile(`^ema_(\d+)$`)
+	reAvg    = regexp.MustCompile(`^avg_(value|volume)_(\d+)d$`)
+	reRange  = regexp.MustCompile(`^(high|low)_(\d+)d$`)
+	reVol    = regexp.MustCompile(`^volatility_(\d+)d$`)
+)
+
+// ColumnHelp documents the column names accepted in expressions.
+const ColumnHelp = `close, open, high, low, volume, value (close*volume), bars,
+ret_<N>d|w|m|y (percent return over N trading days, weeks, months or years),
+sma_<N>, ema_<N> (moving averages of close over N trading days),
+avg_value_<N>d, avg_volume_<N>d, high_<N>d, low_<N>d,
+volatility_<N>d (annualised stdev of daily returns, percent)`
+
+func barsBack(n int) func(time.Time) time.Time {
+	// trading days to calendar days with room for holidays
+	days := n*7/5 + 15
+	return func(d time.Time) time.Time { return d.AddDate(0, 0, -days) }
+}
+
+func lookupColumn(name string) (column, error) {
+	latest := func(pick func(s *series) []float64) func(*series, time.Time) float64 {
+		return func(s *series, _ time.Time) float64 {
+			v := pick(s)
+			if len(v) == 0 {
+				return math.NaN()
+			}
+			return v[len(v)-1]
+		}
+	}
+
+	switch name {
+	case "close":
+		return column{name, barsBack(1), latest(func(s *series) []float64 { return s.close })}, nil
+	case "open":
+		return column{name, barsBack(1), latest(func(s *series) []float64 { return s.open })}, nil
+	case "high":
+		return column{name, barsBack(1), latest(func(s *series) []float64 { return s.high })}, nil
+	case "low":
+		return column{name, barsBack(1), latest(func(s *series) []float64 { return s.low })}, nil
+	case "volume":
+		return column{name, barsBack(1), latest(func(s *series) []float64 { return s.volume })}, nil
+	case "value":
+		return column{name, barsBack(1), func(s *series, _ time.Time) float64 {
+			if s.len() == 0 {
+				return math.NaN()
+			}
+			return s.close[s.len()-1] * s.volume[s.len()-1]
+		}}, nil
+	case "bars":
+		return column{name, barsBack(0), func(s *series, _ time.Time) float64 { return float64(s.len()) }}, nil
+	}
+
+	if m := reReturn.FindStringSubmatch(name); m != nil {
+		n, err := period(name, m[1])
+		if err != nil {
+			return column{}, err
+		}
+		return returnColumn(name, n, m[2]), nil
+	}
+	if m := reSMA.FindStringSubmatch(name); m != nil {
+		n, err := period(name, m[1])
+		if err != nil {
+			return column{}, err
+		}
+		return column{name, barsBack(n), func(s *series, _ time.Time) float64 {
+			return mean(tail(s.close, n), n)
+		}}, nil
+	}
+	if m := reEMA.FindStringSubmatch(name); m != nil {
+		n, err := period(name, m[1])
+		if err != nil {
+			return column{}, err
+		}
+		// seed the EMA over three periods so the start-up value has decayed
+		return column{name, barsBack(3 * n), func(s *series, _ time.Time) float64 {
+			return ema(tail(s.close, 3*n), n)
+		}}, nil
+	}
+	if m := reAvg.FindStringSubmatch(name); m != nil {
+		n, err := period(name, m[2])
+		if err != nil {
+			return column{}, err
+		}
+		if m[1] == "volume" {
+			return column{name, barsBack(n), func(s *series, _ time.Time) float64 {
+				return mean(tail(s.volume, n), n)
+			}}, nil
+		}
+		return column{name, barsBack(n), func(s *series, _ time.Time) float64 {
+			c, v := tail(s.close, n), tail(s.volume, n)
+			if len(c) < n {
+				return math.NaN()
+			}
+			sum := 0.0
+			for i := range c {
+				sum += c[i] * v[i]
+			}
+			return sum / float64(n)
+		}}, nil
+	}
+	if m := reRange.FindStringSubmatch(name); m != nil {
+		n, err := period(name, m[2])
+		if err != nil {
+			return column{}, err
+		}
+		if m[1] == "high" {
+			return column{name, barsBack(n), func(s *series, _ time.Time) float64 {
+				return extreme(tail(s.high, n), math.Max)
+			}}, nil
+		}
+		return column{name, barsBack(n), func(s *series, _ time.Time) float64 {
+			return extreme(tail(s.low, n), math.Min)
+		}}, nil
+	}
+	if m := reVol.FindStringSubmatch(name); m != nil {
+		n, err := period(name, m[1])
+		if err != nil {
+			return column{}, err
+		}
+		return column{name, barsBack(n + 1), func(s *series, _ time.Time) float64 {
+			return volatility(tail(s.close, n+1), n)
+		}}, nil
+	}
+	return column{}, fmt.Errorf("unknown column %q; known columns are:\n%s", name, ColumnHelp)
+}
+
+// period parses the N of a column name, which must be at least one.
+func period(name, digits string) (int, error) {
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("column %q: the period must be at least 1", name)
+	}
+	return n, nil
+}
+
+func returnColumn(name string, n int, unit string) column {
+	if unit == "d" {
+		return column{name, barsBack(n + 1), func(s *series, _ time.Time) float64 {
+			c := tail(s.close, n+1)
+			if len(c) < n+1 || c[0] == 0 {
+				return math.NaN()
+			}
+			return (c[n] - c[0]) / c[0] * 100
+		}}
+	}
+
+	back := func(d time.Time) time.Time {
+		switch unit {
+		case "w":
+			return d.AddDate(0, 0, -7*n)
+		case "m":
+			return d.AddDate(0, -n, 0)
+		default:
+			return d.AddDate(-n, 0, 0)
+		}
+	}
+	return column{
+		name: name,
+		// same as GetTopStocksByReturn: compare against the last close on or
+		// before the lookback date, so allow a few weeks of missing data
+		lookback: func(d time.Time) time.Time { return back(d).AddDate(0, 0, -30) },
+		compute: func(s *series, date time.Time) float64 {
+			if s.len() == 0 {
+				return math.NaN()
+			}
+			past := closeOnOrBefore(s, back(date))
+			if math.IsNaN(past) || past == 0 {
+				return math.NaN()
+			}
+			last := s.close[s.len()-1]
+			return (last - past) / past * 100
+		},
+	}
+}
+
+func closeOnOrBefore(s *series, d time.Time) float64 {
+	for i := s.len() - 1; i >= 0; i-- {
+		if !s.dates[i].After(d) {
+			return s.close[i]
+		}
+	}
+	return math.NaN()
+}
+
+func tail(v []float64, n int) []float64 {
+	if len(v) <= n {
+		return v
+	}
+	return v[len(v)-n:]
+}
+
+func mean(v []float64, n int) float64 {
+	if len(v) < n || n == 0 {
+		return math.NaN()
+	}
+	sum := 0.0
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
+
+func ema(v []float64, n int) float64 {
+	if len(v) < n || len(v) == 0 {
+		return math.NaN()
+	}
+	k := 2 / float64(n+1)
+	e := v[0]
+	for _, x := range v[1:] {
+		e = x*k + e*(1-k)
+	}
+	return e
+}
+
+func extreme(v []float64, pick func(a, b float64) float64) float64 {
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	e := v[0]
+	for _, x := range v[1:] {
+		e = pick(e, x)
+	}
+	return e
+}
+
+func volatility(closes []float64, n int) float64 {
+	if len(closes) < n+1 || n < 2 {
+		return math.NaN()
+	}
+	rets := make([]float64, 0, n)
+	for i := 1; i < len(closes); i++ {
+		if closes[i-1] == 0 {
+			return math.NaN()
+		}
+		rets = append(rets, closes[i]/closes[i-1]-1)
+	}
+	m := mean(rets, len(rets))
+	ss := 0.0
+	for _, r := range rets {
+		ss += (r - m) * (r - m)
+	}
+	return math.Sqrt(ss/float64(len(rets)-1)) * math.Sqrt(252) * 100
+}
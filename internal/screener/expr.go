@@ -0,0 +1,404 @@
+package screener
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed filter or rank expression such as
+// `ret_12m > 30 && close > sma_200`. Booleans evaluate to 1 or 0.
+type Expr interface {
+	Eval(vars map[string]float64) (float64, error)
+	idents(set map[string]struct{})
+}
+
+type numberExpr float64
+
+type identExpr string
+
+type unaryExpr struct {
+	op string
+	x  Expr
+}
+
+type binaryExpr struct {
+	op   string
+	l, r Expr
+}
+
+type callExpr struct {
+	fn   string
+	args []Expr
+}
+
+func (n numberExpr) Eval(map[string]float64) (float64, error) { return float64(n), nil }
+func (n numberExpr) idents(map[string]struct{})               {}
+
+func (i identExpr) Eval(vars map[string]float64) (float64, error) {
+	v, ok := vars[string(i)]
+	if !ok {
+		return 0, fmt.Errorf("unknown column %q", string(i))
+	}
+	return v, nil
+}
+
+func (i identExpr) idents(set map[string]struct{}) { set[string(i)] = struct{}{} }
+
+func (u unaryExpr) Eval(vars map[string]float64) (float64, error) {
+	v, err := u.x.Eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	if u.op == "!" {
+		return boolToFloat(!truthy(v)), nil
+	}
+	return -v, nil
+}
+
+func (u unaryExpr) idents(set map[string]struct{}) { u.x.idents(set) }
+
+func (b binaryExpr) Eval(vars map[string]float64) (float64, error) {
+	l, err := b.l.Eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	// short-circuit so that `bars > 250 && ret_12m > 0` skips the right side
+	switch b.op {
+	case "&&":
+		if !truthy(l) {
+			return 0, nil
+		}
+	case "||":
+		if truthy(l) {
+			return 1, nil
+		}
+	}
+	r, err := b.r.Eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case "&&", "||":
+		return boolToFloat(truthy(r)), nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return math.NaN(), nil
+		}
+		return l / r, nil
+	case "<":
+		return boolToFloat(l < r), nil
+	case "<=":
+		return boolToFloat(l <= r), nil
+	case ">":
+		return boolToFloat(l > r), nil
+	case ">=":
+		return boolToFloat(l >= r), nil
+	case "==":
+		return boolToFloat(l == r), nil
+	case "!=":
+		return boolToFloat(l != r), nil
+	}
+	return 0, fmt.Errorf("unknown operator %q", b.op)
+}
+
+func (b binaryExpr) idents(set map[string]struct{}) {
+	b.l.idents(set)
+	b.r.idents(set)
+}
+
+func (c callExpr) Eval(vars map[string]float64) (float64, error) {
+	args := make([]float64, len(c.args))
+	for i, a := range c.args {
+		v, err := a.Eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	switch c.fn {
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "log":
+		return math.Log(args[0]), nil
+	case "min":
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Min(m, a)
+		}
+		return m, nil
+	case "max":
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Max(m, a)
+		}
+		return m, nil
+	}
+	return 0, fmt.Errorf("unknown function %q", c.fn)
+}
+
+func (c callExpr) idents(set map[string]struct{}) {
+	for _, a := range c.args {
+		a.idents(set)
+	}
+}
+
+var funcArity = map[string][2]int{
+	"abs": {1, 1},
+	"log": {1, 1},
+	"min": {2, -1},
+	"max": {2, -1},
+}
+
+// Idents returns the column names referenced by e.
+func Idents(e Expr) []string {
+	set := make(map[string]struct{})
+	e.idents(set)
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func truthy(v float64) bool { return v != 0 && !math.IsNaN(v) }
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Parse compiles an expression. Supported syntax: numbers (including 5e7),
+// column names, parentheses, unary - and !, arithmetic + - * /, comparisons
+// < <= > >= == != and the logical operators && and ||, plus the functions
+// abs, log, min and max.
+func Parse(src string) (Expr, error) {
+	p := &parser{src: src}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	e, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.toks[p.pos].text, src)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type parser struct {
+	src  string
+	toks []token
+	pos  int
+}
+
+var twoCharOps = []string{"&&", "||", "<=", ">=", "==", "!="}
+
+func (p *parser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(s) && (isDigit(s[j]) || s[j] == '.') {
+				j++
+			}
+			if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+				k := j + 1
+				if k < len(s) && (s[k] == '+' || s[k] == '-') {
+					k++
+				}
+				if k < len(s) && isDigit(s[k]) {
+					for k < len(s) && isDigit(s[k]) {
+						k++
+					}
+					j = k
+				}
+			}
+			p.toks = append(p.toks, token{tokNumber, s[i:j]})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (s[j] == '_' || isDigit(s[j]) || unicode.IsLetter(rune(s[j]))) {
+				j++
+			}
+			p.toks = append(p.toks, token{tokIdent, strings.ToLower(s[i:j])})
+			i = j
+		default:
+			matched := false
+			for _, op := range twoCharOps {
+				if strings.HasPrefix(s[i:], op) {
+					p.toks = append(p.toks, token{tokOp, op})
+					i += 2
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+			if !strings.ContainsRune("+-*/<>!(),", c) {
+				return fmt.Errorf("unexpected character %q in %q", c, s)
+			}
+			p.toks = append(p.toks, token{tokOp, string(c)})
+			i++
+		}
+	}
+	return nil
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
+
+// binary operator precedence, higher binds tighter
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6,
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.toks) {
+		return token{}, false
+	}
+	return p.toks[p.pos], true
+}
+
+func (p *parser) expect(op string) error {
+	t, ok := p.peek()
+	if !ok || t.kind != tokOp || t.text != op {
+		return fmt.Errorf("expected %q in %q", op, p.src)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) parseBinary(minPrec int) (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOp {
+			return left, nil
+		}
+		prec, isBinary := precedence[t.text]
+		if !isBinary || prec <= minPrec {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseBinary(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: t.text, l: left, r: right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t, ok := p.peek()
+	if ok && t.kind == tokOp && (t.text == "-" || t.text == "!") {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op: t.text, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression %q", p.src)
+	}
+	p.pos++
+	switch t.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", t.text, err)
+		}
+		return numberExpr(v), nil
+	case tokIdent:
+		if next, ok := p.peek(); ok && next.kind == tokOp && next.text == "(" {
+			return p.parseCall(t.text)
+		}
+		return identExpr(t.text), nil
+	}
+	if t.text == "(" {
+		e, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("unexpected %q in %q", t.text, p.src)
+}
+
+func (p *parser) parseCall(fn string) (Expr, error) {
+	arity, known := funcArity[fn]
+	if !known {
+		return nil, fmt.Errorf("unknown function %q", fn)
+	}
+	p.pos++ // "("
+	var args []Expr
+	if t, ok := p.peek(); !ok || t.text != ")" {
+		for {
+			a, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			t, ok := p.peek()
+			if ok && t.kind == tokOp && t.text == "," {
+				p.pos++
+				continue
+			}
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if len(args) < arity[0] || (arity[1] >= 0 && len(args) > arity[1]) {
+		return nil, fmt.Errorf("wrong number of arguments to %s", fn)
+	}
+	return callExpr{fn: fn, args: args}, nil
+}
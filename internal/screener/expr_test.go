@@ -0,0 +1,242 @@
+package screener
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func eval(t *testing.T, src string, vars map[string]float64) float64 {
+	t.Helper()
+	e, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", src, err)
+	}
+	v, err := e.Eval(vars)
+	if err != nil {
+		t.Fatalf("Eval(%q): %v", src, err)
+	}
+	return v
+}
+
+func TestPrecedence(t *testing.T) {
+	vars := map[string]float64{"a": 1, "b": 0, "c": 0, "x": 4}
+	for _, c := range []struct {
+		src  string
+		want float64
+	}{
+		{"2 + 3 * 4", 14},
+		{"(2 + 3) * 4", 20},
+		{"2 * 3 + 4", 10},
+		// left associative
+		{"10 - 4 - 3", 3},
+		{"8 / 4 / 2", 1},
+		// && binds tighter than ||: a || (b && c), not (a || b) && c
+		{"a || b && c", 1},
+		{"b && c || a", 1},
+		{"(a || b) && c", 0},
+		// comparisons bind tighter than equality, arithmetic tighter still
+		{"1 < 2 == 1", 1},
+		{"x + 1 > 4 && x * 2 <= 8", 1},
+		{"x != 4 || x >= 5", 0},
+		{"5e7 > 1e6", 1},
+		{"2.5e-1 * 4", 1},
+		// unary minus and not
+		{"-2 * 3", -6},
+		{"-x + 6", 2},
+		{"2 - -3", 5},
+		{"--x", 4},
+		{"-(x - 6)", 2},
+		{"!b", 1},
+		{"!a || b", 0},
+		{"!(x > 3)", 0},
+		// functions
+		{"abs(-x)", 4},
+		{"log(1)", 0},
+		{"min(x, 2, 3)", 2},
+		{"max(-x, -2)", -2},
+		{"abs(x - 10) / 2", 3},
+	} {
+		if got := eval(t, c.src, vars); got != c.want {
+			t.Errorf("%s = %v, want %v", c.src, got, c.want)
+		}
+	}
+	if v := eval(t, "x / 0", vars); !math.IsNaN(v) {
+		t.Errorf("x / 0 = %v, want NaN", v)
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	// the right side names a column that is not there and would fail
+	vars := map[string]float64{"bars": 100}
+	for _, c := range []struct {
+		src  string
+		want float64
+	}{
+		{"bars > 250 && missing > 0", 0},
+		{"bars > 50 || missing > 0", 1},
+		{"0 && missing", 0},
+		{"1 || missing", 1},
+	} {
+		if got := eval(t, c.src, vars); got != c.want {
+			t.Errorf("%s = %v, want %v", c.src, got, c.want)
+		}
+	}
+	// without the short-circuit the missing column is an error
+	for _, src := range []string{"bars > 50 && missing > 0", "bars > 250 || missing > 0"} {
+		e, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.Eval(vars); err == nil || !strings.Contains(err.Error(), `unknown column "missing"`) {
+			t.Errorf("%s: error %v", src, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		want string
+	}{
+		{"abs()", "wrong number of arguments to abs"},
+		{"abs(1, 2)", "wrong number of arguments to abs"},
+		{"log()", "wrong number of arguments to log"},
+		{"log(1, 2)", "wrong number of arguments to log"},
+		{"min(1)", "wrong number of arguments to min"},
+		{"max()", "wrong number of arguments to max"},
+		{"sqrt(4)", `unknown function "sqrt"`},
+		{"1 +", "unexpected end of expression"},
+		{"(1 + 2", `expected ")"`},
+		{"1 2", `unexpected "2"`},
+		{"close $ 2", "unexpected character"},
+		{"min(1, 2", `expected ")"`},
+	} {
+		_, err := Parse(c.src)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Parse(%q) error %v, want %q", c.src, err, c.want)
+		}
+	}
+}
+
+func TestIdentsAndUnknownColumns(t *testing.T) {
+	e, err := Parse("Close > SMA_200 && ret_12m > abs(close - 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// names are case insensitive
+	if got := strings.Join(Idents(e), ","); got != "close,ret_12m,sma_200" {
+		t.Fatalf("idents %s", got)
+	}
+	if _, err := e.Eval(map[string]float64{"close": 10, "sma_200": 5}); err == nil || !strings.Contains(err.Error(), `unknown column "ret_12m"`) {
+		t.Fatalf("error %v", err)
+	}
+	for _, name := range []string{"foo", "ret_12", "ret_m", "ret_3q", "sma_", "avg_price_20d", "volatility_20"} {
+		if _, err := lookupColumn(name); err == nil || !strings.Contains(err.Error(), "unknown column") {
+			t.Errorf("lookupColumn(%q) error %v", name, err)
+		}
+	}
+}
+
+// daily closes from 100 rising by one a day, every calendar day through end
+func rising(start, end time.Time) *series {
+	s := &series{}
+	for d, c := start, 100.0; !d.After(end); d, c = d.AddDate(0, 0, 1), c+1 {
+		s.dates = append(s.dates, d)
+		s.open = append(s.open, c)
+		s.high = append(s.high, c+1)
+		s.low = append(s.low, c-1)
+		s.close = append(s.close, c)
+		s.volume = append(s.volume, 1000)
+	}
+	return s
+}
+
+func TestColumnNames(t *testing.T) {
+	end := time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC)
+	s := rising(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), end)
+	last := s.close[s.len()-1]
+	closeOn := func(d time.Time) float64 { return closeOnOrBefore(s, d) }
+	pct := func(past float64) float64 { return (last - past) / past * 100 }
+	for _, c := range []struct {
+		name string
+		want float64
+		from time.Time // the lookback must reach at least this far
+	}{
+		// N bars back
+		{"ret_5d", pct(s.close[s.len()-6]), end.AddDate(0, 0, -7)},
+		{"ret_1w", pct(closeOn(end.AddDate(0, 0, -7))), end.AddDate(0, 0, -7)},
+		{"ret_2w", pct(closeOn(end.AddDate(0, 0, -14))), end.AddDate(0, 0, -14)},
+		{"ret_6m", pct(closeOn(end.AddDate(0, -6, 0))), end.AddDate(0, -6, 0)},
+		{"ret_12m", pct(closeOn(end.AddDate(-1, 0, 0))), end.AddDate(-1, 0, 0)},
+		{"ret_1y", pct(closeOn(end.AddDate(-1, 0, 0))), end.AddDate(-1, 0, 0)},
+		{"ret_2y", pct(closeOn(end.AddDate(-2, 0, 0))), end.AddDate(-2, 0, 0)},
+		{"sma_3", last - 1, end.AddDate(0, 0, -3)},
+		{"high_5d", last + 1, end.AddDate(0, 0, -5)},
+		{"low_5d", last - 5, end.AddDate(0, 0, -5)},
+		{"avg_volume_20d", 1000, end.AddDate(0, 0, -20)},
+		{"bars", float64(s.len()), end},
+	} {
+		col, err := lookupColumn(c.name)
+		if err != nil {
+			t.Errorf("lookupColumn(%q): %v", c.name, err)
+			continue
+		}
+		if got := col.compute(s, end); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", c.name, got, c.want)
+		}
+		if from := col.lookback(end); from.After(c.from) {
+			t.Errorf("%s looks back to %s, not %s", c.name, from.Format("2006-01-02"), c.from.Format("2006-01-02"))
+		}
+	}
+	// a zero period is refused rather than computed over no bars
+	for _, name := range []string{"ema_0", "sma_0", "ret_0d", "ret_0m", "avg_value_0d", "avg_volume_0d", "high_0d", "low_0d", "volatility_0d", "sma_99999999999999999999"} {
+		if _, err := lookupColumn(name); err == nil || !strings.Contains(err.Error(), "period must be at least 1") {
+			t.Errorf("lookupColumn(%q) error %v", name, err)
+		}
+	}
+	if v := ema(nil, 0); !math.IsNaN(v) {
+		t.Errorf("ema of no bars = %v, want NaN", v)
+	}
+}
+
+func TestMissingHistoryIsNaN(t *testing.T) {
+	end := time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC)
+	s := rising(end.AddDate(0, 0, -9), end) // ten bars
+	vars := map[string]float64{}
+	for _, name := range []string{"close", "sma_200", "ema_50", "ret_12m", "ret_20d", "avg_value_20d", "volatility_20d"} {
+		col, err := lookupColumn(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vars[name] = col.compute(s, end)
+	}
+	for _, name := range []string{"sma_200", "ema_50", "ret_12m", "ret_20d", "avg_value_20d", "volatility_20d"} {
+		if !math.IsNaN(vars[name]) {
+			t.Errorf("%s = %v with ten bars, want NaN", name, vars[name])
+		}
+	}
+	// every comparison with NaN is false, so a stock without the history
+	// fails the filter either way round
+	for _, c := range []struct {
+		src  string
+		want float64
+	}{
+		{"close > sma_200", 0},
+		{"close < sma_200", 0},
+		{"close == sma_200", 0},
+		{"sma_200 > 0 && close > 0", 0},
+		{"sma_200 || close > 0", 1},
+		{"!(close > sma_200)", 1},
+		{"!sma_200", 1},
+		{"ret_12m > 30 || ret_20d > 0", 0},
+	} {
+		if got := eval(t, c.src, vars); got != c.want {
+			t.Errorf("%s = %v, want %v", c.src, got, c.want)
+		}
+	}
+	if v := eval(t, "sma_200 - close", vars); !math.IsNaN(v) {
+		t.Errorf("sma_200 - close = %v, want NaN", v)
+	}
+}
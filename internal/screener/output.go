@@ -0,0 +1,118 @@
+package screener
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+var baseColumns = []string{"symbol", "name", "industry", "scripttype"}
+
+// Write renders the result as "csv", "json", "table" or "symbols", the last
+// being the bare symbol list that stockList.csv has always held.
+func Write(w io.Writer, format string, r Result) error {
+	switch strings.ToLower(format) {
+	case "csv":
+		return writeCSV(w, r)
+	case "json":
+		return writeJSON(w, r)
+	case "table", "":
+		return writeTable(w, r)
+	case "symbols":
+		return writeSymbols(w, r)
+	}
+	return fmt.Errorf("unknown output format %q (want csv, json, table or symbols)", format)
+}
+
+func formatValue(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func writeCSV(w io.Writer, r Result) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{}, baseColumns...), r.Columns...)); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		record := []string{row.Symbol, row.Name, row.Industry, row.ScriptType}
+		for _, v := range row.Values {
+			record = append(record, formatValue(v))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeJSON(w io.Writer, r Result) error {
+	type jsonRow struct {
+		Symbol     string              `json:"symbol"`
+		Name       string              `json:"name"`
+		Industry   string              `json:"industry"`
+		ScriptType string              `json:"scriptType"`
+		Values     map[string]*float64 `json:"values"`
+	}
+	out := struct {
+		Date    string    `json:"date"`
+		Columns []string  `json:"columns"`
+		Rows    []jsonRow `json:"rows"`
+	}{
+		Date:    r.Date.Format("2006-01-02"),
+		Columns: r.Columns,
+		Rows:    make([]jsonRow, 0, len(r.Rows)),
+	}
+	for _, row := range r.Rows {
+		values := make(map[string]*float64, len(r.Columns))
+		for i, c := range r.Columns {
+			v := row.Values[i]
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				values[c] = nil
+				continue
+			}
+			values[c] = &v
+		}
+		out.Rows = append(out.Rows, jsonRow{row.Symbol, row.Name, row.Industry, row.ScriptType, values})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func writeTable(w io.Writer, r Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := append([]string{"#", "symbol", "industry"}, r.Columns...)
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+	for i, row := range r.Rows {
+		cells := []string{strconv.Itoa(i + 1), row.Symbol, row.Industry}
+		for _, v := range row.Values {
+			cells = append(cells, formatValue(v))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t")+"\t")
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d stocks as of %s\n", len(r.Rows), r.Date.Format("2006-01-02"))
+	return err
+}
+
+func writeSymbols(w io.Writer, r Result) error {
+	writer := csv.NewWriter(w)
+	for _, row := range r.Rows {
+		if err := writer.Write([]string{row.Symbol}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,321 @@
+package screener
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/repository"
+	"fund-manager/internal/services"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Config struct {
+	Date        time.Time
+	ScriptTypes []string
+	Fno         string // "", "yes" or "no"
+	Industries  []string
+	Filters     []string
+	Ranks       []string // expression, optionally suffixed with " asc" or " desc" (default)
+	Columns     []string // extra columns to include in the output
+	Limit       int
+}
+
+type Row struct {
+	Symbol     string
+	Name       string
+	Industry   string
+	ScriptType string
+	Values     []float64
+}
+
+type Result struct {
+	Date    time.Time
+	Columns []string
+	Rows    []Row
+}
+
+type rankSpec struct {
+	name string
+	expr Expr
+	asc  bool
+}
+
+func Run(ctx context.Context, svc *services.Service, cfg Config) (Result, error) {
+	filters := make([]Expr, 0, len(cfg.Filters))
+	for _, f := range cfg.Filters {
+		e, err := Parse(f)
+		if err != nil {
+			return Result{}, fmt.Errorf("filter %q: %w", f, err)
+		}
+		filters = append(filters, e)
+	}
+
+	ranks := make([]rankSpec, 0, len(cfg.Ranks))
+	for _, r := range cfg.Ranks {
+		spec, err := parseRank(r)
+		if err != nil {
+			return Result{}, fmt.Errorf("rank %q: %w", r, err)
+		}
+		ranks = append(ranks, spec)
+	}
+
+	// output columns: requested ones, then everything the expressions use
+	names := make([]string, 0)
+	seen := make(map[string]struct{})
+	addName := func(n string) {
+		if _, ok := seen[n]; !ok {
+			seen[n] = struct{}{}
+			names = append(names, n)
+		}
+	}
+	for _, c := range cfg.Columns {
+		addName(strings.ToLower(strings.TrimSpace(c)))
+	}
+	for _, f := range filters {
+		for _, n := range Idents(f) {
+			addName(n)
+		}
+	}
+	for _, r := range ranks {
+		for _, n := range Idents(r.expr) {
+			addName(n)
+		}
+	}
+
+	columns := make([]column, 0, len(names))
+	start := cfg.Date
+	for _, n := range names {
+		c, err := lookupColumn(n)
+		if err != nil {
+			return Result{}, err
+		}
+		columns = append(columns, c)
+		if from := c.lookback(cfg.Date); from.Before(start) {
+			start = from
+		}
+	}
+
+	stocks, err := universe(ctx, svc, cfg)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(stocks) == 0 {
+		return Result{Date: cfg.Date, Columns: outputColumns(names, ranks)}, nil
+	}
+
+	symbols := make([]string, len(stocks))
+	for i, s := range stocks {
+		symbols[i] = s.Symbol
+	}
+	bySymbol, err := loadSeries(ctx, svc, symbols, start, cfg.Date)
+	if err != nil {
+		return Result{}, err
+	}
+
+	type candidate struct {
+		row   Row
+		ranks []float64
+	}
+	candidates := make([]candidate, 0, len(stocks))
+
+stockLoop:
+	for _, st := range stocks {
+		s := bySymbol[st.Symbol]
+		if s == nil || s.len() == 0 {
+			continue
+		}
+
+		vars := make(map[string]float64, len(columns))
+		values := make([]float64, 0, len(columns)+len(ranks))
+		for _, c := range columns {
+			v := c.compute(s, cfg.Date)
+			vars[c.name] = v
+			values = append(values, v)
+		}
+
+		for _, f := range filters {
+			v, err := f.Eval(vars)
+			if err != nil {
+				return Result{}, err
+			}
+			if !truthy(v) {
+				continue stockLoop
+			}
+		}
+
+		rankValues := make([]float64, len(ranks))
+		for i, r := range ranks {
+			v, err := r.expr.Eval(vars)
+			if err != nil {
+				return Result{}, err
+			}
+			rankValues[i] = v
+			if _, isColumn := seen[r.name]; !isColumn {
+				values = append(values, v)
+			}
+		}
+
+		candidates = append(candidates, candidate{
+			row: Row{
+				Symbol:     st.Symbol,
+				Name:       st.Name,
+				Industry:   st.Industry.String,
+				ScriptType: st.Scripttype,
+				Values:     values,
+			},
+			ranks: rankValues,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		for k, r := range ranks {
+			a, b := candidates[i].ranks[k], candidates[j].ranks[k]
+			if a == b || (math.IsNaN(a) && math.IsNaN(b)) {
+				continue
+			}
+			// missing values always sort last
+			if math.IsNaN(a) {
+				return false
+			}
+			if math.IsNaN(b) {
+				return true
+			}
+			if r.asc {
+				return a < b
+			}
+			return a > b
+		}
+		return candidates[i].row.Symbol < candidates[j].row.Symbol
+	})
+
+	if cfg.Limit > 0 && len(candidates) > cfg.Limit {
+		candidates = candidates[:cfg.Limit]
+	}
+
+	result := Result{Date: cfg.Date, Columns: outputColumns(names, ranks)}
+	for _, c := range candidates {
+		result.Rows = append(result.Rows, c.row)
+	}
+	return result, nil
+}
+
+func parseRank(src string) (rankSpec, error) {
+	text := strings.TrimSpace(src)
+	asc := false
+	lower := strings.ToLower(text)
+	switch {
+	case strings.HasSuffix(lower, " asc"):
+		asc = true
+		text = strings.TrimSpace(text[:len(text)-4])
+	case strings.HasSuffix(lower, " desc"):
+		text = strings.TrimSpace(text[:len(text)-5])
+	}
+	e, err := Parse(text)
+	if err != nil {
+		return rankSpec{}, err
+	}
+	return rankSpec{name: strings.ToLower(text), expr: e, asc: asc}, nil
+}
+
+func outputColumns(names []string, ranks []rankSpec) []string {
+	cols := append([]string{}, names...)
+	seen := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		seen[n] = struct{}{}
+	}
+	for _, r := range ranks {
+		if _, ok := seen[r.name]; !ok {
+			cols = append(cols, r.name)
+		}
+	}
+	return cols
+}
+
+func universe(ctx context.Context, svc *services.Service, cfg Config) ([]repository.Stock, error) {
+	all, err := svc.GetStockList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stocks: %w", err)
+	}
+
+	types := toSet(cfg.ScriptTypes)
+	industries := toSet(cfg.Industries)
+
+	stocks := make([]repository.Stock, 0, len(all))
+	for _, s := range all {
+		if len(types) > 0 {
+			if _, ok := types[strings.ToLower(s.Scripttype)]; !ok {
+				continue
+			}
+		}
+		if len(industries) > 0 {
+			if _, ok := industries[strings.ToLower(s.Industry.String)]; !ok {
+				continue
+			}
+		}
+		inFno := svc.InFno(s, cfg.Date)
+		switch cfg.Fno {
+		case "yes":
+			if !inFno {
+				continue
+			}
+		case "no":
+			if inFno {
+				continue
+			}
+		}
+		stocks = append(stocks, s)
+	}
+	return stocks, nil
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			set[v] = struct{}{}
+		}
+	}
+	return set
+}
+
+func loadSeries(ctx context.Context, svc *services.Service, symbols []string, start, end time.Time) (map[string]*series, error) {
+	rows, err := svc.GetDailyBars(ctx, repository.GetDailyBarsBetweenParams{
+		Symbols:   symbols,
+		StartDate: pgtype.Date{Time: start, Valid: true},
+		EndDate:   pgtype.Date{Time: end, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily bars: %w", err)
+	}
+
+	bySymbol := make(map[string]*series)
+	for _, r := range rows {
+		s := bySymbol[r.Symbol]
+		if s == nil {
+			s = &series{}
+			bySymbol[r.Symbol] = s
+		}
+		s.dates = append(s.dates, r.Timestamp.Time)
+		s.open = append(s.open, numericToFloat(r.Open))
+		s.high = append(s.high, numericToFloat(r.High))
+		s.low = append(s.low, numericToFloat(r.Low))
+		s.close = append(s.close, numericToFloat(r.Close))
+		s.volume = append(s.volume, float64(r.Volume.Int64))
+	}
+	return bySymbol, nil
+}
+
+func numericToFloat(n pgtype.Numeric) float64 {
+	if !n.Valid {
+		return math.NaN()
+	}
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return math.NaN()
+	}
+	return f.Float64
+}
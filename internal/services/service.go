@@ -2,8 +2,13 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fund-manager/internal/dividends"
+	"fund-manager/internal/fno"
 	"fund-manager/internal/repository"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -12,13 +17,17 @@ type QueryInterface interface {
 	GetTopStocksByReturn(ctx context.Context, input repository.GetTopStocksByReturnParams) ([]repository.GetTopStocksByReturnRow, error)
 	GetLatestClosePrice(ctx context.Context, input repository.GetLatestClosePriceParams) (pgtype.Numeric, error)
 	GetHistoricalStockPrices(ctx context.Context, input repository.GetHistoricalStockPricesParams) ([]repository.GetHistoricalStockPricesRow, error)
+	GetDailyBarsBetween(ctx context.Context, input repository.GetDailyBarsBetweenParams) ([]repository.GetDailyBarsBetweenRow, error)
+	GetDataFingerprint(ctx context.Context) (repository.GetDataFingerprintRow, error)
 }
 
 type Service struct {
-	Queries QueryInterface
+	Queries   QueryInterface
+	Lots      *fno.Table       // F&O lot sizes, nil when not loaded
+	Dividends *dividends.Table // nil when not loaded
 }
 
-func NewService(queries *repository.Queries) *Service {
+func NewService(queries QueryInterface) *Service {
 	return &Service{Queries: queries}
 }
 
@@ -34,6 +43,57 @@ func (s *Service) GetLatestClose(ctx context.Context, input repository.GetLatest
 	return s.Queries.GetLatestClosePrice(ctx, input)
 }
 
+// Close is the last close of symbol on or before date. ok is false when the
+// symbol has no price by then.
+func (s *Service) Close(ctx context.Context, symbol string, date time.Time) (price float64, ok bool, err error) {
+	n, err := s.Queries.GetLatestClosePrice(ctx, repository.GetLatestClosePriceParams{
+		Symbol:    symbol,
+		Timestamp: pgtype.Date{Time: date, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid || f.Float64 <= 0 {
+		return 0, false, err
+	}
+	return f.Float64, true, nil
+}
+
 func (s *Service) GetStockPrices(ctx context.Context, input repository.GetHistoricalStockPricesParams) ([]repository.GetHistoricalStockPricesRow, error) {
 	return s.Queries.GetHistoricalStockPrices(ctx, input)
 }
+
+func (s *Service) GetDailyBars(ctx context.Context, input repository.GetDailyBarsBetweenParams) ([]repository.GetDailyBarsBetweenRow, error) {
+	return s.Queries.GetDailyBarsBetween(ctx, input)
+}
+
+// FnoLot is the near-month F&O lot of symbol on date. ok is false when the
+// symbol has no contract expiring that month or no lot sizes are loaded.
+func (s *Service) FnoLot(symbol string, date time.Time) (lot fno.Lot, ok bool) {
+	return s.Lots.Lot(symbol, date)
+}
+
+// InFno reports whether stock was in F&O on date: from the lot sizes when
+// they cover the month, otherwise from the stock's own flag, which only
+// knows about the latest list.
+func (s *Service) InFno(stock repository.Stock, date time.Time) bool {
+	if s.Lots.Covers(date) {
+		_, ok := s.Lots.Lot(stock.Symbol, date)
+		return ok
+	}
+	return stock.Fno
+}
+
+// DividendsBetween returns the dividends of symbol that go ex after after
+// and on or before through.
+func (s *Service) DividendsBetween(symbol string, after, through time.Time) []dividends.Dividend {
+	return s.Dividends.Between(symbol, after, through)
+}
+
+func (s *Service) GetDataFingerprint(ctx context.Context) (repository.GetDataFingerprintRow, error) {
+	return s.Queries.GetDataFingerprint(ctx)
+}
@@ -0,0 +1,353 @@
+// Package sqlitestore keeps the stocks and daily tables in a SQLite file and
+// answers the queries of services.QueryInterface with the same semantics as
+// the Postgres queries in sql/query.sql.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fund-manager/internal/instrument"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/repository"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS stocks (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    symbol TEXT NOT NULL,
+    scripttype TEXT NOT NULL,
+    industry TEXT,
+    isin TEXT,
+    fno INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS daily (
+    stockid TEXT NOT NULL REFERENCES stocks(id),
+    timestamp TEXT NOT NULL,
+    open REAL,
+    high REAL,
+    low REAL,
+    close REAL,
+    volume INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS daily_stockid_timestamp ON daily (stockid, timestamp);
+CREATE INDEX IF NOT EXISTS stocks_symbol ON stocks (symbol);
+`
+
+const dateLayout = "2006-01-02"
+
+type DB struct {
+	db *sql.DB
+}
+
+// Open opens or creates the SQLite file at path.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+	return &DB{db: db}, nil
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Import replaces the contents of the database with the stocks and bars of
+// a price store, e.g. one read by csvstore.
+func (d *DB) Import(ctx context.Context, store *pricestore.Store) error {
+	stocks, err := store.GetStocks(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{"DELETE FROM daily", "DELETE FROM stocks"} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	insertStock, err := tx.PrepareContext(ctx, `INSERT INTO stocks (id, name, symbol, scripttype, industry, isin, fno) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertStock.Close()
+	insertBar, err := tx.PrepareContext(ctx, `INSERT INTO daily (stockid, timestamp, open, high, low, close, volume) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertBar.Close()
+
+	for _, st := range stocks {
+		id := uuid.UUID(st.ID.Bytes).String()
+		if _, err := insertStock.ExecContext(ctx, id, st.Name, st.Symbol, st.Scripttype, nullText(st.Industry), nullText(st.Isin), st.Fno); err != nil {
+			return fmt.Errorf("failed to insert %s: %w", st.Symbol, err)
+		}
+		for _, bar := range store.Bars(st.Symbol) {
+			var volume any
+			if bar.Volume >= 0 {
+				volume = bar.Volume
+			}
+			if _, err := insertBar.ExecContext(ctx, id, bar.Date.Format(dateLayout), nullFloat(bar.Open), nullFloat(bar.High), nullFloat(bar.Low), nullFloat(bar.Close), volume); err != nil {
+				return fmt.Errorf("failed to insert %s bar: %w", st.Symbol, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (d *DB) GetStocks(ctx context.Context) ([]repository.Stock, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT id, name, symbol, scripttype, industry, isin, fno FROM stocks ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []repository.Stock
+	for rows.Next() {
+		var i repository.Stock
+		var id string
+		var industry, isin sql.NullString
+		if err := rows.Scan(&id, &i.Name, &i.Symbol, &i.Scripttype, &industry, &isin, &i.Fno); err != nil {
+			return nil, err
+		}
+		if i.ID, err = parseUUID(id); err != nil {
+			return nil, err
+		}
+		i.Industry = pgtype.Text{String: industry.String, Valid: industry.Valid}
+		i.Isin = pgtype.Text{String: isin.String, Valid: isin.Valid}
+		// the file only holds the stock lists, whose script type says it
+		i.InstrumentType = instrument.OfScriptType(i.Scripttype)
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+// The lookback date is computed in Go because SQLite's date modifiers do
+// not clamp to month ends the way Postgres intervals do.
+const getTopStocksByReturn = `
+WITH stock_returns AS (
+    SELECT
+        s.id, s.name, s.symbol,
+        (SELECT close FROM daily d
+         WHERE d.stockid = s.id AND d.timestamp <= ?1
+           AND d.close IS NOT NULL AND d.close != 0
+         ORDER BY d.timestamp DESC LIMIT 1) AS latest_close,
+        (SELECT close FROM daily d
+         WHERE d.stockid = s.id AND d.timestamp <= ?2
+           AND d.close IS NOT NULL AND d.close != 0
+         ORDER BY d.timestamp DESC LIMIT 1) AS old_close
+    FROM stocks s
+    WHERE s.scripttype IN (SELECT value FROM json_each(?3))
+      AND (json_array_length(?5) = 0 OR lower(s.industry) IN (SELECT value FROM json_each(?5)))
+)
+SELECT id, name, symbol, CAST(ROUND((latest_close - old_close) / old_close * 100) AS INTEGER) AS return_percentage
+FROM stock_returns
+WHERE latest_close IS NOT NULL AND old_close IS NOT NULL
+ORDER BY return_percentage DESC, symbol
+LIMIT ?4`
+
+func (d *DB) GetTopStocksByReturn(ctx context.Context, arg repository.GetTopStocksByReturnParams) ([]repository.GetTopStocksByReturnRow, error) {
+	types, err := json.Marshal(arg.Column3)
+	if err != nil {
+		return nil, err
+	}
+	industries, err := json.Marshal(append([]string{}, arg.Column5...))
+	if err != nil {
+		return nil, err
+	}
+	then := pricestore.SubMonths(arg.Column1.Time, int(arg.Column2))
+	rows, err := d.db.QueryContext(ctx, getTopStocksByReturn,
+		arg.Column1.Time.Format(dateLayout),
+		then.Format(dateLayout),
+		string(types),
+		arg.Limit,
+		string(industries),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []repository.GetTopStocksByReturnRow
+	for rows.Next() {
+		var i repository.GetTopStocksByReturnRow
+		var id string
+		if err := rows.Scan(&id, &i.Name, &i.Symbol, &i.ReturnPercentage); err != nil {
+			return nil, err
+		}
+		if i.ID, err = parseUUID(id); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+func (d *DB) GetLatestClosePrice(ctx context.Context, arg repository.GetLatestClosePriceParams) (pgtype.Numeric, error) {
+	var close float64
+	err := d.db.QueryRowContext(ctx, `
+SELECT d.close
+FROM daily d
+JOIN stocks s ON d.stockid = s.id
+WHERE s.symbol = ? AND d.timestamp <= ?
+AND d.close IS NOT NULL
+ORDER BY d.timestamp DESC
+LIMIT 1`, arg.Symbol, arg.Timestamp.Time.Format(dateLayout)).Scan(&close)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pgtype.Numeric{}, pgx.ErrNoRows
+	}
+	if err != nil {
+		return pgtype.Numeric{}, err
+	}
+	return toNumeric(close), nil
+}
+
+func (d *DB) GetHistoricalStockPrices(ctx context.Context, arg repository.GetHistoricalStockPricesParams) ([]repository.GetHistoricalStockPricesRow, error) {
+	rows, err := d.db.QueryContext(ctx, `
+SELECT d.timestamp, d.close
+FROM daily d
+JOIN stocks s ON d.stockid = s.id
+WHERE s.symbol = ?
+  AND d.timestamp >= ?
+  AND d.timestamp <= ?
+  AND d.close IS NOT NULL
+ORDER BY d.timestamp`, arg.Symbol, arg.Timestamp.Time.Format(dateLayout), arg.Timestamp_2.Time.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []repository.GetHistoricalStockPricesRow
+	for rows.Next() {
+		var i repository.GetHistoricalStockPricesRow
+		var date string
+		var close float64
+		if err := rows.Scan(&date, &close); err != nil {
+			return nil, err
+		}
+		if i.Timestamp, err = parseDate(date); err != nil {
+			return nil, err
+		}
+		i.Close = toNumeric(close)
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+func (d *DB) GetDailyBarsBetween(ctx context.Context, arg repository.GetDailyBarsBetweenParams) ([]repository.GetDailyBarsBetweenRow, error) {
+	symbols, err := json.Marshal(arg.Symbols)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.db.QueryContext(ctx, `
+SELECT s.symbol, d.timestamp, d.open, d.high, d.low, d.close, d.volume
+FROM daily d
+JOIN stocks s ON d.stockid = s.id
+WHERE s.symbol IN (SELECT value FROM json_each(?))
+  AND d.timestamp >= ?
+  AND d.timestamp <= ?
+  AND d.close IS NOT NULL
+ORDER BY s.symbol, d.timestamp`, string(symbols), arg.StartDate.Time.Format(dateLayout), arg.EndDate.Time.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []repository.GetDailyBarsBetweenRow
+	for rows.Next() {
+		var i repository.GetDailyBarsBetweenRow
+		var date string
+		var open, high, low, close sql.NullFloat64
+		var volume sql.NullInt64
+		if err := rows.Scan(&i.Symbol, &date, &open, &high, &low, &close, &volume); err != nil {
+			return nil, err
+		}
+		if i.Timestamp, err = parseDate(date); err != nil {
+			return nil, err
+		}
+		i.Open, i.High, i.Low, i.Close = fromNull(open), fromNull(high), fromNull(low), fromNull(close)
+		i.Volume = pgtype.Int8{Int64: volume.Int64, Valid: volume.Valid}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+func (d *DB) GetDataFingerprint(ctx context.Context) (repository.GetDataFingerprintRow, error) {
+	var i repository.GetDataFingerprintRow
+	var maxDate sql.NullString
+	err := d.db.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(DISTINCT stockid), MAX(timestamp) FROM daily`).Scan(&i.RowCount, &i.StockCount, &maxDate)
+	if err != nil {
+		return i, err
+	}
+	if maxDate.Valid {
+		i.MaxDate, err = parseDate(maxDate.String)
+	}
+	return i, err
+}
+
+func parseUUID(s string) (pgtype.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("invalid stock id %q: %w", s, err)
+	}
+	return pgtype.UUID{Bytes: id, Valid: true}, nil
+}
+
+func parseDate(s string) (pgtype.Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return pgtype.Date{}, fmt.Errorf("invalid date %q: %w", s, err)
+	}
+	return pgtype.Date{Time: t, Valid: true}, nil
+}
+
+func toNumeric(f float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	_ = n.Scan(strconv.FormatFloat(f, 'f', -1, 64))
+	return n
+}
+
+func fromNull(f sql.NullFloat64) pgtype.Numeric {
+	if !f.Valid {
+		return pgtype.Numeric{}
+	}
+	return toNumeric(f.Float64)
+}
+
+func nullFloat(f float64) any {
+	if math.IsNaN(f) {
+		return nil
+	}
+	return f
+}
+
+func nullText(t pgtype.Text) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.String
+}
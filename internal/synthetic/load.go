@@ -0,0 +1,127 @@
+package synthetic
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"fund-manager/internal/repository"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// WriteCSV writes the market in the layout of data/stocks and
+// data/nseDaily/daily, for the csv backend or 'fundmgr ingest'.
+func (m Market) WriteCSV(stocksDir, dailyDir string) error {
+	for _, dir := range []string{stocksDir, dailyDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	byType := make(map[string][][]string)
+	var types []string
+	for _, st := range m.Stocks {
+		if _, ok := byType[st.Scripttype]; !ok {
+			types = append(types, st.Scripttype)
+		}
+		byType[st.Scripttype] = append(byType[st.Scripttype], []string{st.Name, st.Industry.String, st.Symbol, "EQ", st.Isin.String})
+	}
+	for _, t := range types {
+		rows := append([][]string{{"Company Name", "Industry", "Symbol", "Series", "ISIN Code"}}, byType[t]...)
+		if err := writeCSV(filepath.Join(stocksDir, t+".csv"), rows); err != nil {
+			return err
+		}
+	}
+
+	for _, st := range m.Stocks {
+		rows := [][]string{{"Date", "Open", "High", "Low", "Close", "Volume"}}
+		for _, bar := range m.Bars[st.Symbol] {
+			rows = append(rows, []string{
+				bar.Date.Format("2006-01-02"),
+				formatPrice(bar.Open),
+				formatPrice(bar.High),
+				formatPrice(bar.Low),
+				formatPrice(bar.Close),
+				strconv.FormatInt(bar.Volume, 10),
+			})
+		}
+		if err := writeCSV(filepath.Join(dailyDir, strings.ToLower(st.Symbol)+".csv"), rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadPostgres inserts the market's stocks and bars with the same bulk
+// copies the ingest commands use.
+func (m Market) LoadPostgres(ctx context.Context, queries *repository.Queries) error {
+	stocks := make([]repository.BulkCreateStocksParams, 0, len(m.Stocks))
+	for _, st := range m.Stocks {
+		stocks = append(stocks, repository.BulkCreateStocksParams{
+			ID:         st.ID,
+			Name:       st.Name,
+			Symbol:     st.Symbol,
+			Scripttype: st.Scripttype,
+			Industry:   st.Industry,
+			Isin:       st.Isin,
+			Fno:        st.Fno,
+		})
+	}
+	if _, err := queries.BulkCreateStocks(ctx, stocks); err != nil {
+		return fmt.Errorf("failed to insert synthetic stocks: %w", err)
+	}
+
+	for _, st := range m.Stocks {
+		var rows []repository.BulkCreateDailyParams
+		for _, bar := range m.Bars[st.Symbol] {
+			rows = append(rows, repository.BulkCreateDailyParams{
+				ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+				Stockid:   st.ID,
+				Open:      numeric(bar.Open),
+				High:      numeric(bar.High),
+				Low:       numeric(bar.Low),
+				Close:     numeric(bar.Close),
+				Volume:    pgtype.Int8{Int64: bar.Volume, Valid: true},
+				Timestamp: pgtype.Date{Time: bar.Date, Valid: true},
+				AdjClose:  numeric(bar.Close),
+				Source:    pgtype.Text{String: "synthetic", Valid: true},
+			})
+		}
+		if _, err := queries.BulkCreateDaily(ctx, rows); err != nil {
+			return fmt.Errorf("failed to insert bars of %s: %w", st.Symbol, err)
+		}
+	}
+	if err := queries.RefreshMonthlyCloses(ctx); err != nil {
+		return fmt.Errorf("failed to refresh monthly closes: %w", err)
+	}
+	return nil
+}
+
+func writeCSV(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func formatPrice(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func numeric(v float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	_ = n.Scan(formatPrice(v))
+	return n
+}
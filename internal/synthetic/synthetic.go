@@ -0,0 +1,219 @@
+// Package synthetic generates reproducible fake markets for tests and
+// research: the same Config always yields the same stocks, bars and events.
+package synthetic
+
+import (
+	"fmt"
+	"fund-manager/internal/instrument"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/repository"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Config struct {
+	Seed        int64
+	Stocks      int
+	ScriptTypes []string // assigned round robin, "mid" when empty
+	Start, End  time.Time
+
+	// Drift and Volatility are annualised. Each stock draws its own drift
+	// around Drift with DriftSpread and scales Volatility by 0.7 to 1.3.
+	Drift       float64
+	DriftSpread float64
+	Volatility  float64
+	Regimes     []Regime
+
+	Splits     int     // splits injected across the universe, prices not adjusted
+	Delistings int     // stocks whose bars stop for good
+	GapProb    float64 // chance that a stock has no bar on a trading day
+	ZeroPrices int     // bad rows with every price at 0
+}
+
+// Regime replaces the market drift and volatility from its date onwards.
+// Stock-level dispersion still applies on top.
+type Regime struct {
+	From       time.Time
+	Drift      float64
+	Volatility float64
+}
+
+const (
+	EventSplit  = "split"
+	EventDelist = "delist"
+	EventZero   = "zero"
+)
+
+type Event struct {
+	Symbol string
+	Date   time.Time
+	Kind   string
+	Ratio  float64 // split ratio, e.g. 5 for a 1:5 split
+}
+
+type Market struct {
+	Stocks []repository.Stock
+	Bars   map[string][]pricestore.Bar
+	Events []Event
+}
+
+var industries = []string{"Capital Goods", "Financial Services", "Healthcare", "Information Technology", "Consumer Durables", "Chemicals"}
+
+// Generate builds the market described by cfg.
+func Generate(cfg Config) (Market, error) {
+	if cfg.Stocks <= 0 {
+		return Market{}, fmt.Errorf("synthetic: Stocks must be positive")
+	}
+	if !cfg.Start.Before(cfg.End) {
+		return Market{}, fmt.Errorf("synthetic: Start must be before End")
+	}
+	types := cfg.ScriptTypes
+	if len(types) == 0 {
+		types = []string{"mid"}
+	}
+	regimes := append([]Regime(nil), cfg.Regimes...)
+	sort.SliceStable(regimes, func(i, j int) bool { return regimes[i].From.Before(regimes[j].From) })
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	days := tradingDays(cfg.Start, cfg.End)
+	m := Market{Bars: make(map[string][]pricestore.Bar, cfg.Stocks)}
+
+	for i := 0; i < cfg.Stocks; i++ {
+		symbol := fmt.Sprintf("SYN%03d", i+1)
+		m.Stocks = append(m.Stocks, repository.Stock{
+			ID:             pgtype.UUID{Bytes: uuid.NewSHA1(uuid.NameSpaceOID, []byte(symbol)), Valid: true},
+			Name:           fmt.Sprintf("Synthetic %03d Ltd.", i+1),
+			Symbol:         symbol,
+			Scripttype:     types[i%len(types)],
+			Industry:       pgtype.Text{String: industries[i%len(industries)], Valid: true},
+			Isin:           pgtype.Text{String: fmt.Sprintf("INE%05dS010", i+1), Valid: true},
+			InstrumentType: instrument.Equity,
+		})
+	}
+
+	// events are drawn before the paths so that changing one kind of event
+	// does not reshuffle the others
+	pick := func() (int, int) {
+		lo := len(days) / 10
+		return rng.Intn(cfg.Stocks), lo + rng.Intn(len(days)-2*lo)
+	}
+	splits := make(map[[2]int]float64)
+	for n := 0; n < cfg.Splits; n++ {
+		s, d := pick()
+		ratio := []float64{2, 5, 10}[rng.Intn(3)]
+		splits[[2]int{s, d}] = ratio
+		m.Events = append(m.Events, Event{Symbol: m.Stocks[s].Symbol, Date: days[d], Kind: EventSplit, Ratio: ratio})
+	}
+	delistAt := make(map[int]int)
+	for n := 0; n < cfg.Delistings; n++ {
+		s, d := pick()
+		if prev, ok := delistAt[s]; ok && prev <= d {
+			continue
+		}
+		delistAt[s] = d
+	}
+	zeros := make(map[[2]int]bool)
+	for n := 0; n < cfg.ZeroPrices; n++ {
+		s, d := pick()
+		zeros[[2]int{s, d}] = true
+		m.Events = append(m.Events, Event{Symbol: m.Stocks[s].Symbol, Date: days[d], Kind: EventZero})
+	}
+	for s, d := range delistAt {
+		m.Events = append(m.Events, Event{Symbol: m.Stocks[s].Symbol, Date: days[d], Kind: EventDelist})
+	}
+	sort.SliceStable(m.Events, func(i, j int) bool {
+		if !m.Events[i].Date.Equal(m.Events[j].Date) {
+			return m.Events[i].Date.Before(m.Events[j].Date)
+		}
+		return m.Events[i].Symbol < m.Events[j].Symbol
+	})
+
+	const dt = 1.0 / 252
+	for s, stock := range m.Stocks {
+		drift := cfg.Drift + rng.NormFloat64()*cfg.DriftSpread
+		volScale := 0.7 + 0.6*rng.Float64()
+		price := 50 + 950*rng.Float64()
+		baseVolume := 1e4 + 1e6*rng.Float64()
+
+		end := len(days)
+		if d, ok := delistAt[s]; ok {
+			end = d
+		}
+		bars := make([]pricestore.Bar, 0, end)
+		for d := 0; d < end; d++ {
+			mu, sigma := drift, cfg.Volatility*volScale
+			if r := regimeAt(regimes, days[d]); r != nil {
+				mu, sigma = r.Drift+(drift-cfg.Drift), r.Volatility*volScale
+			}
+			if ratio, ok := splits[[2]int{s, d}]; ok {
+				price /= ratio
+				baseVolume *= ratio
+			}
+
+			open := price * math.Exp(rng.NormFloat64()*sigma*math.Sqrt(dt)*0.3)
+			price *= math.Exp((mu-sigma*sigma/2)*dt + sigma*math.Sqrt(dt)*rng.NormFloat64())
+			wick := math.Abs(rng.NormFloat64()) * sigma * math.Sqrt(dt) * 0.5
+			volume := int64(baseVolume * math.Exp(rng.NormFloat64()*0.4))
+			missing := rng.Float64() < cfg.GapProb
+
+			bar := pricestore.Bar{
+				Date:   days[d],
+				Open:   round2(open),
+				High:   round2(math.Max(open, price) * (1 + wick)),
+				Low:    round2(math.Min(open, price) * (1 - wick)),
+				Close:  round2(price),
+				Volume: volume,
+			}
+			if zeros[[2]int{s, d}] {
+				bar = pricestore.Bar{Date: days[d]}
+			} else if missing {
+				continue
+			}
+			bars = append(bars, bar)
+		}
+		m.Bars[stock.Symbol] = bars
+	}
+	return m, nil
+}
+
+// Store loads the market into an in-memory price store.
+func (m Market) Store() *pricestore.Store {
+	b := pricestore.NewBuilder()
+	for _, st := range m.Stocks {
+		b.AddStock(st)
+		for _, bar := range m.Bars[st.Symbol] {
+			_ = b.AddBar(st.Symbol, bar)
+		}
+	}
+	return b.Build()
+}
+
+func regimeAt(regimes []Regime, d time.Time) *Regime {
+	var current *Regime
+	for i := range regimes {
+		if regimes[i].From.After(d) {
+			break
+		}
+		current = &regimes[i]
+	}
+	return current
+}
+
+func tradingDays(start, end time.Time) []time.Time {
+	var days []time.Time
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if wd := d.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
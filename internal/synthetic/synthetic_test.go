@@ -0,0 +1,109 @@
+package synthetic_test
+
+import (
+	"context"
+	"fund-manager/internal/csvstore"
+	"fund-manager/internal/pricestore"
+	"fund-manager/internal/repository"
+	"fund-manager/internal/services"
+	"fund-manager/internal/sqlitestore"
+	"fund-manager/internal/synthetic"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var cfg = synthetic.Config{
+	Seed: 7, Stocks: 12, ScriptTypes: []string{"mid", "small"},
+	Start: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	End:   time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC),
+	Drift: 0.1, DriftSpread: 0.2, Volatility: 0.3,
+	Splits: 3, Delistings: 2, GapProb: 0.05, ZeroPrices: 4,
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	a, err := synthetic.Generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := synthetic.Generate(cfg)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("same seed produced different markets")
+	}
+
+	other := cfg
+	other.Seed++
+	c, _ := synthetic.Generate(other)
+	if reflect.DeepEqual(a.Bars, c.Bars) {
+		t.Fatal("different seeds produced the same bars")
+	}
+
+	kinds := make(map[string]int)
+	for _, e := range a.Events {
+		kinds[e.Kind]++
+	}
+	if kinds[synthetic.EventSplit] == 0 || kinds[synthetic.EventDelist] == 0 || kinds[synthetic.EventZero] == 0 {
+		t.Fatalf("missing injected events: %v", kinds)
+	}
+}
+
+// The in-memory, csv and sqlite backends must rank the same market alike.
+func TestBackendsAgree(t *testing.T) {
+	ctx := context.Background()
+	market, err := synthetic.Generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	stocksDir, dailyDir := filepath.Join(dir, "stocks"), filepath.Join(dir, "daily")
+	if err := market.WriteCSV(stocksDir, dailyDir); err != nil {
+		t.Fatal(err)
+	}
+	csvStore, err := csvstore.Open(stocksDir, dailyDir, "", "", pricestore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sqlitestore.Open(filepath.Join(dir, "market.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Import(ctx, market.Store()); err != nil {
+		t.Fatal(err)
+	}
+
+	backends := map[string]services.QueryInterface{"memory": market.Store(), "csv": csvStore, "sqlite": db}
+	for i, day := range []string{"2021-01-29", "2021-06-30", "2022-03-31", "2022-12-30"} {
+		d, _ := time.Parse("2006-01-02", day)
+		params := repository.GetTopStocksByReturnParams{
+			Column1: pgtype.Timestamp{Time: d, Valid: true},
+			Column2: 12,
+			Column3: []string{"mid", "small"},
+			Limit:   20,
+		}
+		if i%2 == 1 {
+			params.Column5 = []string{"healthcare", "chemicals"}
+		}
+		var want []repository.GetTopStocksByReturnRow
+		for _, name := range []string{"memory", "csv", "sqlite"} {
+			got, err := backends[name].GetTopStocksByReturn(ctx, params)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if want == nil {
+				if len(got) == 0 {
+					t.Fatalf("%s on %s: no stocks ranked", name, day)
+				}
+				want = got
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s on %s: got %v, want %v", name, day, got, want)
+			}
+		}
+	}
+}
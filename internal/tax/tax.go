@@ -0,0 +1,426 @@
+// Package tax works out Indian capital gains on listed equity: tax lots
+// matched first in, first out or by specific identification, short- and
+// long-term gains by holding period, and the tax due each financial year.
+package tax
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	ShortTerm = "STCG"
+	LongTerm  = "LTCG"
+)
+
+// Shares bought before GrandfatherFrom are grandfathered: the cost of a
+// long-term sale is at least their fair market value on GrandfatherDate,
+// capped at the sale price.
+var (
+	GrandfatherDate = time.Date(2018, 1, 31, 0, 0, 0, 0, time.UTC)
+	GrandfatherFrom = time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// losses are carried forward for this many financial years
+const carryYears = 8
+
+// quantities below this are rounding left over from fractional units
+const dust = 1e-9
+
+// Regime is the set of rates in force from a date.
+type Regime struct {
+	From          time.Time
+	STCGPct       float64
+	LTCGPct       float64
+	LTCGExemption float64 // of long-term gains, per financial year
+}
+
+type Rules struct {
+	Regimes        []Regime // by date; sales before the first use the first
+	CessPct        float64  // on the tax
+	LongTermMonths int      // held longer than this is long term
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// IndianEquity is the tax on listed shares with STT paid: sections 111A
+// and 112A, and section 10(38) before April 2018.
+func IndianEquity() Rules {
+	return Rules{
+		Regimes: []Regime{
+			{From: date(2008, 4, 1), STCGPct: 15},
+			{From: date(2018, 4, 1), STCGPct: 15, LTCGPct: 10, LTCGExemption: 100000},
+			{From: date(2024, 7, 23), STCGPct: 20, LTCGPct: 12.5, LTCGExemption: 125000},
+		},
+		CessPct:        4,
+		LongTermMonths: 12,
+	}
+}
+
+// Validate checks the rules and sorts the regimes by date.
+func (r *Rules) Validate() error {
+	if len(r.Regimes) == 0 {
+		return fmt.Errorf("no tax regimes")
+	}
+	for _, g := range r.Regimes {
+		if g.STCGPct < 0 || g.LTCGPct < 0 || g.LTCGExemption < 0 {
+			return fmt.Errorf("tax rates and exemptions must not be negative")
+		}
+	}
+	if r.CessPct < 0 || r.LongTermMonths < 0 {
+		return fmt.Errorf("cess and the long-term holding period must not be negative")
+	}
+	sort.SliceStable(r.Regimes, func(i, j int) bool { return r.Regimes[i].From.Before(r.Regimes[j].From) })
+	return nil
+}
+
+// Regime is the regime in force on date.
+func (r Rules) Regime(date time.Time) Regime {
+	in := r.Regimes[0]
+	for _, g := range r.Regimes {
+		if !g.From.After(date) {
+			in = g
+		}
+	}
+	return in
+}
+
+// Term classifies a sale by how long the shares were held.
+func (r Rules) Term(bought, sold time.Time) string {
+	if sold.After(bought.AddDate(0, r.LongTermMonths, 0)) {
+		return LongTerm
+	}
+	return ShortTerm
+}
+
+// FinancialYear is the calendar year in which date's April to March
+// financial year starts.
+func FinancialYear(date time.Time) int {
+	if date.Month() < time.April {
+		return date.Year() - 1
+	}
+	return date.Year()
+}
+
+// FYLabel names a financial year the way returns do, e.g. FY2024-25.
+func FYLabel(fy int) string {
+	return fmt.Sprintf("FY%d-%02d", fy, (fy+1)%100)
+}
+
+// Lot is shares bought together.
+type Lot struct {
+	ID       string
+	Symbol   string
+	Date     time.Time
+	Quantity float64
+	Cost     float64 // of the quantity left, charges included
+}
+
+// Gain is the sale of shares from one lot.
+type Gain struct {
+	Symbol        string
+	LotID         string
+	Bought        time.Time
+	Sold          time.Time
+	Quantity      float64
+	Cost          float64 // grandfathered where that applies
+	Proceeds      float64 // net of charges
+	Term          string
+	Grandfathered bool
+}
+
+// Amount is the gain, negative for a loss.
+func (g Gain) Amount() float64 {
+	return g.Proceeds - g.Cost
+}
+
+// Ledger keeps the open lots of an account and the gains of its sales.
+// Sales must be entered in date order.
+type Ledger struct {
+	rules Rules
+	fmv   func(symbol string) (float64, bool)
+	lots  map[string][]*Lot
+	gains []Gain
+}
+
+// NewLedger starts an empty ledger. fmv gives a symbol's price on
+// GrandfatherDate; it may be nil when nothing was bought before then.
+func NewLedger(rules Rules, fmv func(symbol string) (float64, bool)) *Ledger {
+	return &Ledger{rules: rules, fmv: fmv, lots: make(map[string][]*Lot)}
+}
+
+func (l *Ledger) Buy(lot Lot) {
+	if lot.Quantity <= 0 {
+		return
+	}
+	l.lots[lot.Symbol] = append(l.lots[lot.Symbol], &lot)
+}
+
+// Sell sells quantity shares for proceeds, net of charges. The shares come
+// from lotIDs in the order given, or first in, first out without them.
+func (l *Ledger) Sell(symbol string, date time.Time, quantity, proceeds float64, lotIDs []string) ([]Gain, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("sale of %g %s: quantity must be positive", quantity, symbol)
+	}
+	open := l.lots[symbol]
+	order := open
+	if len(lotIDs) > 0 {
+		order = nil
+		for _, id := range lotIDs {
+			found := false
+			for _, lot := range open {
+				if lot.ID == id {
+					order, found = append(order, lot), true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("no open %s lot %s", symbol, id)
+			}
+		}
+	}
+	available := 0.0
+	for _, lot := range order {
+		if !lot.Date.After(date) {
+			available += lot.Quantity
+		}
+	}
+	if quantity > available+dust {
+		return nil, fmt.Errorf("sale of %g %s on %s exceeds the %g in its lots", quantity, symbol, date.Format("2006-01-02"), available)
+	}
+
+	var gains []Gain
+	left := quantity
+	for _, lot := range order {
+		if left <= dust {
+			break
+		}
+		if lot.Date.After(date) {
+			continue
+		}
+		q := math.Min(left, lot.Quantity)
+		g := Gain{
+			Symbol:   symbol,
+			LotID:    lot.ID,
+			Bought:   lot.Date,
+			Sold:     date,
+			Quantity: q,
+			Cost:     lot.Cost * q / lot.Quantity,
+			Proceeds: proceeds * q / quantity,
+			Term:     l.rules.Term(lot.Date, date),
+		}
+		if g.Term == LongTerm && lot.Date.Before(GrandfatherFrom) && l.fmv != nil {
+			if price, ok := l.fmv(symbol); ok {
+				if floor := math.Min(price*q, g.Proceeds); floor > g.Cost {
+					g.Cost, g.Grandfathered = floor, true
+				}
+			}
+		}
+		lot.Cost -= lot.Cost * q / lot.Quantity
+		lot.Quantity -= q
+		left -= q
+		gains = append(gains, g)
+	}
+
+	kept := open[:0]
+	for _, lot := range open {
+		if lot.Quantity > dust {
+			kept = append(kept, lot)
+		}
+	}
+	l.lots[symbol] = kept
+	l.gains = append(l.gains, gains...)
+	return gains, nil
+}
+
+// Gains lists every sale's gains in the order they were made.
+func (l *Ledger) Gains() []Gain {
+	return l.gains
+}
+
+// Open lists the lots left, by symbol and date.
+func (l *Ledger) Open() []Lot {
+	var lots []Lot
+	for _, held := range l.lots {
+		for _, lot := range held {
+			lots = append(lots, *lot)
+		}
+	}
+	sort.SliceStable(lots, func(i, j int) bool {
+		if lots[i].Symbol != lots[j].Symbol {
+			return lots[i].Symbol < lots[j].Symbol
+		}
+		return lots[i].Date.Before(lots[j].Date)
+	})
+	return lots
+}
+
+// Year is the capital gains statement of a financial year. Losses are
+// positive amounts.
+type Year struct {
+	FY              int
+	ShortTermGains  float64
+	ShortTermLosses float64
+	LongTermGains   float64
+	LongTermLosses  float64
+	BroughtForward  float64 // losses of earlier years set off
+	Exempt          float64
+	TaxableShort    float64
+	TaxableLong     float64
+	Tax             float64 // cess included
+	CarriedForward  float64 // losses left for later years
+}
+
+// bucket is gains taxed at one rate
+type bucket struct {
+	rate   float64
+	amount float64
+}
+
+type carried struct {
+	fy     int
+	term   string
+	amount float64
+}
+
+// setOff takes loss from the buckets, highest rate first, and returns what
+// is left of it.
+func setOff(loss float64, buckets []*bucket) float64 {
+	for _, b := range buckets {
+		take := math.Min(loss, b.amount)
+		b.amount -= take
+		loss -= take
+	}
+	return loss
+}
+
+// Statement works out each financial year with sales. Short-term losses
+// are set off against short- and then long-term gains, long-term losses
+// against long-term gains only, always against the highest rate first;
+// what is left is carried forward for eight years. Gains that are not
+// taxed take no taxable losses. A long-term loss made under a regime that
+// does not tax long-term gains, as before April 2018, is exempt income: it
+// is set off against untaxed gains only and never carried forward. The exemption of the regime in force at the end of the
+// year applies to long-term gains after set-off.
+func (r Rules) Statement(gains []Gain) []Year {
+	byYear := make(map[int][]Gain)
+	for _, g := range gains {
+		fy := FinancialYear(g.Sold)
+		byYear[fy] = append(byYear[fy], g)
+	}
+	fys := make([]int, 0, len(byYear))
+	for fy := range byYear {
+		fys = append(fys, fy)
+	}
+	sort.Ints(fys)
+
+	var losses []carried
+	years := make([]Year, 0, len(fys))
+	for _, fy := range fys {
+		y := Year{FY: fy}
+		rates := make(map[string]map[float64]*bucket)
+		exemptLosses := 0.0
+		for _, g := range byYear[fy] {
+			amount := g.Amount()
+			regime := r.Regime(g.Sold)
+			rate := regime.STCGPct
+			if g.Term == LongTerm {
+				rate = regime.LTCGPct
+			}
+			switch {
+			case amount < 0 && g.Term == ShortTerm:
+				y.ShortTermLosses -= amount
+			case amount < 0:
+				y.LongTermLosses -= amount
+				if regime.LTCGPct == 0 {
+					exemptLosses -= amount
+				}
+			case g.Term == ShortTerm:
+				y.ShortTermGains += amount
+			default:
+				y.LongTermGains += amount
+			}
+			if amount <= 0 {
+				continue
+			}
+			if rates[g.Term] == nil {
+				rates[g.Term] = make(map[float64]*bucket)
+			}
+			if rates[g.Term][rate] == nil {
+				rates[g.Term][rate] = &bucket{rate: rate}
+			}
+			rates[g.Term][rate].amount += amount
+		}
+		sorted := func(term string) []*bucket {
+			var out []*bucket
+			for _, b := range rates[term] {
+				out = append(out, b)
+			}
+			sort.Slice(out, func(i, j int) bool { return out[i].rate > out[j].rate })
+			return out
+		}
+		short, long := sorted(ShortTerm), sorted(LongTerm)
+		// taxable losses go against taxed gains, exempt ones against the
+		// untaxed
+		var taxedLong, untaxed, both []*bucket
+		for _, b := range long {
+			if b.rate > 0 {
+				taxedLong = append(taxedLong, b)
+			} else {
+				untaxed = append(untaxed, b)
+			}
+		}
+		for _, b := range short {
+			if b.rate > 0 {
+				both = append(both, b)
+			}
+		}
+		both = append(both, taxedLong...)
+
+		var fresh []carried
+		if left := setOff(y.ShortTermLosses, both); left > dust {
+			fresh = append(fresh, carried{fy, ShortTerm, left})
+		}
+		// what is left of an exempt loss lapses
+		setOff(exemptLosses, untaxed)
+		if left := setOff(y.LongTermLosses-exemptLosses, taxedLong); left > dust {
+			fresh = append(fresh, carried{fy, LongTerm, left})
+		}
+		for i := range losses {
+			c := &losses[i]
+			if fy-c.fy > carryYears || c.amount <= 0 {
+				continue
+			}
+			against := taxedLong
+			if c.term == ShortTerm {
+				against = both
+			}
+			left := setOff(c.amount, against)
+			y.BroughtForward += c.amount - left
+			c.amount = left
+		}
+		losses = append(losses, fresh...)
+		for _, c := range losses {
+			if fy+1-c.fy <= carryYears {
+				y.CarriedForward += c.amount
+			}
+		}
+
+		exemption := r.Regime(date(fy+1, time.March, 31)).LTCGExemption
+		y.Exempt = exemption - setOff(exemption, long)
+		for _, b := range short {
+			y.TaxableShort += b.amount
+			y.Tax += b.amount * b.rate / 100
+		}
+		for _, b := range long {
+			y.TaxableLong += b.amount
+			y.Tax += b.amount * b.rate / 100
+		}
+		y.Tax *= 1 + r.CessPct/100
+		years = append(years, y)
+	}
+	return years
+}
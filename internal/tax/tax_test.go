@@ -0,0 +1,174 @@
+package tax
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestTermAndFinancialYear(t *testing.T) {
+	r := IndianEquity()
+	if got := r.Term(day("2023-01-10"), day("2024-01-10")); got != ShortTerm {
+		t.Fatalf("a year to the day is %s", got)
+	}
+	if got := r.Term(day("2023-01-10"), day("2024-01-11")); got != LongTerm {
+		t.Fatalf("a year and a day is %s", got)
+	}
+	if FinancialYear(day("2024-03-31")) != 2023 || FinancialYear(day("2024-04-01")) != 2024 || FYLabel(2099) != "FY2099-00" {
+		t.Fatal("financial years")
+	}
+	if g := r.Regime(day("2024-07-22")); g.STCGPct != 15 {
+		t.Fatalf("regime before the 2024 budget %+v", g)
+	}
+	if g := r.Regime(day("2005-01-01")); g.STCGPct != 15 || g.LTCGPct != 0 {
+		t.Fatalf("regime before the first %+v", g)
+	}
+}
+
+func TestFIFOAndSpecificLots(t *testing.T) {
+	l := NewLedger(IndianEquity(), nil)
+	l.Buy(Lot{ID: "a", Symbol: "X", Date: day("2022-01-03"), Quantity: 10, Cost: 1000})
+	l.Buy(Lot{ID: "b", Symbol: "X", Date: day("2023-06-01"), Quantity: 10, Cost: 1500})
+	l.Buy(Lot{ID: "c", Symbol: "X", Date: day("2023-09-01"), Quantity: 10, Cost: 2000})
+
+	gains, err := l.Sell("X", day("2023-10-02"), 15, 2700, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// first in, first out: all of a, half of b
+	if len(gains) != 2 || gains[0].LotID != "a" || gains[0].Term != LongTerm || !near(gains[0].Amount(), 800) ||
+		gains[1].LotID != "b" || gains[1].Term != ShortTerm || !near(gains[1].Amount(), 900-750) {
+		t.Fatalf("fifo gains %+v", gains)
+	}
+
+	gains, err = l.Sell("X", day("2023-10-03"), 6, 1080, []string{"c", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gains) != 1 || gains[0].LotID != "c" || !near(gains[0].Cost, 1200) {
+		t.Fatalf("specific gains %+v", gains)
+	}
+	open := l.Open()
+	if len(open) != 2 || open[0].ID != "b" || !near(open[0].Quantity, 5) || !near(open[0].Cost, 750) || !near(open[1].Quantity, 4) {
+		t.Fatalf("open lots %+v", open)
+	}
+	if _, err := l.Sell("X", day("2023-10-04"), 5, 900, []string{"a"}); err == nil {
+		t.Fatal("sold from a closed lot")
+	}
+	if _, err := l.Sell("X", day("2023-10-04"), 10, 900, nil); err == nil {
+		t.Fatal("sold more than the lots hold")
+	}
+}
+
+func TestGrandfathering(t *testing.T) {
+	fmv := func(symbol string) (float64, bool) { return 150, true }
+	l := NewLedger(IndianEquity(), fmv)
+	l.Buy(Lot{ID: "a", Symbol: "X", Date: day("2016-05-02"), Quantity: 10, Cost: 1000})
+	l.Buy(Lot{ID: "b", Symbol: "X", Date: day("2016-05-02"), Quantity: 10, Cost: 1000})
+
+	// the fair market value of 1500 replaces the cost of 1000
+	gains, _ := l.Sell("X", day("2019-06-03"), 10, 2000, []string{"a"})
+	if !gains[0].Grandfathered || !near(gains[0].Amount(), 500) {
+		t.Fatalf("gain %+v", gains[0])
+	}
+	// sold below the fair market value: no gain, and no loss either
+	gains, _ = l.Sell("X", day("2019-06-03"), 10, 1200, nil)
+	if !gains[0].Grandfathered || !near(gains[0].Amount(), 0) {
+		t.Fatalf("gain %+v", gains[0])
+	}
+}
+
+func TestStatement(t *testing.T) {
+	r := IndianEquity()
+	gain := func(sold, term string, amount float64) Gain {
+		return Gain{Symbol: "X", Sold: day(sold), Term: term, Cost: 1000000, Proceeds: 1000000 + amount}
+	}
+	years := r.Statement([]Gain{
+		// FY2023-24: a short-term loss eats into the long-term gains, the
+		// exemption takes another lakh
+		gain("2023-05-02", ShortTerm, 100000),
+		gain("2023-06-01", ShortTerm, -150000),
+		gain("2023-08-01", LongTerm, 300000),
+		// FY2024-25: gains on both sides of the July 2024 budget; the
+		// long-term loss goes against the 12.5% gains first
+		gain("2024-05-02", ShortTerm, 50000),
+		gain("2024-05-02", LongTerm, 200000),
+		gain("2024-08-01", ShortTerm, 50000),
+		gain("2024-08-01", LongTerm, 200000),
+		gain("2024-09-02", LongTerm, -50000),
+		// FY2025-26: a loss larger than the gains is carried forward
+		gain("2025-05-02", LongTerm, 100000),
+		gain("2025-06-02", ShortTerm, -300000),
+		// FY2026-27: the carried loss sets off the gain
+		gain("2026-05-04", ShortTerm, 250000),
+	})
+	if len(years) != 4 {
+		t.Fatalf("%d years", len(years))
+	}
+
+	y := years[0]
+	if y.FY != 2023 || !near(y.ShortTermGains, 100000) || !near(y.ShortTermLosses, 150000) || !near(y.LongTermGains, 300000) {
+		t.Fatalf("FY2023-24 %+v", y)
+	}
+	if !near(y.TaxableShort, 0) || !near(y.Exempt, 100000) || !near(y.TaxableLong, 150000) || !near(y.Tax, 150000*0.10*1.04) {
+		t.Fatalf("FY2023-24 %+v", y)
+	}
+
+	y = years[1]
+	// 150000 long-term at 12.5% after the loss, less the 125000 exemption
+	// also from the 12.5% gains; 200000 at 10%
+	wantTax := (50000*0.15 + 50000*0.20 + 25000*0.125 + 200000*0.10) * 1.04
+	if !near(y.Exempt, 125000) || !near(y.TaxableLong, 225000) || !near(y.TaxableShort, 100000) || !near(y.Tax, wantTax) {
+		t.Fatalf("FY2024-25 %+v, want tax %.2f", y, wantTax)
+	}
+
+	y = years[2]
+	if !near(y.Tax, 0) || !near(y.CarriedForward, 200000) {
+		t.Fatalf("FY2025-26 %+v", y)
+	}
+	y = years[3]
+	if !near(y.BroughtForward, 200000) || !near(y.TaxableShort, 50000) || !near(y.Tax, 50000*0.20*1.04) || !near(y.CarriedForward, 0) {
+		t.Fatalf("FY2026-27 %+v", y)
+	}
+}
+
+func TestStatementExemptLosses(t *testing.T) {
+	r := IndianEquity()
+	gain := func(sold, term string, amount float64) Gain {
+		return Gain{Symbol: "X", Sold: day(sold), Term: term, Cost: 1000000, Proceeds: 1000000 + amount}
+	}
+	years := r.Statement([]Gain{
+		// FY2017-18: long-term gains were exempt under section 10(38), and
+		// so were the losses
+		gain("2017-09-01", LongTerm, 50000),
+		gain("2018-03-01", LongTerm, -200000),
+		gain("2018-03-02", ShortTerm, -40000),
+		// FY2018-19: 10% on long-term gains above a lakh
+		gain("2018-06-01", LongTerm, 300000),
+	})
+	if len(years) != 2 {
+		t.Fatalf("%d years", len(years))
+	}
+	y := years[0]
+	if !near(y.LongTermLosses, 200000) || !near(y.Tax, 0) || !near(y.CarriedForward, 40000) {
+		t.Fatalf("FY2017-18 %+v", y)
+	}
+	// only the short-term loss comes forward
+	y = years[1]
+	wantTax := (300000 - 40000 - 100000) * 0.10 * 1.04
+	if !near(y.BroughtForward, 40000) || !near(y.TaxableLong, 160000) || !near(y.Tax, wantTax) {
+		t.Fatalf("FY2018-19 %+v, want tax %.2f", y, wantTax)
+	}
+}
@@ -0,0 +1,124 @@
+package universe
+
+import (
+	"encoding/csv"
+	"fmt"
+	"fund-manager/internal/fno"
+	"fund-manager/internal/instrument"
+	"fund-manager/internal/repository"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ReadStocks parses every <stocksDir>/<scriptType>.csv without a database.
+// IDs are derived from the symbol so they are the same on every read. An
+// empty fnoPath leaves every stock flagged as not in F&O.
+func ReadStocks(stocksDir, fnoPath string) ([]repository.Stock, error) {
+	rows, err := readLists(stocksDir, fnoPath)
+	if err != nil {
+		return nil, err
+	}
+	stocks := make([]repository.Stock, 0, len(rows))
+	for _, r := range rows {
+		stocks = append(stocks, repository.Stock{
+			ID:             pgtype.UUID{Bytes: uuid.NewSHA1(uuid.NameSpaceOID, []byte(r.Symbol)), Valid: true},
+			Name:           r.Name,
+			Symbol:         r.Symbol,
+			Scripttype:     r.Scripttype,
+			Industry:       r.Industry,
+			Isin:           r.Isin,
+			Fno:            r.Fno,
+			InstrumentType: r.InstrumentType,
+		})
+	}
+	return stocks, nil
+}
+
+// readLists parses the lists in file name order.
+func readLists(stocksDir, fnoPath string) ([]repository.BulkCreateStocksParams, error) {
+	files, err := os.ReadDir(stocksDir)
+	if err != nil {
+		return nil, err
+	}
+
+	fnoMap := make(map[string]bool)
+	if fnoPath != "" {
+		if fnoMap, err = loadFnoMap(fnoPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var stocks []repository.BulkCreateStocksParams
+	for _, file := range files {
+		if !file.Type().IsRegular() || !strings.HasSuffix(file.Name(), ".csv") {
+			continue
+		}
+		rows, err := readStockCSV(filepath.Join(stocksDir, file.Name()), fnoMap)
+		if err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, rows...)
+	}
+	return stocks, nil
+}
+
+func readStockCSV(filePath string, fnoMap map[string]bool) ([]repository.BulkCreateStocksParams, error) {
+	filename := filepath.Base(filePath)
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	data, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV %s: %w", filename, err)
+	}
+
+	stockType := strings.Split(filename, ".")[0]
+	var stocks []repository.BulkCreateStocksParams
+
+	for _, record := range data {
+		if len(record) < 5 {
+			continue // skip invalid rows
+		}
+		isFno := fnoMap[strings.TrimSpace(record[2])]
+		// INE for companies, INF for the units of ETFs
+		if !strings.HasPrefix(record[4], "INE") && !strings.HasPrefix(record[4], "INF") {
+			continue // skip invalid ISINs
+		}
+
+		stocks = append(stocks, repository.BulkCreateStocksParams{
+			ID:             pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			Name:           record[0],
+			Symbol:         record[2],
+			Scripttype:     stockType,
+			Industry:       pgtype.Text{String: record[1], Valid: true},
+			Isin:           pgtype.Text{String: record[4], Valid: true},
+			Fno:            isFno,
+			InstrumentType: instrument.OfScriptType(stockType),
+		})
+	}
+	return stocks, nil
+}
+
+// loadFnoMap flags the stocks with an F&O contract in any listed month.
+func loadFnoMap(path string) (map[string]bool, error) {
+	underlyings, err := fno.ReadMarketLots(path)
+	if err != nil {
+		return nil, err
+	}
+	fnoMap := make(map[string]bool)
+	for _, u := range underlyings {
+		if !u.Index && len(u.Lots) > 0 {
+			fnoMap[u.Symbol] = true
+		}
+	}
+	return fnoMap, nil
+}
@@ -0,0 +1,250 @@
+package universe
+
+import (
+	"context"
+	"fmt"
+	"fund-manager/internal/amfi"
+	"fund-manager/internal/instrument"
+	"fund-manager/internal/repository"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Fields recorded in stock_changes.
+const (
+	FieldAdded          = "added"
+	FieldRemoved        = "removed"
+	FieldName           = "name"
+	FieldSymbol         = "symbol"
+	FieldIndustry       = "industry"
+	FieldScriptType     = "scripttype"
+	FieldFno            = "fno"
+	FieldInstrumentType = "instrument_type"
+)
+
+// Change is one difference between the database and the universe lists.
+type Change struct {
+	Symbol string
+	Field  string
+	Old    string
+	New    string
+}
+
+// Diff is what a sync changed, or would change on a dry run.
+type Diff struct {
+	Added        []Change
+	Removed      []Change
+	Reclassified []Change
+	Updated      []Change
+	Unchanged    int
+	Warnings     []string
+}
+
+// Sync matches the stocks of the universe lists to the database by ISIN,
+// inserting new ones, updating name, symbol, industry, scriptType and F&O
+// status of known ones, and marking the ones no longer listed as removed.
+// Removed stocks keep their rows and price history. Every change is recorded
+// in stock_changes dated on. With dryRun nothing is written.
+func Sync(ctx context.Context, pool *pgxpool.Pool, stocksDir, fnoPath string, on time.Time, dryRun bool) (Diff, error) {
+	var diff Diff
+	listed, err := readLists(stocksDir, fnoPath)
+	if err != nil {
+		return diff, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return diff, err
+	}
+	defer tx.Rollback(ctx)
+	q := repository.New(tx)
+
+	stocks, err := q.GetStocks(ctx)
+	if err != nil {
+		return diff, fmt.Errorf("failed to get stocks: %w", err)
+	}
+	// mutual fund schemes and index series are not in the lists; they come
+	// from AMFI and index files
+	var existing []repository.Stock
+	for _, st := range stocks {
+		if st.Scripttype != amfi.ScriptType && st.Scripttype != instrument.ScriptIndex {
+			existing = append(existing, st)
+		}
+	}
+	removedIDs, err := q.GetRemovedStockIDs(ctx)
+	if err != nil {
+		return diff, fmt.Errorf("failed to get removed stocks: %w", err)
+	}
+	removed := make(map[pgtype.UUID]bool, len(removedIDs))
+	for _, id := range removedIDs {
+		removed[id] = true
+	}
+
+	// Earlier blind inserts left duplicate rows; the oldest one is kept up
+	// to date and the others are only reported.
+	sort.SliceStable(existing, func(i, j int) bool { return existing[i].CreatedAt.Time.Before(existing[j].CreatedAt.Time) })
+	byISIN := make(map[string]repository.Stock)
+	bySymbol := make(map[string]repository.Stock)
+	duplicates := make(map[string]int)
+	for _, st := range existing {
+		key := st.Isin.String
+		if !st.Isin.Valid || key == "" {
+			bySymbol[st.Symbol] = st
+			continue
+		}
+		if _, ok := byISIN[key]; ok {
+			duplicates[key]++
+			continue
+		}
+		byISIN[key] = st
+	}
+	for _, isin := range sortedKeys(duplicates) {
+		diff.Warnings = append(diff.Warnings, fmt.Sprintf("%d duplicate rows for ISIN %s (%s), keeping %s", duplicates[isin], isin, byISIN[isin].Symbol, uuid.UUID(byISIN[isin].ID.Bytes)))
+	}
+
+	date := pgtype.Date{Time: on, Valid: true}
+	var changes []repository.BulkCreateStockChangesParams
+	record := func(stockID pgtype.UUID, c Change) {
+		changes = append(changes, repository.BulkCreateStockChangesParams{
+			ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			StockID:   stockID,
+			ChangedOn: date,
+			Field:     c.Field,
+			OldValue:  pgtype.Text{String: c.Old, Valid: c.Field != FieldAdded},
+			NewValue:  pgtype.Text{String: c.New, Valid: c.Field != FieldRemoved},
+		})
+	}
+
+	seen := make(map[pgtype.UUID]bool)
+	listedIn := make(map[string]string)
+	for _, row := range listed {
+		isin := row.Isin.String
+		if first, ok := listedIn[isin]; ok {
+			diff.Warnings = append(diff.Warnings, fmt.Sprintf("%s (%s) is listed in both %s and %s, keeping %s", row.Symbol, isin, first, row.Scripttype, first))
+			continue
+		}
+		listedIn[isin] = row.Scripttype
+
+		st, ok := byISIN[isin]
+		if !ok {
+			st, ok = bySymbol[row.Symbol]
+		}
+		if !ok {
+			row.ID = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+			if _, err := q.CreateStock(ctx, repository.CreateStockParams(row)); err != nil {
+				return diff, fmt.Errorf("failed to insert %s: %w", row.Symbol, err)
+			}
+			c := Change{Symbol: row.Symbol, Field: FieldAdded, New: row.Scripttype}
+			diff.Added = append(diff.Added, c)
+			record(row.ID, c)
+			continue
+		}
+		seen[st.ID] = true
+
+		if removed[st.ID] {
+			c := Change{Symbol: row.Symbol, Field: FieldAdded, New: row.Scripttype}
+			diff.Added = append(diff.Added, c)
+			record(st.ID, c)
+		}
+		fields := []Change{
+			{row.Symbol, FieldName, st.Name, row.Name},
+			{row.Symbol, FieldSymbol, st.Symbol, row.Symbol},
+			{row.Symbol, FieldIndustry, st.Industry.String, row.Industry.String},
+			{row.Symbol, FieldScriptType, st.Scripttype, row.Scripttype},
+			{row.Symbol, FieldFno, strconv.FormatBool(st.Fno), strconv.FormatBool(row.Fno)},
+			{row.Symbol, FieldInstrumentType, st.InstrumentType, row.InstrumentType},
+		}
+		changed := false
+		for _, c := range fields {
+			if c.Old == c.New {
+				continue
+			}
+			changed = true
+			record(st.ID, c)
+			if c.Field == FieldScriptType {
+				diff.Reclassified = append(diff.Reclassified, c)
+			} else {
+				diff.Updated = append(diff.Updated, c)
+			}
+		}
+		if !changed {
+			if !removed[st.ID] {
+				diff.Unchanged++
+			}
+			continue
+		}
+		if err := q.UpdateStock(ctx, repository.UpdateStockParams{
+			ID:             st.ID,
+			Name:           row.Name,
+			Symbol:         row.Symbol,
+			Scripttype:     row.Scripttype,
+			Industry:       row.Industry,
+			Fno:            row.Fno,
+			InstrumentType: row.InstrumentType,
+		}); err != nil {
+			return diff, fmt.Errorf("failed to update %s: %w", row.Symbol, err)
+		}
+	}
+
+	for _, st := range existing {
+		if seen[st.ID] || removed[st.ID] {
+			continue
+		}
+		if st.Isin.Valid && st.Isin.String != "" && byISIN[st.Isin.String].ID != st.ID {
+			continue // a duplicate, reported above
+		}
+		c := Change{Symbol: st.Symbol, Field: FieldRemoved, Old: st.Scripttype}
+		diff.Removed = append(diff.Removed, c)
+		record(st.ID, c)
+	}
+
+	if len(changes) > 0 {
+		if _, err := q.BulkCreateStockChanges(ctx, changes); err != nil {
+			return diff, fmt.Errorf("failed to record changes: %w", err)
+		}
+	}
+	if dryRun {
+		return diff, nil
+	}
+	return diff, tx.Commit(ctx)
+}
+
+// Write prints the diff as a report.
+func (d Diff) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	section := func(title string, changes []Change, format func(Change) string) {
+		if len(changes) == 0 {
+			return
+		}
+		sort.SliceStable(changes, func(i, j int) bool { return changes[i].Symbol < changes[j].Symbol })
+		fmt.Fprintf(tw, "%s (%d):\n", title, len(changes))
+		for _, c := range changes {
+			fmt.Fprintf(tw, "  %s\t%s\n", c.Symbol, format(c))
+		}
+	}
+	section("Added", d.Added, func(c Change) string { return c.New })
+	section("Removed", d.Removed, func(c Change) string { return c.Old })
+	section("Reclassified", d.Reclassified, func(c Change) string { return c.Old + " → " + c.New })
+	section("Updated", d.Updated, func(c Change) string { return fmt.Sprintf("%s: %s → %s", c.Field, c.Old, c.New) })
+	fmt.Fprintf(tw, "Unchanged: %d\n", d.Unchanged)
+	for _, msg := range d.Warnings {
+		fmt.Fprintf(tw, "⚠️ %s\n", msg)
+	}
+	return tw.Flush()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
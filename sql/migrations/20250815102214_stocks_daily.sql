@@ -35,5 +35,6 @@ FOREIGN KEY (stockId) REFERENCES stocks(id);
 
 -- +goose Down
 -- +goose StatementBegin
-SELECT 'down SQL query';
+DROP TABLE daily;
+DROP TABLE stocks;
 -- +goose StatementEnd
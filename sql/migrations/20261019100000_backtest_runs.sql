@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE
+    backtest_runs (
+        id uuid PRIMARY KEY,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        name VARCHAR(100) NOT NULL,
+        run_key VARCHAR(50) NOT NULL,
+        started_at TIMESTAMPTZ NOT NULL,
+        finished_at TIMESTAMPTZ NOT NULL,
+        git_commit VARCHAR(64) NOT NULL,
+        git_dirty boolean NOT NULL,
+        data_rows BIGINT NOT NULL,
+        data_stocks BIGINT NOT NULL,
+        data_max_date DATE,
+        spec TEXT NOT NULL
+    );
+
+CREATE TABLE
+    backtest_params (
+        run_id uuid NOT NULL REFERENCES backtest_runs(id) ON DELETE CASCADE,
+        key VARCHAR(100) NOT NULL,
+        value TEXT NOT NULL,
+        PRIMARY KEY (run_id, key)
+    );
+
+CREATE TABLE
+    backtest_trades (
+        id uuid PRIMARY KEY,
+        run_id uuid NOT NULL REFERENCES backtest_runs(id) ON DELETE CASCADE,
+        symbol VARCHAR(50) NOT NULL,
+        entry_date DATE NOT NULL,
+        exit_date DATE NOT NULL,
+        entry_price DOUBLE PRECISION NOT NULL,
+        exit_price DOUBLE PRECISION NOT NULL,
+        quantity DOUBLE PRECISION NOT NULL,
+        amount_used DOUBLE PRECISION NOT NULL,
+        profit DOUBLE PRECISION NOT NULL,
+        profit_pct DOUBLE PRECISION NOT NULL,
+        days_held INTEGER NOT NULL,
+        max_drawdown DOUBLE PRECISION NOT NULL,
+        costs DOUBLE PRECISION NOT NULL
+    );
+
+CREATE TABLE
+    backtest_equity (
+        run_id uuid NOT NULL REFERENCES backtest_runs(id) ON DELETE CASCADE,
+        date DATE NOT NULL,
+        equity DOUBLE PRECISION NOT NULL,
+        PRIMARY KEY (run_id, date)
+    );
+
+CREATE TABLE
+    backtest_metrics (
+        run_id uuid NOT NULL REFERENCES backtest_runs(id) ON DELETE CASCADE,
+        name VARCHAR(50) NOT NULL,
+        value DOUBLE PRECISION NOT NULL,
+        PRIMARY KEY (run_id, name)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE backtest_metrics;
+DROP TABLE backtest_equity;
+DROP TABLE backtest_trades;
+DROP TABLE backtest_params;
+DROP TABLE backtest_runs;
+-- +goose StatementEnd
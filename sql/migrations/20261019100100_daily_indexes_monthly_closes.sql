@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX daily_stockid_timestamp_idx ON daily (stockId, timestamp);
+
+CREATE INDEX stocks_scripttype_idx ON stocks (scriptType);
+
+-- Last non-zero close of every stock in every calendar month, refreshed by
+-- ingestion. Ranking reads lookback prices from here instead of daily.
+CREATE MATERIALIZED VIEW monthly_closes AS
+SELECT DISTINCT ON (d.stockId, date_trunc('month', d.timestamp))
+    d.stockId,
+    date_trunc('month', d.timestamp)::date AS month,
+    d.timestamp AS last_date,
+    d.close
+FROM daily d
+WHERE d.timestamp IS NOT NULL
+    AND d.close IS NOT NULL
+    AND d.close != 0
+ORDER BY d.stockId, date_trunc('month', d.timestamp), d.timestamp DESC;
+
+CREATE UNIQUE INDEX monthly_closes_stockid_month_idx ON monthly_closes (stockId, month);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP MATERIALIZED VIEW monthly_closes;
+DROP INDEX stocks_scripttype_idx;
+DROP INDEX daily_stockid_timestamp_idx;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE daily ALTER COLUMN volume TYPE BIGINT;
+
+ALTER TABLE daily
+    ADD COLUMN traded_value DECIMAL,
+    ADD COLUMN num_trades BIGINT,
+    ADD COLUMN deliverable_qty BIGINT,
+    ADD COLUMN adj_close DECIMAL,
+    ADD COLUMN source VARCHAR(20);
+
+-- Every row so far came from the daily CSVs, none of them adjusted.
+-- Traded value, trades and delivery were never recorded and stay NULL.
+UPDATE daily SET adj_close = close, source = 'csv';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE daily
+    DROP COLUMN source,
+    DROP COLUMN adj_close,
+    DROP COLUMN deliverable_qty,
+    DROP COLUMN num_trades,
+    DROP COLUMN traded_value;
+
+-- Fails if a volume no longer fits in INTEGER.
+ALTER TABLE daily ALTER COLUMN volume TYPE INTEGER;
+-- +goose StatementEnd
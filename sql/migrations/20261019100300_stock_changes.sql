@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE
+    stock_changes (
+        id uuid PRIMARY KEY,
+        stock_id uuid NOT NULL REFERENCES stocks(id) ON DELETE CASCADE,
+        changed_on DATE NOT NULL,
+        field VARCHAR(20) NOT NULL,
+        old_value TEXT,
+        new_value TEXT,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX stock_changes_stock_id_idx ON stock_changes (stock_id, changed_on);
+
+CREATE INDEX stocks_isin_idx ON stocks (isin);
+
+-- Stocks loaded before the sync existed count as added when first inserted.
+INSERT INTO stock_changes (id, stock_id, changed_on, field, old_value, new_value)
+SELECT gen_random_uuid(), id, COALESCE(created_at::date, CURRENT_DATE), 'added', NULL, scriptType
+FROM stocks;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX stocks_isin_idx;
+DROP TABLE stock_changes;
+-- +goose StatementEnd
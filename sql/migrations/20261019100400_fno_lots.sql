@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE
+    fno_underlyings (
+        symbol VARCHAR(50) PRIMARY KEY,
+        name VARCHAR(100) NOT NULL,
+        is_index boolean NOT NULL,
+        updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    fno_lot_sizes (
+        symbol VARCHAR(50) NOT NULL REFERENCES fno_underlyings(symbol) ON DELETE CASCADE,
+        expiry_month DATE NOT NULL,
+        lot_size INTEGER NOT NULL,
+        recorded_on DATE NOT NULL,
+        PRIMARY KEY (symbol, expiry_month)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE fno_lot_sizes;
+DROP TABLE fno_underlyings;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE
+    portfolio_accounts (
+        id uuid PRIMARY KEY,
+        name VARCHAR(100) NOT NULL UNIQUE,
+        broker VARCHAR(50) NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    portfolio_transactions (
+        id uuid PRIMARY KEY,
+        account_id uuid NOT NULL REFERENCES portfolio_accounts(id) ON DELETE CASCADE,
+        trade_date DATE NOT NULL,
+        kind VARCHAR(10) NOT NULL CHECK (kind IN ('buy', 'sell', 'dividend', 'fee', 'deposit', 'withdrawal')),
+        symbol VARCHAR(50) NOT NULL DEFAULT '',
+        quantity DOUBLE PRECISION NOT NULL DEFAULT 0,
+        price DOUBLE PRECISION NOT NULL DEFAULT 0,
+        amount DOUBLE PRECISION NOT NULL DEFAULT 0,
+        fees DOUBLE PRECISION NOT NULL DEFAULT 0,
+        note TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX portfolio_transactions_account_idx ON portfolio_transactions (account_id, trade_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE portfolio_transactions;
+DROP TABLE portfolio_accounts;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE
+    paper_accounts (
+        id uuid PRIMARY KEY,
+        name VARCHAR(100) NOT NULL UNIQUE,
+        spec TEXT NOT NULL,
+        start_date DATE NOT NULL,
+        initial_capital DOUBLE PRECISION NOT NULL,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    paper_fills (
+        id uuid PRIMARY KEY,
+        account_id uuid NOT NULL REFERENCES paper_accounts(id) ON DELETE CASCADE,
+        trade_date DATE NOT NULL,
+        symbol VARCHAR(50) NOT NULL,
+        side VARCHAR(4) NOT NULL CHECK (side IN ('BUY', 'SELL')),
+        quantity DOUBLE PRECISION NOT NULL,
+        price DOUBLE PRECISION NOT NULL,
+        cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX paper_fills_account_idx ON paper_fills (account_id, trade_date);
+
+CREATE TABLE
+    paper_days (
+        account_id uuid NOT NULL REFERENCES paper_accounts(id) ON DELETE CASCADE,
+        trade_date DATE NOT NULL,
+        cash DOUBLE PRECISION NOT NULL,
+        equity DOUBLE PRECISION NOT NULL,
+        rebalanced BOOLEAN NOT NULL DEFAULT FALSE,
+        PRIMARY KEY (account_id, trade_date)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE paper_days;
+DROP TABLE paper_fills;
+DROP TABLE paper_accounts;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The buy transactions a sale takes its shares from, comma separated;
+-- empty for first in, first out.
+ALTER TABLE portfolio_transactions ADD COLUMN lots TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE portfolio_transactions DROP COLUMN lots;
+-- +goose StatementEnd
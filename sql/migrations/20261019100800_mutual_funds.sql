@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Mutual fund schemes from AMFI's scheme master and NAV reports. Each
+-- scheme is also a stock, symbol MF<code> with script type mf, whose daily
+-- rows are its NAVs.
+CREATE TABLE
+    mf_schemes (
+        code INTEGER PRIMARY KEY,
+        stock_id uuid NOT NULL REFERENCES stocks(id),
+        amc VARCHAR(100) NOT NULL DEFAULT '',
+        name TEXT NOT NULL,
+        scheme_type VARCHAR(50) NOT NULL DEFAULT '',
+        category VARCHAR(100) NOT NULL DEFAULT '',
+        isin_growth VARCHAR(20) NOT NULL DEFAULT '',
+        isin_reinvest VARCHAR(20) NOT NULL DEFAULT '',
+        launch_date DATE,
+        updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX mf_schemes_category_idx ON mf_schemes (category);
+
+CREATE TABLE
+    mf_navs (
+        scheme_code INTEGER NOT NULL REFERENCES mf_schemes(code) ON DELETE CASCADE,
+        nav_date DATE NOT NULL,
+        nav DOUBLE PRECISION NOT NULL,
+        repurchase_price DOUBLE PRECISION,
+        sale_price DOUBLE PRECISION,
+        PRIMARY KEY (scheme_code, nav_date)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mf_navs;
+DROP TABLE mf_schemes;
+-- +goose StatementEnd
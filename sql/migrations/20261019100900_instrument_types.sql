@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- What a stock is, next to the universe its script type ranks it in.
+ALTER TABLE stocks ADD COLUMN instrument_type VARCHAR(20) NOT NULL DEFAULT 'equity'
+    CHECK (instrument_type IN ('equity', 'etf', 'index', 'mf', 'cash'));
+
+UPDATE stocks SET instrument_type = 'etf' WHERE scriptType = 'etf';
+UPDATE stocks SET instrument_type = 'cash' WHERE scriptType = 'cash';
+UPDATE stocks SET instrument_type = 'index' WHERE scriptType = 'index';
+UPDATE stocks SET instrument_type = 'mf' WHERE scriptType = 'mf';
+UPDATE stocks s SET instrument_type = 'cash'
+FROM mf_schemes m
+WHERE m.stock_id = s.id
+  AND (m.category ILIKE '%liquid fund%' OR m.category ILIKE '%overnight fund%' OR m.category ILIKE '%money market fund%');
+
+CREATE INDEX stocks_instrument_type_idx ON stocks (instrument_type);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX stocks_instrument_type_idx;
+ALTER TABLE stocks DROP COLUMN instrument_type;
+-- +goose StatementEnd
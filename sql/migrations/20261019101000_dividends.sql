@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Cash dividends per share, from NSE corporate action files. Dividends
+-- declared for the same ex-date (final and special) are stored summed.
+CREATE TABLE
+    dividends (
+        stock_id uuid NOT NULL REFERENCES stocks(id),
+        ex_date DATE NOT NULL,
+        record_date DATE,
+        amount DOUBLE PRECISION NOT NULL,
+        purpose VARCHAR(200) NOT NULL DEFAULT '',
+        PRIMARY KEY (stock_id, ex_date)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE dividends;
+-- +goose StatementEnd
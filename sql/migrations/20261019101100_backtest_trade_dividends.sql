@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Dividends a trade earned while held, which profit leaves out, and its
+-- return with them. Earlier runs had no dividends.
+ALTER TABLE backtest_trades
+    ADD COLUMN dividends DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN total_return_pct DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+UPDATE backtest_trades SET total_return_pct = profit_pct;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE backtest_trades
+    DROP COLUMN total_return_pct,
+    DROP COLUMN dividends;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The return of each equity point net of contributions and withdrawals;
+-- NULL for runs stored before it was kept.
+ALTER TABLE backtest_equity ADD COLUMN period_return DOUBLE PRECISION;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE backtest_equity DROP COLUMN period_return;
+-- +goose StatementEnd
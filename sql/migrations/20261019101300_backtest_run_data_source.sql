@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- What the data fingerprint counted: the backend, and the prices loaded
+-- for the run when the backend loads only those. Empty for earlier runs.
+ALTER TABLE backtest_runs
+    ADD COLUMN data_backend VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN data_window VARCHAR(500) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE backtest_runs
+    DROP COLUMN data_window,
+    DROP COLUMN data_backend;
+-- +goose StatementEnd
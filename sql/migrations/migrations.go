@@ -0,0 +1,8 @@
+// Package migrations embeds the goose-style SQL migrations so that the
+// binary can migrate a database without the source tree.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
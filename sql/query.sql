@@ -8,70 +8,108 @@ ORDER BY name;
 
 -- name: CreateStock :one
 INSERT INTO stocks (
-    id, name, symbol, scriptType, industry, isin, fno
+    id, name, symbol, scriptType, industry, isin, fno, instrument_type
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8
 )
 RETURNING *;
 
 -- name: BulkCreateStocks :copyfrom
 INSERT INTO stocks (
-    id, name, symbol, scriptType, industry, isin, fno
+    id, name, symbol, scriptType, industry, isin, fno, instrument_type
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: UpdateStock :exec
+UPDATE stocks
+SET name = $2, symbol = $3, scriptType = $4, industry = $5, fno = $6, instrument_type = $7, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: BulkCreateStockChanges :copyfrom
+INSERT INTO stock_changes (
+    id, stock_id, changed_on, field, old_value, new_value
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6
 );
 
+-- name: GetRemovedStockIDs :many
+SELECT c.stock_id
+FROM (
+    SELECT DISTINCT ON (stock_id) stock_id, field
+    FROM stock_changes
+    WHERE field IN ('added', 'removed')
+    ORDER BY stock_id, changed_on DESC, created_at DESC
+) c
+WHERE c.field = 'removed';
+
+-- name: GetStockChanges :many
+SELECT s.symbol, c.changed_on, c.field, c.old_value, c.new_value
+FROM stock_changes c
+JOIN stocks s ON c.stock_id = s.id
+WHERE c.changed_on >= @since::date
+ORDER BY c.changed_on, s.symbol, c.field;
+
 -- name: BulkCreateDaily :copyfrom
 INSERT INTO daily (
-    id, stockId, open, high, low, close, volume, timestamp
+    id, stockId, open, high, low, close, volume, timestamp,
+    traded_value, num_trades, deliverable_qty, adj_close, source
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
 );
 
 -- name: GetTopStocksByReturn :many
-WITH one_year_ago_prices AS (
-    SELECT DISTINCT ON (d.stockid)
-        d.stockid,
-        d.close
-    FROM
-        daily d
-    JOIN stocks s ON d.stockid = s.id
-    WHERE
-        d.timestamp <= ($1::timestamp - make_interval(months => $2::int))
-        AND d.close IS NOT NULL
-        AND d.close != 0
-        AND s.scriptType = ANY($3::text[])
-    ORDER BY d.stockid, d.timestamp DESC
-),
-latest_prices AS (
-    SELECT DISTINCT ON (d.stockid)
-        d.stockid,
-        d.close
-    FROM
-        daily d
-    JOIN stocks s ON d.stockid = s.id
-    WHERE
-        d.timestamp <= $1::timestamp
-        AND d.close IS NOT NULL
-        AND d.close != 0
-        AND s.scriptType = ANY($3::text[])
-    ORDER BY d.stockid, d.timestamp DESC
-),
-stock_returns AS (
-    SELECT
-        l.stockid,
-        ROUND((l.close - o.close) / o.close * 100)::int AS return_percentage
-    FROM latest_prices l
-    JOIN one_year_ago_prices o ON l.stockid = o.stockid
-)
 SELECT
     s.id,
     s.name,
     s.symbol,
-    sr.return_percentage
-FROM stock_returns sr
-JOIN stocks s ON sr.stockid = s.id
-ORDER BY sr.return_percentage DESC
+    ROUND((l.close - o.close) / o.close * 100)::int AS return_percentage
+FROM stocks s
+CROSS JOIN LATERAL (
+    SELECT d.close
+    FROM daily d
+    WHERE
+        d.stockid = s.id
+        AND d.timestamp <= $1::timestamp
+        AND d.close IS NOT NULL
+        AND d.close != 0
+    ORDER BY d.timestamp DESC
+    LIMIT 1
+) l
+CROSS JOIN LATERAL (
+    -- The lookback close is either in the lookback date's own month, read
+    -- from daily, or the last month-end close before it.
+    SELECT c.close
+    FROM (
+        (
+            SELECT d.timestamp, d.close
+            FROM daily d
+            WHERE
+                d.stockid = s.id
+                AND d.timestamp <= ($1::timestamp - make_interval(months => $2::int))
+                AND d.timestamp >= date_trunc('month', $1::timestamp - make_interval(months => $2::int))
+                AND d.close IS NOT NULL
+                AND d.close != 0
+            ORDER BY d.timestamp DESC
+            LIMIT 1
+        )
+        UNION ALL
+        (
+            SELECT m.last_date, m.close
+            FROM monthly_closes m
+            WHERE
+                m.stockid = s.id
+                AND m.month < date_trunc('month', $1::timestamp - make_interval(months => $2::int))
+            ORDER BY m.month DESC
+            LIMIT 1
+        )
+    ) c
+    ORDER BY c.timestamp DESC
+    LIMIT 1
+) o
+WHERE s.scriptType = ANY($3::text[])
+  AND (COALESCE(cardinality($5::text[]), 0) = 0 OR lower(s.industry) = ANY($5::text[]))
+ORDER BY return_percentage DESC, s.symbol
 LIMIT $4;
 
 -- name: GetLatestClosePrice :one
@@ -91,4 +129,239 @@ WHERE s.symbol = $1
   AND d.timestamp >= $2
   AND d.timestamp <= $3
   AND d.close IS NOT NULL
-ORDER BY d.timestamp;
\ No newline at end of file
+ORDER BY d.timestamp;
+
+-- name: GetDailyBarsBetween :many
+SELECT s.symbol, d.timestamp, d.open, d.high, d.low, d.close, d.volume
+FROM daily d
+JOIN stocks s ON d.stockid = s.id
+WHERE s.symbol = ANY(@symbols::text[])
+  AND d.timestamp >= @start_date
+  AND d.timestamp <= @end_date
+  AND d.close IS NOT NULL
+ORDER BY s.symbol, d.timestamp;
+
+-- name: RefreshMonthlyCloses :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY monthly_closes;
+
+-- name: GetDataFingerprint :one
+SELECT
+    COUNT(*)::bigint AS row_count,
+    COUNT(DISTINCT stockid)::bigint AS stock_count,
+    MAX(timestamp)::date AS max_date
+FROM daily;
+
+-- name: CreateBacktestRun :one
+INSERT INTO backtest_runs (
+    id, name, run_key, started_at, finished_at, git_commit, git_dirty,
+    data_rows, data_stocks, data_max_date, spec, data_backend, data_window
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+RETURNING *;
+
+-- name: BulkCreateBacktestParams :copyfrom
+INSERT INTO backtest_params (
+    run_id, key, value
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: BulkCreateBacktestTrades :copyfrom
+INSERT INTO backtest_trades (
+    id, run_id, symbol, entry_date, exit_date, entry_price, exit_price,
+    quantity, amount_used, profit, profit_pct, days_held, max_drawdown, costs,
+    dividends, total_return_pct
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
+);
+
+-- name: BulkCreateBacktestEquity :copyfrom
+INSERT INTO backtest_equity (
+    run_id, date, equity, period_return
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: BulkCreateBacktestMetrics :copyfrom
+INSERT INTO backtest_metrics (
+    run_id, name, value
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: GetBacktestRun :one
+SELECT * FROM backtest_runs
+WHERE id = $1 LIMIT 1;
+
+-- name: ListBacktestRuns :many
+SELECT * FROM backtest_runs
+ORDER BY started_at DESC
+LIMIT $1;
+
+-- name: GetBacktestParams :many
+SELECT key, value FROM backtest_params
+WHERE run_id = $1
+ORDER BY key;
+
+-- name: GetBacktestTrades :many
+SELECT * FROM backtest_trades
+WHERE run_id = $1
+ORDER BY exit_date, symbol;
+
+-- name: GetBacktestEquity :many
+SELECT date, equity, period_return FROM backtest_equity
+WHERE run_id = $1
+ORDER BY date;
+
+-- name: GetBacktestMetrics :many
+SELECT name, value FROM backtest_metrics
+WHERE run_id = $1
+ORDER BY name;
+
+-- name: UpsertFnoUnderlying :exec
+INSERT INTO fno_underlyings (symbol, name, is_index)
+VALUES ($1, $2, $3)
+ON CONFLICT (symbol) DO UPDATE
+SET name = EXCLUDED.name, is_index = EXCLUDED.is_index, updated_at = CURRENT_TIMESTAMP;
+
+-- name: UpsertFnoLotSize :one
+-- Returns the lot size stored before, 0 for a new month.
+WITH previous AS (
+    SELECT lot_size FROM fno_lot_sizes
+    WHERE symbol = @symbol AND expiry_month = @expiry_month
+)
+INSERT INTO fno_lot_sizes (symbol, expiry_month, lot_size, recorded_on)
+VALUES (@symbol, @expiry_month, @lot_size, @recorded_on)
+ON CONFLICT (symbol, expiry_month) DO UPDATE
+SET lot_size = EXCLUDED.lot_size, recorded_on = EXCLUDED.recorded_on
+RETURNING COALESCE((SELECT lot_size FROM previous), 0)::int AS previous_lot_size;
+
+-- name: GetFnoLotSizes :many
+SELECT l.symbol, u.is_index, l.expiry_month, l.lot_size
+FROM fno_lot_sizes l
+JOIN fno_underlyings u ON u.symbol = l.symbol
+ORDER BY l.symbol, l.expiry_month;
+
+-- name: CreatePortfolioAccount :one
+INSERT INTO portfolio_accounts (id, name, broker)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetPortfolioAccountByName :one
+SELECT * FROM portfolio_accounts
+WHERE name = $1 LIMIT 1;
+
+-- name: ListPortfolioAccounts :many
+SELECT * FROM portfolio_accounts
+ORDER BY name;
+
+-- name: CreatePortfolioTransaction :one
+INSERT INTO portfolio_transactions (
+    id, account_id, trade_date, kind, symbol, quantity, price, amount, fees, note, lots
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+RETURNING *;
+
+-- name: GetPortfolioTransactions :many
+SELECT * FROM portfolio_transactions
+WHERE account_id = $1
+ORDER BY trade_date, created_at, id;
+
+-- name: DeletePortfolioTransaction :execrows
+DELETE FROM portfolio_transactions
+WHERE account_id = $1 AND id = $2;
+
+-- name: CreatePaperAccount :one
+INSERT INTO paper_accounts (id, name, spec, start_date, initial_capital)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetPaperAccountByName :one
+SELECT * FROM paper_accounts
+WHERE name = $1 LIMIT 1;
+
+-- name: ListPaperAccounts :many
+SELECT * FROM paper_accounts
+ORDER BY name;
+
+-- name: DeletePaperAccount :execrows
+DELETE FROM paper_accounts
+WHERE name = $1;
+
+-- name: CreatePaperFill :exec
+INSERT INTO paper_fills (id, account_id, trade_date, symbol, side, quantity, price, cost)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetPaperFills :many
+SELECT * FROM paper_fills
+WHERE account_id = $1
+ORDER BY trade_date, created_at, id;
+
+-- name: UpsertPaperDay :exec
+INSERT INTO paper_days (account_id, trade_date, cash, equity, rebalanced)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (account_id, trade_date) DO UPDATE
+SET cash = EXCLUDED.cash, equity = EXCLUDED.equity, rebalanced = EXCLUDED.rebalanced;
+
+-- name: GetPaperDays :many
+SELECT * FROM paper_days
+WHERE account_id = $1
+ORDER BY trade_date;
+
+-- name: GetTradingDays :many
+SELECT DISTINCT d.timestamp::date AS trading_day
+FROM daily d
+WHERE d.timestamp > @after::date
+  AND d.timestamp <= @until::date
+  AND d.close IS NOT NULL
+ORDER BY trading_day;
+
+-- name: GetMfSchemes :many
+SELECT * FROM mf_schemes
+ORDER BY code;
+
+-- name: UpsertMfScheme :exec
+INSERT INTO mf_schemes (
+    code, stock_id, amc, name, scheme_type, category, isin_growth, isin_reinvest, launch_date
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (code) DO UPDATE
+SET amc = EXCLUDED.amc, name = EXCLUDED.name, scheme_type = EXCLUDED.scheme_type,
+    category = EXCLUDED.category, isin_growth = EXCLUDED.isin_growth,
+    isin_reinvest = EXCLUDED.isin_reinvest, launch_date = EXCLUDED.launch_date,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteMfNavs :exec
+DELETE FROM mf_navs
+WHERE scheme_code = ANY(@codes::int[])
+  AND nav_date >= @from_date::date
+  AND nav_date <= @to_date::date;
+
+-- name: BulkCreateMfNavs :copyfrom
+INSERT INTO mf_navs (
+    scheme_code, nav_date, nav, repurchase_price, sale_price
+) VALUES (
+    $1, $2, $3, $4, $5
+);
+
+-- name: DeleteDailyBySource :exec
+DELETE FROM daily
+WHERE stockid = ANY(@stock_ids::uuid[])
+  AND source = @source
+  AND timestamp >= @from_date::date
+  AND timestamp <= @to_date::date;
+
+-- name: GetDividends :many
+SELECT s.symbol, v.ex_date, v.record_date, v.amount
+FROM dividends v
+JOIN stocks s ON s.id = v.stock_id
+ORDER BY s.symbol, v.ex_date;
+
+-- name: UpsertDividend :exec
+INSERT INTO dividends (stock_id, ex_date, record_date, amount, purpose)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (stock_id, ex_date) DO UPDATE
+SET record_date = EXCLUDED.record_date, amount = EXCLUDED.amount, purpose = EXCLUDED.purpose;
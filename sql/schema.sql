@@ -8,7 +8,10 @@ CREATE TABLE
         scriptType VARCHAR(50) NOT NULL,
         industry VARCHAR(50),
         isin VARCHAR(50),
-        fno boolean NOT NULL
+        fno boolean NOT NULL,
+        -- what the stock is, next to the universe its script type ranks it in
+        instrument_type VARCHAR(20) NOT NULL DEFAULT 'equity'
+            CHECK (instrument_type IN ('equity', 'etf', 'index', 'mf', 'cash'))
     );
 
 CREATE TABLE
@@ -21,11 +24,247 @@ CREATE TABLE
         high DECIMAL,
         low DECIMAL,
         close DECIMAL,
-        volume INTEGER,
-        timestamp DATE
+        volume BIGINT,
+        timestamp DATE,
+        traded_value DECIMAL,
+        num_trades BIGINT,
+        deliverable_qty BIGINT,
+        adj_close DECIMAL,
+        source VARCHAR(20)
     );
 
 -- Establish the one-to-many relationship between stocks and daily
 ALTER TABLE daily
 ADD CONSTRAINT fk_daily_stockid
-FOREIGN KEY (stockId) REFERENCES stocks(id);
\ No newline at end of file
+FOREIGN KEY (stockId) REFERENCES stocks(id);
+
+-- Backtest results
+CREATE TABLE
+    backtest_runs (
+        id uuid PRIMARY KEY,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        name VARCHAR(100) NOT NULL,
+        run_key VARCHAR(50) NOT NULL,
+        started_at TIMESTAMPTZ NOT NULL,
+        finished_at TIMESTAMPTZ NOT NULL,
+        git_commit VARCHAR(64) NOT NULL,
+        git_dirty boolean NOT NULL,
+        data_rows BIGINT NOT NULL,
+        data_stocks BIGINT NOT NULL,
+        data_max_date DATE,
+        spec TEXT NOT NULL,
+        data_backend VARCHAR(20) NOT NULL DEFAULT '',
+        data_window VARCHAR(500) NOT NULL DEFAULT ''
+    );
+
+CREATE TABLE
+    backtest_params (
+        run_id uuid NOT NULL REFERENCES backtest_runs(id) ON DELETE CASCADE,
+        key VARCHAR(100) NOT NULL,
+        value TEXT NOT NULL,
+        PRIMARY KEY (run_id, key)
+    );
+
+CREATE TABLE
+    backtest_trades (
+        id uuid PRIMARY KEY,
+        run_id uuid NOT NULL REFERENCES backtest_runs(id) ON DELETE CASCADE,
+        symbol VARCHAR(50) NOT NULL,
+        entry_date DATE NOT NULL,
+        exit_date DATE NOT NULL,
+        entry_price DOUBLE PRECISION NOT NULL,
+        exit_price DOUBLE PRECISION NOT NULL,
+        quantity DOUBLE PRECISION NOT NULL,
+        amount_used DOUBLE PRECISION NOT NULL,
+        profit DOUBLE PRECISION NOT NULL,
+        profit_pct DOUBLE PRECISION NOT NULL,
+        days_held INTEGER NOT NULL,
+        max_drawdown DOUBLE PRECISION NOT NULL,
+        costs DOUBLE PRECISION NOT NULL,
+        dividends DOUBLE PRECISION NOT NULL DEFAULT 0,
+        total_return_pct DOUBLE PRECISION NOT NULL DEFAULT 0
+    );
+
+CREATE TABLE
+    backtest_equity (
+        run_id uuid NOT NULL REFERENCES backtest_runs(id) ON DELETE CASCADE,
+        date DATE NOT NULL,
+        equity DOUBLE PRECISION NOT NULL,
+        period_return DOUBLE PRECISION,
+        PRIMARY KEY (run_id, date)
+    );
+
+CREATE TABLE
+    backtest_metrics (
+        run_id uuid NOT NULL REFERENCES backtest_runs(id) ON DELETE CASCADE,
+        name VARCHAR(50) NOT NULL,
+        value DOUBLE PRECISION NOT NULL,
+        PRIMARY KEY (run_id, name)
+    );
+
+-- Ranking indexes and precomputed month-end closes
+CREATE INDEX daily_stockid_timestamp_idx ON daily (stockId, timestamp);
+
+CREATE INDEX stocks_scripttype_idx ON stocks (scriptType);
+
+-- Last non-zero close of every stock in every calendar month, refreshed by
+-- ingestion. Ranking reads lookback prices from here instead of daily.
+CREATE MATERIALIZED VIEW monthly_closes AS
+SELECT DISTINCT ON (d.stockId, date_trunc('month', d.timestamp))
+    d.stockId,
+    date_trunc('month', d.timestamp)::date AS month,
+    d.timestamp AS last_date,
+    d.close
+FROM daily d
+WHERE d.timestamp IS NOT NULL
+    AND d.close IS NOT NULL
+    AND d.close != 0
+ORDER BY d.stockId, date_trunc('month', d.timestamp), d.timestamp DESC;
+
+CREATE UNIQUE INDEX monthly_closes_stockid_month_idx ON monthly_closes (stockId, month);
+
+-- Universe history: one row per changed field of a stock, plus 'added' and
+-- 'removed' when it enters or leaves the universe lists
+CREATE TABLE
+    stock_changes (
+        id uuid PRIMARY KEY,
+        stock_id uuid NOT NULL REFERENCES stocks(id) ON DELETE CASCADE,
+        changed_on DATE NOT NULL,
+        field VARCHAR(20) NOT NULL,
+        old_value TEXT,
+        new_value TEXT,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX stock_changes_stock_id_idx ON stock_changes (stock_id, changed_on);
+
+CREATE INDEX stocks_isin_idx ON stocks (isin);
+
+CREATE INDEX stocks_instrument_type_idx ON stocks (instrument_type);
+
+-- F&O underlyings and the lot size of each expiry month (first of the month)
+CREATE TABLE
+    fno_underlyings (
+        symbol VARCHAR(50) PRIMARY KEY,
+        name VARCHAR(100) NOT NULL,
+        is_index boolean NOT NULL,
+        updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    fno_lot_sizes (
+        symbol VARCHAR(50) NOT NULL REFERENCES fno_underlyings(symbol) ON DELETE CASCADE,
+        expiry_month DATE NOT NULL,
+        lot_size INTEGER NOT NULL,
+        recorded_on DATE NOT NULL,
+        PRIMARY KEY (symbol, expiry_month)
+    );
+
+-- Live portfolios: an account's holdings and cash are derived from its
+-- transactions. amount is the cash of dividends, fees, deposits and
+-- withdrawals; trades use quantity and price.
+CREATE TABLE
+    portfolio_accounts (
+        id uuid PRIMARY KEY,
+        name VARCHAR(100) NOT NULL UNIQUE,
+        broker VARCHAR(50) NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    portfolio_transactions (
+        id uuid PRIMARY KEY,
+        account_id uuid NOT NULL REFERENCES portfolio_accounts(id) ON DELETE CASCADE,
+        trade_date DATE NOT NULL,
+        kind VARCHAR(10) NOT NULL CHECK (kind IN ('buy', 'sell', 'dividend', 'fee', 'deposit', 'withdrawal')),
+        symbol VARCHAR(50) NOT NULL DEFAULT '',
+        quantity DOUBLE PRECISION NOT NULL DEFAULT 0,
+        price DOUBLE PRECISION NOT NULL DEFAULT 0,
+        amount DOUBLE PRECISION NOT NULL DEFAULT 0,
+        fees DOUBLE PRECISION NOT NULL DEFAULT 0,
+        note TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        -- buy transaction ids a sale takes its shares from, comma
+        -- separated; empty for first in, first out
+        lots TEXT NOT NULL DEFAULT ''
+    );
+
+CREATE INDEX portfolio_transactions_account_idx ON portfolio_transactions (account_id, trade_date);
+
+-- Paper trading: a simulated account that runs a pinned spec forward one
+-- trading day at a time. Holdings and cash follow from the fills; each
+-- processed day records the account's close-of-day value.
+CREATE TABLE
+    paper_accounts (
+        id uuid PRIMARY KEY,
+        name VARCHAR(100) NOT NULL UNIQUE,
+        spec TEXT NOT NULL,
+        start_date DATE NOT NULL,
+        initial_capital DOUBLE PRECISION NOT NULL,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    paper_fills (
+        id uuid PRIMARY KEY,
+        account_id uuid NOT NULL REFERENCES paper_accounts(id) ON DELETE CASCADE,
+        trade_date DATE NOT NULL,
+        symbol VARCHAR(50) NOT NULL,
+        side VARCHAR(4) NOT NULL CHECK (side IN ('BUY', 'SELL')),
+        quantity DOUBLE PRECISION NOT NULL,
+        price DOUBLE PRECISION NOT NULL,
+        cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX paper_fills_account_idx ON paper_fills (account_id, trade_date);
+
+CREATE TABLE
+    paper_days (
+        account_id uuid NOT NULL REFERENCES paper_accounts(id) ON DELETE CASCADE,
+        trade_date DATE NOT NULL,
+        cash DOUBLE PRECISION NOT NULL,
+        equity DOUBLE PRECISION NOT NULL,
+        rebalanced BOOLEAN NOT NULL DEFAULT FALSE,
+        PRIMARY KEY (account_id, trade_date)
+    );
+
+-- Mutual fund schemes from AMFI's scheme master and NAV reports. Each
+-- scheme is also a stock, symbol MF<code> with script type mf, whose daily
+-- rows are its NAVs.
+CREATE TABLE
+    mf_schemes (
+        code INTEGER PRIMARY KEY,
+        stock_id uuid NOT NULL REFERENCES stocks(id),
+        amc VARCHAR(100) NOT NULL DEFAULT '',
+        name TEXT NOT NULL,
+        scheme_type VARCHAR(50) NOT NULL DEFAULT '',
+        category VARCHAR(100) NOT NULL DEFAULT '',
+        isin_growth VARCHAR(20) NOT NULL DEFAULT '',
+        isin_reinvest VARCHAR(20) NOT NULL DEFAULT '',
+        launch_date DATE,
+        updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX mf_schemes_category_idx ON mf_schemes (category);
+
+CREATE TABLE
+    mf_navs (
+        scheme_code INTEGER NOT NULL REFERENCES mf_schemes(code) ON DELETE CASCADE,
+        nav_date DATE NOT NULL,
+        nav DOUBLE PRECISION NOT NULL,
+        repurchase_price DOUBLE PRECISION,
+        sale_price DOUBLE PRECISION,
+        PRIMARY KEY (scheme_code, nav_date)
+    );
+
+-- Cash dividends per share; dividends of one ex-date are summed
+CREATE TABLE
+    dividends (
+        stock_id uuid NOT NULL REFERENCES stocks(id),
+        ex_date DATE NOT NULL,
+        record_date DATE,
+        amount DOUBLE PRECISION NOT NULL,
+        purpose VARCHAR(200) NOT NULL DEFAULT '',
+        PRIMARY KEY (stock_id, ex_date)
+    );